@@ -0,0 +1,247 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/hectorgimenez/d2go/pkg/nip"
+	"github.com/hectorgimenez/koolo/internal/action"
+	"github.com/hectorgimenez/koolo/internal/config"
+	"github.com/hectorgimenez/koolo/internal/ledger"
+	"github.com/hectorgimenez/koolo/internal/maprender"
+	"github.com/hectorgimenez/koolo/internal/pickit"
+	"github.com/hectorgimenez/koolo/internal/utils/winproc"
+)
+
+// Subcommands run instead of the UI, for example "koolo.exe validate". They return the process exit code
+var commands = map[string]func(args []string) int{
+	"validate":   validateCommand,
+	"render-map": renderMapCommand,
+	"pickit-sim": pickitSimCommand,
+}
+
+// runCommand executes the subcommand in args, it returns false if there is no subcommand and the UI should start
+func runCommand(args []string) (int, bool) {
+	if len(args) == 0 {
+		return 0, false
+	}
+
+	cmd, found := commands[args[0]]
+	if !found {
+		return 0, false
+	}
+
+	attachConsole()
+
+	return cmd(args[1:]), true
+}
+
+// attachConsole makes the output visible when launched from a terminal, Koolo is built as a GUI application so it
+// doesn't get a console by default. Redirected output already works and is left untouched
+func attachConsole() {
+	if _, err := os.Stdout.Stat(); err == nil {
+		return
+	}
+
+	if r, _, _ := winproc.AttachConsole.Call(winproc.ATTACH_PARENT_PROCESS); r == 0 {
+		return
+	}
+
+	if out, err := os.OpenFile("CONOUT$", os.O_WRONLY, 0); err == nil {
+		os.Stdout = out
+		os.Stderr = out
+	}
+}
+
+// validateCommand checks all the character configs and prints every error found, usage: koolo.exe validate
+func validateCommand(_ []string) int {
+	results, err := config.CheckFiles("config")
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+
+	if len(results) == 0 {
+		fmt.Println("All character configs are valid")
+		return 0
+	}
+
+	names := make([]string, 0, len(results))
+	for name := range results {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	for _, name := range names {
+		fmt.Printf("%s (config/%s/config.yaml):\n", name, name)
+		for _, e := range results[name] {
+			fmt.Printf("  - %s\n", e)
+		}
+	}
+
+	return 1
+}
+
+// renderMapCommand renders a scene saved from the json format of /api/debug/map, usage:
+// koolo.exe render-map [-scale 2] [-o map.svg] scene.json
+// The format is taken from the output extension, png by default
+func renderMapCommand(args []string) int {
+	fs := flag.NewFlagSet("render-map", flag.ContinueOnError)
+	scale := fs.Int("scale", 2, fmt.Sprintf("pixels per tile, between %d and %d", maprender.MinScale, maprender.MaxScale))
+	output := fs.String("o", "", "output file, .png or .svg, defaults to the scene file name with .png extension")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() != 1 {
+		fmt.Fprintln(os.Stderr, "usage: koolo.exe render-map [-scale 2] [-o map.svg] scene.json")
+		return 2
+	}
+
+	if *scale < maprender.MinScale || *scale > maprender.MaxScale {
+		*scale = min(max(*scale, maprender.MinScale), maprender.MaxScale)
+		fmt.Fprintf(os.Stderr, "scale must be between %d and %d, using %d\n", maprender.MinScale, maprender.MaxScale, *scale)
+	}
+
+	input := fs.Arg(0)
+	if *output == "" {
+		*output = strings.TrimSuffix(input, filepath.Ext(input)) + ".png"
+	}
+
+	format := strings.TrimPrefix(strings.ToLower(filepath.Ext(*output)), ".")
+	if format != maprender.FormatPNG && format != maprender.FormatSVG {
+		fmt.Fprintln(os.Stderr, "output file must have .png or .svg extension")
+		return 2
+	}
+
+	scene, err := maprender.LoadScene(input)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	f, err := os.Create(*output)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	defer f.Close()
+
+	if err = maprender.Render(f, scene, format, *scale); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	fmt.Printf("%s rendered to %s\n", input, *output)
+
+	return 0
+}
+
+// pickitSimCommand evaluates the character pickit rules against recorded items and optionally compares them with
+// another rules directory, usage: koolo.exe pickit-sim -character sorc [-corpus items.jsonl] [-compare dir] [-json]
+// Without corpus the drop ledger is used
+func pickitSimCommand(args []string) int {
+	fs := flag.NewFlagSet("pickit-sim", flag.ContinueOnError)
+	character := fs.String("character", "", "character name, its pickit rules are the ones in use (local or centralized)")
+	corpus := fs.String("corpus", "", "recorded items: drop ledger export, ground item capture or data.Item list (json or jsonl)")
+	compare := fs.String("compare", "", "directory with the candidate .nip files to compare with")
+	gold := fs.Int("gold", math.MaxInt32, "total gold of the character, below minGoldPickupThreshold every magic item is picked up")
+	verbose := fs.Bool("v", false, "print the decision for every item")
+	jsonOutput := fs.Bool("json", false, "print the full report as json")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if *character == "" {
+		fmt.Fprintln(os.Stderr, "usage: koolo.exe pickit-sim -character name [-corpus items.jsonl] [-compare dir] [-gold n] [-v] [-json]")
+		return 2
+	}
+
+	if err := config.Load(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	cfg, found := config.Characters[*character]
+	if !found {
+		fmt.Fprintf(os.Stderr, "character %s not found\n", *character)
+		return 2
+	}
+
+	samples, err := loadPickitCorpus(*corpus)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	var candidate nip.Rules
+	if *compare != "" {
+		if candidate, err = pickit.LoadRules(*compare); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+	}
+
+	report := pickit.Evaluate(cfg.Runtime.Rules, candidate, samples, action.PickitSimulationState(cfg, *gold))
+	if *jsonOutput {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err = enc.Encode(report); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		return 0
+	}
+
+	if *verbose {
+		for _, d := range report.Decisions {
+			fmt.Printf("%s: %s\n", d.Source, formatPickitDecision(d))
+		}
+	}
+	fmt.Printf("%d items, %d picked up, %d stashed\n", report.Summary.Items, report.Summary.Pickup, report.Summary.Stash)
+
+	if report.CandidateSummary == nil {
+		return 0
+	}
+	fmt.Printf("%s: %d picked up, %d stashed, %d changes\n", *compare, report.CandidateSummary.Pickup, report.CandidateSummary.Stash, len(report.Changes))
+	for _, c := range report.Changes {
+		fmt.Printf("  %s: %s -> %s\n", c.Source, formatPickitDecision(c.Old), formatPickitDecision(c.New))
+	}
+
+	return 0
+}
+
+func loadPickitCorpus(path string) ([]pickit.Sample, error) {
+	if path != "" {
+		return pickit.LoadCorpus(path)
+	}
+
+	l, err := ledger.New(config.Koolo.Stats.Directory)
+	if err != nil {
+		return nil, err
+	}
+	defer l.Close()
+
+	// Oldest first, the order matters for maxquantity
+	entries := l.Query(ledger.Filter{})
+	slices.Reverse(entries)
+
+	return pickit.FromLedger(entries), nil
+}
+
+func formatPickitDecision(d pickit.Decision) string {
+	item := fmt.Sprintf("%s [%s]", d.Name, d.Quality)
+	switch {
+	case d.Stash && d.Rule != "":
+		return fmt.Sprintf("%s stash (%s)", item, d.Rule)
+	case d.Stash:
+		return fmt.Sprintf("%s stash (%s)", item, d.StashReason)
+	case d.Pickup:
+		return fmt.Sprintf("%s pickup, not stashed (%s)", item, d.StashReason)
+	}
+
+	return fmt.Sprintf("%s skip (%s)", item, d.PickupReason)
+}
@@ -6,15 +6,24 @@ import (
 	"log"
 	"log/slog"
 	_ "net/http/pprof"
+	"os"
 	"runtime/debug"
+	"strconv"
+	"time"
 
 	sloggger "github.com/hectorgimenez/koolo/cmd/koolo/log"
 	"github.com/hectorgimenez/koolo/internal/bot"
 	"github.com/hectorgimenez/koolo/internal/config"
 	"github.com/hectorgimenez/koolo/internal/event"
+	"github.com/hectorgimenez/koolo/internal/ledger"
+	"github.com/hectorgimenez/koolo/internal/metrics"
+	"github.com/hectorgimenez/koolo/internal/remote/command"
 	"github.com/hectorgimenez/koolo/internal/remote/discord"
+	"github.com/hectorgimenez/koolo/internal/remote/hub"
 	"github.com/hectorgimenez/koolo/internal/remote/telegram"
+	"github.com/hectorgimenez/koolo/internal/remote/webhook"
 	"github.com/hectorgimenez/koolo/internal/server"
+	"github.com/hectorgimenez/koolo/internal/terrorzone"
 	"github.com/hectorgimenez/koolo/internal/utils"
 	"github.com/hectorgimenez/koolo/internal/utils/winproc"
 	"github.com/inkeliz/gowebview"
@@ -22,6 +31,10 @@ import (
 )
 
 func main() {
+	if code, ok := runCommand(os.Args[1:]); ok {
+		os.Exit(code)
+	}
+
 	err := config.Load()
 	if err != nil {
 		utils.ShowDialog("Error loading configuration", err.Error())
@@ -52,10 +65,61 @@ func main() {
 	winproc.SetProcessDpiAware.Call() // Set DPI awareness to be able to read the correct scale and show the window correctly
 
 	eventListener := event.NewListener(logger)
-	manager := bot.NewSupervisorManager(logger, eventListener)
+
+	var statsStore bot.StatsStore
+	if config.Koolo.Stats.Persist {
+		jsonlStore, err := bot.NewJSONLStatsStore(config.Koolo.Stats.Directory)
+		if err != nil {
+			logger.Error("Stats persistence could not be initialized, stats will only be kept in memory", slog.Any("error", err))
+		} else {
+			statsStore = jsonlStore
+			defer jsonlStore.Close()
+		}
+	}
+
+	manager := bot.NewSupervisorManager(logger, eventListener, statsStore)
 	scheduler := bot.NewScheduler(manager, logger)
 	go scheduler.Start()
-	srv, err := server.New(logger, manager)
+	metricsCollector := metrics.NewCollector()
+	eventListener.Subscribe(metricsCollector.Handle, event.WithName("metrics"))
+	dropLedger := ledger.NewInMemory()
+	if config.Koolo.Stats.Persist {
+		persistedLedger, err := ledger.New(config.Koolo.Stats.Directory)
+		if err != nil {
+			logger.Error("Drop ledger could not be opened, drops will only be kept in memory", slog.Any("error", err))
+		} else {
+			dropLedger = persistedLedger
+		}
+	}
+	defer dropLedger.Close()
+	eventListener.Subscribe(dropLedger.Handle, event.WithName("ledger"))
+	tzTracker := terrorzone.NewInMemory()
+	if config.Koolo.Stats.Persist {
+		persistedTracker, err := terrorzone.New(config.Koolo.Stats.Directory)
+		if err != nil {
+			logger.Error("Terror zone tracker could not be opened, terror zones will only be kept in memory", slog.Any("error", err))
+		} else {
+			tzTracker = persistedTracker
+		}
+	}
+	defer tzTracker.Close()
+	eventListener.Subscribe(tzTracker.Handle, event.WithName("terrorzone"))
+
+	// Coordination with other instances, this one can be the hub, connect to a hub or both
+	var coordinationHub *hub.Hub
+	if config.Koolo.Hub.Enabled {
+		coordinationHub = hub.NewHub(config.Koolo.HubInstance(), config.Koolo.Hub.Token, logger)
+		eventListener.Subscribe(coordinationHub.Handle, event.WithName("hub"), event.WithPolicy(event.PolicyDropOldest))
+	}
+	if config.Koolo.Hub.URL != "" {
+		hubClient := hub.NewClient(config.Koolo.Hub.URL, config.Koolo.Hub.Token, config.Koolo.HubInstance(), manager.HubStatus, logger)
+		eventListener.Subscribe(hubClient.Handle, event.WithName("hub-client"), event.WithPolicy(event.PolicyDropOldest))
+		g.Go(func() error {
+			return hubClient.Run(ctx)
+		})
+	}
+
+	srv, err := server.New(logger, manager, scheduler, metricsCollector, dropLedger, tzTracker, coordinationHub)
 	if err != nil {
 		log.Fatalf("Error starting local server: %s", err.Error())
 	}
@@ -63,7 +127,11 @@ func main() {
 	g.Go(func() error {
 		defer cancel()
 		displayScale := config.GetCurrentDisplayScale()
-		w, err := gowebview.New(&gowebview.Config{URL: "http://localhost:8087", WindowConfig: &gowebview.WindowConfig{
+		url := fmt.Sprintf("http://localhost:%d", config.Koolo.ServerPort())
+		if config.Koolo.TLSEnabled() {
+			url = fmt.Sprintf("https://localhost:%d", config.Koolo.ServerPort())
+		}
+		w, err := gowebview.New(&gowebview.Config{URL: url, WindowConfig: &gowebview.WindowConfig{
 			Title: "Koolo",
 			Size: &gowebview.Point{
 				X: int64(1280 * displayScale),
@@ -88,13 +156,14 @@ func main() {
 
 	// Discord Bot initialization
 	if config.Koolo.Discord.Enabled {
-		discordBot, err := discord.NewBot(config.Koolo.Discord.Token, config.Koolo.Discord.ChannelID, manager)
+		discordBot, err := discord.NewBot(config.Koolo.Discord.Token, config.Koolo.Discord.ChannelID, command.NewRegistry(manager, config.Koolo.Discord.BotAdmins), logger)
 		if err != nil {
 			logger.Error("Discord could not been initialized", slog.Any("error", err))
 			return
 		}
 
-		eventListener.Register(discordBot.Handle)
+		// Notifications are not critical, better to lose some of them than slowing down the bots
+		eventListener.Subscribe(discordBot.Handle, event.WithName("discord"), event.WithPolicy(event.PolicyDropOldest))
 		g.Go(func() error {
 			return discordBot.Start(ctx)
 		})
@@ -102,21 +171,42 @@ func main() {
 
 	// Telegram Bot initialization
 	if config.Koolo.Telegram.Enabled {
-		telegramBot, err := telegram.NewBot(config.Koolo.Telegram.Token, config.Koolo.Telegram.ChatID, logger)
+		admins := make([]string, 0, len(config.Koolo.Telegram.BotAdmins))
+		for _, admin := range config.Koolo.Telegram.BotAdmins {
+			admins = append(admins, strconv.FormatInt(admin, 10))
+		}
+		telegramBot, err := telegram.NewBot(config.Koolo.Telegram.Token, config.Koolo.Telegram.ChatID, command.NewRegistry(manager, admins), logger)
 		if err != nil {
 			logger.Error("Telegram could not been initialized", slog.Any("error", err))
 			return
 		}
 
-		eventListener.Register(telegramBot.Handle)
+		eventListener.Subscribe(telegramBot.Handle, event.WithName("telegram"), event.WithPolicy(event.PolicyDropOldest))
 		g.Go(func() error {
 			return telegramBot.Start(ctx)
 		})
 	}
 
+	// Webhook notifications
+	if config.Koolo.Webhook.Enabled {
+		notifier, err := webhook.NewNotifier(config.Koolo.Webhook.Endpoints, logger)
+		if err != nil {
+			logger.Error("Webhooks could not been initialized", slog.Any("error", err))
+		} else {
+			eventListener.Subscribe(notifier.Handle, event.WithName("webhook"), event.WithPolicy(event.PolicyDropOldest))
+		}
+	}
+
+	if config.Koolo.WatchConfig {
+		g.Go(func() error {
+			manager.WatchConfig(ctx, 5*time.Second)
+			return nil
+		})
+	}
+
 	g.Go(func() error {
 		defer cancel()
-		return srv.Listen(8087)
+		return srv.Listen(config.Koolo.ServerPort())
 	})
 
 	g.Go(func() error {
@@ -58,6 +58,21 @@ func ClearThroughPath(pos data.Position, radius int, filter data.MonsterFilter)
 			Y: path[movementDistance-1].Y + ctx.Data.AreaData.OffsetY,
 		}
 
+		// Dangerous monsters close to the next part of the path are engaged too, even if they are out of radius, we
+		// don't want to walk next to them. Same setting the pathfinder uses to go around them
+		if ctx.CharacterCfg.Character.AvoidDangerousMonsters {
+			segment := path[:movementDistance]
+			ctx.Char.KillMonsterSequence(func(d game.Data) (data.UnitID, bool) {
+				for _, m := range d.Monsters.Enemies(filter) {
+					if dangerRadius := pather.DangerRadius(m); dangerRadius > 0 && ctx.Data.AreaData.IsWalkable(m.Position) && segment.Intersects(d, m.Position, dangerRadius) {
+						return m.UnitID, true
+					}
+				}
+
+				return 0, false
+			}, nil)
+		}
+
 		// Let's handle the last movement logic to MoveToCoords function, we will trust the pathfinder because
 		// it can finish within a bigger distance than we expect (because blockers), so we will just check how far
 		// we should be after the latest movement in a theoretical way
@@ -3,6 +3,8 @@ package action
 import (
 	"errors"
 	"fmt"
+	"slices"
+	"time"
 
 	"github.com/hectorgimenez/d2go/pkg/data"
 	"github.com/hectorgimenez/d2go/pkg/data/stat"
@@ -12,11 +14,26 @@ import (
 )
 
 func ClearCurrentLevel(openChests bool, filter data.MonsterFilter) error {
+	return ClearCurrentLevelUntil(time.Time{}, openChests, filter)
+}
+
+// ClearCurrentLevelUntil clears the level room by room, it stops when the deadline is reached, zero means no deadline
+func ClearCurrentLevelUntil(deadline time.Time, openChests bool, filter data.MonsterFilter) error {
 	ctx := context.Get()
 	ctx.SetLastAction("ClearCurrentLevel")
 
+	// No monsters are returned once the deadline is reached, so the room being cleared is left too
+	if !deadline.IsZero() {
+		filter = deadlineFilter(deadline, filter)
+	}
+
 	rooms := ctx.PathFinder.OptimizeRoomsTraverseOrder()
 	for _, r := range rooms {
+		if DeadlineReached(deadline) {
+			ctx.Logger.Debug("Deadline reached, stopping level clear")
+			return nil
+		}
+
 		err := clearRoom(r, filter)
 		if err != nil {
 			ctx.Logger.Warn("Failed to clear room: %v", err)
@@ -28,19 +45,7 @@ func ClearCurrentLevel(openChests bool, filter data.MonsterFilter) error {
 
 		for _, o := range ctx.Data.Objects {
 			if o.IsChest() && o.Selectable && r.IsInside(o.Position) {
-				err = MoveToCoords(o.Position)
-				if err != nil {
-					ctx.Logger.Warn("Failed moving to chest: %v", err)
-					continue
-				}
-				err = InteractObject(o, func() bool {
-					chest, _ := ctx.Data.Objects.FindByID(o.ID)
-					return !chest.Selectable
-				})
-				if err != nil {
-					ctx.Logger.Warn("Failed interacting with chest: %v", err)
-				}
-				utils.Sleep(500) // Add small delay to allow the game to open the chest and drop the content
+				openChest(o)
 			}
 		}
 	}
@@ -48,6 +53,63 @@ func ClearCurrentLevel(openChests bool, filter data.MonsterFilter) error {
 	return nil
 }
 
+// DeadlineReached returns true if the deadline is set and already passed
+func DeadlineReached(deadline time.Time) bool {
+	return !deadline.IsZero() && time.Now().After(deadline)
+}
+
+func deadlineFilter(deadline time.Time, filter data.MonsterFilter) data.MonsterFilter {
+	return func(m data.Monsters) []data.Monster {
+		if DeadlineReached(deadline) {
+			return nil
+		}
+		if filter == nil {
+			return m
+		}
+
+		return filter(m)
+	}
+}
+
+// OpenChestsAround opens the selectable chests close to the player, closest first
+func OpenChestsAround(radius int, superChestsOnly bool) error {
+	ctx := context.Get()
+	ctx.SetLastAction("OpenChestsAround")
+
+	chests := make([]data.Object, 0)
+	for _, o := range ctx.Data.Objects {
+		if o.IsChest() && o.Selectable && (!superChestsOnly || o.IsSuperChest()) && ctx.PathFinder.DistanceFromMe(o.Position) <= radius {
+			chests = append(chests, o)
+		}
+	}
+	slices.SortFunc(chests, func(a, b data.Object) int {
+		return ctx.PathFinder.DistanceFromMe(a.Position) - ctx.PathFinder.DistanceFromMe(b.Position)
+	})
+
+	for _, o := range chests {
+		openChest(o)
+	}
+
+	return nil
+}
+
+func openChest(o data.Object) {
+	ctx := context.Get()
+
+	if err := MoveToCoords(o.Position); err != nil {
+		ctx.Logger.Warn("Failed moving to chest: %v", err)
+		return
+	}
+	err := InteractObject(o, func() bool {
+		chest, _ := ctx.Data.Objects.FindByID(o.ID)
+		return !chest.Selectable
+	})
+	if err != nil {
+		ctx.Logger.Warn("Failed interacting with chest: %v", err)
+	}
+	utils.Sleep(500) // Add small delay to allow the game to open the chest and drop the content
+}
+
 func clearRoom(room data.Room, filter data.MonsterFilter) error {
 	ctx := context.Get()
 	ctx.SetLastAction("clearRoom")
@@ -582,3 +582,15 @@ func getPurchasedItem(ctx *context.Status, purchaseItems []string) data.Item {
 	}
 	return data.Item{}
 }
+
+// RecipeItems returns the items used by the given recipes
+func RecipeItems(enabledRecipes []string) []string {
+	items := make([]string, 0)
+	for _, recipe := range Recipes {
+		if slices.Contains(enabledRecipes, recipe.Name) {
+			items = append(items, recipe.Items...)
+		}
+	}
+
+	return items
+}
@@ -2,12 +2,17 @@ package action
 
 import (
 	"fmt"
+	"math"
+	"slices"
 
 	"github.com/hectorgimenez/d2go/pkg/data"
 	"github.com/hectorgimenez/d2go/pkg/data/item"
+	"github.com/hectorgimenez/d2go/pkg/data/stat"
 	"github.com/hectorgimenez/d2go/pkg/nip"
+	"github.com/hectorgimenez/koolo/internal/config"
 	"github.com/hectorgimenez/koolo/internal/context"
 	"github.com/hectorgimenez/koolo/internal/game"
+	"github.com/hectorgimenez/koolo/internal/pickit"
 	"github.com/hectorgimenez/koolo/internal/ui"
 	"github.com/hectorgimenez/koolo/internal/utils"
 )
@@ -16,27 +21,40 @@ func doesExceedQuantity(rule nip.Rule) bool {
 	ctx := context.Get()
 	ctx.SetLastAction("doesExceedQuantity")
 
-	stashItems := ctx.Data.Inventory.ByLocation(item.LocationStash, item.LocationSharedStash)
+	return pickit.ExceedsQuantity(rule, ctx.Data.Inventory.ByLocation(item.LocationStash, item.LocationSharedStash))
+}
 
-	maxQuantity := rule.MaxQuantity()
-	if maxQuantity == 0 {
-		return false
-	}
+// pickitState collects the character data used by the pickit decisions
+func pickitState(firstRun bool) pickit.State {
+	ctx := context.Get()
 
-	if maxQuantity == 0 {
-		return false
+	gold, _ := ctx.Data.PlayerUnit.FindStat(stat.Gold, 0)
+	_, isLevelingChar := ctx.Char.(context.LevelingCharacter)
+
+	return pickit.State{
+		Stash:                  ctx.Data.Inventory.ByLocation(item.LocationStash, item.LocationSharedStash),
+		Gold:                   gold.Value,
+		MaxGold:                ctx.Data.PlayerUnit.MaxGold(),
+		TotalGold:              ctx.Data.PlayerUnit.TotalPlayerGold(),
+		MinGoldPickupThreshold: ctx.CharacterCfg.Game.MinGoldPickupThreshold,
+		Leveling:               isLevelingChar,
+		QuestRuns:              slices.Contains(ctx.CharacterCfg.Game.Runs, "quests") || slices.Contains(ctx.CharacterCfg.Game.Runs, "leveling"),
+		RecipeItems:            RecipeItems(ctx.CharacterCfg.CubeRecipes.EnabledRecipes),
+		FirstRun:               firstRun,
 	}
+}
 
-	matchedItemsInStash := 0
-
-	for _, stashItem := range stashItems {
-		res, _ := rule.Evaluate(stashItem)
-		if res == nip.RuleResultFullMatch {
-			matchedItemsInStash += 1
-		}
+// PickitSimulationState is the state used by the pickit simulator, there is no game data so the stash starts empty
+// and gold is only known if given
+func PickitSimulationState(cfg *config.CharacterCfg, totalGold int) pickit.State {
+	return pickit.State{
+		MaxGold:                math.MaxInt,
+		TotalGold:              totalGold,
+		MinGoldPickupThreshold: cfg.Game.MinGoldPickupThreshold,
+		Leveling:               len(cfg.Game.Runs) > 0 && cfg.Game.Runs[0] == "leveling",
+		QuestRuns:              slices.Contains(cfg.Game.Runs, "quests") || slices.Contains(cfg.Game.Runs, "leveling"),
+		RecipeItems:            RecipeItems(cfg.CubeRecipes.EnabledRecipes),
 	}
-
-	return matchedItemsInStash >= maxQuantity
 }
 
 func DropMouseItem() {
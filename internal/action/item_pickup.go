@@ -4,15 +4,15 @@ import (
 	"errors"
 	"fmt"
 	"log/slog"
-	"slices"
+	"time"
 
 	"github.com/hectorgimenez/d2go/pkg/data"
 	"github.com/hectorgimenez/d2go/pkg/data/area"
 	"github.com/hectorgimenez/d2go/pkg/data/item"
 	"github.com/hectorgimenez/d2go/pkg/data/stat"
-	"github.com/hectorgimenez/d2go/pkg/nip"
 	"github.com/hectorgimenez/koolo/internal/action/step"
 	"github.com/hectorgimenez/koolo/internal/context"
+	"github.com/hectorgimenez/koolo/internal/pickit"
 )
 
 func itemFitsInventory(i data.Item) bool {
@@ -84,6 +84,9 @@ func ItemPickup(maxDistance int) error {
 
 		err = step.PickupItem(itemToPickup)
 		if err == nil {
+			if ctx.CurrentGame.PickedUpItems != nil {
+				ctx.CurrentGame.PickedUpItems[itemToPickup.UnitID] = context.PickedUpItem{Area: ctx.Data.PlayerUnit.Area, Run: ctx.CurrentGame.CurrentRun, At: time.Now()}
+			}
 			continue // Item picked up successfully, move to next item
 		}
 
@@ -179,49 +182,42 @@ func shouldBePickedUp(i data.Item) bool {
 	ctx := context.Get()
 	ctx.SetLastAction("shouldBePickedUp")
 
-	// Always pickup Runewords and Wirt's Leg
-	if i.IsRuneword || i.Name == "WirtsLeg" {
-		return true
-	}
-
-	// Pick up quest items if we're in leveling or questing run
-	specialRuns := slices.Contains(ctx.CharacterCfg.Game.Runs, "quests") || slices.Contains(ctx.CharacterCfg.Game.Runs, "leveling")
-	if specialRuns {
-		switch i.Name {
-		case "Scrollofinifuss", "LamEsensTome", "HoradricCube", "AmuletoftheViper", "StaffofKings", "HoradricStaff", "AJadeFigurine", "KhalimsEye", "KhalimsBrain", "KhalimsHeart", "KhalimsFlail":
-			return true
-		}
-	}
-	if i.ID == 552 { // Book of Skill doesnt work by name, so we find it by ID
-		return true
-	}
-	// Skip picking up gold if we can not carry more
-	gold, _ := ctx.Data.PlayerUnit.FindStat(stat.Gold, 0)
-	if gold.Value >= ctx.Data.PlayerUnit.MaxGold() && i.Name == "Gold" {
+	pickup, reason, rule := pickit.ShouldPickup(ctx.Data.CharacterCfg.Runtime.Rules, i, pickitState(false))
+	if reason == pickit.ReasonGoldFull {
 		ctx.Logger.Debug("Skipping gold pickup, inventory full")
-		return false
 	}
+	captureGroundItem(i, pickup, reason, rule)
 
-	// Skip picking up gold, usually early game there are small amounts of gold in many places full of enemies, better
-	// stay away of that
-	_, isLevelingChar := ctx.Char.(context.LevelingCharacter)
-	if isLevelingChar && ctx.Data.PlayerUnit.TotalPlayerGold() < 50000 && i.Name != "Gold" {
-		return true
-	}
-
-	// Pickup all magic or superior items if total gold is low, filter will not pass and items will be sold to vendor
-	minGoldPickupThreshold := ctx.CharacterCfg.Game.MinGoldPickupThreshold
-	if ctx.Data.PlayerUnit.TotalPlayerGold() < minGoldPickupThreshold && i.Quality >= item.QualityMagic {
-		return true
-	}
+	return pickup
+}
 
-	// Evaluate item based on NIP rules
-	matchedRule, result := ctx.Data.CharacterCfg.Runtime.Rules.EvaluateAll(i)
-	if result == nip.RuleResultNoMatch {
-		return false
+// captureGroundItem records the pickup decision once per item and game, potions are skipped since they depend on the
+// belt and not on the pickit rules
+func captureGroundItem(i data.Item, pickup bool, reason, rule string) {
+	ctx := context.Get()
+	if ctx.ItemCapture == nil || i.IsPotion() || ctx.CurrentGame.CapturedItems[i.UnitID] {
+		return
 	}
-	if result == nip.RuleResultPartial {
-		return true
+	ctx.CurrentGame.CapturedItems[i.UnitID] = true
+
+	sockets, _ := i.FindStat(stat.NumSockets, 0)
+	gameName := ctx.GameReader.LastGameName()
+	areaName := ctx.Data.PlayerUnit.Area.Area().Name
+
+	err := ctx.ItemCapture.Record(pickit.Capture{
+		Sample:     pickit.Sample{Source: fmt.Sprintf("%s %s (%s)", ctx.Name, gameName, areaName), Item: i},
+		Time:       time.Now(),
+		Supervisor: ctx.Name,
+		Game:       gameName,
+		Area:       areaName,
+		Run:        ctx.CurrentGame.CurrentRun,
+		Quality:    i.Quality.ToString(),
+		Sockets:    sockets.Value,
+		Pickup:     pickup,
+		Reason:     reason,
+		Rule:       rule,
+	})
+	if err != nil {
+		ctx.Logger.Warn("Error recording ground item", slog.String("error", err.Error()))
 	}
-	return !doesExceedQuantity(matchedRule)
 }
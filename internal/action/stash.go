@@ -4,8 +4,6 @@ import (
 	"errors"
 	"fmt"
 	"log/slog"
-	"slices"
-	"strconv"
 
 	"github.com/hectorgimenez/d2go/pkg/data"
 	"github.com/hectorgimenez/d2go/pkg/data/area"
@@ -16,6 +14,7 @@ import (
 	"github.com/hectorgimenez/koolo/internal/context"
 	"github.com/hectorgimenez/koolo/internal/event"
 	"github.com/hectorgimenez/koolo/internal/game"
+	"github.com/hectorgimenez/koolo/internal/pickit"
 	"github.com/hectorgimenez/koolo/internal/ui"
 	"github.com/hectorgimenez/koolo/internal/utils"
 	"github.com/lxn/win"
@@ -176,91 +175,21 @@ func shouldStashIt(i data.Item, firstRun bool) (bool, string, string) {
 	ctx := context.Get()
 	ctx.SetLastStep("shouldStashIt")
 
-	// Don't stash items in protected slots
-	if ctx.CharacterCfg.Inventory.InventoryLock[i.Position.Y][i.Position.X] == 0 {
-		return false, "", ""
-	}
-
-	// Don't stash items from quests during leveling process, it makes things easier to track
-	if _, isLevelingChar := ctx.Char.(context.LevelingCharacter); isLevelingChar && i.IsFromQuest() {
-		return false, "", ""
-	}
-
-	if i.IsRuneword {
-		return true, "Runeword", ""
-	}
-
-	// Stash items that are part of a recipe which are not covered by the NIP rules
-	if shouldKeepRecipeItem(i) {
-		return true, "Item is part of a enabled recipe", ""
-	}
-
-	// Don't stash the Tomes, keys and WirtsLeg
-	if i.Name == item.TomeOfTownPortal || i.Name == item.TomeOfIdentify || i.Name == item.Key || i.Name == "WirtsLeg" {
-		return false, "", ""
-	}
-
 	if i.Position.Y >= len(ctx.CharacterCfg.Inventory.InventoryLock) || i.Position.X >= len(ctx.CharacterCfg.Inventory.InventoryLock[0]) {
 		return false, "", ""
 	}
 
-	if i.Location.LocationType == item.LocationInventory && ctx.CharacterCfg.Inventory.InventoryLock[i.Position.Y][i.Position.X] == 0 || i.IsPotion() {
+	// Don't stash items in protected slots
+	if i.Location.LocationType == item.LocationInventory && ctx.CharacterCfg.Inventory.InventoryLock[i.Position.Y][i.Position.X] == 0 {
 		return false, "", ""
 	}
 
-	// Let's stash everything during first run, we don't want to sell items from the user
-	if firstRun {
-		return true, "FirstRun", ""
-	}
-
-	rule, res := ctx.CharacterCfg.Runtime.Rules.EvaluateAll(i)
-	if res == nip.RuleResultFullMatch && doesExceedQuantity(rule) {
+	stashIt, reason, ruleFile := pickit.ShouldStash(ctx.CharacterCfg.Runtime.Rules, i, pickitState(firstRun))
+	if !stashIt {
 		return false, "", ""
 	}
 
-	// Full rule match
-	if res == nip.RuleResultFullMatch {
-		return true, rule.RawLine, rule.Filename + ":" + strconv.Itoa(rule.LineNumber)
-	}
-	return false, "", ""
-}
-
-func shouldKeepRecipeItem(i data.Item) bool {
-	ctx := context.Get()
-	ctx.SetLastStep("shouldKeepRecipeItem")
-
-	// No items with quality higher than magic can be part of a recipe
-	if i.Quality > item.QualityMagic {
-		return false
-	}
-
-	itemInStashNotMatchingRule := false
-
-	// Check if we already have the item in our stash and if it doesn't match any of our pickit rules
-	for _, it := range ctx.Data.Inventory.ByLocation(item.LocationStash, item.LocationSharedStash) {
-		if it.Name == i.Name {
-			_, res := ctx.CharacterCfg.Runtime.Rules.EvaluateAll(it)
-			if res != nip.RuleResultFullMatch {
-				itemInStashNotMatchingRule = true
-			}
-		}
-	}
-
-	recipeMatch := false
-
-	// Check if the item is part of a recipe and if that recipe is enabled
-	for _, recipe := range Recipes {
-		if slices.Contains(recipe.Items, string(i.Name)) && slices.Contains(ctx.CharacterCfg.CubeRecipes.EnabledRecipes, recipe.Name) {
-			recipeMatch = true
-			break
-		}
-	}
-
-	if recipeMatch && !itemInStashNotMatchingRule {
-		return true
-	}
-
-	return false
+	return true, reason, ruleFile
 }
 
 func stashItemAction(i data.Item, rule string, ruleFile string, skipLogging bool) bool {
@@ -283,7 +212,12 @@ func stashItemAction(i data.Item, rule string, ruleFile string, skipLogging bool
 
 	// Don't log items that we already have in inventory during first run
 	if !skipLogging {
-		event.Send(event.ItemStashed(event.WithScreenshot(ctx.Name, fmt.Sprintf("Item %s [%d] stashed", i.Name, i.Quality), screenshot), data.Drop{Item: i, Rule: rule, RuleFile: ruleFile}))
+		drop := data.Drop{Item: i, Rule: rule, RuleFile: ruleFile}
+		pickedUp, found := ctx.CurrentGame.PickedUpItems[i.UnitID]
+		if found {
+			drop.DropLocation = pickedUp.Area.Area().Name
+		}
+		event.Send(event.ItemStashed(event.WithScreenshot(ctx.Name, fmt.Sprintf("Item %s [%d] stashed", i.Name, i.Quality), screenshot), drop, pickedUp.Run, pickedUp.At))
 	}
 
 	return true
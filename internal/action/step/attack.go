@@ -12,6 +12,7 @@ import (
 	"github.com/hectorgimenez/d2go/pkg/data/stat"
 	"github.com/hectorgimenez/d2go/pkg/utils"
 	"github.com/hectorgimenez/koolo/internal/context"
+	"github.com/hectorgimenez/koolo/internal/event"
 	"github.com/hectorgimenez/koolo/internal/game"
 )
 
@@ -70,9 +71,13 @@ func EnsureAura(aura skill.ID) AttackOption {
 	}
 }
 
+// Companion leaders announce the same target again after this time, so followers keep attacking it
+const announceTargetInterval = 2 * time.Second
+
 // PrimaryAttack initiates a primary (left-click) attack sequence
 func PrimaryAttack(target data.UnitID, numOfAttacks int, standStill bool, opts ...AttackOption) error {
 	ctx := context.Get()
+	announceTarget(ctx, target)
 
 	// Special handling for Berserker characters
 	if berserker, ok := ctx.Char.(interface{ PerformBerserkAttack(data.UnitID) }); ok {
@@ -97,6 +102,8 @@ func PrimaryAttack(target data.UnitID, numOfAttacks int, standStill bool, opts .
 
 // SecondaryAttack initiates a secondary (right-click) attack sequence with a specific skill
 func SecondaryAttack(skill skill.ID, target data.UnitID, numOfAttacks int, opts ...AttackOption) error {
+	announceTarget(context.Get(), target)
+
 	settings := attackSettings{
 		target:           target,
 		numOfAttacks:     numOfAttacks,
@@ -116,6 +123,20 @@ func SecondaryAttack(skill skill.ID, target data.UnitID, numOfAttacks int, opts
 	return attack(settings)
 }
 
+// announceTarget lets the companions know what the leader is attacking
+func announceTarget(ctx *context.Status, target data.UnitID) {
+	if !ctx.CharacterCfg.Companion.Enabled || !ctx.CharacterCfg.Companion.Leader || target == 0 {
+		return
+	}
+	if ctx.CurrentGame.AnnouncedTarget == target && time.Since(ctx.CurrentGame.AnnouncedTargetAt) < announceTargetInterval {
+		return
+	}
+
+	ctx.CurrentGame.AnnouncedTarget = target
+	ctx.CurrentGame.AnnouncedTargetAt = time.Now()
+	event.Send(event.CompanionLeaderAttack(event.Text(ctx.Name, ""), target))
+}
+
 // Helper function to validate if a monster should be targetable
 func isValidEnemy(monster data.Monster, ctx *context.Status) bool {
 	// Special case: Always allow Vizier seal boss even if off grid
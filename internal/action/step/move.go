@@ -10,6 +10,7 @@ import (
 	"github.com/hectorgimenez/d2go/pkg/data/mode"
 	"github.com/hectorgimenez/d2go/pkg/data/skill"
 	"github.com/hectorgimenez/koolo/internal/context"
+	"github.com/hectorgimenez/koolo/internal/pather"
 	"github.com/hectorgimenez/koolo/internal/utils"
 )
 
@@ -75,7 +76,17 @@ func MoveTo(dest data.Position) error {
 			}
 		}
 
-		path, distance, found := ctx.PathFinder.GetPath(dest)
+		// Teleport paths only contain the landing points, so we click on the next one instead of skipping ahead
+		var path pather.Path
+		var distance int
+		found, teleportPath := false, false
+		if ctx.Data.CanTeleport() {
+			path, distance, found = ctx.PathFinder.GetTeleportPath(dest)
+			teleportPath = found
+		}
+		if !found {
+			path, distance, found = ctx.PathFinder.GetPath(dest)
+		}
 		if !found {
 			if ctx.PathFinder.DistanceFromMe(dest) < minDistanceToFinishMoving+5 {
 				return nil
@@ -83,7 +94,7 @@ func MoveTo(dest data.Position) error {
 
 			return errors.New("path could not be calculated. Current area: [" + ctx.Data.PlayerUnit.Area.Area().Name + "]. Trying to path to Destination: [" + fmt.Sprintf("%d,%d", dest.X, dest.Y) + "]")
 		}
-		if distance <= minDistanceToFinishMoving || len(path) <= minDistanceToFinishMoving || len(path) == 0 {
+		if distance <= minDistanceToFinishMoving || len(path) == 0 || (!teleportPath && len(path) <= minDistanceToFinishMoving) {
 			return nil
 		}
 
@@ -120,6 +131,10 @@ func MoveTo(dest data.Position) error {
 
 		previousPosition = ctx.Data.PlayerUnit.Position
 		previousDistance = distance
-		ctx.PathFinder.MoveThroughPath(path, walkDuration)
+		if teleportPath {
+			ctx.PathFinder.TeleportThroughPath(path)
+		} else {
+			ctx.PathFinder.MoveThroughPath(path, walkDuration)
+		}
 	}
 }
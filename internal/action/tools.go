@@ -5,6 +5,7 @@ import (
 	"github.com/hectorgimenez/d2go/pkg/data/npc"
 	"github.com/hectorgimenez/koolo/internal/action/step"
 	"github.com/hectorgimenez/koolo/internal/context"
+	"github.com/hectorgimenez/koolo/internal/event"
 	"github.com/hectorgimenez/koolo/internal/utils"
 )
 
@@ -12,10 +13,13 @@ func OpenTPIfLeader() error {
 	ctx := context.Get()
 	ctx.SetLastAction("OpenTPIfLeader")
 
-	isLeader := ctx.CharacterCfg.Companion.Leader
+	isLeader := ctx.CharacterCfg.Companion.Enabled && ctx.CharacterCfg.Companion.Leader
 
 	if isLeader {
-		return step.OpenPortal()
+		if err := step.OpenPortal(); err != nil {
+			return err
+		}
+		event.Send(event.CompanionRequestedTP(event.Text(ctx.Name, "Portal opened for the companions")))
 	}
 
 	return nil
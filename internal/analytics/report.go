@@ -0,0 +1,90 @@
+// Package analytics measures how each run performs for a character and decides which runs are worth running
+package analytics
+
+import (
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/hectorgimenez/koolo/internal/event"
+)
+
+// Outcome is a single finished run
+type Outcome struct {
+	Run        string
+	Reason     event.FinishReason
+	StartedAt  time.Time
+	FinishedAt time.Time
+	// Rule file of every item picked up during the run and stashed later
+	StashedRules []string
+}
+
+func (o Outcome) Failed() bool {
+	return o.Reason != event.FinishedOK
+}
+
+// RunReport contains the efficiency of a run, durations only take successful runs into account. Items have no price in
+// Koolo, the loot of a run is measured by the amount of stashed items, not by their value
+type RunReport struct {
+	Run         string                     `json:"run"`
+	Runs        int                        `json:"runs"`
+	AvgDuration time.Duration              `json:"avgDuration"`
+	FailureRate float64                    `json:"failureRate"`
+	Failures    map[event.FinishReason]int `json:"failures"`
+	// Amount of stashed items
+	Stashed int `json:"stashed"`
+	// Amount of stashed items by NIP rule file
+	StashedByRule map[string]int `json:"stashedByRule"`
+	// Stashed items per hour spent in the run, failed runs included
+	StashedPerHour float64 `json:"stashedPerHour"`
+}
+
+// Report summarizes the outcomes per run, sorted by run name
+func Report(outcomes []Outcome) []RunReport {
+	byRun := make(map[string]*RunReport)
+	okDuration := make(map[string]time.Duration)
+	totalDuration := make(map[string]time.Duration)
+
+	for _, o := range outcomes {
+		r, found := byRun[o.Run]
+		if !found {
+			r = &RunReport{Run: o.Run, Failures: make(map[event.FinishReason]int), StashedByRule: make(map[string]int)}
+			byRun[o.Run] = r
+		}
+
+		r.Runs++
+		duration := o.FinishedAt.Sub(o.StartedAt)
+		totalDuration[o.Run] += duration
+		if o.Failed() {
+			r.Failures[o.Reason]++
+		} else {
+			okDuration[o.Run] += duration
+		}
+		for _, rule := range o.StashedRules {
+			r.Stashed++
+			r.StashedByRule[rule]++
+		}
+	}
+
+	reports := make([]RunReport, 0, len(byRun))
+	for name, r := range byRun {
+		failures := 0
+		for _, n := range r.Failures {
+			failures += n
+		}
+		r.FailureRate = float64(failures) / float64(r.Runs)
+		if ok := r.Runs - failures; ok > 0 {
+			r.AvgDuration = okDuration[name] / time.Duration(ok)
+		}
+		if hours := totalDuration[name].Hours(); hours > 0 {
+			r.StashedPerHour = float64(r.Stashed) / hours
+		}
+		reports = append(reports, *r)
+	}
+
+	slices.SortFunc(reports, func(a, b RunReport) int {
+		return strings.Compare(a.Run, b.Run)
+	})
+
+	return reports
+}
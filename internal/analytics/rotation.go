@@ -0,0 +1,224 @@
+package analytics
+
+import (
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+)
+
+const (
+	DecisionDropped  = "dropped"
+	DecisionRetest   = "retest"
+	DecisionRestored = "restored"
+	DecisionKeptAll  = "kept all"
+
+	maxDecisions = 100
+)
+
+// RotationSettings controls when a run is dropped, zero values use the defaults
+type RotationSettings struct {
+	// Amount of recent runs used to calculate the failure rate
+	Window int
+	// Runs needed before taking any decision
+	MinRuns int
+	// Failure rate (0-1) at which the run is dropped
+	MaxFailureRate float64
+	// Time the run is dropped before testing it again
+	Cooldown time.Duration
+}
+
+func (s RotationSettings) withDefaults() RotationSettings {
+	if s.Window <= 0 {
+		s.Window = 20
+	}
+	if s.MinRuns <= 0 {
+		s.MinRuns = 5
+	}
+	if s.MaxFailureRate <= 0 {
+		s.MaxFailureRate = 0.5
+	}
+	if s.Cooldown <= 0 {
+		s.Cooldown = time.Hour
+	}
+
+	return s
+}
+
+// Decision is an entry of the rotation log
+type Decision struct {
+	Time   time.Time `json:"time"`
+	Run    string    `json:"run"`
+	Action string    `json:"action"`
+	Reason string    `json:"reason"`
+}
+
+// Rotation reorders runs by failure rate and drops the ones failing too often, a dropped run is tested again after the
+// cooldown: if the first run after the cooldown fails it's dropped again, otherwise it starts from a clean history
+type Rotation struct {
+	settings RotationSettings
+	// Runs never dropped, like leveling
+	protected []string
+
+	mu        sync.Mutex
+	runs      map[string]*rotationState
+	decisions []Decision
+}
+
+type rotationState struct {
+	droppedUntil time.Time
+	retesting    bool
+	// Outcomes before this time are ignored, it's set when the run is tested again
+	since time.Time
+}
+
+func NewRotation(settings RotationSettings, protected ...string) *Rotation {
+	return &Rotation{
+		settings:  settings.withDefaults(),
+		protected: protected,
+		runs:      make(map[string]*rotationState),
+	}
+}
+
+// Configure replaces the settings, the state of every run is kept
+func (r *Rotation) Configure(settings RotationSettings) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.settings = settings.withDefaults()
+}
+
+// Plan returns the runs to execute in the next game. Outcomes are all the known outcomes of the character, oldest
+// first. Runs with less failures go first, runs with the same failure rate keep their order
+func (r *Rotation) Plan(runs []string, outcomes []Outcome, now time.Time) []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	rates := make(map[string]float64, len(runs))
+	planned := make([]string, 0, len(runs))
+	for _, name := range runs {
+		if slices.Contains(r.protected, name) {
+			planned = append(planned, name)
+			continue
+		}
+
+		st, found := r.runs[name]
+		if !found {
+			st = &rotationState{}
+			r.runs[name] = st
+		}
+
+		if now.Before(st.droppedUntil) {
+			continue
+		}
+		if !st.droppedUntil.IsZero() && !st.retesting {
+			st.retesting = true
+			st.since = now
+			r.log(now, name, DecisionRetest, "cooldown finished")
+		}
+
+		recent := recentOutcomes(outcomes, name, st.since, r.settings.Window)
+		if st.retesting && len(recent) > 0 {
+			if recent[len(recent)-1].Failed() {
+				r.drop(st, now, name, fmt.Sprintf("failed again after the cooldown (%s)", recent[len(recent)-1].Reason))
+				continue
+			}
+			st.retesting = false
+			st.droppedUntil = time.Time{}
+			r.log(now, name, DecisionRestored, "succeeded after the cooldown")
+		}
+
+		rate := failureRate(recent)
+		if len(recent) >= r.settings.MinRuns && rate >= r.settings.MaxFailureRate {
+			r.drop(st, now, name, fmt.Sprintf("failed %.0f%% of the last %d runs", rate*100, len(recent)))
+			continue
+		}
+
+		rates[name] = rate
+		planned = append(planned, name)
+	}
+
+	// There is always something to run, even if everything fails
+	if len(planned) == 0 && len(runs) > 0 {
+		r.log(now, "", DecisionKeptAll, "every run is dropped, running all of them")
+		return slices.Clone(runs)
+	}
+
+	slices.SortStableFunc(planned, func(a, b string) int {
+		switch {
+		case rates[a] < rates[b]:
+			return -1
+		case rates[a] > rates[b]:
+			return 1
+		}
+		return 0
+	})
+
+	return planned
+}
+
+func (r *Rotation) drop(st *rotationState, now time.Time, name, reason string) {
+	st.droppedUntil = now.Add(r.settings.Cooldown)
+	st.retesting = false
+	st.since = st.droppedUntil
+	r.log(now, name, DecisionDropped, fmt.Sprintf("%s, retest at %s", reason, st.droppedUntil.Format("15:04")))
+}
+
+func (r *Rotation) log(now time.Time, run, action, reason string) {
+	r.decisions = append(r.decisions, Decision{Time: now, Run: run, Action: action, Reason: reason})
+	if len(r.decisions) > maxDecisions {
+		r.decisions = r.decisions[len(r.decisions)-maxDecisions:]
+	}
+}
+
+// Decisions returns the decision log, oldest first
+func (r *Rotation) Decisions() []Decision {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return slices.Clone(r.decisions)
+}
+
+// Dropped returns the dropped runs and when they will be tested again
+func (r *Rotation) Dropped(now time.Time) map[string]time.Time {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	dropped := make(map[string]time.Time)
+	for name, st := range r.runs {
+		if now.Before(st.droppedUntil) {
+			dropped[name] = st.droppedUntil
+		}
+	}
+
+	return dropped
+}
+
+// recentOutcomes returns the last outcomes of the run started after since, up to window
+func recentOutcomes(outcomes []Outcome, run string, since time.Time, window int) []Outcome {
+	recent := make([]Outcome, 0, window)
+	for i := len(outcomes) - 1; i >= 0 && len(recent) < window; i-- {
+		o := outcomes[i]
+		if o.Run == run && !o.StartedAt.Before(since) {
+			recent = append(recent, o)
+		}
+	}
+	slices.Reverse(recent)
+
+	return recent
+}
+
+func failureRate(outcomes []Outcome) float64 {
+	if len(outcomes) == 0 {
+		return 0
+	}
+
+	failures := 0
+	for _, o := range outcomes {
+		if o.Failed() {
+			failures++
+		}
+	}
+
+	return float64(failures) / float64(len(outcomes))
+}
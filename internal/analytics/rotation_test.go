@@ -0,0 +1,128 @@
+package analytics
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/hectorgimenez/koolo/internal/event"
+)
+
+var start = time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+
+func outcomes(run string, from time.Time, reasons ...event.FinishReason) []Outcome {
+	result := make([]Outcome, 0, len(reasons))
+	for i, r := range reasons {
+		at := from.Add(time.Duration(i) * time.Minute)
+		result = append(result, Outcome{Run: run, Reason: r, StartedAt: at, FinishedAt: at.Add(30 * time.Second)})
+	}
+
+	return result
+}
+
+func TestReport(t *testing.T) {
+	history := outcomes("pindleskin", start, event.FinishedOK, event.FinishedOK, event.FinishedChicken, event.FinishedError)
+	history[0].StashedRules = []string{"unique.nip", "runes.nip"}
+	history[2].StashedRules = []string{"unique.nip"}
+	history = append(history, outcomes("andariel", start, event.FinishedOK)...)
+
+	reports := Report(history)
+	if len(reports) != 2 || reports[0].Run != "andariel" || reports[1].Run != "pindleskin" {
+		t.Fatalf("unexpected reports: %+v", reports)
+	}
+
+	pindle := reports[1]
+	if pindle.Runs != 4 || pindle.FailureRate != 0.5 {
+		t.Errorf("runs = %d, failure rate = %v", pindle.Runs, pindle.FailureRate)
+	}
+	if pindle.Failures[event.FinishedChicken] != 1 || pindle.Failures[event.FinishedError] != 1 {
+		t.Errorf("unexpected failures: %v", pindle.Failures)
+	}
+	if pindle.AvgDuration != 30*time.Second {
+		t.Errorf("avg duration = %s", pindle.AvgDuration)
+	}
+	if pindle.Stashed != 3 || pindle.StashedByRule["unique.nip"] != 2 || pindle.StashedByRule["runes.nip"] != 1 {
+		t.Errorf("stashed = %d, by rule = %v", pindle.Stashed, pindle.StashedByRule)
+	}
+	// 3 items in 2 minutes
+	if pindle.StashedPerHour != 90 {
+		t.Errorf("stashed per hour = %v", pindle.StashedPerHour)
+	}
+}
+
+func TestRotationReordersByFailureRate(t *testing.T) {
+	r := NewRotation(RotationSettings{MinRuns: 5})
+	history := append(
+		outcomes("mephisto", start, event.FinishedOK, event.FinishedChicken, event.FinishedOK),
+		outcomes("andariel", start, event.FinishedOK, event.FinishedOK)...,
+	)
+
+	plan := r.Plan([]string{"mephisto", "andariel", "countess"}, history, start.Add(time.Hour))
+	if !slices.Equal(plan, []string{"andariel", "countess", "mephisto"}) {
+		t.Errorf("unexpected plan: %v", plan)
+	}
+	if len(r.Decisions()) != 0 {
+		t.Errorf("unexpected decisions: %v", r.Decisions())
+	}
+}
+
+func TestRotationDropsAndRetests(t *testing.T) {
+	r := NewRotation(RotationSettings{MinRuns: 3, MaxFailureRate: 0.6, Cooldown: time.Hour}, "leveling")
+	runs := []string{"leveling", "pit", "andariel"}
+	history := append(
+		outcomes("pit", start, event.FinishedChicken, event.FinishedOK, event.FinishedError),
+		outcomes("leveling", start, event.FinishedError, event.FinishedError, event.FinishedError)...,
+	)
+
+	now := start.Add(10 * time.Minute)
+	if plan := r.Plan(runs, history, now); !slices.Equal(plan, []string{"leveling", "andariel"}) {
+		t.Fatalf("pit should be dropped: %v", plan)
+	}
+	if d := r.Dropped(now); len(d) != 1 || !d["pit"].Equal(now.Add(time.Hour)) {
+		t.Errorf("unexpected dropped runs: %v", d)
+	}
+
+	// Still on cooldown
+	if plan := r.Plan(runs, history, now.Add(30*time.Minute)); slices.Contains(plan, "pit") {
+		t.Errorf("pit should still be dropped: %v", plan)
+	}
+
+	// Cooldown finished, old failures are ignored
+	retest := now.Add(time.Hour)
+	if plan := r.Plan(runs, history, retest); !slices.Contains(plan, "pit") {
+		t.Fatalf("pit should be tested again: %v", plan)
+	}
+
+	// The retest fails, dropped again
+	history = append(history, outcomes("pit", retest.Add(time.Minute), event.FinishedChicken)...)
+	if plan := r.Plan(runs, history, retest.Add(5*time.Minute)); slices.Contains(plan, "pit") {
+		t.Fatalf("pit should be dropped after failing the retest: %v", plan)
+	}
+
+	// Second retest succeeds
+	retest = retest.Add(5*time.Minute + time.Hour)
+	r.Plan(runs, history, retest)
+	history = append(history, outcomes("pit", retest.Add(time.Minute), event.FinishedOK)...)
+	if plan := r.Plan(runs, history, retest.Add(5*time.Minute)); !slices.Contains(plan, "pit") {
+		t.Fatalf("pit should be restored: %v", plan)
+	}
+
+	actions := make([]string, 0)
+	for _, d := range r.Decisions() {
+		actions = append(actions, d.Action)
+	}
+	expected := []string{DecisionDropped, DecisionRetest, DecisionDropped, DecisionRetest, DecisionRestored}
+	if !slices.Equal(actions, expected) {
+		t.Errorf("decisions = %v, expected %v", actions, expected)
+	}
+}
+
+func TestRotationKeepsAllWhenEverythingFails(t *testing.T) {
+	r := NewRotation(RotationSettings{MinRuns: 1})
+	history := append(outcomes("pit", start, event.FinishedChicken), outcomes("cows", start, event.FinishedDied)...)
+
+	plan := r.Plan([]string{"pit", "cows"}, history, start.Add(time.Minute))
+	if !slices.Equal(plan, []string{"pit", "cows"}) {
+		t.Errorf("unexpected plan: %v", plan)
+	}
+}
@@ -4,10 +4,12 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"sync/atomic"
 	"time"
 
 	"github.com/hectorgimenez/d2go/pkg/data"
 	"github.com/hectorgimenez/koolo/internal/action"
+	"github.com/hectorgimenez/koolo/internal/config"
 	botCtx "github.com/hectorgimenez/koolo/internal/context"
 	"github.com/hectorgimenez/koolo/internal/event"
 	"github.com/hectorgimenez/koolo/internal/health"
@@ -17,6 +19,8 @@ import (
 
 type Bot struct {
 	ctx *botCtx.Context
+	// Config reloaded while the bot was running, it's applied between games, see ApplyPendingConfig
+	pendingCfg atomic.Pointer[config.CharacterCfg]
 }
 
 func NewBot(ctx *botCtx.Context) *Bot {
@@ -178,6 +182,7 @@ func (b *Bot) Run(ctx context.Context, firstRun bool, runs []run.Run) error {
 
 		b.ctx.AttachRoutine(botCtx.PriorityNormal)
 		for _, r := range runs {
+			b.ctx.CurrentGame.CurrentRun = r.Name()
 			event.Send(event.RunStarted(event.Text(b.ctx.Name, fmt.Sprintf("Starting run: %s", r.Name())), r.Name()))
 			err = action.PreRun(firstRun)
 			if err != nil {
@@ -224,3 +229,29 @@ func (b *Bot) Stop() {
 	b.ctx.SwitchPriority(botCtx.PriorityStop)
 	b.ctx.Detach()
 }
+
+// QueueConfig stores the config to be applied at the next safe point, replacing any config still pending
+func (b *Bot) QueueConfig(cfg *config.CharacterCfg) {
+	b.pendingCfg.Store(cfg)
+}
+
+// PendingConfig returns if there is a reloaded config waiting to be applied
+func (b *Bot) PendingConfig() bool {
+	return b.pendingCfg.Load() != nil
+}
+
+// ApplyPendingConfig replaces the running config with the pending one, it must be called when there is no game
+// running, the config is shared by pointer with all the bot routines and it's not safe to change it while they run
+func (b *Bot) ApplyPendingConfig() {
+	cfg := b.pendingCfg.Swap(nil)
+	if cfg == nil {
+		return
+	}
+
+	// Values not coming from the config file
+	cfg.Game.PublicGameCounter = b.ctx.CharacterCfg.Game.PublicGameCounter
+	cfg.Runtime.Drops = b.ctx.CharacterCfg.Runtime.Drops
+
+	*b.ctx.CharacterCfg = *cfg
+	b.ctx.Logger.Info("New configuration applied")
+}
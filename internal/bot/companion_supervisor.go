@@ -1,122 +1,170 @@
 package bot
 
-// TODO companion sueprvisor
-//
-//import (
-//	"context"
-//	"errors"
-//	"fmt"
-//	"log/slog"
-//	"time"
-//
-//	"github.com/hectorgimenez/koolo/internal/action"
-//
-//	"github.com/hectorgimenez/koolo/internal/container"
-//
-//	"github.com/hectorgimenez/koolo/internal/config"
-//	"github.com/hectorgimenez/koolo/internal/event"
-//	"github.com/hectorgimenez/koolo/internal/helper"
-//	"github.com/hectorgimenez/koolo/internal/run"
-//)
-//
-//type CompanionSupervisor struct {
-//	*baseSupervisor
-//}
-//
-//func NewCompanionSupervisor(name string, bot *Bot, runFactory *run.Factory, statsHandler *StatsHandler, c container.Container, pid uint32, hwnd uintptr) (*CompanionSupervisor, error) {
-//	bs, err := newBaseSupervisor(bot, runFactory, name, statsHandler, c)
-//	if err != nil {
-//		return nil, err
-//	}
-//
-//	return &CompanionSupervisor{
-//		baseSupervisor: bs,
-//	}, nil
-//}
-//
-//// Start will return error if it can not be started, otherwise will always return nil
-//func (s *CompanionSupervisor) Start() error {
-//	ctx, cancel := context.WithCancel(context.Background())
-//	s.cancelFn = cancel
-//
-//	err := s.ensureProcessIsRunningAndPrepare(ctx)
-//	if err != nil {
-//		return fmt.Errorf("error preparing game: %w", err)
-//	}
-//
-//	gameCounter := 0
-//	firstRun := true
-//	err = s.waitUntilCharacterSelectionScreen()
-//	if err != nil {
-//		return fmt.Errorf("error waiting for character selection screen: %w", err)
-//	}
-//
-//	for {
-//		select {
-//		case <-ctx.Done():
-//			return nil
-//		default:
-//			if s.c.CharacterCfg.Companion.Leader {
-//				time.Sleep(time.Second * 5)
-//				gameName, err := s.c.Manager.CreateOnlineGame(gameCounter)
-//				gameCounter++ // Sometimes game is created but error during join, so game name will be in use
-//				if err != nil {
-//					s.c.Logger.Error(fmt.Sprintf("Error creating new game: %s", err.Error()))
-//					continue
-//				}
-//
-//				if config.Koolo.Discord.EnableGameCreatedMessages {
-//					event.Send(event.GameCreated(event.Text(s.name, "New game created: %s"), gameName, config.Characters[s.name].Companion.GamePassword))
-//				} else {
-//					event.Send(event.GameCreated(event.Text(s.name, ""), gameName, config.Characters[s.name].Companion.GamePassword))
-//				}
-//				err = s.startBot(ctx, s.runFactory.BuildRuns(), firstRun)
-//				if err != nil {
-//					return err
-//				}
-//				action.ResetBuffTime(s.Name())
-//				firstRun = false
-//			} else {
-//				s.c.Logger.Debug("Waiting for new game to be created...")
-//				evt := s.c.EventListener.WaitForEvent(ctx)
-//				if gcEvent, ok := evt.(event.GameCreatedEvent); ok && gcEvent.Name != "" {
-//					err = s.c.Manager.JoinOnlineGame(gcEvent.Name, gcEvent.Password)
-//					if err != nil {
-//						s.c.Logger.Error(err.Error())
-//						continue
-//					}
-//
-//					runs := s.runFactory.BuildRuns()
-//					err = s.startBot(ctx, runs, firstRun)
-//					firstRun = false
-//					if err != nil {
-//						return err
-//					}
-//					action.ResetBuffTime(s.Name())
-//				}
-//			}
-//		}
-//	}
-//}
-//
-//func (s *CompanionSupervisor) startBot(ctx context.Context, runs []run.Run, firstRun bool) error {
-//	gameStart := time.Now()
-//	s.logGameStart(runs)
-//	err := s.bot.Run(ctx, firstRun, runs)
-//	if err != nil {
-//		if errors.Is(context.Canceled, ctx.Err()) {
-//			return nil
-//		}
-//		errorMsg := fmt.Sprintf("Game finished with errors, reason: %s. Game total time: %0.2fs", err.Error(), time.Since(gameStart).Seconds())
-//		event.Send(event.GameFinished(event.WithScreenshot(s.name, errorMsg, s.c.Reader.Screenshot()), event.FinishedError))
-//		s.c.Logger.Warn(errorMsg, slog.String("supervisor", s.name))
-//	}
-//	if exitErr := s.c.Manager.ExitGame(); exitErr != nil {
-//		return fmt.Errorf("error exiting game: %s", exitErr)
-//	}
-//	firstRun = false
-//
-//	helper.Sleep(5000)
-//
-//	return nil
-//}
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"time"
+
+	"github.com/hectorgimenez/koolo/internal/companion"
+	"github.com/hectorgimenez/koolo/internal/config"
+	ct "github.com/hectorgimenez/koolo/internal/context"
+	"github.com/hectorgimenez/koolo/internal/event"
+	"github.com/hectorgimenez/koolo/internal/game"
+	"github.com/hectorgimenez/koolo/internal/run"
+	"github.com/hectorgimenez/koolo/internal/utils"
+)
+
+// Give the followers some time to leave the previous game before creating the next one
+const companionLeaderCreateDelay = 5 * time.Second
+
+// CompanionSupervisor plays online games together with other supervisors, the leader creates the games and executes
+// the configured runs, followers join the leader games and assist the leader
+type CompanionSupervisor struct {
+	*baseSupervisor
+	// Only one of them is set, depending on the role
+	leader   *companion.Leader
+	follower *companion.Follower
+}
+
+func (s *CompanionSupervisor) GetData() *game.Data {
+	return s.bot.ctx.Data
+}
+
+func (s *CompanionSupervisor) GetContext() *ct.Context {
+	return s.bot.ctx
+}
+
+func NewCompanionSupervisor(name string, bot *Bot, statsHandler *StatsHandler) (*CompanionSupervisor, error) {
+	bs, err := newBaseSupervisor(bot, name, statsHandler)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &CompanionSupervisor{baseSupervisor: bs}
+	cfg := config.Characters[name].Companion
+	if cfg.Leader {
+		s.leader = companion.NewLeader(name, cfg.GamePassword, bot.ctx.Manager)
+		return s, nil
+	}
+
+	leaderSupervisor, leaderCharacter, found := companion.ResolveLeader(cfg.LeaderName, config.Characters)
+	if !found {
+		// The leader can be a supervisor of another instance, its character name comes with its events
+		if !config.Koolo.Hub.Enabled && config.Koolo.Hub.URL == "" {
+			return nil, fmt.Errorf("companion leader %s not found, it must be the name of another supervisor or its character", cfg.LeaderName)
+		}
+		leaderSupervisor = cfg.LeaderName
+	}
+	if leaderCharacter == "" {
+		leaderCharacter = cfg.LeaderName
+	}
+	s.follower = companion.NewFollower(name, leaderSupervisor, leaderCharacter, bot.ctx.Manager)
+
+	return s, nil
+}
+
+// Start will return error if it can not be started, otherwise will always return nil
+func (s *CompanionSupervisor) Start() error {
+	ctx, cancel := context.WithCancel(context.Background())
+	s.cancelFn = cancel
+
+	err := s.ensureProcessIsRunningAndPrepare()
+	if err != nil {
+		return fmt.Errorf("error preparing game: %w", err)
+	}
+
+	err = s.waitUntilCharacterSelectionScreen()
+	if err != nil {
+		return fmt.Errorf("error waiting for character selection screen: %w", err)
+	}
+
+	if s.follower != nil {
+		sub := s.bot.ctx.EventListener.Subscribe(s.follower.Handle,
+			event.WithName("companion-"+s.name),
+			event.WithFilter(event.ForSupervisor(s.follower.Leader())),
+		)
+		defer sub.Unsubscribe()
+	}
+
+	firstRun := true
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		default:
+		}
+
+		// Safe point to apply a reloaded config, bot routines only run while in game
+		s.bot.ApplyPendingConfig()
+
+		if !s.bot.ctx.Manager.InGame() {
+			if err = s.enterLobby(); err != nil {
+				s.bot.ctx.Logger.Error(err.Error())
+				continue
+			}
+
+			if err = s.createOrJoinGame(ctx, firstRun); err != nil {
+				if ctx.Err() != nil {
+					return nil
+				}
+				s.bot.ctx.Logger.Error(err.Error())
+				continue
+			}
+		}
+
+		runs := s.buildRuns()
+		gameStart := time.Now()
+		s.bot.ctx.LastBuffAt = time.Time{}
+		s.logGameStart(runs)
+
+		// Refresh game data to make sure we have the latest information
+		s.bot.ctx.RefreshGameData()
+
+		// Perform keybindings check on the first run only
+		if firstRun {
+			s.checkKeyBindings()
+		}
+
+		err = s.bot.Run(ctx, firstRun, runs)
+		firstRun = false
+
+		if err = s.finishGame(err, gameStart); err != nil {
+			return err
+		}
+	}
+}
+
+// createOrJoinGame creates the next game if leader, otherwise waits for the leader to create it and joins
+func (s *CompanionSupervisor) createOrJoinGame(ctx context.Context, firstRun bool) error {
+	if s.leader != nil {
+		if !firstRun {
+			utils.Sleep(int(companionLeaderCreateDelay.Milliseconds()))
+		}
+		_, err := s.leader.CreateGame()
+
+		return err
+	}
+
+	s.bot.ctx.Logger.Debug("Waiting for the leader to create a new game...")
+	g, err := s.follower.JoinNextGame(ctx)
+	if err != nil {
+		return err
+	}
+	event.Send(event.GameCreated(event.Text(s.name, fmt.Sprintf("Joined game: %s", g.Name)), g.Name, ""))
+
+	return nil
+}
+
+func (s *CompanionSupervisor) buildRuns() []run.Run {
+	if s.follower != nil {
+		return []run.Run{run.NewCompanion(s.follower)}
+	}
+
+	runs := run.BuildRuns(s.bot.ctx.CharacterCfg)
+	if s.bot.ctx.CharacterCfg.Game.RandomizeRuns {
+		rand.Shuffle(len(runs), func(i, j int) { runs[i], runs[j] = runs[j], runs[i] })
+	}
+
+	return s.adaptRuns(runs)
+}
@@ -2,13 +2,18 @@ package bot
 
 import (
 	"fmt"
+	"image"
 	"log/slog"
+	"maps"
 	"strconv"
+	"sync"
 	"syscall"
 	"time"
 	"unsafe"
 
+	"github.com/hectorgimenez/d2go/pkg/data"
 	"github.com/hectorgimenez/koolo/cmd/koolo/log"
+	"github.com/hectorgimenez/koolo/internal/analytics"
 	"github.com/hectorgimenez/koolo/internal/character"
 	"github.com/hectorgimenez/koolo/internal/config"
 	"github.com/hectorgimenez/koolo/internal/context"
@@ -16,27 +21,41 @@ import (
 	"github.com/hectorgimenez/koolo/internal/game"
 	"github.com/hectorgimenez/koolo/internal/health"
 	"github.com/hectorgimenez/koolo/internal/pather"
+	"github.com/hectorgimenez/koolo/internal/pickit"
+	"github.com/hectorgimenez/koolo/internal/remote/hub"
 	"github.com/hectorgimenez/koolo/internal/utils"
 	"github.com/hectorgimenez/koolo/internal/utils/winproc"
 	"github.com/lxn/win"
 )
 
 type SupervisorManager struct {
-	logger         *slog.Logger
+	logger *slog.Logger
+	// mu guards the maps, supervisors are read from the HTTP server, the hub client and the chat commands
+	mu             sync.RWMutex
 	supervisors    map[string]Supervisor
 	crashDetectors map[string]*game.CrashDetector
 	eventListener  *event.Listener
+	statsStore     StatsStore
+	statsSubs      map[string]*event.Subscription
 }
 
-func NewSupervisorManager(logger *slog.Logger, eventListener *event.Listener) *SupervisorManager {
+// NewSupervisorManager creates the manager, statsStore is optional and when nil stats are only kept in memory
+func NewSupervisorManager(logger *slog.Logger, eventListener *event.Listener, statsStore StatsStore) *SupervisorManager {
 	return &SupervisorManager{
 		logger:         logger,
 		supervisors:    make(map[string]Supervisor),
 		crashDetectors: make(map[string]*game.CrashDetector),
 		eventListener:  eventListener,
+		statsStore:     statsStore,
+		statsSubs:      make(map[string]*event.Subscription),
 	}
 }
 
+// EventMetrics returns the event bus queues and handler stats
+func (mng *SupervisorManager) EventMetrics() event.Metrics {
+	return mng.eventListener.Metrics()
+}
+
 func (mng *SupervisorManager) AvailableSupervisors() []string {
 	availableSupervisors := make([]string, 0)
 	for name := range config.Characters {
@@ -49,9 +68,8 @@ func (mng *SupervisorManager) AvailableSupervisors() []string {
 }
 
 func (mng *SupervisorManager) Start(supervisorName string, attachToExisting bool, pidHwnd ...uint32) error {
-	// Avoid multiple instances of the supervisor - shitstorm prevention
-	if _, exists := mng.supervisors[supervisorName]; exists {
-		return fmt.Errorf("supervisor %s is already running", supervisorName)
+	if err := mng.canStart(supervisorName); err != nil {
+		return err
 	}
 
 	// Reload config to get the latest local changes before starting the supervisor
@@ -83,12 +101,20 @@ func (mng *SupervisorManager) Start(supervisorName string, attachToExisting bool
 		return err
 	}
 
+	mng.mu.Lock()
+	// Started from somewhere else while the supervisor was being built
+	if err = mng.canStartLocked(supervisorName); err != nil {
+		mng.mu.Unlock()
+		crashDetector.Stop()
+		return err
+	}
 	if oldCrashDetector, exists := mng.crashDetectors[supervisorName]; exists {
 		oldCrashDetector.Stop() // Stop the old crash detector if it exists
 	}
 
 	mng.supervisors[supervisorName] = supervisor
 	mng.crashDetectors[supervisorName] = crashDetector
+	mng.mu.Unlock()
 
 	if config.Koolo.GameWindowArrangement {
 		go func() {
@@ -110,92 +136,104 @@ func (mng *SupervisorManager) Start(supervisorName string, attachToExisting bool
 	return nil
 }
 
-func (mng *SupervisorManager) ReloadConfig() error {
+// canStart returns an error if the supervisor is already running or the max concurrent supervisors is reached
+func (mng *SupervisorManager) canStart(supervisorName string) error {
+	mng.mu.RLock()
+	defer mng.mu.RUnlock()
 
-	// Load fresh configs
-	if err := config.Load(); err != nil {
-		return err
+	return mng.canStartLocked(supervisorName)
+}
+
+// canStartLocked is canStart for callers already holding the mutex
+func (mng *SupervisorManager) canStartLocked(supervisorName string) error {
+	// Avoid multiple instances of the supervisor - shitstorm prevention
+	if _, exists := mng.supervisors[supervisorName]; exists {
+		return fmt.Errorf("supervisor %s is already running", supervisorName)
 	}
 
-	// Apply new configs to running supervisors
-	for name, sup := range mng.supervisors {
-		newCfg, exists := config.Characters[name]
-		if !exists {
-			continue
-		}
+	if maxSupervisors := config.Koolo.MaxConcurrentSupervisors; maxSupervisors > 0 && len(mng.supervisors) >= maxSupervisors {
+		return fmt.Errorf("max concurrent supervisors reached (%d)", maxSupervisors)
+	}
 
-		ctx := sup.GetContext()
-		if ctx == nil {
-			continue
-		}
+	return nil
+}
 
-		// Preserve runtime data
-		//oldRuntimeData := ctx.CharacterCfg.Runtime
+// supervisor returns the running supervisor with the given name
+func (mng *SupervisorManager) supervisor(name string) (Supervisor, bool) {
+	mng.mu.RLock()
+	defer mng.mu.RUnlock()
 
-		// Update the config
-		*ctx.CharacterCfg = *newCfg
-		//ctx.CharacterCfg.Runtime = oldRuntimeData
-	}
+	s, found := mng.supervisors[name]
+	return s, found
+}
 
-	return nil
+// running returns a copy of the running supervisors, they are used without holding the mutex because stopping or
+// reading a supervisor can take a while
+func (mng *SupervisorManager) running() map[string]Supervisor {
+	mng.mu.RLock()
+	defer mng.mu.RUnlock()
+
+	return maps.Clone(mng.supervisors)
+}
+
+// RunningCount returns the number of running supervisors
+func (mng *SupervisorManager) RunningCount() int {
+	mng.mu.RLock()
+	defer mng.mu.RUnlock()
+
+	return len(mng.supervisors)
 }
 
 func (mng *SupervisorManager) StopAll() {
-	for _, s := range mng.supervisors {
+	for _, s := range mng.running() {
 		s.Stop()
 	}
 }
 
 func (mng *SupervisorManager) Stop(supervisor string) {
-
+	mng.mu.Lock()
 	s, found := mng.supervisors[supervisor]
-	if found {
+	cd, cdFound := mng.crashDetectors[supervisor]
+	// Delete him from the list of Supervisors
+	delete(mng.supervisors, supervisor)
+	delete(mng.crashDetectors, supervisor)
+	mng.mu.Unlock()
 
+	if found {
 		// Stop the Supervisor
 		s.Stop()
-
-		// Delete him from the list of Supervisors
-		delete(mng.supervisors, supervisor)
-
-		if cd, ok := mng.crashDetectors[supervisor]; ok {
-			cd.Stop()
-			delete(mng.crashDetectors, supervisor)
-		}
+	}
+	if cdFound {
+		cd.Stop()
 	}
 }
 
 func (mng *SupervisorManager) TogglePause(supervisor string) {
-	s, found := mng.supervisors[supervisor]
+	s, found := mng.supervisor(supervisor)
 	if found {
 		s.TogglePause()
 	}
 }
 
 func (mng *SupervisorManager) Status(characterName string) Stats {
-	for name, supervisor := range mng.supervisors {
-		if name == characterName {
-			return supervisor.Stats()
-		}
+	if s, found := mng.supervisor(characterName); found {
+		return s.Stats()
 	}
 
 	return Stats{}
 }
 
 func (mng *SupervisorManager) GetData(characterName string) *game.Data {
-	for name, supervisor := range mng.supervisors {
-		if name == characterName {
-			return supervisor.GetData()
-		}
+	if s, found := mng.supervisor(characterName); found {
+		return s.GetData()
 	}
 
 	return nil
 }
 
 func (mng *SupervisorManager) GetContext(characterName string) *context.Context {
-	for name, supervisor := range mng.supervisors {
-		if name == characterName {
-			return supervisor.GetContext()
-		}
+	if s, found := mng.supervisor(characterName); found {
+		return s.GetContext()
 	}
 
 	return nil
@@ -259,14 +297,37 @@ func (mng *SupervisorManager) buildSupervisor(supervisorName string, logger *slo
 	}
 	ctx.Char = char
 
+	if cfg.ItemCapture.Enabled {
+		recorder, err := pickit.NewRecorder(config.Koolo.Stats.Directory, supervisorName, cfg.ItemCapture.MaxSizeMB)
+		if err != nil {
+			// Only used to tune the pickit rules, not worth stopping the bot
+			logger.Warn("Item capture disabled", slog.String("error", err.Error()))
+		} else {
+			ctx.ItemCapture = recorder
+		}
+	}
+
 	bot := NewBot(ctx.Context)
 
-	statsHandler := NewStatsHandler(supervisorName, logger)
-	mng.eventListener.Register(statsHandler.Handle)
+	statsHandler := NewStatsHandler(supervisorName, logger, mng.statsStore)
+	// Replace the handler from the previous run, otherwise every event would be counted once per restart
+	mng.mu.Lock()
+	if oldSub, found := mng.statsSubs[supervisorName]; found {
+		oldSub.Unsubscribe()
+	}
+	mng.statsSubs[supervisorName] = mng.eventListener.Subscribe(statsHandler.Handle,
+		event.WithName("stats-"+supervisorName),
+		event.WithFilter(event.ForSupervisor(supervisorName)),
+	)
+	mng.mu.Unlock()
 
 	var supervisor Supervisor
 
-	supervisor, err = NewSinglePlayerSupervisor(supervisorName, bot, statsHandler)
+	if cfg.Companion.Enabled {
+		supervisor, err = NewCompanionSupervisor(supervisorName, bot, statsHandler)
+	} else {
+		supervisor, err = NewSinglePlayerSupervisor(supervisorName, bot, statsHandler)
+	}
 
 	if err != nil {
 		return nil, nil, err
@@ -341,10 +402,110 @@ func (mng *SupervisorManager) buildSupervisor(supervisorName string, logger *slo
 }
 
 func (mng *SupervisorManager) GetSupervisorStats(supervisor string) Stats {
-	if mng.supervisors[supervisor] == nil {
+	s, found := mng.supervisor(supervisor)
+	if !found || s == nil {
 		return Stats{}
 	}
-	return mng.supervisors[supervisor].Stats()
+	return s.Stats()
+}
+
+// Drops returns the items picked up by the supervisor in the current session
+func (mng *SupervisorManager) Drops(supervisor string) []data.Drop {
+	return mng.GetSupervisorStats(supervisor).Drops
+}
+
+// Screenshot captures the game window of a running supervisor
+func (mng *SupervisorManager) Screenshot(supervisor string) (image.Image, error) {
+	ctx := mng.GetContext(supervisor)
+	if ctx == nil || ctx.GameReader == nil {
+		return nil, fmt.Errorf("supervisor %s is not running", supervisor)
+	}
+
+	return ctx.GameReader.Screenshot(), nil
+}
+
+// GetRunRotation returns the adaptive runs state of a running supervisor with adaptive runs enabled, nil otherwise
+func (mng *SupervisorManager) GetRunRotation(supervisor string) *analytics.Rotation {
+	sup, _ := mng.supervisor(supervisor)
+	if sup == nil || !config.Characters[supervisor].Game.AdaptiveRuns.Enabled {
+		return nil
+	}
+
+	return sup.Rotation()
+}
+
+// GetRunAnalytics returns the efficiency of every run, decisions are only available while the supervisor is running
+func (mng *SupervisorManager) GetRunAnalytics(supervisor string) (RunAnalytics, error) {
+	if sup, _ := mng.supervisor(supervisor); sup != nil {
+		return sup.RunAnalytics(), nil
+	}
+
+	ra := RunAnalytics{Reports: []analytics.RunReport{}, Dropped: map[string]time.Time{}, Decisions: []analytics.Decision{}}
+	if cfg, found := config.Characters[supervisor]; found {
+		ra.Adaptive = cfg.Game.AdaptiveRuns.Enabled
+	}
+	if mng.statsStore != nil {
+		st, err := mng.statsStore.Load(supervisor, time.Time{}, time.Time{})
+		if err != nil {
+			return RunAnalytics{}, err
+		}
+		ra.Reports = analytics.Report(st.Outcomes())
+	}
+
+	return ra, nil
+}
+
+// HubStatus returns the status of every supervisor, reported to the hub when this instance is connected to one
+func (mng *SupervisorManager) HubStatus() []hub.SupervisorStatus {
+	statuses := make([]hub.SupervisorStatus, 0)
+	for _, name := range mng.AvailableSupervisors() {
+		st := mng.GetSupervisorStats(name)
+		status := hub.SupervisorStatus{
+			Name:      name,
+			Status:    string(st.SupervisorStatus),
+			StartedAt: st.StartedAt,
+			Games:     st.TotalGames(),
+			Drops:     len(st.Drops),
+			Chickens:  st.TotalChickens(),
+			Deaths:    st.TotalDeaths(),
+			Errors:    st.TotalErrors(),
+		}
+		if cfg, found := config.Characters[name]; found {
+			status.Character = cfg.CharacterName
+		}
+		if status.Status == "" {
+			status.Status = string(NotStarted)
+		}
+		if len(st.Games) > 0 {
+			if g := st.Games[len(st.Games)-1]; g.FinishedAt.IsZero() && len(g.Runs) > 0 {
+				status.Run = g.Runs[len(g.Runs)-1].Name
+			}
+		}
+		statuses = append(statuses, status)
+	}
+
+	return statuses
+}
+
+// HasStatsHistory returns true if stats are persisted, so they can be queried for any time window
+func (mng *SupervisorManager) HasStatsHistory() bool {
+	return mng.statsStore != nil
+}
+
+// StatsBetween rebuilds the supervisor stats for the given time window (zero values mean no limit) from the stats
+// store, if stats are not persisted the stats for the current session are returned instead
+func (mng *SupervisorManager) StatsBetween(supervisor string, from, to time.Time) (Stats, error) {
+	if mng.statsStore == nil {
+		return mng.GetSupervisorStats(supervisor), nil
+	}
+
+	st, err := mng.statsStore.Load(supervisor, from, to)
+	if err != nil {
+		return Stats{}, err
+	}
+	st.SupervisorStatus = mng.GetSupervisorStats(supervisor).SupervisorStatus
+
+	return st, nil
 }
 
 func (mng *SupervisorManager) rearrangeWindows() {
@@ -365,7 +526,7 @@ func (mng *SupervisorManager) rearrangeWindows() {
 	)
 
 	var column, row int32
-	for _, sp := range mng.supervisors {
+	for _, sp := range mng.running() {
 		// reminder that columns are vertical (they go up and down) and rows are horizontal (they go left and right)
 		if column > maxColumns {
 			column = 0
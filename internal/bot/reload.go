@@ -0,0 +1,164 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/hectorgimenez/koolo/internal/config"
+)
+
+type ReloadOptions struct {
+	// Keep the NIP rules currently used by running supervisors instead of the ones read from disk
+	KeepRules bool
+}
+
+// ReloadReport describes what changed for each running supervisor
+type ReloadReport struct {
+	Supervisors []SupervisorReload `json:"supervisors"`
+}
+
+type SupervisorReload struct {
+	Supervisor string          `json:"supervisor"`
+	Changes    []config.Change `json:"changes"`
+	// NIP rules read from disk are different from the ones in use
+	RulesChanged bool `json:"rulesChanged"`
+	// Some changes will only be applied after restarting the supervisor, see config.Change
+	RestartRequired bool `json:"restartRequired"`
+	// The new config is waiting for the current game to finish
+	Pending bool `json:"pending"`
+	// The new config is not valid and it was not applied
+	Errors config.ValidationErrors `json:"errors,omitempty"`
+}
+
+// ReloadConfig reads the configs from disk and queues them on the running supervisors, they are applied between
+// games. Supervisors without changes are not included in the report
+func (mng *SupervisorManager) ReloadConfig(opts ReloadOptions) (ReloadReport, error) {
+	report := ReloadReport{Supervisors: make([]SupervisorReload, 0)}
+
+	if err := config.Load(); err != nil {
+		return report, err
+	}
+
+	for name, sup := range mng.running() {
+		loaded, exists := config.Characters[name]
+		if !exists {
+			continue
+		}
+
+		ctx := sup.GetContext()
+		if ctx == nil {
+			continue
+		}
+
+		if errs := loaded.Check(); len(errs) > 0 {
+			mng.logger.Warn("Reloaded config is not valid, keeping the current one", slog.String("supervisor", name), slog.Any("error", errs))
+			report.Supervisors = append(report.Supervisors, SupervisorReload{Supervisor: name, Changes: make([]config.Change, 0), Errors: errs})
+			continue
+		}
+
+		// Running bots can't share the config with config.Characters, it's replaced on every Load
+		updated := loaded.Clone()
+		current := ctx.CharacterCfg
+		result := SupervisorReload{Supervisor: name, Changes: config.Diff(current, updated)}
+		if opts.KeepRules {
+			updated.Runtime.Rules = current.Runtime.Rules
+		} else {
+			result.RulesChanged = config.RulesChanged(current.Runtime.Rules, updated.Runtime.Rules)
+		}
+
+		if len(result.Changes) == 0 && !result.RulesChanged {
+			continue
+		}
+
+		for _, c := range result.Changes {
+			if c.RestartRequired {
+				result.RestartRequired = true
+			}
+		}
+		config.KeepRestartRequired(updated, current)
+
+		sup.QueueConfig(updated)
+		result.Pending = true
+		report.Supervisors = append(report.Supervisors, result)
+
+		mng.logger.Info("Config reloaded, it will be applied when the current game finishes",
+			slog.String("supervisor", name),
+			slog.Int("changes", len(result.Changes)),
+			slog.Bool("rulesChanged", result.RulesChanged),
+			slog.Bool("restartRequired", result.RestartRequired),
+		)
+	}
+
+	return report, nil
+}
+
+// WatchConfig reloads the configs when any file in the config directory or the centralized pickit path changes,
+// until ctx is cancelled. Changes are applied once the files didn't change for a full interval, editors usually write
+// the same file more than once
+func (mng *SupervisorManager) WatchConfig(ctx context.Context, interval time.Duration) {
+	dirs := watchedConfigDirs()
+	applied := filesSignature(dirs)
+	previous := applied
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			current := filesSignature(dirs)
+			if current != applied && current == previous {
+				applied = current
+				if _, err := mng.ReloadConfig(ReloadOptions{}); err != nil {
+					mng.logger.Error("Error reloading config after file changes", slog.Any("error", err))
+				}
+			}
+			previous = current
+		}
+	}
+}
+
+// filesSignature is a hash of the name, size and modification time of all the files under dirs
+func filesSignature(dirs []string) uint64 {
+	h := fnv.New64a()
+	for _, dir := range dirs {
+		if dir == "" {
+			continue
+		}
+
+		filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+			if err != nil || d.IsDir() {
+				return nil
+			}
+
+			info, err := d.Info()
+			if err != nil {
+				return nil
+			}
+			fmt.Fprintf(h, "%s|%d|%d\n", path, info.Size(), info.ModTime().UnixNano())
+
+			return nil
+		})
+	}
+
+	return h.Sum64()
+}
+
+// watchedConfigDirs returns the directories containing character configs and pickit rules
+func watchedConfigDirs() []string {
+	dirs := []string{"config"}
+	if config.Koolo.CentralizedPickitPath != "" {
+		if _, err := os.Stat(config.Koolo.CentralizedPickitPath); err == nil {
+			dirs = append(dirs, config.Koolo.CentralizedPickitPath)
+		}
+	}
+
+	return dirs
+}
@@ -2,34 +2,59 @@ package bot
 
 import (
 	"log/slog"
+	"sync"
 	"time"
 
 	"github.com/hectorgimenez/koolo/internal/config"
+	"github.com/hectorgimenez/koolo/internal/schedule"
+)
+
+const (
+	// Max time between checks, config changes are picked up at this interval
+	schedulerMaxInterval = 30 * time.Second
+	// Time we wait for a supervisor to report it's running after the scheduler started it
+	schedulerStartGrace = 2 * time.Minute
+	// Play history older than this is not needed for breaks and daily/weekly limits
+	schedulerHistoryRetention = 8 * 24 * time.Hour
 )
 
 type Scheduler struct {
 	manager *SupervisorManager
 	logger  *slog.Logger
+	clock   schedule.Clock
 	stop    chan struct{}
+
+	mu sync.Mutex
+	// Play history is kept in memory, limits and breaks start from zero when Koolo is restarted
+	history   map[string][]schedule.Session
+	starting  map[string]time.Time
+	decisions map[string]schedule.Decision
 }
 
 func NewScheduler(manager *SupervisorManager, logger *slog.Logger) *Scheduler {
 	return &Scheduler{
-		manager: manager,
-		logger:  logger,
-		stop:    make(chan struct{}),
+		manager:   manager,
+		logger:    logger,
+		clock:     schedule.SystemClock{},
+		stop:      make(chan struct{}),
+		history:   make(map[string][]schedule.Session),
+		starting:  make(map[string]time.Time),
+		decisions: make(map[string]schedule.Decision),
 	}
 }
 
 func (s *Scheduler) Start() {
 	s.logger.Info("Scheduler started")
-	ticker := time.NewTicker(30 * time.Second)
-	defer ticker.Stop()
+	timer := time.NewTimer(0)
+	defer timer.Stop()
 
 	for {
 		select {
-		case <-ticker.C:
-			s.checkSchedules()
+		case <-timer.C:
+			next := s.checkSchedules()
+			// Wake up just after the next planned start/stop, but never sleep more than the max interval
+			wait := min(max(next.Sub(s.clock.Now())+time.Second, time.Second), schedulerMaxInterval)
+			timer.Reset(wait)
 		case <-s.stop:
 			s.logger.Info("Scheduler stopped")
 			return
@@ -41,47 +66,107 @@ func (s *Scheduler) Stop() {
 	close(s.stop)
 }
 
-func (s *Scheduler) checkSchedules() {
-	now := time.Now()
-	currentDay := int(now.Weekday())
+// Decisions returns the last schedule evaluation for every supervisor with the scheduler enabled
+func (s *Scheduler) Decisions() map[string]schedule.Decision {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	decisions := make(map[string]schedule.Decision, len(s.decisions))
+	for name, d := range s.decisions {
+		decisions[name] = d
+	}
+
+	return decisions
+}
+
+// checkSchedules starts or stops the supervisors based on their schedule, it returns the next planned start/stop
+func (s *Scheduler) checkSchedules() time.Time {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.clock.Now()
+	next := now.Add(schedulerMaxInterval)
 
 	for supervisorName, cfg := range config.Characters {
+		running := s.trackSession(supervisorName, now)
+
 		if !cfg.Scheduler.Enabled {
+			delete(s.decisions, supervisorName)
 			continue
 		}
 
-		for _, day := range cfg.Scheduler.Days {
-			if day.DayOfWeek != currentDay {
+		plan, err := cfg.Scheduler.Plan(supervisorName)
+		if err != nil {
+			s.logger.Warn("Invalid scheduler configuration, skipping", slog.String("supervisor", supervisorName), slog.Any("error", err))
+			delete(s.decisions, supervisorName)
+			continue
+		}
+
+		d := schedule.Evaluate(plan, s.history[supervisorName], now)
+		s.decisions[supervisorName] = d
+
+		switch {
+		case d.ShouldRun && !running:
+			if maxSupervisors := config.Koolo.MaxConcurrentSupervisors; maxSupervisors > 0 && s.manager.RunningCount() >= maxSupervisors {
+				s.logger.Debug("Max concurrent supervisors reached, delaying scheduled start", slog.String("supervisor", supervisorName))
 				continue
 			}
 
-			actionTaken := false
-
-			// Check if any time range is active
-			for _, timeRange := range day.TimeRanges {
-				start := time.Date(now.Year(), now.Month(), now.Day(), timeRange.Start.Hour(), timeRange.Start.Minute(), 0, 0, now.Location())
-				end := time.Date(now.Year(), now.Month(), now.Day(), timeRange.End.Hour(), timeRange.End.Minute(), 0, 0, now.Location())
-
-				if now.After(start) && now.Before(end) && s.supervisorNotStarted(supervisorName) {
-					s.logger.Info("Starting supervisor based on schedule. Time range: "+start.Format("15:04")+" - "+end.Format("15:04"), "supervisor", supervisorName)
-					go s.startSupervisor(supervisorName)
-					actionTaken = true
-					break
-				} else if now.After(end) || now == end || now.Before(start) && !s.supervisorNotStarted(supervisorName) {
-					s.logger.Info("Stopping supervisor based on schedule. Time range: "+start.Format("15:04")+" - "+end.Format("15:04"), "supervisor", supervisorName)
-					s.stopSupervisor(supervisorName)
-					actionTaken = true
-					break
-				}
-			}
+			s.logger.Info("Starting supervisor based on schedule", slog.String("supervisor", supervisorName), slog.Time("nextStop", d.NextStop))
+			s.starting[supervisorName] = now
+			s.history[supervisorName] = append(s.history[supervisorName], schedule.Session{Start: now})
+			go s.startSupervisor(supervisorName)
+		case !d.ShouldRun && running:
+			s.logger.Info("Stopping supervisor based on schedule", slog.String("supervisor", supervisorName), slog.String("reason", string(d.Reason)), slog.Time("nextStart", d.NextStart))
+			delete(s.starting, supervisorName)
+			s.closeSession(supervisorName, now)
+			s.stopSupervisor(supervisorName)
+		}
 
-			// To prevent unnecessary checks since it can be only one day.
-			// Also avoid starting/stoping a supervisor in the same minute ... this is rediculous
-			if actionTaken {
-				break
+		for _, t := range []time.Time{d.NextStart, d.NextStop} {
+			if t.After(now) && t.Before(next) {
+				next = t
 			}
 		}
 	}
+
+	return next
+}
+
+// trackSession keeps the play history up to date with the supervisor status, also for manual starts and stops, so
+// they count for the breaks and play limits. It returns if the supervisor is running.
+func (s *Scheduler) trackSession(name string, now time.Time) bool {
+	running := !s.supervisorNotStarted(name)
+	if startedAt, found := s.starting[name]; found {
+		if running || now.Sub(startedAt) > schedulerStartGrace {
+			delete(s.starting, name)
+		} else {
+			// Still starting, status is not updated yet
+			running = true
+		}
+	}
+
+	history := s.history[name]
+	isOpen := len(history) > 0 && history[len(history)-1].End.IsZero()
+	if running && !isOpen {
+		history = append(history, schedule.Session{Start: now})
+	} else if !running && isOpen {
+		history[len(history)-1].End = now
+	}
+
+	for len(history) > 0 && !history[0].End.IsZero() && now.Sub(history[0].End) > schedulerHistoryRetention {
+		history = history[1:]
+	}
+	s.history[name] = history
+
+	return running
+}
+
+func (s *Scheduler) closeSession(name string, now time.Time) {
+	history := s.history[name]
+	if len(history) > 0 && history[len(history)-1].End.IsZero() {
+		history[len(history)-1].End = now
+	}
 }
 
 func (s *Scheduler) supervisorNotStarted(name string) bool {
@@ -6,20 +6,21 @@ import (
 	"fmt"
 	"log/slog"
 	"math/rand"
+	"slices"
 	"time"
 
-	"github.com/hectorgimenez/d2go/pkg/data/skill"
 	"github.com/hectorgimenez/koolo/internal/config"
 	ct "github.com/hectorgimenez/koolo/internal/context"
 	"github.com/hectorgimenez/koolo/internal/event"
 	"github.com/hectorgimenez/koolo/internal/game"
-	"github.com/hectorgimenez/koolo/internal/health"
 	"github.com/hectorgimenez/koolo/internal/run"
 	"github.com/hectorgimenez/koolo/internal/utils"
 )
 
 type SinglePlayerSupervisor struct {
 	*baseSupervisor
+	// Consecutive games left because of the terror zone
+	tzHops int
 }
 
 func (s *SinglePlayerSupervisor) GetData() *game.Data {
@@ -64,6 +65,9 @@ func (s *SinglePlayerSupervisor) Start() error {
 				}
 			}
 
+			// Safe point to apply a reloaded config, bot routines only run while in game
+			s.bot.ApplyPendingConfig()
+
 			// By this point, we should be in the character selection screen.
 			if !s.bot.ctx.Manager.InGame() {
 				// Create the game
@@ -84,63 +88,67 @@ func (s *SinglePlayerSupervisor) Start() error {
 			if config.Characters[s.name].Game.RandomizeRuns {
 				rand.Shuffle(len(runs), func(i, j int) { runs[i], runs[j] = runs[j], runs[i] })
 			}
-			event.Send(event.GameCreated(event.Text(s.name, "New game created"), "", ""))
+			runs = s.adaptRuns(runs)
+			event.Send(event.GameCreated(event.Text(s.name, "New game created"), s.bot.ctx.GameReader.LastGameName(), ""))
 			s.bot.ctx.LastBuffAt = time.Time{}
 			s.logGameStart(runs)
 
 			// Refresh game data to make sure we have the latest information
 			s.bot.ctx.RefreshGameData()
 
+			hopped, err := s.terrorZoneHop()
+			if err != nil {
+				return err
+			}
+			if hopped {
+				continue
+			}
+
 			// Perform keybindings check on the first run only
 			if firstRun {
-				missingKeybindings := s.bot.ctx.Char.CheckKeyBindings()
-				if len(missingKeybindings) > 0 {
-					var missingKeybindingsText = "Missing key binding for skill(s):"
-					for _, v := range missingKeybindings {
-						missingKeybindingsText += fmt.Sprintf("\n%s", skill.SkillNames[v])
-					}
-					missingKeybindingsText += "\nPlease bind the skills. Pausing bot..."
-
-					utils.ShowDialog("Missing keybindings for "+s.bot.ctx.Name, missingKeybindingsText)
-					s.TogglePause()
-				}
+				s.checkKeyBindings()
 			}
 
 			err = s.bot.Run(ctx, firstRun, runs)
 			firstRun = false
 
-			var gameFinishReason event.FinishReason
-			if err != nil {
-				switch {
-				case errors.Is(err, health.ErrChicken):
-					gameFinishReason = event.FinishedChicken
-				case errors.Is(err, health.ErrMercChicken):
-					gameFinishReason = event.FinishedMercChicken
-				case errors.Is(err, health.ErrDied):
-					gameFinishReason = event.FinishedDied
-				default:
-					gameFinishReason = event.FinishedError
-				}
-				event.Send(event.GameFinished(event.WithScreenshot(s.name, err.Error(), s.bot.ctx.GameReader.Screenshot()), gameFinishReason))
-				s.bot.ctx.Logger.Warn(
-					fmt.Sprintf("Game finished with errors, reason: %s. Game total time: %0.2fs", err.Error(), time.Since(gameStart).Seconds()),
-					slog.String("supervisor", s.name),
-					slog.Uint64("mapSeed", uint64(s.bot.ctx.GameReader.MapSeed())),
-				)
-			} else {
-				gameFinishReason = event.FinishedOK
-				event.Send(event.GameFinished(event.Text(s.name, "Game finished successfully"), gameFinishReason))
-			}
-
-			if exitErr := s.bot.ctx.Manager.ExitGame(); exitErr != nil {
-				errMsg := fmt.Sprintf("Error exiting game %s", exitErr.Error())
-				event.Send(event.GameFinished(event.WithScreenshot(s.name, errMsg, s.bot.ctx.GameReader.Screenshot()), event.FinishedError))
-				return errors.New(errMsg)
+			if err = s.finishGame(err, gameStart); err != nil {
+				return err
 			}
 		}
 	}
 }
 
+// terrorZoneHop reports the active terror zones and leaves the game if none of them is enabled for the character and
+// hopping is enabled, returns true if the game was left
+func (s *SinglePlayerSupervisor) terrorZoneHop() (bool, error) {
+	cfg := s.bot.ctx.CharacterCfg
+	zones := s.bot.ctx.Data.TerrorZones
+	if !slices.Contains(cfg.Game.Runs, config.TerrorZoneRun) || len(zones) == 0 {
+		return false, nil
+	}
+
+	tz := cfg.Game.TerrorZone
+	hop := tz.HopGames && len(cfg.EnabledTerrorZones(zones)) == 0 && (tz.MaxHops == 0 || s.tzHops < tz.MaxHops)
+	event.Send(event.TerrorZoneDetected(event.Text(s.name, fmt.Sprintf("Terror zone: %s", zones[0].Area().Name)), zones, hop))
+	if !hop {
+		s.tzHops = 0
+		return false, nil
+	}
+
+	s.tzHops++
+	s.bot.ctx.Logger.Info(fmt.Sprintf("Terror zone %s is not enabled, leaving the game", zones[0].Area().Name), slog.Int("hops", s.tzHops))
+	event.Send(event.GameFinished(event.Text(s.name, "Terror zone not enabled, hopping to a new game"), event.FinishedOK))
+	if err := s.bot.ctx.Manager.ExitGame(); err != nil {
+		errMsg := fmt.Sprintf("Error exiting game %s", err.Error())
+		event.Send(event.GameFinished(event.WithScreenshot(s.name, errMsg, s.bot.ctx.GameReader.Screenshot()), event.FinishedError))
+		return false, errors.New(errMsg)
+	}
+	utils.Sleep(tz.HopDelaySeconds * 1000)
+
+	return true, nil
+}
+
 // This function is responsible for handling all interactions with joining/creating games
 func (s *SinglePlayerSupervisor) HandleOutOfGameFlow() error {
 	// Refresh the data
@@ -172,17 +180,8 @@ func (s *SinglePlayerSupervisor) HandleOutOfGameFlow() error {
 		// TODO: Add Joining Games
 
 		if s.bot.ctx.CharacterCfg.Game.CreateLobbyGames {
-			retryCount := 0
-			for !s.bot.ctx.GameReader.IsInLobby() {
-
-				// Prevent an infinite loop
-				if retryCount >= 5 && !s.bot.ctx.Data.IsInLobby {
-					return fmt.Errorf("failed to enter bnet lobby after 5 retries")
-				}
-
-				// Try to enter bnet lobby
-				s.bot.ctx.HID.Click(game.LeftButton, 744, 650)
-				utils.Sleep(1000)
+			if err := s.enterLobby(); err != nil {
+				return err
 			}
 
 			if _, err := s.bot.ctx.Manager.CreateOnlineGame(s.bot.ctx.CharacterCfg.Game.PublicGameCounter); err != nil {
@@ -3,10 +3,13 @@ package bot
 import (
 	"context"
 	"log/slog"
+	"slices"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/hectorgimenez/d2go/pkg/data"
+	"github.com/hectorgimenez/koolo/internal/analytics"
 	"github.com/hectorgimenez/koolo/internal/event"
 )
 
@@ -21,15 +24,24 @@ const (
 type SupervisorStatus string
 
 type StatsHandler struct {
+	// mu guards stats, events are applied from the event bus while the HTTP server and the supervisor read them
+	mu     sync.RWMutex
 	stats  *Stats
 	name   string
 	logger *slog.Logger
+	store  StatsStore
+
+	// Outcomes of previous sessions, they don't change so they are only loaded from the store once
+	historyMu     sync.Mutex
+	history       []analytics.Outcome
+	historyLoaded bool
 }
 
-func NewStatsHandler(name string, logger *slog.Logger) *StatsHandler {
+func NewStatsHandler(name string, logger *slog.Logger, store StatsStore) *StatsHandler {
 	return &StatsHandler{
 		name:   name,
 		logger: logger,
+		store:  store,
 		stats: &Stats{
 			SupervisorStatus: Starting,
 			StartedAt:        time.Now(),
@@ -43,80 +55,183 @@ func (h *StatsHandler) Handle(_ context.Context, e event.Event) error {
 		return nil
 	}
 
+	if h.store != nil {
+		if err := h.store.Record(h.name, e); err != nil {
+			h.logger.Warn("Failed persisting stats event", slog.Any("error", err))
+		}
+	}
+
+	h.mu.Lock()
+	h.stats.apply(e)
+	h.mu.Unlock()
+
+	return nil
+}
+
+// Stats returns a copy of the current stats, it's not modified by the events applied later
+func (h *StatsHandler) Stats() Stats {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	return h.stats.clone()
+}
+
+// Outcomes returns every finished run of the supervisor, including previous sessions if stats are persisted
+func (h *StatsHandler) Outcomes() []analytics.Outcome {
+	h.mu.RLock()
+	current := h.stats.Outcomes()
+	startedAt := h.stats.StartedAt
+	h.mu.RUnlock()
+	if h.store == nil {
+		return current
+	}
+
+	h.historyMu.Lock()
+	defer h.historyMu.Unlock()
+
+	if !h.historyLoaded {
+		// Events of this session are already in memory, only the previous ones are read
+		st, err := h.store.Load(h.name, time.Time{}, startedAt)
+		if err != nil {
+			h.logger.Warn("Failed loading stats history, using current session only", slog.Any("error", err))
+			return current
+		}
+		h.history = st.Outcomes()
+		h.historyLoaded = true
+	}
+
+	return append(slices.Clip(h.history), current...)
+}
+
+type Stats struct {
+	StartedAt        time.Time
+	SupervisorStatus SupervisorStatus
+	Details          string
+	Drops            []data.Drop
+	Games            []GameStats
+}
+
+type GameStats struct {
+	StartedAt  time.Time
+	FinishedAt time.Time
+	Reason     event.FinishReason
+	Runs       []RunStats
+}
+
+type RunStats struct {
+	Name        string
+	Reason      event.FinishReason
+	StartedAt   time.Time
+	Items       []data.Item
+	FinishedAt  time.Time
+	UsedPotions []event.UsedPotionEvent
+	// Items picked up during the run and stashed later, in this run or in the next ones
+	Drops []data.Drop
+}
+
+// clone copies the games and runs, apply changes them in place
+func (s Stats) clone() Stats {
+	s.Drops = slices.Clone(s.Drops)
+	s.Games = slices.Clone(s.Games)
+	for i := range s.Games {
+		s.Games[i].Runs = slices.Clone(s.Games[i].Runs)
+	}
+
+	return s
+}
+
+// apply updates the stats with the given event, it's used both for live events and for events loaded from a StatsStore
+func (s *Stats) apply(e event.Event) {
 	switch evt := e.(type) {
 	case event.GameCreatedEvent:
-		h.stats.Games = append(h.stats.Games, GameStats{
+		s.Games = append(s.Games, GameStats{
 			StartedAt: evt.OccurredAt(),
 		})
-		h.stats.SupervisorStatus = InGame
+		s.SupervisorStatus = InGame
 
 	case event.GameFinishedEvent:
-		if len(h.stats.Games) > 0 {
-			h.stats.Games[len(h.stats.Games)-1].FinishedAt = evt.OccurredAt()
-			h.stats.Games[len(h.stats.Games)-1].Reason = evt.Reason
+		if len(s.Games) > 0 {
+			s.Games[len(s.Games)-1].FinishedAt = evt.OccurredAt()
+			s.Games[len(s.Games)-1].Reason = evt.Reason
 		}
 
 	case event.RunStartedEvent:
-		if len(h.stats.Games) > 0 {
-			h.stats.Games[len(h.stats.Games)-1].Runs = append(h.stats.Games[len(h.stats.Games)-1].Runs, RunStats{
+		if len(s.Games) > 0 {
+			s.Games[len(s.Games)-1].Runs = append(s.Games[len(s.Games)-1].Runs, RunStats{
 				Name:      evt.RunName,
 				StartedAt: evt.OccurredAt(),
 			})
 		}
 
 	case event.RunFinishedEvent:
-		if len(h.stats.Games) > 0 && len(h.stats.Games[len(h.stats.Games)-1].Runs) > 0 {
-			lastRun := &h.stats.Games[len(h.stats.Games)-1].Runs[len(h.stats.Games[len(h.stats.Games)-1].Runs)-1]
+		if len(s.Games) > 0 && len(s.Games[len(s.Games)-1].Runs) > 0 {
+			lastRun := &s.Games[len(s.Games)-1].Runs[len(s.Games[len(s.Games)-1].Runs)-1]
 			lastRun.FinishedAt = evt.OccurredAt()
 			lastRun.Reason = evt.Reason
 		}
 
 	case event.GamePausedEvent:
 		if evt.Paused {
-			h.stats.SupervisorStatus = Paused
+			s.SupervisorStatus = Paused
 		} else {
-			h.stats.SupervisorStatus = InGame
+			s.SupervisorStatus = InGame
 		}
 
 	case event.ItemStashedEvent:
-		h.stats.Drops = append(h.stats.Drops, evt.Item)
+		s.Drops = append(s.Drops, evt.Item)
+		if r := s.pickedUpIn(evt.RunName, evt.PickedUpAt); r != nil {
+			r.Drops = append(r.Drops, evt.Item)
+		}
 
 	case event.UsedPotionEvent:
-		if len(h.stats.Games) > 0 && len(h.stats.Games[len(h.stats.Games)-1].Runs) > 0 {
-			lastRun := &h.stats.Games[len(h.stats.Games)-1].Runs[len(h.stats.Games[len(h.stats.Games)-1].Runs)-1]
+		if len(s.Games) > 0 && len(s.Games[len(s.Games)-1].Runs) > 0 {
+			lastRun := &s.Games[len(s.Games)-1].Runs[len(s.Games[len(s.Games)-1].Runs)-1]
 			lastRun.UsedPotions = append(lastRun.UsedPotions, evt)
 		}
 	}
-
-	return nil
 }
 
-func (h *StatsHandler) Stats() Stats {
-	return *h.stats
-}
+// pickedUpIn returns the run with the given name where an item picked up at the given time was found, it can be in a
+// previous game. Without the time, only the last run with the name in the current game is taken into account
+func (s *Stats) pickedUpIn(name string, at time.Time) *RunStats {
+	if name == "" {
+		return nil
+	}
 
-type Stats struct {
-	StartedAt        time.Time
-	SupervisorStatus SupervisorStatus
-	Details          string
-	Drops            []data.Drop
-	Games            []GameStats
-}
+	for g := len(s.Games) - 1; g >= 0; g-- {
+		runs := s.Games[g].Runs
+		for i := len(runs) - 1; i >= 0; i-- {
+			if runs[i].Name == name && (at.IsZero() || !at.Before(runs[i].StartedAt)) {
+				return &runs[i]
+			}
+		}
+		// Runs of older games started before this one
+		if at.IsZero() || !at.Before(s.Games[g].StartedAt) {
+			return nil
+		}
+	}
 
-type GameStats struct {
-	StartedAt  time.Time
-	FinishedAt time.Time
-	Reason     event.FinishReason
-	Runs       []RunStats
+	return nil
 }
 
-type RunStats struct {
-	Name        string
-	Reason      event.FinishReason
-	StartedAt   time.Time
-	Items       []data.Item
-	FinishedAt  time.Time
-	UsedPotions []event.UsedPotionEvent
+// Outcomes returns the finished runs, oldest first
+func (s Stats) Outcomes() []analytics.Outcome {
+	outcomes := make([]analytics.Outcome, 0)
+	for _, g := range s.Games {
+		for _, r := range g.Runs {
+			if r.FinishedAt.IsZero() {
+				continue
+			}
+
+			o := analytics.Outcome{Run: r.Name, Reason: r.Reason, StartedAt: r.StartedAt, FinishedAt: r.FinishedAt}
+			for _, d := range r.Drops {
+				o.StashedRules = append(o.StashedRules, d.RuleFile)
+			}
+			outcomes = append(outcomes, o)
+		}
+	}
+
+	return outcomes
 }
 
 func (s Stats) TotalGames() int {
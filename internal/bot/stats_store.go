@@ -0,0 +1,308 @@
+package bot
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/hectorgimenez/d2go/pkg/data"
+	"github.com/hectorgimenez/koolo/internal/event"
+	"github.com/hectorgimenez/koolo/internal/jsonl"
+)
+
+// StatsStore persists the events used to build the supervisor Stats, so they survive Koolo restarts
+type StatsStore interface {
+	// Record stores the event for the given supervisor, events not relevant for the stats are ignored
+	Record(supervisor string, e event.Event) error
+	// Load rebuilds the Stats for the given supervisor with the events between from and to, zero values mean no limit
+	Load(supervisor string, from, to time.Time) (Stats, error)
+	Close() error
+}
+
+const (
+	recordGameCreated  = "game_created"
+	recordGameFinished = "game_finished"
+	recordRunStarted   = "run_started"
+	recordRunFinished  = "run_finished"
+	recordItemStashed  = "item_stashed"
+	recordUsedPotion   = "used_potion"
+)
+
+type statsRecord struct {
+	Type       string             `json:"type"`
+	OccurredAt time.Time          `json:"occurredAt"`
+	GameName   string             `json:"gameName,omitempty"`
+	RunName    string             `json:"runName,omitempty"`
+	Reason     event.FinishReason `json:"reason,omitempty"`
+	Drop       *data.Drop         `json:"drop,omitempty"`
+	PickedUpAt *time.Time         `json:"pickedUpAt,omitempty"`
+	PotionType data.PotionType    `json:"potionType,omitempty"`
+	OnMerc     bool               `json:"onMerc,omitempty"`
+}
+
+func newStatsRecord(e event.Event) (statsRecord, bool) {
+	rec := statsRecord{OccurredAt: e.OccurredAt()}
+	switch evt := e.(type) {
+	case event.GameCreatedEvent:
+		rec.Type = recordGameCreated
+		rec.GameName = evt.Name
+	case event.GameFinishedEvent:
+		rec.Type = recordGameFinished
+		rec.Reason = evt.Reason
+	case event.RunStartedEvent:
+		rec.Type = recordRunStarted
+		rec.RunName = evt.RunName
+	case event.RunFinishedEvent:
+		rec.Type = recordRunFinished
+		rec.RunName = evt.RunName
+		rec.Reason = evt.Reason
+	case event.ItemStashedEvent:
+		rec.Type = recordItemStashed
+		rec.Drop = &evt.Item
+		rec.RunName = evt.RunName
+		if !evt.PickedUpAt.IsZero() {
+			rec.PickedUpAt = &evt.PickedUpAt
+		}
+	case event.UsedPotionEvent:
+		rec.Type = recordUsedPotion
+		rec.PotionType = evt.PotionType
+		rec.OnMerc = evt.OnMerc
+	default:
+		return statsRecord{}, false
+	}
+
+	return rec, true
+}
+
+func (r statsRecord) event(supervisor string) event.Event {
+	be := event.TextAt(supervisor, "", r.OccurredAt)
+	switch r.Type {
+	case recordGameCreated:
+		return event.GameCreated(be, r.GameName, "")
+	case recordGameFinished:
+		return event.GameFinished(be, r.Reason)
+	case recordRunStarted:
+		return event.RunStarted(be, r.RunName)
+	case recordRunFinished:
+		return event.RunFinished(be, r.RunName, r.Reason)
+	case recordItemStashed:
+		if r.Drop != nil {
+			var pickedUpAt time.Time
+			if r.PickedUpAt != nil {
+				pickedUpAt = *r.PickedUpAt
+			}
+			return event.ItemStashed(be, *r.Drop, r.RunName, pickedUpAt)
+		}
+	case recordUsedPotion:
+		return event.UsedPotion(be, r.PotionType, r.OnMerc)
+	}
+
+	return nil
+}
+
+// maxCachedRecords is the number of records kept in memory per supervisor, older ones are read from disk when needed
+const maxCachedRecords = 50000
+
+// JSONLStatsStore is an append-only StatsStore, it writes one JSON line per event into {dir}/{supervisor}.jsonl
+type JSONLStatsStore struct {
+	dir       string
+	maxCached int
+	mu        sync.Mutex
+	files     map[string]*os.File
+	caches    map[string]*recordCache
+}
+
+// recordCache keeps the latest records of a supervisor, the oldest ones are dropped when it grows over the limit
+type recordCache struct {
+	records []statsRecord
+	// truncated is set when records were dropped, all of them happened at or before droppedUntil
+	truncated    bool
+	droppedUntil time.Time
+}
+
+func (c *recordCache) add(rec statsRecord, limit int) {
+	c.records = append(c.records, rec)
+	if len(c.records) <= limit {
+		return
+	}
+
+	// A tenth is dropped at once, so the records are not copied every time a new one is added
+	drop := len(c.records) - limit + limit/10
+	c.droppedUntil = c.records[drop-1].OccurredAt
+	c.records = slices.Clone(c.records[drop:])
+	c.truncated = true
+}
+
+// covers returns true if every record since from is cached
+func (c *recordCache) covers(from time.Time) bool {
+	return !c.truncated || from.After(c.droppedUntil)
+}
+
+func NewJSONLStatsStore(dir string) (*JSONLStatsStore, error) {
+	if dir == "" {
+		dir = "stats"
+	}
+
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		return nil, fmt.Errorf("error creating stats directory: %w", err)
+	}
+
+	return &JSONLStatsStore{
+		dir:       dir,
+		maxCached: maxCachedRecords,
+		files:     make(map[string]*os.File),
+		caches:    make(map[string]*recordCache),
+	}, nil
+}
+
+func (s *JSONLStatsStore) Record(supervisor string, e event.Event) error {
+	rec, ok := newStatsRecord(e)
+	if !ok {
+		return nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	f, found := s.files[supervisor]
+	if !found {
+		var err error
+		f, err = jsonl.OpenAppend(s.filePath(supervisor))
+		if err != nil {
+			return fmt.Errorf("error opening stats file: %w", err)
+		}
+		s.files[supervisor] = f
+	}
+
+	if err := jsonl.Append(f, rec); err != nil {
+		return fmt.Errorf("error writing stats record: %w", err)
+	}
+
+	// Records not loaded yet are read from the file the first time they are needed, this one included
+	if c, found := s.caches[supervisor]; found {
+		c.add(rec, s.maxCached)
+	}
+
+	return nil
+}
+
+func (s *JSONLStatsStore) Load(supervisor string, from, to time.Time) (Stats, error) {
+	st := Stats{SupervisorStatus: NotStarted}
+	replay := func(rec statsRecord) {
+		if !from.IsZero() && rec.OccurredAt.Before(from) {
+			return
+		}
+		if !to.IsZero() && rec.OccurredAt.After(to) {
+			return
+		}
+
+		e := rec.event(supervisor)
+		if e == nil {
+			return
+		}
+
+		if st.StartedAt.IsZero() {
+			st.StartedAt = rec.OccurredAt
+		}
+		st.apply(e)
+	}
+
+	if err := s.eachRecord(supervisor, from, replay); err != nil {
+		return Stats{}, err
+	}
+
+	// Status is not part of the history, apply() may have changed it while replaying
+	st.SupervisorStatus = NotStarted
+
+	return st, nil
+}
+
+func (s *JSONLStatsStore) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var errs []error
+	for name, f := range s.files {
+		errs = append(errs, f.Close())
+		delete(s.files, name)
+	}
+
+	return errors.Join(errs...)
+}
+
+// eachRecord calls fn for every record since from, oldest first. The file is read without holding the mutex, so
+// Record is not blocked while a long history is loaded
+func (s *JSONLStatsStore) eachRecord(supervisor string, from time.Time, fn func(statsRecord)) error {
+	s.mu.Lock()
+	c, cached := s.caches[supervisor]
+	if cached && c.covers(from) {
+		// add never changes the records already in the slice
+		records := c.records
+		s.mu.Unlock()
+		for _, rec := range records {
+			fn(rec)
+		}
+		return nil
+	}
+	// Records are written holding the mutex, so the file is made of complete records up to this size
+	size, err := s.fileSize(supervisor)
+	s.mu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	if cached {
+		// Older records than the cached ones are only kept on disk
+		return s.readRecords(supervisor, 0, size, fn)
+	}
+
+	c = &recordCache{}
+	if err = s.readRecords(supervisor, 0, size, func(rec statsRecord) { c.add(rec, s.maxCached) }); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	if _, found := s.caches[supervisor]; !found {
+		// Records written while the file was read
+		if err = s.readRecords(supervisor, size, -1, func(rec statsRecord) { c.add(rec, s.maxCached) }); err != nil {
+			s.mu.Unlock()
+			return err
+		}
+		s.caches[supervisor] = c
+	}
+	s.mu.Unlock()
+
+	// Served from the cache now, or from disk if the cache doesn't go that far back
+	return s.eachRecord(supervisor, from, fn)
+}
+
+// fileSize returns the size of the stats file of the supervisor, 0 if it doesn't exist yet
+func (s *JSONLStatsStore) fileSize(supervisor string) (int64, error) {
+	info, err := os.Stat(s.filePath(supervisor))
+	if errors.Is(err, os.ErrNotExist) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("error reading stats file: %w", err)
+	}
+
+	return info.Size(), nil
+}
+
+// readRecords calls fn for every record stored on disk for the supervisor between the byte offsets from and to (to < 0
+// means until the end), oldest first
+func (s *JSONLStatsStore) readRecords(supervisor string, from, to int64, fn func(statsRecord)) error {
+	if err := jsonl.ReadRange(s.filePath(supervisor), from, to, fn); err != nil {
+		return fmt.Errorf("error reading stats file: %w", err)
+	}
+
+	return nil
+}
+
+func (s *JSONLStatsStore) filePath(supervisor string) string {
+	return filepath.Join(s.dir, supervisor+".jsonl")
+}
@@ -0,0 +1,320 @@
+package bot
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/hectorgimenez/d2go/pkg/data"
+	"github.com/hectorgimenez/d2go/pkg/data/item"
+	"github.com/hectorgimenez/koolo/internal/event"
+)
+
+var day = time.Date(2024, 12, 1, 10, 0, 0, 0, time.UTC)
+
+func record(t *testing.T, s *JSONLStatsStore, events ...event.Event) {
+	t.Helper()
+
+	for _, e := range events {
+		if err := s.Record("sorc", e); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// recordGame records a game with a finished mephisto run and a stashed item, starting at the given time
+func recordGame(t *testing.T, s *JSONLStatsStore, at time.Time, reason event.FinishReason) {
+	t.Helper()
+
+	be := event.TextAt("sorc", "", at)
+	record(t, s,
+		event.GameCreated(be, "mf-1", ""),
+		event.RunStarted(be, "mephisto"),
+		event.ItemStashed(event.TextAt("sorc", "", at.Add(time.Minute)), data.Drop{Item: data.Item{Name: "Shako", Quality: item.QualityUnique}}, "mephisto", at.Add(30*time.Second)),
+		event.RunFinished(event.TextAt("sorc", "", at.Add(2*time.Minute)), "mephisto", reason),
+		event.GameFinished(event.TextAt("sorc", "", at.Add(3*time.Minute)), event.FinishedOK),
+	)
+}
+
+func load(t *testing.T, s *JSONLStatsStore, from, to time.Time) Stats {
+	t.Helper()
+
+	st, err := s.Load("sorc", from, to)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return st
+}
+
+func TestStatsStoreAppendAndReload(t *testing.T) {
+	dir := t.TempDir()
+	s, err := NewJSONLStatsStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	recordGame(t, s, day, event.FinishedOK)
+	// Events not used by the stats are not stored
+	record(t, s, event.GamePaused(event.TextAt("sorc", "", day), true))
+	recordGame(t, s, day.Add(time.Hour), event.FinishedDied)
+
+	st := load(t, s, time.Time{}, time.Time{})
+	if st.TotalGames() != 2 || st.TotalDeaths() != 1 || len(st.Drops) != 2 || len(st.Games[0].Runs[0].Drops) != 1 {
+		t.Fatalf("unexpected stats %+v", st)
+	}
+	if st.SupervisorStatus != NotStarted || !st.StartedAt.Equal(day) {
+		t.Errorf("unexpected status %s or start %s", st.SupervisorStatus, st.StartedAt)
+	}
+	if err = s.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// A new store reads the history from disk and keeps appending to it
+	s, err = NewJSONLStatsStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	recordGame(t, s, day.Add(2*time.Hour), event.FinishedChicken)
+	if st = load(t, s, time.Time{}, time.Time{}); st.TotalGames() != 3 || st.TotalChickens() != 1 || len(st.Outcomes()) != 3 {
+		t.Errorf("unexpected stats after reload %+v", st)
+	}
+}
+
+func TestStatsStoreSkipsTruncatedLine(t *testing.T) {
+	dir := t.TempDir()
+	s, err := NewJSONLStatsStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	recordGame(t, s, day, event.FinishedOK)
+	if err = s.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Koolo crashed while writing the last line
+	f, err := os.OpenFile(filepath.Join(dir, "sorc.jsonl"), os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = f.WriteString(`{"type":"game_created","occurredAt":"2024-12`); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	s, err = NewJSONLStatsStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	if st := load(t, s, time.Time{}, time.Time{}); st.TotalGames() != 1 || len(st.Drops) != 1 {
+		t.Errorf("the truncated line should be skipped, got %+v", st)
+	}
+}
+
+func TestStatsStoreRange(t *testing.T) {
+	s, err := NewJSONLStatsStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	for i := range 3 {
+		recordGame(t, s, day.Add(time.Duration(i)*24*time.Hour), event.FinishedOK)
+	}
+
+	for name, tc := range map[string]struct {
+		from, to time.Time
+		games    int
+	}{
+		"all":        {games: 3},
+		"from":       {from: day.Add(24 * time.Hour), games: 2},
+		"to":         {to: day.Add(24*time.Hour + time.Hour), games: 2},
+		"day":        {from: day.Add(24 * time.Hour), to: day.Add(25 * time.Hour), games: 1},
+		"no matches": {from: day.Add(-2 * time.Hour), to: day.Add(-time.Hour)},
+	} {
+		st := load(t, s, tc.from, tc.to)
+		if st.TotalGames() != tc.games {
+			t.Errorf("%s: expected %d games, got %d", name, tc.games, st.TotalGames())
+		}
+		if tc.games > 0 && st.StartedAt.IsZero() {
+			t.Errorf("%s: start should be the first record in range", name)
+		}
+	}
+}
+
+func TestStatsStoreCacheLimit(t *testing.T) {
+	s, err := NewJSONLStatsStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	s.maxCached = 20
+
+	// 5 records per game, older games don't fit in memory
+	for i := range 10 {
+		recordGame(t, s, day.Add(time.Duration(i)*time.Hour), event.FinishedOK)
+	}
+	// Later records are added to the cache once it's loaded
+	load(t, s, day.Add(9*time.Hour), time.Time{})
+	recordGame(t, s, day.Add(10*time.Hour), event.FinishedOK)
+
+	c := s.caches["sorc"]
+	if len(c.records) > s.maxCached || !c.truncated {
+		t.Fatalf("cache should be limited to %d records, got %d", s.maxCached, len(c.records))
+	}
+
+	// Ranges not fully cached are read from disk
+	if st := load(t, s, time.Time{}, time.Time{}); st.TotalGames() != 11 || len(st.Drops) != 11 {
+		t.Errorf("expected the 11 games from disk, got %d", st.TotalGames())
+	}
+	if st := load(t, s, day.Add(8*time.Hour), time.Time{}); st.TotalGames() != 3 || len(st.Drops) != 3 {
+		t.Errorf("expected the last 3 games, got %d", st.TotalGames())
+	}
+}
+
+func TestStashedInLaterGame(t *testing.T) {
+	s, err := NewJSONLStatsStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	next := day.Add(10 * time.Minute)
+	record(t, s,
+		event.GameCreated(event.TextAt("sorc", "", day), "mf-1", ""),
+		event.RunStarted(event.TextAt("sorc", "", day), "pit"),
+		event.RunFinished(event.TextAt("sorc", "", day.Add(5*time.Minute)), "pit", event.FinishedOK),
+		event.GameCreated(event.TextAt("sorc", "", next), "mf-2", ""),
+		event.RunStarted(event.TextAt("sorc", "", next), "pit"),
+		// Picked up in the first game, stashed in the second one
+		event.ItemStashed(event.TextAt("sorc", "", next.Add(time.Minute)), data.Drop{RuleFile: "runes.nip"}, "pit", day.Add(2*time.Minute)),
+		event.ItemStashed(event.TextAt("sorc", "", next.Add(time.Minute)), data.Drop{RuleFile: "unique.nip"}, "pit", next.Add(30*time.Second)),
+	)
+
+	st := load(t, s, time.Time{}, time.Time{})
+	first, second := st.Games[0].Runs[0].Drops, st.Games[1].Runs[0].Drops
+	if len(first) != 1 || first[0].RuleFile != "runes.nip" || len(second) != 1 || second[0].RuleFile != "unique.nip" {
+		t.Errorf("unexpected drops, first game %v, second game %v", first, second)
+	}
+}
+
+func TestStatsStoreRecordWhileLoading(t *testing.T) {
+	dir := t.TempDir()
+	s, err := NewJSONLStatsStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range 50 {
+		recordGame(t, s, day.Add(time.Duration(i)*time.Hour), event.FinishedOK)
+	}
+	s.Close()
+
+	// New store, the first load reads the file and builds the cache while games are recorded
+	s, err = NewJSONLStatsStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := range 50 {
+			at := day.Add(time.Duration(50+i) * time.Hour)
+			if err := s.Record("sorc", event.GameCreated(event.TextAt("sorc", "", at), "mf-1", "")); err != nil {
+				t.Error(err)
+			}
+		}
+	}()
+	for range 5 {
+		load(t, s, time.Time{}, time.Time{})
+	}
+	<-done
+
+	if st := load(t, s, time.Time{}, time.Time{}); st.TotalGames() != 100 {
+		t.Errorf("expected 100 games, got %d", st.TotalGames())
+	}
+}
+
+// countingStore counts the loads, previous sessions should only be read once
+type countingStore struct {
+	StatsStore
+	loads int
+}
+
+func (s *countingStore) Load(supervisor string, from, to time.Time) (Stats, error) {
+	s.loads++
+	return s.StatsStore.Load(supervisor, from, to)
+}
+
+func TestStatsHandlerOutcomes(t *testing.T) {
+	s, err := NewJSONLStatsStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	recordGame(t, s, day, event.FinishedChicken)
+
+	store := &countingStore{StatsStore: s}
+	h := NewStatsHandler("sorc", slog.New(slog.NewTextHandler(io.Discard, nil)), store)
+	now := time.Now()
+	for i := range 3 {
+		at := now.Add(time.Duration(i) * time.Minute)
+		for _, e := range []event.Event{
+			event.GameCreated(event.TextAt("sorc", "", at), "mf", ""),
+			event.RunStarted(event.TextAt("sorc", "", at), "pit"),
+			event.RunFinished(event.TextAt("sorc", "", at.Add(30*time.Second)), "pit", event.FinishedOK),
+		} {
+			if err = h.Handle(context.Background(), e); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		outcomes := h.Outcomes()
+		if len(outcomes) != i+2 || outcomes[0].Run != "mephisto" || outcomes[0].Reason != event.FinishedChicken || outcomes[i+1].Run != "pit" {
+			t.Fatalf("unexpected outcomes %+v", outcomes)
+		}
+	}
+
+	if store.loads != 1 {
+		t.Errorf("previous sessions should be loaded once, loaded %d times", store.loads)
+	}
+}
+
+func TestStatsHandlerConcurrentReads(t *testing.T) {
+	h := NewStatsHandler("sorc", slog.New(slog.NewTextHandler(io.Discard, nil)), nil)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := range 200 {
+			at := day.Add(time.Duration(i) * time.Minute)
+			_ = h.Handle(context.Background(), event.GameCreated(event.TextAt("sorc", "", at), "mf", ""))
+			_ = h.Handle(context.Background(), event.RunStarted(event.TextAt("sorc", "", at), "pit"))
+			_ = h.Handle(context.Background(), event.RunFinished(event.TextAt("sorc", "", at.Add(time.Second)), "pit", event.FinishedOK))
+		}
+	}()
+
+	// Read while the events are applied, run with -race
+	for running := true; running; {
+		select {
+		case <-done:
+			running = false
+		default:
+		}
+		_ = h.Outcomes()
+		_ = h.Stats().TotalGames()
+	}
+
+	if st := h.Stats(); st.TotalGames() != 200 || len(h.Outcomes()) != 200 {
+		t.Errorf("expected 200 games and outcomes, got %d and %d", st.TotalGames(), len(h.Outcomes()))
+	}
+}
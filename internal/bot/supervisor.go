@@ -2,16 +2,22 @@ package bot
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
 	"os"
 	"strings"
 	"time"
 
+	"github.com/hectorgimenez/d2go/pkg/data/skill"
+	"github.com/hectorgimenez/koolo/internal/analytics"
+	"github.com/hectorgimenez/koolo/internal/config"
 	ct "github.com/hectorgimenez/koolo/internal/context"
 	"github.com/hectorgimenez/koolo/internal/event"
 	"github.com/hectorgimenez/koolo/internal/game"
+	"github.com/hectorgimenez/koolo/internal/health"
 	"github.com/hectorgimenez/koolo/internal/run"
+	"github.com/hectorgimenez/koolo/internal/utils"
 	"github.com/hectorgimenez/koolo/internal/utils/winproc"
 	"github.com/lxn/win"
 )
@@ -25,12 +31,26 @@ type Supervisor interface {
 	SetWindowPosition(x, y int)
 	GetData() *game.Data
 	GetContext() *ct.Context
+	// QueueConfig replaces the running config at the next safe point
+	QueueConfig(cfg *config.CharacterCfg)
+	PendingConfig() bool
+	RunAnalytics() RunAnalytics
+	Rotation() *analytics.Rotation
+}
+
+// RunAnalytics is the efficiency of every run and the decisions taken by the adaptive runs mode
+type RunAnalytics struct {
+	Adaptive  bool                  `json:"adaptive"`
+	Reports   []analytics.RunReport `json:"reports"`
+	Dropped   map[string]time.Time  `json:"dropped"`
+	Decisions []analytics.Decision  `json:"decisions"`
 }
 
 type baseSupervisor struct {
 	bot          *Bot
 	name         string
 	statsHandler *StatsHandler
+	rotation     *analytics.Rotation
 	cancelFn     context.CancelFunc
 }
 
@@ -43,6 +63,7 @@ func newBaseSupervisor(
 		bot:          bot,
 		name:         name,
 		statsHandler: statsHandler,
+		rotation:     analytics.NewRotation(analytics.RotationSettings{}, string(config.LevelingRun)),
 	}, nil
 }
 
@@ -54,6 +75,66 @@ func (s *baseSupervisor) Stats() Stats {
 	return s.statsHandler.Stats()
 }
 
+func (s *baseSupervisor) Rotation() *analytics.Rotation {
+	return s.rotation
+}
+
+func (s *baseSupervisor) RunAnalytics() RunAnalytics {
+	return RunAnalytics{
+		Adaptive:  config.Characters[s.name].Game.AdaptiveRuns.Enabled,
+		Reports:   analytics.Report(s.statsHandler.Outcomes()),
+		Dropped:   s.rotation.Dropped(time.Now()),
+		Decisions: s.rotation.Decisions(),
+	}
+}
+
+// adaptRuns reorders the runs by failure rate and removes the ones failing too much, if adaptive runs are enabled
+func (s *baseSupervisor) adaptRuns(runs []run.Run) []run.Run {
+	cfg := config.Characters[s.name].Game.AdaptiveRuns
+	if !cfg.Enabled {
+		return runs
+	}
+
+	s.rotation.Configure(analytics.RotationSettings{
+		Window:         cfg.Window,
+		MinRuns:        cfg.MinRuns,
+		MaxFailureRate: float64(cfg.MaxFailureRate) / 100,
+		Cooldown:       time.Duration(cfg.CooldownMinutes) * time.Minute,
+	})
+
+	names := make([]string, 0, len(runs))
+	byName := make(map[string][]run.Run, len(runs))
+	for _, r := range runs {
+		if _, found := byName[r.Name()]; !found {
+			names = append(names, r.Name())
+		}
+		byName[r.Name()] = append(byName[r.Name()], r)
+	}
+
+	now := time.Now()
+	planned := s.rotation.Plan(names, s.statsHandler.Outcomes(), now)
+	for _, d := range s.rotation.Decisions() {
+		if !d.Time.Before(now) {
+			s.bot.ctx.Logger.Info("Adaptive runs: "+d.Reason, slog.String("run", d.Run), slog.String("action", d.Action))
+		}
+	}
+
+	adapted := make([]run.Run, 0, len(runs))
+	for _, name := range planned {
+		adapted = append(adapted, byName[name]...)
+	}
+
+	return adapted
+}
+
+func (s *baseSupervisor) QueueConfig(cfg *config.CharacterCfg) {
+	s.bot.QueueConfig(cfg)
+}
+
+func (s *baseSupervisor) PendingConfig() bool {
+	return s.bot.PendingConfig()
+}
+
 func (s *baseSupervisor) TogglePause() {
 	if s.bot.ctx.ExecutionPriority == ct.PriorityPause {
 		s.bot.ctx.MemoryInjector.Load()
@@ -78,6 +159,9 @@ func (s *baseSupervisor) Stop() {
 
 	s.bot.ctx.MemoryInjector.Unload()
 	s.bot.ctx.GameReader.Close()
+	if s.bot.ctx.ItemCapture != nil {
+		s.bot.ctx.ItemCapture.Close()
+	}
 
 	if s.bot.ctx.CharacterCfg.KillD2OnStop || s.bot.ctx.CharacterCfg.Scheduler.Enabled {
 		s.KillClient()
@@ -149,6 +233,72 @@ func (s *baseSupervisor) waitUntilCharacterSelectionScreen() error {
 	return nil
 }
 
+// checkKeyBindings pauses the bot if some of the character skills are not bound
+func (s *baseSupervisor) checkKeyBindings() {
+	missingKeybindings := s.bot.ctx.Char.CheckKeyBindings()
+	if len(missingKeybindings) == 0 {
+		return
+	}
+
+	var missingKeybindingsText = "Missing key binding for skill(s):"
+	for _, v := range missingKeybindings {
+		missingKeybindingsText += fmt.Sprintf("\n%s", skill.SkillNames[v])
+	}
+	missingKeybindingsText += "\nPlease bind the skills. Pausing bot..."
+
+	utils.ShowDialog("Missing keybindings for "+s.bot.ctx.Name, missingKeybindingsText)
+	s.TogglePause()
+}
+
+// enterLobby moves from the character selection screen to the bnet lobby, needed to create or join online games
+func (s *baseSupervisor) enterLobby() error {
+	for retryCount := 0; !s.bot.ctx.GameReader.IsInLobby(); retryCount++ {
+		// Prevent an infinite loop
+		if retryCount >= 5 {
+			return fmt.Errorf("failed to enter bnet lobby after 5 retries")
+		}
+
+		// Try to enter bnet lobby
+		s.bot.ctx.HID.Click(game.LeftButton, 744, 650)
+		utils.Sleep(1000)
+	}
+
+	return nil
+}
+
+// finishGame sends the game finished event with the reason of the error returned by the bot and leaves the game
+func (s *baseSupervisor) finishGame(err error, gameStart time.Time) error {
+	if err != nil {
+		var gameFinishReason event.FinishReason
+		switch {
+		case errors.Is(err, health.ErrChicken):
+			gameFinishReason = event.FinishedChicken
+		case errors.Is(err, health.ErrMercChicken):
+			gameFinishReason = event.FinishedMercChicken
+		case errors.Is(err, health.ErrDied):
+			gameFinishReason = event.FinishedDied
+		default:
+			gameFinishReason = event.FinishedError
+		}
+		event.Send(event.GameFinished(event.WithScreenshot(s.name, err.Error(), s.bot.ctx.GameReader.Screenshot()), gameFinishReason))
+		s.bot.ctx.Logger.Warn(
+			fmt.Sprintf("Game finished with errors, reason: %s. Game total time: %0.2fs", err.Error(), time.Since(gameStart).Seconds()),
+			slog.String("supervisor", s.name),
+			slog.Uint64("mapSeed", uint64(s.bot.ctx.GameReader.MapSeed())),
+		)
+	} else {
+		event.Send(event.GameFinished(event.Text(s.name, "Game finished successfully"), event.FinishedOK))
+	}
+
+	if exitErr := s.bot.ctx.Manager.ExitGame(); exitErr != nil {
+		errMsg := fmt.Sprintf("Error exiting game %s", exitErr.Error())
+		event.Send(event.GameFinished(event.WithScreenshot(s.name, errMsg, s.bot.ctx.GameReader.Screenshot()), event.FinishedError))
+		return errors.New(errMsg)
+	}
+
+	return nil
+}
+
 func (s *baseSupervisor) SetWindowPosition(x, y int) {
 	uFlags := win.SWP_NOZORDER | win.SWP_NOSIZE | win.SWP_NOACTIVATE
 	win.SetWindowPos(s.bot.ctx.GameReader.HWND, 0, int32(x), int32(y), 0, 0, uint32(uFlags))
@@ -0,0 +1,244 @@
+// Package companion coordinates a leader supervisor creating online games with the follower supervisors joining them.
+// The leader publishes the games, portals and attack targets on the event bus and followers react to them
+package companion
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hectorgimenez/d2go/pkg/data"
+	"github.com/hectorgimenez/koolo/internal/config"
+	"github.com/hectorgimenez/koolo/internal/event"
+)
+
+const (
+	// Leader targets older than this are ignored, the leader announces them again while attacking
+	targetTTL = 5 * time.Second
+	// Joining the same game is retried a few times, the game may not be ready yet when the leader publishes it
+	maxJoinAttempts = 3
+)
+
+// GameManager is the part of game.Manager used to create and join games
+type GameManager interface {
+	CreateOnlineGame(gameCounter int) (string, error)
+	JoinOnlineGame(gameName, password string) error
+}
+
+type Game struct {
+	Name     string
+	Password string
+}
+
+// Leader creates the games and publishes them for the followers
+type Leader struct {
+	supervisor string
+	password   string
+	manager    GameManager
+	send       func(event.Event)
+	counter    int
+}
+
+func NewLeader(supervisor, password string, manager GameManager) *Leader {
+	return &Leader{
+		supervisor: supervisor,
+		password:   password,
+		manager:    manager,
+		send:       event.Send,
+		counter:    1,
+	}
+}
+
+// CreateGame creates a new online game and publishes it, the counter is increased even on error because sometimes the
+// game is created but the join fails, so the name is already in use
+func (l *Leader) CreateGame() (Game, error) {
+	counter := l.counter
+	l.counter++
+
+	name, err := l.manager.CreateOnlineGame(counter)
+	if err != nil {
+		return Game{}, fmt.Errorf("error creating companion game: %w", err)
+	}
+
+	g := Game{Name: name, Password: l.password}
+	l.send(event.GameCreated(event.Text(l.supervisor, fmt.Sprintf("New game created: %s", name)), g.Name, g.Password))
+
+	return g, nil
+}
+
+// Follower tracks the leader events, it's meant to be subscribed to the event listener filtered by the leader
+// supervisor, see Handle
+type Follower struct {
+	supervisor string
+	leader     string
+	manager    GameManager
+	now        func() time.Time
+
+	mu sync.Mutex
+	// In game name of the leader, updated from the events of a remote leader
+	character   string
+	next        *Game
+	attempts    int
+	ready       chan struct{}
+	target      data.UnitID
+	targetAt    time.Time
+	tpRequested bool
+	leaderLeft  bool
+}
+
+func NewFollower(supervisor, leaderSupervisor, leaderCharacter string, manager GameManager) *Follower {
+	return &Follower{
+		supervisor: supervisor,
+		leader:     leaderSupervisor,
+		character:  leaderCharacter,
+		manager:    manager,
+		now:        time.Now,
+		ready:      make(chan struct{}, 1),
+	}
+}
+
+// Leader returns the supervisor name of the leader
+func (f *Follower) Leader() string {
+	return f.leader
+}
+
+// LeaderCharacter returns the in game name of the leader, used to find the leader in the party and its portals
+func (f *Follower) LeaderCharacter() string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return f.character
+}
+
+// Handle is the event handler, events from other supervisors are ignored. The leader can be a supervisor running in
+// another koolo instance, its events are received through the hub
+func (f *Follower) Handle(_ context.Context, e event.Event) error {
+	if e.Supervisor() != f.leader {
+		return nil
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if remote, ok := e.(event.RemoteEvent); ok {
+		if remote.Character != "" {
+			f.character = remote.Character
+		}
+		e = remote.Event
+	}
+
+	switch evt := e.(type) {
+	case event.GameCreatedEvent:
+		if evt.Name == "" {
+			return nil
+		}
+		// Only the latest game matters, if we are still in the previous one the leader already left it
+		f.next = &Game{Name: evt.Name, Password: evt.Password}
+		f.attempts = 0
+		f.leaderLeft = true
+		select {
+		case f.ready <- struct{}{}:
+		default:
+		}
+	case event.GameFinishedEvent:
+		f.leaderLeft = true
+	case event.CompanionLeaderAttackEvent:
+		f.target = evt.TargetUnitID
+		f.targetAt = f.now()
+	case event.CompanionRequestedTPEvent:
+		f.tpRequested = true
+	}
+
+	return nil
+}
+
+// JoinNextGame waits for a game published by the leader and joins it. If the join fails the same game is retried on
+// the next call, unless the leader already published a new one
+func (f *Follower) JoinNextGame(ctx context.Context) (Game, error) {
+	for {
+		f.mu.Lock()
+		next := f.next
+		f.mu.Unlock()
+		if next != nil {
+			break
+		}
+
+		select {
+		case <-ctx.Done():
+			return Game{}, ctx.Err()
+		case <-f.ready:
+		}
+	}
+
+	f.mu.Lock()
+	g := *f.next
+	f.attempts++
+	if f.attempts >= maxJoinAttempts {
+		f.next = nil
+	}
+	f.mu.Unlock()
+
+	if err := f.manager.JoinOnlineGame(g.Name, g.Password); err != nil {
+		return g, fmt.Errorf("error joining companion game %s: %w", g.Name, err)
+	}
+
+	f.mu.Lock()
+	if f.next != nil && *f.next == g {
+		f.next = nil
+	}
+	f.target = 0
+	f.tpRequested = false
+	f.leaderLeft = f.next != nil
+	f.mu.Unlock()
+
+	return g, nil
+}
+
+// Target returns the last monster attacked by the leader, if it's recent enough
+func (f *Follower) Target() (data.UnitID, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.target == 0 || f.now().Sub(f.targetAt) > targetTTL {
+		return 0, false
+	}
+
+	return f.target, true
+}
+
+// TakeTPRequest returns true once after the leader opens a portal
+func (f *Follower) TakeTPRequest() bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	requested := f.tpRequested
+	f.tpRequested = false
+
+	return requested
+}
+
+// LeaderLeft returns true when the leader finished the current game or already created a new one
+func (f *Follower) LeaderLeft() bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return f.leaderLeft
+}
+
+// ResolveLeader finds the leader configured for a follower, the name can be the supervisor or the character name.
+// Returns the supervisor name and the in game character name, used to find the leader portals
+func ResolveLeader(name string, characters map[string]*config.CharacterCfg) (string, string, bool) {
+	if cfg, found := characters[name]; found {
+		return name, cfg.CharacterName, true
+	}
+
+	for supervisor, cfg := range characters {
+		if strings.EqualFold(cfg.CharacterName, name) || strings.EqualFold(supervisor, name) {
+			return supervisor, cfg.CharacterName, true
+		}
+	}
+
+	return "", "", false
+}
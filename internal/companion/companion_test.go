@@ -0,0 +1,195 @@
+package companion
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/hectorgimenez/koolo/internal/companion/companiontest"
+	"github.com/hectorgimenez/koolo/internal/config"
+	"github.com/hectorgimenez/koolo/internal/event"
+)
+
+// bus delivers the leader events to the followers, like the event listener does
+type bus struct {
+	followers []*Follower
+}
+
+func (b *bus) send(e event.Event) {
+	for _, f := range b.followers {
+		_ = f.Handle(context.Background(), e)
+	}
+}
+
+func TestLeaderAndFollowersJoinTheGame(t *testing.T) {
+	leaderManager := &companiontest.GameManager{FailNext: true}
+	b := &bus{}
+	leader := NewLeader("leader", "secret", leaderManager)
+	leader.send = b.send
+
+	managers := []*companiontest.GameManager{{}, {}}
+	for i, m := range managers {
+		b.followers = append(b.followers, NewFollower("follower"+strconv.Itoa(i+1), "leader", "Leader", m))
+	}
+
+	// The counter is increased even if the creation fails, the name may be in use
+	if _, err := leader.CreateGame(); err == nil {
+		t.Fatal("expected error creating the game")
+	}
+	g, err := leader.CreateGame()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if g != (Game{Name: "game-2", Password: "secret"}) || len(leaderManager.Created()) != 2 {
+		t.Fatalf("unexpected game %+v, created %v", g, leaderManager.Created())
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	for i, f := range b.followers {
+		joined, err := f.JoinNextGame(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if joined != g || len(managers[i].Joined()) != 1 || f.LeaderLeft() {
+			t.Errorf("follower %d joined %+v (%v), left %v", i, joined, managers[i].Joined(), f.LeaderLeft())
+		}
+	}
+
+	// Nothing else to join until the leader creates a new game
+	short, cancelShort := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancelShort()
+	if _, err = b.followers[0].JoinNextGame(short); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected to wait for the next game, got %v", err)
+	}
+}
+
+func TestFollowerWaitsForTheGame(t *testing.T) {
+	m := &companiontest.GameManager{}
+	f := NewFollower("follower", "leader", "Leader", m)
+
+	result := make(chan Game)
+	go func() {
+		g, _ := f.JoinNextGame(context.Background())
+		result <- g
+	}()
+
+	// Events from other supervisors are ignored
+	_ = f.Handle(context.Background(), event.GameCreated(event.Text("other", ""), "other-1", ""))
+	_ = f.Handle(context.Background(), event.GameCreated(event.Text("leader", ""), "game-1", "pass"))
+
+	select {
+	case g := <-result:
+		if g.Name != "game-1" || g.Password != "pass" {
+			t.Errorf("joined the wrong game: %+v", g)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("follower didn't join the game")
+	}
+}
+
+func TestFollowerRetriesJoin(t *testing.T) {
+	m := &companiontest.GameManager{FailJoins: 1}
+	f := NewFollower("follower", "leader", "Leader", m)
+	_ = f.Handle(context.Background(), event.GameCreated(event.Text("leader", ""), "game-1", ""))
+
+	if _, err := f.JoinNextGame(context.Background()); err == nil {
+		t.Fatal("expected join error")
+	}
+	if g, err := f.JoinNextGame(context.Background()); err != nil || g.Name != "game-1" {
+		t.Fatalf("expected to retry the same game, got %+v %v", g, err)
+	}
+
+	// Gives up after a few attempts
+	m.FailJoins = maxJoinAttempts
+	_ = f.Handle(context.Background(), event.GameCreated(event.Text("leader", ""), "game-2", ""))
+	for range maxJoinAttempts {
+		if _, err := f.JoinNextGame(context.Background()); err == nil {
+			t.Fatal("expected join error")
+		}
+	}
+	short, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := f.JoinNextGame(short); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected to give up the game, got %v", err)
+	}
+}
+
+func TestFollowerTracksTheLeader(t *testing.T) {
+	now := time.Now()
+	f := NewFollower("follower", "leader", "Leader", &companiontest.GameManager{})
+	f.now = func() time.Time { return now }
+
+	_ = f.Handle(context.Background(), event.GameCreated(event.Text("leader", ""), "game-1", ""))
+	if _, err := f.JoinNextGame(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, found := f.Target(); found {
+		t.Error("no target expected before the leader attacks")
+	}
+	_ = f.Handle(context.Background(), event.CompanionLeaderAttack(event.Text("other", ""), 7))
+	_ = f.Handle(context.Background(), event.CompanionLeaderAttack(event.Text("leader", ""), 42))
+	if target, found := f.Target(); !found || target != 42 {
+		t.Errorf("expected leader target 42, got %d %v", target, found)
+	}
+	now = now.Add(targetTTL + time.Second)
+	if _, found := f.Target(); found {
+		t.Error("old targets should be ignored")
+	}
+
+	_ = f.Handle(context.Background(), event.CompanionRequestedTP(event.Text("leader", "")))
+	if !f.TakeTPRequest() || f.TakeTPRequest() {
+		t.Error("tp request should be taken once")
+	}
+
+	_ = f.Handle(context.Background(), event.GameFinished(event.Text("leader", ""), event.FinishedOK))
+	if !f.LeaderLeft() {
+		t.Error("leader should have left the game")
+	}
+}
+
+func TestResolveLeader(t *testing.T) {
+	characters := map[string]*config.CharacterCfg{
+		"sorc": {CharacterName: "MySorc"},
+		"pala": {CharacterName: "MyPala"},
+	}
+
+	tests := []struct {
+		name       string
+		supervisor string
+		character  string
+		found      bool
+	}{
+		{"sorc", "sorc", "MySorc", true},
+		{"mypala", "pala", "MyPala", true},
+		{"unknown", "", "", false},
+	}
+	for _, tt := range tests {
+		supervisor, character, found := ResolveLeader(tt.name, characters)
+		if supervisor != tt.supervisor || character != tt.character || found != tt.found {
+			t.Errorf("%s: got %s %s %v", tt.name, supervisor, character, found)
+		}
+	}
+}
+
+func TestFollowerRemoteLeader(t *testing.T) {
+	f := NewFollower("follower", "leader", "", &companiontest.GameManager{})
+
+	remote := event.Remote(event.GameCreated(event.Text("leader", ""), "game-1", "pass"), "pc2", "LeaderChar")
+	_ = f.Handle(context.Background(), remote)
+	g, err := f.JoinNextGame(context.Background())
+	if err != nil || g.Name != "game-1" || g.Password != "pass" {
+		t.Fatalf("expected to join the remote leader game, got %+v %v", g, err)
+	}
+	if f.LeaderCharacter() != "LeaderChar" {
+		t.Errorf("leader character not learned from the remote event: %q", f.LeaderCharacter())
+	}
+
+	_ = f.Handle(context.Background(), event.Remote(event.CompanionLeaderAttack(event.Text("leader", ""), 42), "pc2", "LeaderChar"))
+	if target, found := f.Target(); !found || target != 42 {
+		t.Errorf("expected remote leader target 42, got %d %v", target, found)
+	}
+}
@@ -0,0 +1,59 @@
+// Package companiontest provides a fake companion.GameManager for the tests of the leaders and followers
+package companiontest
+
+import (
+	"errors"
+	"slices"
+	"strconv"
+	"sync"
+)
+
+// GameManager creates games named game-{counter} and records the games joined. Joins fail while FailJoins > 0 and
+// the next creation fails if FailNext is set
+type GameManager struct {
+	mu        sync.Mutex
+	created   []int
+	joined    []string
+	FailJoins int
+	FailNext  bool
+}
+
+func (m *GameManager) CreateOnlineGame(gameCounter int) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.created = append(m.created, gameCounter)
+	if m.FailNext {
+		m.FailNext = false
+		return "", errors.New("timeout")
+	}
+
+	return "game-" + strconv.Itoa(gameCounter), nil
+}
+
+func (m *GameManager) JoinOnlineGame(gameName, _ string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.FailJoins > 0 {
+		m.FailJoins--
+		return errors.New("timeout")
+	}
+	m.joined = append(m.joined, gameName)
+
+	return nil
+}
+
+// Created returns the game counters of every creation attempt
+func (m *GameManager) Created() []int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return slices.Clone(m.created)
+}
+
+// Joined returns the names of the games joined
+func (m *GameManager) Joined() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return slices.Clone(m.joined)
+}
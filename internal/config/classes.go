@@ -0,0 +1,7 @@
+package config
+
+// Classes supported by character.BuildCharacter, keep both lists in sync with it
+var (
+	AvailableClasses = []string{"sorceress", "nova", "hydraorb", "hammerdin", "foh", "trapsin", "mosaic", "winddruid", "javazon", "berserker"}
+	LevelingClasses  = []string{"sorceress_leveling_lightning", "sorceress_leveling", "paladin"}
+)
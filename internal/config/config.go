@@ -4,9 +4,11 @@ import (
 	"errors"
 	"fmt"
 	"path/filepath"
+	"slices"
 	"time"
 
 	"github.com/hectorgimenez/d2go/pkg/data"
+	"github.com/hectorgimenez/koolo/internal/schedule"
 	"github.com/hectorgimenez/koolo/internal/utils"
 
 	"os"
@@ -42,7 +44,39 @@ type KooloCfg struct {
 	D2LoDPath             string `yaml:"D2LoDPath"`
 	D2RPath               string `yaml:"D2RPath"`
 	CentralizedPickitPath string `yaml:"centralizedPickitPath"`
-	Discord               struct {
+	// Max amount of supervisors running at the same time, 0 means no limit
+	MaxConcurrentSupervisors int `yaml:"maxConcurrentSupervisors"`
+	// Reload character configs and pickit rules when their files change, running supervisors apply them between games
+	WatchConfig bool `yaml:"watchConfig"`
+	// Parsed map data of recent games, so the same game doesn't need koolo-map again
+	MapCache struct {
+		Enabled   bool   `yaml:"enabled"`
+		Directory string `yaml:"directory"`
+		MaxSizeMB int    `yaml:"maxSizeMB"`
+	} `yaml:"mapCache"`
+	// Pathfinding algorithm, "astar" or "hierarchical" (faster on big areas, paths can be slightly longer)
+	Pathfinding string `yaml:"pathfinding"`
+	Stats       struct {
+		Persist   bool   `yaml:"persist"`
+		Directory string `yaml:"directory"`
+	} `yaml:"stats"`
+	Server struct {
+		Auth struct {
+			Enabled        bool       `yaml:"enabled"`
+			Username       string     `yaml:"username"`
+			PasswordHash   string     `yaml:"passwordHash"`
+			TrustLocalhost bool       `yaml:"trustLocalhost"`
+			SessionHours   int        `yaml:"sessionHours"`
+			Tokens         []APIToken `yaml:"tokens"`
+		} `yaml:"auth"`
+		TLS struct {
+			CertFile string `yaml:"certFile"`
+			KeyFile  string `yaml:"keyFile"`
+		} `yaml:"tls"`
+		// Web UI port, 8087 by default. Change it to run more than one instance on the same machine
+		Port int `yaml:"port"`
+	} `yaml:"server"`
+	Discord struct {
 		Enabled                      bool     `yaml:"enabled"`
 		EnableGameCreatedMessages    bool     `yaml:"enableGameCreatedMessages"`
 		EnableNewRunMessages         bool     `yaml:"enableNewRunMessages"`
@@ -51,12 +85,93 @@ type KooloCfg struct {
 		BotAdmins                    []string `yaml:"botAdmins"`
 		ChannelID                    string   `yaml:"channelId"`
 		Token                        string   `yaml:"token"`
+		// Slash commands are registered in this server only, empty to register them globally (they can take up to an
+		// hour to show up)
+		GuildID      string              `yaml:"guildId"`
+		DropChannels DiscordDropChannels `yaml:"dropChannels"`
 	} `yaml:"discord"`
 	Telegram struct {
 		Enabled bool   `yaml:"enabled"`
 		ChatID  int64  `yaml:"chatId"`
 		Token   string `yaml:"token"`
+		// Telegram user IDs allowed to use the bot commands
+		BotAdmins []int64 `yaml:"botAdmins"`
 	}
+	Webhook struct {
+		Enabled   bool              `yaml:"enabled"`
+		Endpoints []WebhookEndpoint `yaml:"endpoints"`
+	} `yaml:"webhook"`
+	// Coordination between koolo instances running on different machines, companions can play with supervisors of
+	// other instances and the hub dashboard shows the supervisors of all of them
+	Hub struct {
+		// Accept other instances at /hub/ws, they must send the hub token
+		Enabled bool `yaml:"enabled"`
+		// Hub to connect to, like ws://192.168.1.10:8087/hub/ws, empty to not connect to any hub
+		URL string `yaml:"url"`
+		// Shared secret of the hub, required to enable it and sent when connecting to one
+		Token string `yaml:"token"`
+		// Name of this instance in the hub, the hostname by default
+		Instance string `yaml:"instance"`
+	} `yaml:"hub"`
+}
+
+// DiscordDropChannels sends the stashed items to their own channels, with the item stats and the pickit rule matching
+// them. Items are sent to the channel of the supervisor and to the channel of the item quality, the default channel is
+// used when none of them is configured
+type DiscordDropChannels struct {
+	Enabled bool `yaml:"enabled"`
+	// Empty to use the main channel
+	ChannelID string `yaml:"channelId"`
+	// Supervisor name -> channel ID
+	Supervisors map[string]string `yaml:"supervisors"`
+	// Item quality (LowQuality, Normal, Superior, Magic, Set, Rare, Unique or Crafted) -> channel ID
+	Qualities map[string]string `yaml:"qualities"`
+}
+
+type WebhookEndpoint struct {
+	Name string `yaml:"name"`
+	URL  string `yaml:"url"`
+	// Payload shape: generic (default), slack, matrix, ntfy, gotify or custom (uses Template)
+	Format   string `yaml:"format"`
+	Template string `yaml:"template"`
+	// Events to send: game_created, game_finished, run_started, run_finished, item_stashed, chicken, death, error,
+	// used_potion, game_paused or all. Empty sends the default ones.
+	Events     []string          `yaml:"events"`
+	Secret     string            `yaml:"secret"`
+	Headers    map[string]string `yaml:"headers"`
+	MaxRetries int               `yaml:"maxRetries"`
+}
+
+// APIToken is a token used by scripts to access the web API, only the SHA-256 hash of the token is stored
+type APIToken struct {
+	Name string `yaml:"name"`
+	Hash string `yaml:"hash"`
+}
+
+// ServerPort returns the web UI port
+func (c KooloCfg) ServerPort() int {
+	if c.Server.Port == 0 {
+		return 8087
+	}
+
+	return c.Server.Port
+}
+
+// HubInstance returns the name of this instance in the hub
+func (c KooloCfg) HubInstance() string {
+	if c.Hub.Instance != "" {
+		return c.Hub.Instance
+	}
+	if hostname, err := os.Hostname(); err == nil {
+		return hostname
+	}
+
+	return "koolo"
+}
+
+// TLSEnabled returns true if the web server should be served over HTTPS
+func (c KooloCfg) TLSEnabled() bool {
+	return c.Server.TLS.CertFile != "" && c.Server.TLS.KeyFile != ""
 }
 
 type Day struct {
@@ -65,8 +180,65 @@ type Day struct {
 }
 
 type Scheduler struct {
-	Enabled bool  `yaml:"enabled"`
-	Days    []Day `yaml:"days"`
+	Enabled bool         `yaml:"enabled"`
+	Days    []Day        `yaml:"days"`
+	Cron    []CronWindow `yaml:"cron"`
+	// Randomly moves start and stop times up to these minutes, before or after
+	StartJitterMinutes int `yaml:"startJitterMinutes"`
+	StopJitterMinutes  int `yaml:"stopJitterMinutes"`
+	// Take a break of BreakMinutes after PlayMinutesBeforeBreak of continuous play, 0 disables breaks
+	PlayMinutesBeforeBreak int `yaml:"playMinutesBeforeBreak"`
+	BreakMinutes           int `yaml:"breakMinutes"`
+	// Max play time per day and per week, 0 means no limit
+	DailyLimitMinutes  int `yaml:"dailyLimitMinutes"`
+	WeeklyLimitMinutes int `yaml:"weeklyLimitMinutes"`
+}
+
+// CronWindow starts playing at every activation of the cron expression, for DurationMinutes
+type CronWindow struct {
+	Expression      string `yaml:"expression"`
+	DurationMinutes int    `yaml:"durationMinutes"`
+}
+
+// Plan converts the scheduler config into a schedule.Plan, name is used to give every supervisor a different jitter
+func (s Scheduler) Plan(name string) (schedule.Plan, error) {
+	plan := schedule.Plan{
+		Name:            name,
+		StartJitter:     time.Duration(s.StartJitterMinutes) * time.Minute,
+		StopJitter:      time.Duration(s.StopJitterMinutes) * time.Minute,
+		PlayBeforeBreak: time.Duration(s.PlayMinutesBeforeBreak) * time.Minute,
+		BreakDuration:   time.Duration(s.BreakMinutes) * time.Minute,
+		DailyLimit:      time.Duration(s.DailyLimitMinutes) * time.Minute,
+		WeeklyLimit:     time.Duration(s.WeeklyLimitMinutes) * time.Minute,
+	}
+
+	for _, day := range s.Days {
+		for _, tr := range day.TimeRanges {
+			plan.Daily = append(plan.Daily, schedule.DailyRange{
+				Weekday: time.Weekday(day.DayOfWeek),
+				Start:   time.Duration(tr.Start.Hour())*time.Hour + time.Duration(tr.Start.Minute())*time.Minute,
+				End:     time.Duration(tr.End.Hour())*time.Hour + time.Duration(tr.End.Minute())*time.Minute,
+			})
+		}
+	}
+
+	for _, c := range s.Cron {
+		cron, err := schedule.ParseCron(c.Expression)
+		if err != nil {
+			return plan, err
+		}
+		if c.DurationMinutes <= 0 {
+			return plan, fmt.Errorf("cron %q duration must be greater than 0", c.Expression)
+		}
+
+		plan.Cron = append(plan.Cron, schedule.CronWindow{Cron: cron, Duration: time.Duration(c.DurationMinutes) * time.Minute})
+	}
+
+	if s.PlayMinutesBeforeBreak > 0 && s.BreakMinutes <= 0 {
+		return plan, errors.New("break duration must be greater than 0 when breaks are enabled")
+	}
+
+	return plan, nil
 }
 
 type TimeRange struct {
@@ -108,7 +280,9 @@ type CharacterCfg struct {
 		UseMerc       bool   `yaml:"useMerc"`
 		StashToShared bool   `yaml:"stashToShared"`
 		UseTeleport   bool   `yaml:"useTeleport"`
-		BerserkerBarb struct {
+		// Walk and teleport around elites and dangerous monsters instead of going through them
+		AvoidDangerousMonsters bool `yaml:"avoidDangerousMonsters"`
+		BerserkerBarb          struct {
 			FindItemSwitch              bool `yaml:"find_item_switch"`
 			SkipPotionPickupInTravincal bool `yaml:"skip_potion_pickup_in_travincal"`
 		} `yaml:"berserker_barb"`
@@ -136,6 +310,14 @@ type CharacterCfg struct {
 		Pindleskin             struct {
 			SkipOnImmunities []stat.Resist `yaml:"skipOnImmunities"`
 		} `yaml:"pindleskin"`
+		// AdaptiveRuns moves runs failing often to the end and drops them for a while when failing too much
+		AdaptiveRuns struct {
+			Enabled         bool `yaml:"enabled"`
+			Window          int  `yaml:"window"`
+			MinRuns         int  `yaml:"minRuns"`
+			MaxFailureRate  int  `yaml:"maxFailureRate"`
+			CooldownMinutes int  `yaml:"cooldownMinutes"`
+		} `yaml:"adaptiveRuns"`
 		Cows struct {
 			OpenChests bool `yaml:"openChests"`
 		}
@@ -204,6 +386,12 @@ type CharacterCfg struct {
 			SkipOnImmunities  []stat.Resist `yaml:"skipOnImmunities"`
 			SkipOtherRuns     bool          `yaml:"skipOtherRuns"`
 			Areas             []area.ID     `yaml:"areas"`
+			// Per zone settings by area id
+			Zones map[area.ID]TerrorZoneProfile `yaml:"zones"`
+			// Leave the game right away when none of the active zones is enabled, instead of doing the other runs
+			HopGames        bool `yaml:"hopGames"`
+			MaxHops         int  `yaml:"maxHops"`
+			HopDelaySeconds int  `yaml:"hopDelaySeconds"`
 		} `yaml:"terror_zone"`
 		Leveling struct {
 			EnsurePointsAllocation bool `yaml:"ensurePointsAllocation"`
@@ -223,8 +411,13 @@ type CharacterCfg struct {
 		} `yaml:"quests"`
 	} `yaml:"game"`
 	Companion struct {
-		Leader           bool   `yaml:"leader"`
-		LeaderName       string `yaml:"leaderName"`
+		Enabled bool `yaml:"enabled"`
+		Leader  bool `yaml:"leader"`
+		// Supervisor or character name of the leader, only used by followers
+		LeaderName string `yaml:"leaderName"`
+		// Followers attack the leader targets and follow the leader around
+		Attack           bool   `yaml:"attack"`
+		FollowLeader     bool   `yaml:"followLeader"`
 		GameNameTemplate string `yaml:"gameNameTemplate"`
 		GamePassword     string `yaml:"gamePassword"`
 	} `yaml:"companion"`
@@ -244,9 +437,18 @@ type CharacterCfg struct {
 		MercDied        bool `yaml:"mercDied"`
 		EquipmentBroken bool `yaml:"equipmentBroken"`
 	} `yaml:"backtotown"`
+	// Records the ground items seen and the pickup decision to {stats directory}/captures/{character}.jsonl, the file
+	// can be used as corpus by koolo.exe pickit-sim. Applied when the supervisor starts
+	ItemCapture struct {
+		Enabled bool `yaml:"enabled"`
+		// Size at which the file is rotated, the previous one is kept as {character}.1.jsonl. Default 10
+		MaxSizeMB int `yaml:"maxSizeMB"`
+	} `yaml:"itemCapture"`
 	Runtime struct {
 		Rules nip.Rules   `yaml:"-"`
 		Drops []data.Item `yaml:"-"`
+		// Custom runs loaded from config/{character}/runs, keyed by run name
+		RunScripts map[Run]*RunScript `yaml:"-"`
 	} `yaml:"-"`
 }
 
@@ -299,6 +501,10 @@ func Load() error {
 	if err = d.Decode(&Koolo); err != nil {
 		return fmt.Errorf("error reading config %s: %w", kooloPath, err)
 	}
+	Koolo.Validate()
+	if errs := Koolo.Check(); len(errs) > 0 {
+		return fmt.Errorf("invalid config %s: %w", kooloPath, errs)
+	}
 
 	configDir := getAbsPath("config")
 	entries, err := os.ReadDir(configDir)
@@ -362,6 +568,13 @@ func Load() error {
 		}
 
 		charCfg.Runtime.Rules = rules
+
+		scripts, err := LoadRunScripts(getAbsPath(filepath.Join("config", entry.Name())))
+		if err != nil {
+			return err
+		}
+		charCfg.Runtime.RunScripts = scripts
+
 		Characters[entry.Name()] = &charCfg
 	}
 
@@ -404,6 +617,11 @@ func ValidateAndSaveConfig(config KooloCfg) error {
 		return errors.New("D2RPath is not valid")
 	}
 
+	config.Validate()
+	if errs := config.Check(); len(errs) > 0 {
+		return errs
+	}
+
 	text, err := yaml.Marshal(config)
 	if err != nil {
 		return fmt.Errorf("error parsing koolo config: %w", err)
@@ -433,6 +651,14 @@ func SaveSupervisorConfig(supervisorName string, config *CharacterCfg) error {
 	return Load()
 }
 
+// Validate fixes values that would break Koolo features instead of configuring them
+func (c *KooloCfg) Validate() {
+	for i := range c.Webhook.Endpoints {
+		// Negative retries would skip the first attempt too
+		c.Webhook.Endpoints[i].MaxRetries = max(c.Webhook.Endpoints[i].MaxRetries, 0)
+	}
+}
+
 func (c *CharacterCfg) Validate() {
 	if c.Character.Class == "nova" {
 		minThreshold := 65 // Default
@@ -449,3 +675,34 @@ func (c *CharacterCfg) Validate() {
 		}
 	}
 }
+
+// Clone returns a deep copy of the config, so it can be modified without affecting supervisors using the original
+func (c *CharacterCfg) Clone() *CharacterCfg {
+	cp := *c
+
+	cp.Scheduler.Days = make([]Day, len(c.Scheduler.Days))
+	for i, d := range c.Scheduler.Days {
+		d.TimeRanges = slices.Clone(d.TimeRanges)
+		cp.Scheduler.Days[i] = d
+	}
+	cp.Scheduler.Cron = slices.Clone(c.Scheduler.Cron)
+	cp.Inventory.InventoryLock = make([][]int, len(c.Inventory.InventoryLock))
+	for i, row := range c.Inventory.InventoryLock {
+		cp.Inventory.InventoryLock[i] = slices.Clone(row)
+	}
+	cp.Game.Runs = slices.Clone(c.Game.Runs)
+	cp.Game.Pindleskin.SkipOnImmunities = slices.Clone(c.Game.Pindleskin.SkipOnImmunities)
+	cp.Game.TerrorZone.SkipOnImmunities = slices.Clone(c.Game.TerrorZone.SkipOnImmunities)
+	cp.Game.TerrorZone.Areas = slices.Clone(c.Game.TerrorZone.Areas)
+	if c.Game.TerrorZone.Zones != nil {
+		cp.Game.TerrorZone.Zones = make(map[area.ID]TerrorZoneProfile, len(c.Game.TerrorZone.Zones))
+		for id, p := range c.Game.TerrorZone.Zones {
+			p.SkipOnImmunities = slices.Clone(p.SkipOnImmunities)
+			cp.Game.TerrorZone.Zones[id] = p
+		}
+	}
+	cp.Gambling.Items = slices.Clone(c.Gambling.Items)
+	cp.CubeRecipes.EnabledRecipes = slices.Clone(c.CubeRecipes.EnabledRecipes)
+
+	return &cp
+}
@@ -0,0 +1,33 @@
+package config
+
+import "testing"
+
+func TestKooloValidate(t *testing.T) {
+	cfg := KooloCfg{}
+	cfg.Webhook.Endpoints = []WebhookEndpoint{{MaxRetries: -2}, {MaxRetries: 5}}
+	cfg.Validate()
+
+	if cfg.Webhook.Endpoints[0].MaxRetries != 0 || cfg.Webhook.Endpoints[1].MaxRetries != 5 {
+		t.Errorf("negative webhook retries should be clamped to 0: %+v", cfg.Webhook.Endpoints)
+	}
+}
+
+func TestKooloCheck(t *testing.T) {
+	cfg := KooloCfg{}
+	cfg.Hub.Enabled = true
+	if errs := cfg.Check(); len(errs) != 1 || errs[0].Field != "hub.token" {
+		t.Errorf("hub without token should be refused, got %v", errs)
+	}
+
+	cfg.Hub.Token = "secret"
+	if errs := cfg.Check(); len(errs) != 0 {
+		t.Errorf("hub with token should be valid, got %v", errs)
+	}
+
+	// Connecting to a hub doesn't need a token on this side, the hub refuses the connection
+	cfg = KooloCfg{}
+	cfg.Hub.URL = "ws://192.168.1.10:8087/hub/ws"
+	if errs := cfg.Check(); len(errs) != 0 {
+		t.Errorf("hub client without token should be valid, got %v", errs)
+	}
+}
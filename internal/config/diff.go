@@ -0,0 +1,120 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+
+	"github.com/hectorgimenez/d2go/pkg/nip"
+)
+
+// Change is a single config value that differs between two configs
+type Change struct {
+	Field string `json:"field"`
+	Old   string `json:"old"`
+	New   string `json:"new"`
+	// The supervisor reads this value only when it's started, it keeps the old value until it's restarted
+	RestartRequired bool `json:"restartRequired"`
+}
+
+// Values only used when the game client is launched or the supervisor is built
+var restartRequiredFields = []struct {
+	field string
+	keep  func(dst, src *CharacterCfg)
+}{
+	{"username", func(dst, src *CharacterCfg) { dst.Username = src.Username }},
+	{"password", func(dst, src *CharacterCfg) { dst.Password = src.Password }},
+	{"authMethod", func(dst, src *CharacterCfg) { dst.AuthMethod = src.AuthMethod }},
+	{"authToken", func(dst, src *CharacterCfg) { dst.AuthToken = src.AuthToken }},
+	{"realm", func(dst, src *CharacterCfg) { dst.Realm = src.Realm }},
+	{"characterName", func(dst, src *CharacterCfg) { dst.CharacterName = src.CharacterName }},
+	{"commandLineArgs", func(dst, src *CharacterCfg) { dst.CommandLineArgs = src.CommandLineArgs }},
+	{"character.class", func(dst, src *CharacterCfg) { dst.Character.Class = src.Character.Class }},
+	{"companion.enabled", func(dst, src *CharacterCfg) { dst.Companion.Enabled = src.Companion.Enabled }},
+	{"companion.leader", func(dst, src *CharacterCfg) { dst.Companion.Leader = src.Companion.Leader }},
+	{"companion.leaderName", func(dst, src *CharacterCfg) { dst.Companion.LeaderName = src.Companion.LeaderName }},
+	{"itemCapture.enabled", func(dst, src *CharacterCfg) { dst.ItemCapture.Enabled = src.ItemCapture.Enabled }},
+	{"itemCapture.maxSizeMB", func(dst, src *CharacterCfg) { dst.ItemCapture.MaxSizeMB = src.ItemCapture.MaxSizeMB }},
+}
+
+// Sensitive values are reported as changed without showing them
+var secretFields = []string{"password", "authToken"}
+
+// Diff returns the values changed from old to updated, using the yaml path of each value. Runtime data is ignored
+func Diff(old, updated *CharacterCfg) []Change {
+	changes := make([]Change, 0)
+	diffValues("", reflect.ValueOf(*old), reflect.ValueOf(*updated), &changes)
+
+	for i, c := range changes {
+		for _, f := range restartRequiredFields {
+			if c.Field == f.field {
+				changes[i].RestartRequired = true
+			}
+		}
+		if slices.Contains(secretFields, c.Field) {
+			changes[i].Old, changes[i].New = "***", "***"
+		}
+	}
+
+	return changes
+}
+
+func diffValues(path string, old, updated reflect.Value, changes *[]Change) {
+	if old.Kind() == reflect.Struct {
+		t := old.Type()
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			name := yamlName(f)
+			if name == "-" || !f.IsExported() {
+				continue
+			}
+			if path != "" {
+				name = path + "." + name
+			}
+			diffValues(name, old.Field(i), updated.Field(i), changes)
+		}
+		return
+	}
+
+	// Nil and empty are the same for yaml
+	if (old.Kind() == reflect.Slice || old.Kind() == reflect.Map) && old.Len() == 0 && updated.Len() == 0 {
+		return
+	}
+
+	if !reflect.DeepEqual(old.Interface(), updated.Interface()) {
+		*changes = append(*changes, Change{Field: path, Old: fmt.Sprint(old.Interface()), New: fmt.Sprint(updated.Interface())})
+	}
+}
+
+func yamlName(f reflect.StructField) string {
+	name, _, _ := strings.Cut(f.Tag.Get("yaml"), ",")
+	if name == "" {
+		// Same default as the yaml package
+		return strings.ToLower(f.Name)
+	}
+
+	return name
+}
+
+// KeepRestartRequired copies from current the values that can not be changed without restarting the supervisor
+func KeepRestartRequired(dst, current *CharacterCfg) {
+	for _, f := range restartRequiredFields {
+		f.keep(dst, current)
+	}
+}
+
+// RulesChanged returns if both rule sets are different, rules are compared by file, line and content
+func RulesChanged(old, updated nip.Rules) bool {
+	if len(old) != len(updated) {
+		return true
+	}
+
+	for i := range old {
+		if old[i].Filename != updated[i].Filename || old[i].RawLine != updated[i].RawLine || old[i].Enabled != updated[i].Enabled {
+			return true
+		}
+	}
+
+	return false
+}
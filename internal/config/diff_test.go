@@ -0,0 +1,51 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/hectorgimenez/d2go/pkg/nip"
+)
+
+func TestDiff(t *testing.T) {
+	old := &CharacterCfg{Password: "secret", MaxGameLength: 600}
+	old.Character.Class = "sorceress"
+	old.Game.Runs = []Run{"pindleskin"}
+
+	updated := old.Clone()
+	updated.Password = "other"
+	updated.MaxGameLength = 900
+	updated.Character.Class = "nova"
+	updated.Game.Runs = append(updated.Game.Runs, "mephisto")
+	updated.Runtime.Rules = nip.Rules{{RawLine: "[name] == ring"}}
+
+	changes := Diff(old, updated)
+	byField := make(map[string]Change)
+	for _, c := range changes {
+		byField[c.Field] = c
+	}
+	if len(changes) != 4 {
+		t.Fatalf("expected 4 changes, got %+v", changes)
+	}
+
+	if c := byField["password"]; !c.RestartRequired || c.Old != "***" || c.New != "***" {
+		t.Errorf("password should be masked and require restart, got %+v", c)
+	}
+	if c := byField["character.class"]; !c.RestartRequired || c.Old != "sorceress" || c.New != "nova" {
+		t.Errorf("unexpected class change %+v", c)
+	}
+	if c := byField["maxGameLength"]; c.RestartRequired || c.New != "900" {
+		t.Errorf("unexpected maxGameLength change %+v", c)
+	}
+	if _, found := byField["game.runs"]; !found {
+		t.Errorf("missing game.runs change")
+	}
+
+	KeepRestartRequired(updated, old)
+	if updated.Password != "secret" || updated.Character.Class != "sorceress" || updated.MaxGameLength != 900 {
+		t.Errorf("restart required values should be kept, got password %q class %q", updated.Password, updated.Character.Class)
+	}
+
+	if !RulesChanged(old.Runtime.Rules, updated.Runtime.Rules) {
+		t.Errorf("rules should be different")
+	}
+}
@@ -0,0 +1,232 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/hectorgimenez/d2go/pkg/data/area"
+	"github.com/hectorgimenez/d2go/pkg/data/stat"
+	"gopkg.in/yaml.v3"
+)
+
+// Directory inside the character config directory containing the custom runs
+const runScriptsDir = "runs"
+
+const (
+	MonsterFilterAny   = "any"
+	MonsterFilterElite = "elite"
+
+	defaultOpenChestsRadius = 20
+)
+
+// RunScript is a farming route defined in a yaml file, it can be added to game.runs by its name like any other run.
+// Steps are executed in order, the run stops on the first failing step.
+//
+//	name: countess_elites
+//	steps:
+//	  - waypoint: Black Marsh
+//	  - moveToArea: Forgotten Tower
+//	  - moveToArea: Tower Cellar Level 1
+//	  # ... down to Tower Cellar Level 5
+//	  - clearLevel: { filter: elite, openChests: true }
+//	  - killUnique: { npc: 45 } # Countess
+//	    if: { minLevel: 20 }
+//	  - returnToTown: true
+type RunScript struct {
+	Name  Run       `yaml:"name"`
+	Steps []RunStep `yaml:"steps"`
+	// File the script was loaded from, used for error messages
+	File string `yaml:"-"`
+}
+
+// RunStep is a single action of a run script, only one of them can be set per step
+type RunStep struct {
+	If           *RunCondition   `yaml:"if,omitempty"`
+	WayPoint     string          `yaml:"waypoint,omitempty"`
+	MoveToArea   string          `yaml:"moveToArea,omitempty"`
+	MoveToObject *int            `yaml:"moveToObject,omitempty"`
+	ClearLevel   *ClearLevelStep `yaml:"clearLevel,omitempty"`
+	KillUnique   *KillUniqueStep `yaml:"killUnique,omitempty"`
+	OpenChests   *OpenChestsStep `yaml:"openChests,omitempty"`
+	ReturnToTown bool            `yaml:"returnToTown,omitempty"`
+}
+
+// Actions returns how many actions are set, a valid step has exactly one
+func (s RunStep) Actions() int {
+	actions := 0
+	for _, set := range []bool{s.WayPoint != "", s.MoveToArea != "", s.MoveToObject != nil, s.ClearLevel != nil, s.KillUnique != nil, s.OpenChests != nil, s.ReturnToTown} {
+		if set {
+			actions++
+		}
+	}
+
+	return actions
+}
+
+type ClearLevelStep struct {
+	OpenChests bool   `yaml:"openChests"`
+	Filter     string `yaml:"filter"`
+}
+
+// KillUniqueStep kills a super unique or unique monster by its NPC id, the one used by d2go
+type KillUniqueStep struct {
+	NPC              int           `yaml:"npc"`
+	SkipOnImmunities []stat.Resist `yaml:"skipOnImmunities"`
+}
+
+type OpenChestsStep struct {
+	Radius          int  `yaml:"radius"`
+	SuperChestsOnly bool `yaml:"superChestsOnly"`
+}
+
+// RunCondition skips the step if the character doesn't match it, quests are identified by d2go quest id
+type RunCondition struct {
+	MinLevel          int  `yaml:"minLevel"`
+	MaxLevel          int  `yaml:"maxLevel"`
+	QuestCompleted    *int `yaml:"questCompleted"`
+	QuestNotCompleted *int `yaml:"questNotCompleted"`
+}
+
+// ChestsRadius returns the radius to look for chests, with the default value if not set
+func (s OpenChestsStep) ChestsRadius() int {
+	if s.Radius <= 0 {
+		return defaultOpenChestsRadius
+	}
+
+	return s.Radius
+}
+
+// AreaByName returns the area by its name, case, spaces, underscores and apostrophes are ignored so "Black Marsh",
+// "BlackMarsh" and "black_marsh" are the same area. The area id is accepted as well
+func AreaByName(name string) (area.ID, bool) {
+	if id, err := strconv.Atoi(strings.TrimSpace(name)); err == nil {
+		_, found := area.Areas[area.ID(id)]
+		return area.ID(id), found && id > 0
+	}
+
+	normalized := normalizeAreaName(name)
+	if normalized == "" {
+		return 0, false
+	}
+	for id, a := range area.Areas {
+		if normalizeAreaName(a.Name) == normalized {
+			return id, true
+		}
+	}
+
+	return 0, false
+}
+
+func normalizeAreaName(name string) string {
+	return strings.NewReplacer(" ", "", "_", "", "'", "", "-", "").Replace(strings.ToLower(name))
+}
+
+// LoadRunScripts reads every yaml file in the runs directory of the character config, a missing directory is not an
+// error. Unknown fields are rejected, a typo would silently skip a step otherwise. Scripts are checked too, the
+// returned error wraps the ValidationErrors of the first invalid one
+func LoadRunScripts(characterDir string) (map[Run]*RunScript, error) {
+	scripts := make(map[Run]*RunScript)
+
+	dir := filepath.Join(characterDir, runScriptsDir)
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return scripts, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading run scripts directory %s: %w", dir, err)
+	}
+
+	for _, e := range entries {
+		ext := strings.ToLower(filepath.Ext(e.Name()))
+		if e.IsDir() || (ext != ".yaml" && ext != ".yml") {
+			continue
+		}
+
+		path := filepath.Join(dir, e.Name())
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, fmt.Errorf("error reading run script %s: %w", path, err)
+		}
+
+		script := &RunScript{}
+		d := yaml.NewDecoder(f)
+		d.KnownFields(true)
+		err = d.Decode(script)
+		f.Close()
+		if err != nil {
+			return nil, fmt.Errorf("error reading run script %s: %w", path, err)
+		}
+
+		script.File = filepath.ToSlash(filepath.Join(runScriptsDir, e.Name()))
+		if script.Name == "" {
+			script.Name = Run(strings.TrimSuffix(e.Name(), filepath.Ext(e.Name())))
+		}
+		if errs := script.Check(); len(errs) > 0 {
+			return nil, fmt.Errorf("invalid run script %s: %w", path, errs)
+		}
+		if other, found := scripts[script.Name]; found {
+			return nil, fmt.Errorf("run script %s has the same name as %s: %s", script.File, other.File, script.Name)
+		}
+		scripts[script.Name] = script
+	}
+
+	return scripts, nil
+}
+
+// Check returns all the problems found in the script, field paths start with the script file
+func (s *RunScript) Check() ValidationErrors {
+	errs := make(ValidationErrors, 0)
+
+	if _, found := AvailableRuns[s.Name]; found {
+		errs.add(s.File+":name", "%q is a built-in run, custom runs need a different name", s.Name)
+	}
+	if len(s.Steps) == 0 {
+		errs.add(s.File+":steps", "at least one step is required")
+	}
+
+	for i, step := range s.Steps {
+		field := fmt.Sprintf("%s:steps[%d]", s.File, i)
+
+		if step.WayPoint != "" {
+			if _, found := AreaByName(step.WayPoint); !found {
+				errs.add(field+".waypoint", "unknown area %q", step.WayPoint)
+			}
+		}
+		if step.MoveToArea != "" {
+			if _, found := AreaByName(step.MoveToArea); !found {
+				errs.add(field+".moveToArea", "unknown area %q", step.MoveToArea)
+			}
+		}
+		if step.ClearLevel != nil {
+			if f := strings.ToLower(step.ClearLevel.Filter); f != "" && f != MonsterFilterAny && f != MonsterFilterElite {
+				errs.add(field+".clearLevel.filter", "invalid value %q, allowed values: %s, %s", step.ClearLevel.Filter, MonsterFilterAny, MonsterFilterElite)
+			}
+		}
+		if step.KillUnique != nil {
+			for j, r := range step.KillUnique.SkipOnImmunities {
+				if !slices.Contains(immunities, r) {
+					errs.add(fmt.Sprintf("%s.killUnique.skipOnImmunities[%d]", field, j), "invalid value %q, allowed values: cold, fire, light, poison, magic", r)
+				}
+			}
+		}
+		switch actions := step.Actions(); {
+		case actions == 0:
+			errs.add(field, "step has no action")
+		case actions > 1:
+			errs.add(field, "step has %d actions, only one is allowed per step", actions)
+		}
+
+		if c := step.If; c != nil && c.MaxLevel > 0 && c.MinLevel > c.MaxLevel {
+			errs.add(field+".if", "minLevel (%d) can't be higher than maxLevel (%d)", c.MinLevel, c.MaxLevel)
+		}
+	}
+
+	return errs
+}
+
+var immunities = []stat.Resist{stat.ColdImmune, stat.FireImmune, stat.LightImmune, stat.PoisonImmune, stat.MagicImmune}
@@ -0,0 +1,134 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/hectorgimenez/d2go/pkg/data/area"
+)
+
+func writeRunScript(t *testing.T, dir, name, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Join(dir, runScriptsDir), os.ModePerm); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, runScriptsDir, name), []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestAreaByName(t *testing.T) {
+	for _, name := range []string{"Black Marsh", "blackmarsh", "black_marsh", "6"} {
+		if id, found := AreaByName(name); !found || id != area.BlackMarsh {
+			t.Errorf("%q: expected Black Marsh, got %d %v", name, id, found)
+		}
+	}
+	for _, name := range []string{"", "Black Swamp", "0", "9999"} {
+		if _, found := AreaByName(name); found {
+			t.Errorf("%q: expected not found", name)
+		}
+	}
+}
+
+func TestLoadRunScripts(t *testing.T) {
+	dir := t.TempDir()
+	if scripts, err := LoadRunScripts(dir); err != nil || len(scripts) != 0 {
+		t.Fatalf("missing directory: expected no scripts, got %v %v", scripts, err)
+	}
+
+	writeRunScript(t, dir, "tower.yaml", `
+name: countess_elites
+steps:
+  - waypoint: Black Marsh
+  - moveToArea: Forgotten Tower
+  - clearLevel: { filter: elite, openChests: true }
+  - killUnique: { npc: 45, skipOnImmunities: [ cold ] }
+    if: { minLevel: 20, questNotCompleted: 4 }
+  - openChests: {}
+  - returnToTown: true
+`)
+	// Name is taken from the file when it's missing
+	writeRunScript(t, dir, "chests.yml", "steps:\n  - moveToObject: 580\n")
+	writeRunScript(t, dir, "notes.txt", "not a script")
+
+	scripts, err := LoadRunScripts(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(scripts) != 2 {
+		t.Fatalf("expected 2 scripts, got %v", scripts)
+	}
+
+	tower := scripts["countess_elites"]
+	if tower == nil || tower.File != "runs/tower.yaml" || len(tower.Steps) != 6 {
+		t.Fatalf("unexpected script: %+v", tower)
+	}
+	if errs := tower.Check(); len(errs) != 0 {
+		t.Errorf("unexpected errors: %v", errs)
+	}
+	if c := tower.Steps[3].If; c == nil || c.MinLevel != 20 || c.QuestNotCompleted == nil || *c.QuestNotCompleted != 4 {
+		t.Errorf("unexpected condition: %+v", c)
+	}
+	if r := tower.Steps[4].OpenChests.ChestsRadius(); r != defaultOpenChestsRadius {
+		t.Errorf("expected default radius, got %d", r)
+	}
+	if chests := scripts["chests"]; chests == nil || *chests.Steps[0].MoveToObject != 580 {
+		t.Errorf("unexpected script: %+v", chests)
+	}
+
+	// Typos are rejected instead of skipping the step
+	writeRunScript(t, dir, "typo.yaml", "steps:\n  - wayPoint: Black Marsh\n")
+	if _, err = LoadRunScripts(dir); err == nil || !strings.Contains(err.Error(), "typo.yaml") {
+		t.Errorf("expected unknown field error, got %v", err)
+	}
+	os.Remove(filepath.Join(dir, runScriptsDir, "typo.yaml"))
+
+	// Invalid scripts are rejected when loading, not only when the config is checked
+	writeRunScript(t, dir, "both.yaml", "steps:\n  - moveToArea: Nowhere\n    returnToTown: true\n")
+	_, err = LoadRunScripts(dir)
+	var errs ValidationErrors
+	if !errors.As(err, &errs) || len(errs) != 2 || errs[0].Field != "runs/both.yaml:steps[0].moveToArea" || errs[1].Field != "runs/both.yaml:steps[0]" {
+		t.Errorf("expected the check errors, got %v", err)
+	}
+}
+
+func TestRunScriptCheck(t *testing.T) {
+	s := &RunScript{Name: PitRun, File: "runs/pit.yaml", Steps: []RunStep{
+		{WayPoint: "Nowhere"},
+		{},
+		{MoveToArea: "Pit Level 1", ReturnToTown: true},
+		{ClearLevel: &ClearLevelStep{Filter: "bosses"}},
+		{ReturnToTown: true, If: &RunCondition{MinLevel: 30, MaxLevel: 20}},
+	}}
+
+	expected := []string{
+		"runs/pit.yaml:name",
+		"runs/pit.yaml:steps[0].waypoint",
+		"runs/pit.yaml:steps[1]",
+		"runs/pit.yaml:steps[2]",
+		"runs/pit.yaml:steps[3].clearLevel.filter",
+		"runs/pit.yaml:steps[4].if",
+	}
+	errs := s.Check()
+	if len(errs) != len(expected) {
+		t.Fatalf("expected %d errors, got %v", len(expected), errs)
+	}
+	for i, e := range errs {
+		if e.Field != expected[i] {
+			t.Errorf("error %d: expected field %s, got %s", i, expected[i], e.Field)
+		}
+	}
+
+	// Custom runs are accepted in game.runs
+	cfg := &CharacterCfg{}
+	cfg.Game.Runs = []Run{"countess_elites", "unknown"}
+	cfg.Runtime.RunScripts = map[Run]*RunScript{"countess_elites": {Name: "countess_elites", Steps: []RunStep{{ReturnToTown: true}}}}
+	for _, e := range cfg.Check() {
+		if e.Field == "game.runs[0]" {
+			t.Errorf("custom run reported as unknown: %v", e)
+		}
+	}
+}
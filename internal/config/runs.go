@@ -31,6 +31,9 @@ const (
 	DrifterCavernRun    Run = "drifter_cavern"
 	SpiderCavernRun     Run = "spider_cavern"
 	EnduguRun           Run = "endugu"
+
+	// Only used by companion followers, it can't be selected
+	CompanionRun Run = "companion"
 )
 
 var AvailableRuns = map[Run]interface{}{
@@ -0,0 +1,111 @@
+package config
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"time"
+
+	"github.com/hectorgimenez/d2go/pkg/data/area"
+	"github.com/hectorgimenez/d2go/pkg/data/stat"
+)
+
+// TerrorZoneProfile overrides the terror_zone settings for a single zone, unset values use the terror_zone ones. A
+// zone with a profile is enabled even if it's not in the areas list, unless enabled is false
+type TerrorZoneProfile struct {
+	Enabled           *bool         `yaml:"enabled,omitempty"`
+	FocusOnElitePacks *bool         `yaml:"focusOnElitePacks,omitempty"`
+	SkipOnImmunities  []stat.Resist `yaml:"skipOnImmunities,omitempty"`
+	// Max time spent in the zone, 0 means no limit
+	MaxMinutes int  `yaml:"maxMinutes,omitempty"`
+	OpenChests bool `yaml:"openChests,omitempty"`
+}
+
+// TerrorZoneStrategy is the resolved profile for a zone
+type TerrorZoneStrategy struct {
+	Area              area.ID
+	Enabled           bool
+	FocusOnElitePacks bool
+	SkipOnImmunities  []stat.Resist
+	MaxDuration       time.Duration
+	OpenChests        bool
+}
+
+// TerrorZoneStrategy returns the settings to use for the given zone
+func (c *CharacterCfg) TerrorZoneStrategy(zone area.ID) TerrorZoneStrategy {
+	tz := c.Game.TerrorZone
+	s := TerrorZoneStrategy{
+		Area:              zone,
+		Enabled:           slices.Contains(tz.Areas, zone),
+		FocusOnElitePacks: tz.FocusOnElitePacks,
+		SkipOnImmunities:  tz.SkipOnImmunities,
+	}
+
+	p, found := tz.Zones[zone]
+	if !found {
+		return s
+	}
+
+	s.Enabled = p.Enabled == nil || *p.Enabled
+	if p.FocusOnElitePacks != nil {
+		s.FocusOnElitePacks = *p.FocusOnElitePacks
+	}
+	if p.SkipOnImmunities != nil {
+		s.SkipOnImmunities = p.SkipOnImmunities
+	}
+	s.MaxDuration = time.Duration(p.MaxMinutes) * time.Minute
+	s.OpenChests = p.OpenChests
+
+	return s
+}
+
+// EnabledTerrorZones returns the active terror zones enabled for this character, keeping the game order
+func (c *CharacterCfg) EnabledTerrorZones(active []area.ID) []area.ID {
+	enabled := make([]area.ID, 0, len(active))
+	for _, zone := range active {
+		if c.TerrorZoneStrategy(zone).Enabled {
+			enabled = append(enabled, zone)
+		}
+	}
+
+	return enabled
+}
+
+func (c *CharacterCfg) checkTerrorZone(errs *ValidationErrors) {
+	tz := c.Game.TerrorZone
+
+	for i, id := range tz.Areas {
+		if _, found := area.Areas[id]; !found {
+			errs.add(fmt.Sprintf("game.terror_zone.areas[%d]", i), "unknown area %d", id)
+		}
+	}
+	for i, r := range tz.SkipOnImmunities {
+		if !slices.Contains(immunities, r) {
+			errs.add(fmt.Sprintf("game.terror_zone.skipOnImmunities[%d]", i), "invalid value %q, allowed values: cold, fire, light, poison, magic", r)
+		}
+	}
+
+	for _, id := range slices.Sorted(maps.Keys(tz.Zones)) {
+		field := fmt.Sprintf("game.terror_zone.zones[%d]", id)
+		if _, found := area.Areas[id]; !found {
+			errs.add(field, "unknown area %d", id)
+		}
+
+		p := tz.Zones[id]
+		if p.MaxMinutes < 0 {
+			errs.add(field+".maxMinutes", "invalid value %d, can't be negative", p.MaxMinutes)
+		}
+		for i, r := range p.SkipOnImmunities {
+			if !slices.Contains(immunities, r) {
+				errs.add(fmt.Sprintf("%s.skipOnImmunities[%d]", field, i), "invalid value %q, allowed values: cold, fire, light, poison, magic", r)
+			}
+		}
+	}
+
+	if tz.MaxHops < 0 {
+		errs.add("game.terror_zone.maxHops", "invalid value %d, can't be negative", tz.MaxHops)
+	}
+	if tz.HopDelaySeconds < 0 {
+		errs.add("game.terror_zone.hopDelaySeconds", "invalid value %d, can't be negative", tz.HopDelaySeconds)
+	}
+}
@@ -0,0 +1,62 @@
+package config
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/hectorgimenez/d2go/pkg/data/area"
+	"github.com/hectorgimenez/d2go/pkg/data/stat"
+)
+
+func TestTerrorZoneStrategy(t *testing.T) {
+	disabled, elite := false, true
+	cfg := &CharacterCfg{}
+	cfg.Game.TerrorZone.Areas = []area.ID{area.BurialGrounds, area.Crypt, area.ChaosSanctuary}
+	cfg.Game.TerrorZone.SkipOnImmunities = []stat.Resist{stat.FireImmune}
+	cfg.Game.TerrorZone.Zones = map[area.ID]TerrorZoneProfile{
+		area.ChaosSanctuary: {Enabled: &disabled},
+		area.Crypt:          {FocusOnElitePacks: &elite, SkipOnImmunities: []stat.Resist{stat.ColdImmune}, MaxMinutes: 5, OpenChests: true},
+		// Not in the areas list, enabled by the profile
+		area.Travincal: {},
+	}
+
+	if s := cfg.TerrorZoneStrategy(area.BurialGrounds); !s.Enabled || s.FocusOnElitePacks || !slices.Equal(s.SkipOnImmunities, []stat.Resist{stat.FireImmune}) {
+		t.Errorf("burial grounds should use the global settings, got %+v", s)
+	}
+	crypt := cfg.TerrorZoneStrategy(area.Crypt)
+	if !crypt.Enabled || !crypt.FocusOnElitePacks || !crypt.OpenChests || crypt.MaxDuration != 5*time.Minute || !slices.Equal(crypt.SkipOnImmunities, []stat.Resist{stat.ColdImmune}) {
+		t.Errorf("crypt should use its profile, got %+v", crypt)
+	}
+
+	active := []area.ID{area.Mausoleum, area.Crypt, area.ChaosSanctuary, area.Travincal}
+	if enabled := cfg.EnabledTerrorZones(active); !slices.Equal(enabled, []area.ID{area.Crypt, area.Travincal}) {
+		t.Errorf("unexpected enabled zones %v", enabled)
+	}
+}
+
+func TestCheckTerrorZone(t *testing.T) {
+	cfg := &CharacterCfg{}
+	cfg.Game.TerrorZone.Areas = []area.ID{area.BurialGrounds, 9999}
+	cfg.Game.TerrorZone.Zones = map[area.ID]TerrorZoneProfile{
+		area.Crypt: {MaxMinutes: -1, SkipOnImmunities: []stat.Resist{"ice"}},
+	}
+	cfg.Game.TerrorZone.MaxHops = -1
+
+	errs := make(ValidationErrors, 0)
+	cfg.checkTerrorZone(&errs)
+
+	fields := make([]string, 0, len(errs))
+	for _, e := range errs {
+		fields = append(fields, e.Field)
+	}
+	expected := []string{
+		"game.terror_zone.areas[1]",
+		"game.terror_zone.zones[18].maxMinutes",
+		"game.terror_zone.zones[18].skipOnImmunities[0]",
+		"game.terror_zone.maxHops",
+	}
+	if !slices.Equal(fields, expected) {
+		t.Errorf("expected errors on %v, got %v", expected, fields)
+	}
+}
@@ -0,0 +1,268 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	inventoryRows    = 4
+	inventoryColumns = 10
+)
+
+var beltColumnTypes = []string{"healing", "mana", "rejuvenation"}
+
+// FieldError is a single validation error, Field is the yaml path of the wrong value, like "health.chickenAt" or
+// "game.runs[2]"
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+func (e FieldError) Error() string {
+	if e.Field == "" {
+		return e.Message
+	}
+
+	return e.Field + ": " + e.Message
+}
+
+type ValidationErrors []FieldError
+
+func (v ValidationErrors) Error() string {
+	msgs := make([]string, 0, len(v))
+	for _, e := range v {
+		msgs = append(msgs, e.Error())
+	}
+
+	return strings.Join(msgs, "\n")
+}
+
+func (v *ValidationErrors) add(field, format string, args ...any) {
+	*v = append(*v, FieldError{Field: field, Message: fmt.Sprintf(format, args...)})
+}
+
+// Check returns the problems found in the Koolo config that would make it unsafe to start
+func (c KooloCfg) Check() ValidationErrors {
+	errs := make(ValidationErrors, 0)
+
+	// Without a token any host of the network could join the hub and steer the companions
+	if c.Hub.Enabled && c.Hub.Token == "" {
+		errs.add("hub.token", "a token is required when the hub is enabled")
+	}
+
+	return errs
+}
+
+// Check returns all the problems found in the character config, unlike Validate it doesn't change any value
+func (c *CharacterCfg) Check() ValidationErrors {
+	errs := make(ValidationErrors, 0)
+
+	leveling := false
+	for i, r := range c.Game.Runs {
+		field := fmt.Sprintf("game.runs[%d]", i)
+		_, builtIn := AvailableRuns[r]
+		if _, custom := c.Runtime.RunScripts[r]; !builtIn && !custom {
+			errs.add(field, "unknown run %q", r)
+		}
+		// Leveling character and pickit rules are only used when leveling is the first run
+		if r == LevelingRun {
+			if i == 0 {
+				leveling = true
+			} else {
+				errs.add(field, "leveling must be the first run")
+			}
+		}
+	}
+
+	class := strings.ToLower(c.Character.Class)
+	switch {
+	case class == "":
+		errs.add("character.class", "class is required")
+	case leveling && !slices.Contains(LevelingClasses, class):
+		errs.add("character.class", "%q can not be used for leveling, allowed values: %s", c.Character.Class, strings.Join(LevelingClasses, ", "))
+	case !leveling && !slices.Contains(AvailableClasses, class):
+		if slices.Contains(LevelingClasses, class) {
+			errs.add("character.class", "%q is a leveling class, it requires the leveling run", c.Character.Class)
+		} else {
+			errs.add("character.class", "unknown class %q, allowed values: %s", c.Character.Class, strings.Join(AvailableClasses, ", "))
+		}
+	}
+
+	c.checkHealth(&errs)
+	c.checkTerrorZone(&errs)
+
+	adaptive := c.Game.AdaptiveRuns
+	if adaptive.MaxFailureRate < 0 || adaptive.MaxFailureRate > 100 {
+		errs.add("game.adaptiveRuns.maxFailureRate", "invalid value %d, expected a percentage between 0 and 100", adaptive.MaxFailureRate)
+	}
+	counters := []struct {
+		field string
+		value int
+	}{
+		{"window", adaptive.Window},
+		{"minRuns", adaptive.MinRuns},
+		{"cooldownMinutes", adaptive.CooldownMinutes},
+	}
+	for _, n := range counters {
+		if n.value < 0 {
+			errs.add("game.adaptiveRuns."+n.field, "invalid value %d, can't be negative", n.value)
+		}
+	}
+	if adaptive.Window > 0 && adaptive.MinRuns > adaptive.Window {
+		errs.add("game.adaptiveRuns.minRuns", "minRuns (%d) can't be higher than window (%d)", adaptive.MinRuns, adaptive.Window)
+	}
+
+	if c.Companion.Enabled {
+		if c.Companion.Leader && c.Companion.GameNameTemplate == "" {
+			errs.add("companion.gameNameTemplate", "game name template is required for the leader")
+		}
+		if !c.Companion.Leader && c.Companion.LeaderName == "" {
+			errs.add("companion.leaderName", "leader name is required for followers")
+		}
+	}
+
+	if c.ItemCapture.MaxSizeMB < 0 {
+		errs.add("itemCapture.maxSizeMB", "invalid value %d, can't be negative", c.ItemCapture.MaxSizeMB)
+	}
+
+	// Scripts are checked even if they are not used yet, so they can be fixed before enabling them
+	names := make([]Run, 0, len(c.Runtime.RunScripts))
+	for name := range c.Runtime.RunScripts {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	for _, name := range names {
+		errs = append(errs, c.Runtime.RunScripts[name].Check()...)
+	}
+
+	for i, col := range c.Inventory.BeltColumns {
+		if !slices.Contains(beltColumnTypes, strings.ToLower(col)) {
+			errs.add(fmt.Sprintf("inventory.beltColumns[%d]", i), "invalid value %q, allowed values: %s", col, strings.Join(beltColumnTypes, ", "))
+		}
+	}
+
+	if len(c.Inventory.InventoryLock) != inventoryRows {
+		errs.add("inventory.inventoryLock", "expected %d rows, got %d", inventoryRows, len(c.Inventory.InventoryLock))
+	}
+	for y, row := range c.Inventory.InventoryLock {
+		if len(row) != inventoryColumns {
+			errs.add(fmt.Sprintf("inventory.inventoryLock[%d]", y), "expected %d columns, got %d", inventoryColumns, len(row))
+		}
+		for x, v := range row {
+			if v != 0 && v != 1 {
+				errs.add(fmt.Sprintf("inventory.inventoryLock[%d][%d]", y, x), "invalid value %d, allowed values: 0 (locked), 1 (unlocked)", v)
+			}
+		}
+	}
+
+	if _, err := c.Scheduler.Plan(""); err != nil {
+		errs.add("scheduler", "%s", err.Error())
+	}
+
+	return errs
+}
+
+func (c *CharacterCfg) checkHealth(errs *ValidationErrors) {
+	h := c.Health
+	percentages := []struct {
+		field string
+		value int
+	}{
+		{"healingPotionAt", h.HealingPotionAt},
+		{"manaPotionAt", h.ManaPotionAt},
+		{"rejuvPotionAtLife", h.RejuvPotionAtLife},
+		{"rejuvPotionAtMana", h.RejuvPotionAtMana},
+		{"chickenAt", h.ChickenAt},
+		{"mercHealingPotionAt", h.MercHealingPotionAt},
+		{"mercRejuvPotionAt", h.MercRejuvPotionAt},
+		{"mercChickenAt", h.MercChickenAt},
+	}
+	for _, p := range percentages {
+		if p.value < 0 || p.value > 100 {
+			errs.add("health."+p.field, "must be between 0 and 100, got %d", p.value)
+		}
+	}
+
+	// Chicken should be the last resort, if it's above a potion threshold that potion is never used
+	if h.ChickenAt > 0 && h.HealingPotionAt > 0 && h.ChickenAt >= h.HealingPotionAt {
+		errs.add("health.chickenAt", "chicken (%d) must be lower than healingPotionAt (%d)", h.ChickenAt, h.HealingPotionAt)
+	}
+	if h.ChickenAt > 0 && h.RejuvPotionAtLife > 0 && h.ChickenAt >= h.RejuvPotionAtLife {
+		errs.add("health.chickenAt", "chicken (%d) must be lower than rejuvPotionAtLife (%d)", h.ChickenAt, h.RejuvPotionAtLife)
+	}
+	if c.Character.UseMerc {
+		if h.MercChickenAt > 0 && h.MercHealingPotionAt > 0 && h.MercChickenAt >= h.MercHealingPotionAt {
+			errs.add("health.mercChickenAt", "merc chicken (%d) must be lower than mercHealingPotionAt (%d)", h.MercChickenAt, h.MercHealingPotionAt)
+		}
+		if h.MercChickenAt > 0 && h.MercRejuvPotionAt > 0 && h.MercChickenAt >= h.MercRejuvPotionAt {
+			errs.add("health.mercChickenAt", "merc chicken (%d) must be lower than mercRejuvPotionAt (%d)", h.MercChickenAt, h.MercRejuvPotionAt)
+		}
+	}
+}
+
+// CheckFiles validates every character config in configDir without loading them, the returned map is keyed by
+// supervisor name and only contains the invalid ones. Unlike Load it doesn't stop on the first broken file
+func CheckFiles(configDir string) (map[string]ValidationErrors, error) {
+	entries, err := os.ReadDir(configDir)
+	if err != nil {
+		return nil, fmt.Errorf("error reading config directory %s: %w", configDir, err)
+	}
+
+	result := make(map[string]ValidationErrors)
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+
+		path := filepath.Join(configDir, entry.Name(), "config.yaml")
+		b, err := os.ReadFile(path)
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("error reading %s: %w", path, err)
+		}
+
+		var cfg CharacterCfg
+		if err = yaml.Unmarshal(b, &cfg); err != nil {
+			result[entry.Name()] = yamlErrors(err)
+			continue
+		}
+
+		if cfg.Runtime.RunScripts, err = LoadRunScripts(filepath.Join(configDir, entry.Name())); err != nil {
+			var errs ValidationErrors
+			if !errors.As(err, &errs) {
+				errs = ValidationErrors{{Field: runScriptsDir, Message: err.Error()}}
+			}
+			result[entry.Name()] = errs
+			continue
+		}
+
+		if errs := cfg.Check(); len(errs) > 0 {
+			result[entry.Name()] = errs
+		}
+	}
+
+	return result, nil
+}
+
+// yamlErrors splits yaml type errors, they already contain the line number of each wrong value
+func yamlErrors(err error) ValidationErrors {
+	var typeErr *yaml.TypeError
+	if errors.As(err, &typeErr) {
+		errs := make(ValidationErrors, 0, len(typeErr.Errors))
+		for _, e := range typeErr.Errors {
+			errs.add("", "%s", e)
+		}
+		return errs
+	}
+
+	return ValidationErrors{{Message: err.Error()}}
+}
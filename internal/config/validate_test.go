@@ -0,0 +1,139 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/hectorgimenez/d2go/pkg/data/area"
+)
+
+// validConfig returns a config without errors, each test case breaks one value
+func validConfig() *CharacterCfg {
+	cfg := &CharacterCfg{}
+	cfg.Character.Class = "sorceress"
+	cfg.Game.Runs = []Run{MephistoRun, AndarielRun}
+	cfg.Health.HealingPotionAt = 60
+	cfg.Health.RejuvPotionAtLife = 40
+	cfg.Health.ChickenAt = 30
+	cfg.Inventory.BeltColumns = BeltColumns{"healing", "healing", "mana", "rejuvenation"}
+	cfg.Inventory.InventoryLock = make([][]int, inventoryRows)
+	for i := range cfg.Inventory.InventoryLock {
+		cfg.Inventory.InventoryLock[i] = make([]int, inventoryColumns)
+	}
+
+	return cfg
+}
+
+func TestCheck(t *testing.T) {
+	if errs := validConfig().Check(); len(errs) != 0 {
+		t.Fatalf("base config should be valid, got %v", errs)
+	}
+
+	tests := []struct {
+		name   string
+		change func(c *CharacterCfg)
+		fields []string
+	}{
+		{"unknown run", func(c *CharacterCfg) { c.Game.Runs = append(c.Game.Runs, "diablo2") }, []string{"game.runs[2]"}},
+		{"custom run", func(c *CharacterCfg) {
+			c.Game.Runs = append(c.Game.Runs, "cows_fast")
+			c.Runtime.RunScripts = map[Run]*RunScript{"cows_fast": {Name: "cows_fast", File: "cows_fast.yaml", Steps: []RunStep{{ReturnToTown: true}}}}
+		}, nil},
+		{"invalid run script", func(c *CharacterCfg) {
+			c.Runtime.RunScripts = map[Run]*RunScript{"empty": {Name: "empty", File: "empty.yaml"}}
+		}, []string{"empty.yaml:steps"}},
+		{"leveling not first", func(c *CharacterCfg) { c.Game.Runs = append(c.Game.Runs, LevelingRun) }, []string{"game.runs[2]"}},
+		{"missing class", func(c *CharacterCfg) { c.Character.Class = "" }, []string{"character.class"}},
+		{"unknown class", func(c *CharacterCfg) { c.Character.Class = "necromancer" }, []string{"character.class"}},
+		{"leveling class without leveling", func(c *CharacterCfg) { c.Character.Class = "paladin" }, []string{"character.class"}},
+		{"class not for leveling", func(c *CharacterCfg) { c.Game.Runs = []Run{LevelingRun} }, []string{"character.class"}},
+		{"leveling", func(c *CharacterCfg) { c.Character.Class, c.Game.Runs = "paladin", []Run{LevelingRun} }, nil},
+		{"percentage out of range", func(c *CharacterCfg) { c.Health.ManaPotionAt, c.Health.MercChickenAt = -1, 101 }, []string{"health.manaPotionAt", "health.mercChickenAt"}},
+		{"chicken above potions", func(c *CharacterCfg) { c.Health.ChickenAt = 50 }, []string{"health.chickenAt"}},
+		{"chicken above both potions", func(c *CharacterCfg) { c.Health.ChickenAt = 70 }, []string{"health.chickenAt", "health.chickenAt"}},
+		{"merc chicken above potions", func(c *CharacterCfg) {
+			c.Character.UseMerc = true
+			c.Health.MercHealingPotionAt, c.Health.MercRejuvPotionAt, c.Health.MercChickenAt = 50, 20, 30
+		}, []string{"health.mercChickenAt"}},
+		{"merc chicken without merc", func(c *CharacterCfg) {
+			c.Health.MercHealingPotionAt, c.Health.MercChickenAt = 20, 30
+		}, nil},
+		{"terror zone", func(c *CharacterCfg) { c.Game.TerrorZone.Areas = []area.ID{9999} }, []string{"game.terror_zone.areas[0]"}},
+		{"adaptive failure rate", func(c *CharacterCfg) { c.Game.AdaptiveRuns.MaxFailureRate = 120 }, []string{"game.adaptiveRuns.maxFailureRate"}},
+		{"adaptive negative counters", func(c *CharacterCfg) {
+			c.Game.AdaptiveRuns.Window, c.Game.AdaptiveRuns.MinRuns, c.Game.AdaptiveRuns.CooldownMinutes = -1, -1, -1
+		}, []string{"game.adaptiveRuns.window", "game.adaptiveRuns.minRuns", "game.adaptiveRuns.cooldownMinutes"}},
+		{"adaptive min runs above window", func(c *CharacterCfg) {
+			c.Game.AdaptiveRuns.Window, c.Game.AdaptiveRuns.MinRuns = 5, 10
+		}, []string{"game.adaptiveRuns.minRuns"}},
+		{"companion leader", func(c *CharacterCfg) { c.Companion.Enabled, c.Companion.Leader = true, true }, []string{"companion.gameNameTemplate"}},
+		{"companion follower", func(c *CharacterCfg) { c.Companion.Enabled = true }, []string{"companion.leaderName"}},
+		{"companion disabled", func(c *CharacterCfg) { c.Companion.Leader = true }, nil},
+		{"item capture size", func(c *CharacterCfg) { c.ItemCapture.MaxSizeMB = -1 }, []string{"itemCapture.maxSizeMB"}},
+		{"belt column", func(c *CharacterCfg) { c.Inventory.BeltColumns[2] = "stamina" }, []string{"inventory.beltColumns[2]"}},
+		{"belt column case", func(c *CharacterCfg) { c.Inventory.BeltColumns[0] = "Healing" }, nil},
+		{"inventory rows", func(c *CharacterCfg) { c.Inventory.InventoryLock = c.Inventory.InventoryLock[:3] }, []string{"inventory.inventoryLock"}},
+		{"inventory columns", func(c *CharacterCfg) { c.Inventory.InventoryLock[1] = []int{1, 1} }, []string{"inventory.inventoryLock[1]"}},
+		{"inventory values", func(c *CharacterCfg) { c.Inventory.InventoryLock[3][9] = 2 }, []string{"inventory.inventoryLock[3][9]"}},
+		{"scheduler cron", func(c *CharacterCfg) {
+			c.Scheduler.Cron = []CronWindow{{Expression: "61 * * * *", DurationMinutes: 60}}
+		}, []string{"scheduler"}},
+		{"scheduler duration", func(c *CharacterCfg) {
+			c.Scheduler.Cron = []CronWindow{{Expression: "@daily", DurationMinutes: 0}}
+		}, []string{"scheduler"}},
+	}
+
+	for _, tc := range tests {
+		cfg := validConfig()
+		tc.change(cfg)
+
+		fields := make([]string, 0)
+		for _, e := range cfg.Check() {
+			fields = append(fields, e.Field)
+		}
+		if !slices.Equal(fields, tc.fields) {
+			t.Errorf("%s: expected errors on %v, got %v", tc.name, tc.fields, fields)
+		}
+	}
+}
+
+func TestCheckFiles(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"valid/config.yaml":   "character:\n  class: sorceress\ngame:\n  runs: [mephisto]\ninventory:\n  beltColumns: [healing, healing, mana, mana]\n  inventoryLock: [[1,1,1,1,1,1,1,1,1,1],[1,1,1,1,1,1,1,1,1,1],[1,1,1,1,1,1,1,1,1,1],[1,1,1,1,1,1,1,1,1,1]]\n",
+		"invalid/config.yaml": "character:\n  class: sorceress\ngame:\n  runs: [nope]\ninventory:\n  beltColumns: [healing, healing, mana, mana]\n  inventoryLock: [[1,1,1,1,1,1,1,1,1,1],[1,1,1,1,1,1,1,1,1,1],[1,1,1,1,1,1,1,1,1,1],[1,1,1,1,1,1,1,1,1,1]]\n",
+		"broken/config.yaml":  "health:\n  chickenAt: lots\n",
+		"empty/readme.txt":    "no config here",
+		"scripts/config.yaml": "character:\n  class: sorceress\ngame:\n  runs: [mephisto]\ninventory:\n  beltColumns: [healing, healing, mana, mana]\n  inventoryLock: [[1,1,1,1,1,1,1,1,1,1],[1,1,1,1,1,1,1,1,1,1],[1,1,1,1,1,1,1,1,1,1],[1,1,1,1,1,1,1,1,1,1]]\n",
+		"scripts/runs/a.yaml": "steps:\n  - waypoint: Nowhere\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	result, err := CheckFiles(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result) != 3 {
+		t.Fatalf("expected the invalid, broken and scripts configs, got %v", result)
+	}
+	if errs := result["invalid"]; len(errs) != 1 || errs[0].Field != "game.runs[0]" {
+		t.Errorf("unexpected errors for invalid %v", errs)
+	}
+	if errs := result["broken"]; len(errs) != 1 || !strings.Contains(errs[0].Message, "line 2") {
+		t.Errorf("yaml errors should keep the line, got %v", errs)
+	}
+	if errs := result["scripts"]; len(errs) != 1 || errs[0].Field != "runs/a.yaml:steps[0].waypoint" {
+		t.Errorf("run script errors should keep their field, got %v", errs)
+	}
+}
@@ -15,6 +15,7 @@ import (
 	"github.com/hectorgimenez/koolo/internal/game"
 	"github.com/hectorgimenez/koolo/internal/health"
 	"github.com/hectorgimenez/koolo/internal/pather"
+	"github.com/hectorgimenez/koolo/internal/pickit"
 )
 
 var mu sync.Mutex
@@ -53,6 +54,8 @@ type Context struct {
 	LastBuffAt        time.Time
 	ContextDebug      map[Priority]*Debug
 	CurrentGame       *CurrentGameHelper
+	// Ground item recorder, only set when itemCapture is enabled
+	ItemCapture *pickit.Recorder
 }
 
 type Debug struct {
@@ -67,6 +70,21 @@ type CurrentGameHelper struct {
 		ExpectedArea area.ID
 	}
 	PickupItems bool
+	// Where each item was picked up, reported by the drop ledger and the run stats when the item is stashed
+	PickedUpItems map[data.UnitID]PickedUpItem
+	// Run being executed, set by the bot before starting it
+	CurrentRun string
+	// Ground items already recorded by the item capture
+	CapturedItems map[data.UnitID]bool
+	// Last target announced to the companions and when, only used by the companion leader
+	AnnouncedTarget   data.UnitID
+	AnnouncedTargetAt time.Time
+}
+
+type PickedUpItem struct {
+	Area area.ID
+	Run  string
+	At   time.Time
 }
 
 func NewContext(name string) *Status {
@@ -90,7 +108,9 @@ func NewContext(name string) *Status {
 
 func NewGameHelper() *CurrentGameHelper {
 	return &CurrentGameHelper{
-		PickupItems: true,
+		PickupItems:   true,
+		PickedUpItems: make(map[data.UnitID]PickedUpItem),
+		CapturedItems: make(map[data.UnitID]bool),
 	}
 }
 
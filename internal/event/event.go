@@ -54,3 +54,12 @@ func Text(supervisor string, message string) BaseEvent {
 		supervisor: supervisor,
 	}
 }
+
+// TextAt is like Text but keeps the given occurrence time, used to rebuild events that were stored previously
+func TextAt(supervisor string, message string, occurredAt time.Time) BaseEvent {
+	return BaseEvent{
+		message:    message,
+		occurredAt: occurredAt,
+		supervisor: supervisor,
+	}
+}
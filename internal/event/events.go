@@ -1,7 +1,10 @@
 package event
 
 import (
+	"time"
+
 	"github.com/hectorgimenez/d2go/pkg/data"
+	"github.com/hectorgimenez/d2go/pkg/data/area"
 )
 
 const (
@@ -73,12 +76,17 @@ func RunFinished(be BaseEvent, runName string, reason FinishReason) RunFinishedE
 type ItemStashedEvent struct {
 	BaseEvent
 	Item data.Drop
+	// Run where the item was picked up and when, empty if unknown. The item can be stashed in a later game
+	RunName    string
+	PickedUpAt time.Time
 }
 
-func ItemStashed(be BaseEvent, drop data.Drop) ItemStashedEvent {
+func ItemStashed(be BaseEvent, drop data.Drop, runName string, pickedUpAt time.Time) ItemStashedEvent {
 	return ItemStashedEvent{
-		BaseEvent: be,
-		Item:      drop,
+		BaseEvent:  be,
+		Item:       drop,
+		RunName:    runName,
+		PickedUpAt: pickedUpAt,
 	}
 }
 
@@ -139,3 +147,35 @@ func GamePaused(be BaseEvent, paused bool) GamePausedEvent {
 		Paused:    paused,
 	}
 }
+
+type TerrorZoneDetectedEvent struct {
+	BaseEvent
+	Zones []area.ID
+	// None of the zones is enabled for the character, the supervisor leaves the game without running anything
+	Hopped bool
+}
+
+func TerrorZoneDetected(be BaseEvent, zones []area.ID, hopped bool) TerrorZoneDetectedEvent {
+	return TerrorZoneDetectedEvent{
+		BaseEvent: be,
+		Zones:     zones,
+		Hopped:    hopped,
+	}
+}
+
+// RemoteEvent is an event from a supervisor running in another koolo instance, received through the hub. It's wrapped
+// so handlers only interested in local supervisors (stats, notifications...) don't process it
+type RemoteEvent struct {
+	Event
+	Instance string
+	// In game name of the remote character, needed to find its portals
+	Character string
+}
+
+func Remote(e Event, instance string, character string) RemoteEvent {
+	return RemoteEvent{
+		Event:     e,
+		Instance:  instance,
+		Character: character,
+	}
+}
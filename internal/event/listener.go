@@ -4,94 +4,383 @@ import (
 	"context"
 	"fmt"
 	"log/slog"
-	"math"
-	"math/rand"
 	"os"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/hectorgimenez/koolo/internal/config"
 	"github.com/hectorgimenez/koolo/internal/utils"
 )
 
-var events = make(chan Event)
+const (
+	// Events sent but not dispatched yet, Send only blocks when this is full
+	pendingEventsSize = 256
+	defaultQueueSize  = 100
+)
+
+var (
+	events    = make(chan Event, pendingEventsSize)
+	published atomic.Uint64
+)
 
+// Listener is the event bus, events sent with Send are dispatched to every matching subscriber. Each subscriber has
+// its own buffered queue and goroutine, so a slow handler only delays its own events.
 type Listener struct {
-	handlers         []Handler
-	deliveryHandlers map[int]Handler
-	logger           *slog.Logger
+	logger *slog.Logger
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	mu            sync.RWMutex
+	subscriptions map[uint64]*Subscription
+	nextID        uint64
 }
 
 type Handler func(ctx context.Context, e Event) error
 
+// Policy defines what happens when a subscriber queue is full
+type Policy int
+
+const (
+	// PolicyBlock waits until the subscriber has room, slowing down the publishers, use it when events can't be lost
+	PolicyBlock Policy = iota
+	// PolicyDropNewest discards the event being published
+	PolicyDropNewest
+	// PolicyDropOldest discards the oldest queued event to make room for the new one
+	PolicyDropOldest
+)
+
+// Filter selects the events a subscriber is interested in
+type Filter func(e Event) bool
+
+// OfType matches events of the given type, for example OfType[GameFinishedEvent]()
+func OfType[T Event]() Filter {
+	return func(e Event) bool {
+		_, ok := e.(T)
+		return ok
+	}
+}
+
+// ForSupervisor matches events sent by the given supervisor
+func ForSupervisor(name string) Filter {
+	return func(e Event) bool {
+		return e.Supervisor() == name
+	}
+}
+
+type SubscribeOption func(s *Subscription)
+
+// WithName sets the subscriber name used in logs and metrics
+func WithName(name string) SubscribeOption {
+	return func(s *Subscription) {
+		s.name = name
+	}
+}
+
+func WithQueueSize(size int) SubscribeOption {
+	return func(s *Subscription) {
+		s.queueSize = max(size, 1)
+	}
+}
+
+func WithPolicy(p Policy) SubscribeOption {
+	return func(s *Subscription) {
+		s.policy = p
+	}
+}
+
+// WithFilter only delivers events matching all the filters
+func WithFilter(filters ...Filter) SubscribeOption {
+	return func(s *Subscription) {
+		s.filters = append(s.filters, filters...)
+	}
+}
+
+type Subscription struct {
+	id        uint64
+	name      string
+	listener  *Listener
+	handler   Handler
+	filters   []Filter
+	policy    Policy
+	queueSize int
+	queue     chan Event
+	done      chan struct{}
+	closeOnce sync.Once
+
+	delivered    atomic.Uint64
+	dropped      atomic.Uint64
+	failed       atomic.Uint64
+	totalLatency atomic.Int64
+	maxLatency   atomic.Int64
+}
+
+// SubscriberMetrics is a snapshot of the subscriber queue and handler stats
+type SubscriberMetrics struct {
+	Name          string
+	Policy        Policy
+	QueueDepth    int
+	QueueCapacity int
+	Delivered     uint64
+	Dropped       uint64
+	Failed        uint64
+	AvgLatency    time.Duration
+	MaxLatency    time.Duration
+}
+
+type Metrics struct {
+	Published     uint64
+	PendingEvents int
+	Subscribers   []SubscriberMetrics
+}
+
 func NewListener(logger *slog.Logger) *Listener {
-	return &Listener{
-		logger:           logger,
-		deliveryHandlers: make(map[int]Handler),
+	ctx, cancel := context.WithCancel(context.Background())
+	l := &Listener{
+		logger:        logger,
+		ctx:           ctx,
+		cancel:        cancel,
+		subscriptions: make(map[uint64]*Subscription),
 	}
+
+	// Saving screenshots is slow, it's fine to skip some of them if we're flooded
+	l.Subscribe(l.saveScreenshot, WithName("screenshots"), WithPolicy(PolicyDropNewest), WithFilter(func(e Event) bool {
+		return e.Image() != nil
+	}))
+
+	return l
 }
 
+// Register subscribes the handler to all the events, events are never dropped
 func (l *Listener) Register(h Handler) {
-	l.handlers = append(l.handlers, h)
+	l.Subscribe(h)
+}
+
+// Subscribe starts delivering the events to the handler in its own goroutine, by default all the events are delivered
+// with PolicyBlock. A nil handler is allowed, events can be read with Next.
+func (l *Listener) Subscribe(h Handler, opts ...SubscribeOption) *Subscription {
+	s := &Subscription{
+		listener:  l,
+		handler:   h,
+		policy:    PolicyBlock,
+		queueSize: defaultQueueSize,
+		done:      make(chan struct{}),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	s.queue = make(chan Event, s.queueSize)
+
+	l.mu.Lock()
+	l.nextID++
+	s.id = l.nextID
+	if s.name == "" {
+		s.name = fmt.Sprintf("subscriber-%d", s.id)
+	}
+	l.subscriptions[s.id] = s
+	l.mu.Unlock()
+
+	if h != nil {
+		go s.run(l.ctx)
+	}
+
+	return s
 }
 
 func (l *Listener) Listen(ctx context.Context) error {
+	defer l.cancel()
+
 	for {
 		select {
 		case e := <-events:
-			if _, err := os.Stat("screenshots"); os.IsNotExist(err) {
-				err = os.MkdirAll("screenshots", os.ModePerm)
-				if err != nil {
-					l.logger.Error("error creating screenshots directory", slog.Any("error", err))
-				}
-			}
-
-			if e.Image() != nil && config.Koolo.Debug.Screenshots {
-				fileName := fmt.Sprintf("screenshots/error-%s.jpeg", time.Now().Format("2006-01-02 15_04_05"))
-				err := utils.SaveImageJPEG(e.Image(), fileName)
-				if err != nil {
-					l.logger.Error("error saving screenshot", slog.Any("error", err))
-				}
-			}
-
-			for _, h := range l.handlers {
-				if err := h(ctx, e); err != nil && e.Message() != "" {
-					l.logger.Error("error running event handler", slog.Any("error", err))
-				}
-			}
-			for _, h := range l.deliveryHandlers {
-				if err := h(ctx, e); err != nil {
-					l.logger.Error("error running event delivery handler", slog.Any("error", err))
-				}
-			}
-
+			l.dispatch(ctx, e)
 		case <-ctx.Done():
 			return nil
 		}
 	}
 }
 
-func (l *Listener) WaitForEvent(ctx context.Context) Event {
-	evtChan := make(chan Event)
-	idx := rand.Intn(math.MaxInt64)
-	l.deliveryHandlers[idx] = func(ctx context.Context, e Event) error {
-		evtChan <- e
+func (l *Listener) dispatch(ctx context.Context, e Event) {
+	l.mu.RLock()
+	subs := make([]*Subscription, 0, len(l.subscriptions))
+	for _, s := range l.subscriptions {
+		subs = append(subs, s)
+	}
+	l.mu.RUnlock()
+
+	for _, s := range subs {
+		if s.matches(e) {
+			s.enqueue(ctx, e)
+		}
+	}
+}
+
+// WaitForEvent returns the next event matching all the filters, or nil if the context is done first
+func (l *Listener) WaitForEvent(ctx context.Context, filters ...Filter) Event {
+	s := l.Subscribe(nil, WithName("wait-for-event"), WithQueueSize(1), WithPolicy(PolicyDropNewest), WithFilter(filters...))
+	defer s.Unsubscribe()
+
+	e, _ := s.Next(ctx)
+	return e
+}
+
+// Metrics returns a snapshot of the bus queues and handler latencies
+func (l *Listener) Metrics() Metrics {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	m := Metrics{
+		Published:     published.Load(),
+		PendingEvents: len(events),
+		Subscribers:   make([]SubscriberMetrics, 0, len(l.subscriptions)),
+	}
+	for _, s := range l.subscriptions {
+		m.Subscribers = append(m.Subscribers, s.Metrics())
+	}
+
+	return m
+}
+
+func (l *Listener) saveScreenshot(_ context.Context, e Event) error {
+	if !config.Koolo.Debug.Screenshots {
 		return nil
 	}
-	// Clean up the handler when we're done
-	defer func() {
-		delete(l.deliveryHandlers, idx)
-	}()
 
-	for {
+	if _, err := os.Stat("screenshots"); os.IsNotExist(err) {
+		err = os.MkdirAll("screenshots", os.ModePerm)
+		if err != nil {
+			return fmt.Errorf("error creating screenshots directory: %w", err)
+		}
+	}
+
+	fileName := fmt.Sprintf("screenshots/error-%s.jpeg", e.OccurredAt().Format("2006-01-02 15_04_05"))
+	if err := utils.SaveImageJPEG(e.Image(), fileName); err != nil {
+		return fmt.Errorf("error saving screenshot: %w", err)
+	}
+
+	return nil
+}
+
+func (s *Subscription) matches(e Event) bool {
+	for _, f := range s.filters {
+		if !f(e) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (s *Subscription) enqueue(ctx context.Context, e Event) {
+	select {
+	case <-s.done:
+		return
+	default:
+	}
+
+	switch s.policy {
+	case PolicyBlock:
 		select {
-		case e := <-evtChan:
-			return e
+		case s.queue <- e:
+		case <-s.done:
 		case <-ctx.Done():
-			return nil
 		}
+	case PolicyDropNewest:
+		select {
+		case s.queue <- e:
+		default:
+			s.dropped.Add(1)
+		}
+	case PolicyDropOldest:
+		for {
+			select {
+			case s.queue <- e:
+				return
+			default:
+			}
+
+			// Make room, the handler goroutine may have taken it meanwhile, in that case just retry
+			select {
+			case <-s.queue:
+				s.dropped.Add(1)
+			default:
+			}
+		}
+	}
+}
+
+func (s *Subscription) run(ctx context.Context) {
+	for {
+		e, ok := s.Next(ctx)
+		if !ok {
+			return
+		}
+
+		start := time.Now()
+		err := s.handler(ctx, e)
+		latency := time.Since(start)
+
+		s.delivered.Add(1)
+		s.totalLatency.Add(int64(latency))
+		for {
+			current := s.maxLatency.Load()
+			if int64(latency) <= current || s.maxLatency.CompareAndSwap(current, int64(latency)) {
+				break
+			}
+		}
+
+		if err != nil {
+			s.failed.Add(1)
+			if e.Message() != "" {
+				s.listener.logger.Error("error running event handler", slog.String("subscriber", s.name), slog.Any("error", err))
+			}
+		}
+	}
+}
+
+// Next blocks until an event is received, it returns false if the subscription or the context are done
+func (s *Subscription) Next(ctx context.Context) (Event, bool) {
+	select {
+	case e := <-s.queue:
+		return e, true
+	case <-s.done:
+		return nil, false
+	case <-ctx.Done():
+		return nil, false
+	}
+}
+
+// Unsubscribe stops the delivery of events, pending events in the queue are discarded
+func (s *Subscription) Unsubscribe() {
+	s.closeOnce.Do(func() {
+		s.listener.mu.Lock()
+		delete(s.listener.subscriptions, s.id)
+		s.listener.mu.Unlock()
+
+		close(s.done)
+	})
+}
+
+func (s *Subscription) Metrics() SubscriberMetrics {
+	m := SubscriberMetrics{
+		Name:          s.name,
+		Policy:        s.policy,
+		QueueDepth:    len(s.queue),
+		QueueCapacity: cap(s.queue),
+		Delivered:     s.delivered.Load(),
+		Dropped:       s.dropped.Load(),
+		Failed:        s.failed.Load(),
+		MaxLatency:    time.Duration(s.maxLatency.Load()),
+	}
+	if m.Delivered > 0 {
+		m.AvgLatency = time.Duration(s.totalLatency.Load() / int64(m.Delivered))
 	}
+
+	return m
 }
 
+// Send publishes the event, it only blocks if the bus is saturated or a PolicyBlock subscriber is too slow
 func Send(e Event) {
+	published.Add(1)
 	events <- e
 }
@@ -0,0 +1,152 @@
+package event
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"sync"
+	"testing"
+	"time"
+)
+
+func newTestListener(t *testing.T) (*Listener, context.CancelFunc) {
+	t.Helper()
+
+	l := NewListener(slog.New(slog.NewTextHandler(io.Discard, nil)))
+	ctx, cancel := context.WithCancel(context.Background())
+	go l.Listen(ctx)
+	t.Cleanup(cancel)
+
+	return l, cancel
+}
+
+func TestSubscribeFilters(t *testing.T) {
+	l, _ := newTestListener(t)
+
+	var mu sync.Mutex
+	received := make([]Event, 0)
+	done := make(chan struct{})
+	l.Subscribe(func(_ context.Context, e Event) error {
+		mu.Lock()
+		defer mu.Unlock()
+		received = append(received, e)
+		if len(received) == 2 {
+			close(done)
+		}
+		return nil
+	}, WithFilter(OfType[GameFinishedEvent](), ForSupervisor("sorc")))
+
+	Send(GameFinished(Text("hammerdin", ""), FinishedOK))
+	Send(RunStarted(Text("sorc", ""), "mephisto"))
+	Send(GameFinished(Text("sorc", ""), FinishedOK))
+	Send(GameFinished(Text("sorc", ""), FinishedChicken))
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("timeout waiting for events")
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	for _, e := range received {
+		if e.Supervisor() != "sorc" {
+			t.Errorf("unexpected event from %s", e.Supervisor())
+		}
+	}
+	if received[1].(GameFinishedEvent).Reason != FinishedChicken {
+		t.Errorf("events must be delivered in order")
+	}
+}
+
+func TestSlowSubscriberDoesNotBlockOthers(t *testing.T) {
+	l, _ := newTestListener(t)
+
+	release := make(chan struct{})
+	defer close(release)
+	slow := l.Subscribe(func(_ context.Context, e Event) error {
+		<-release
+		return nil
+	}, WithName("slow"), WithQueueSize(2), WithPolicy(PolicyDropNewest))
+
+	fast := make(chan Event, 100)
+	l.Subscribe(func(_ context.Context, e Event) error {
+		fast <- e
+		return nil
+	}, WithName("fast"))
+
+	for i := 0; i < 20; i++ {
+		Send(RunStarted(Text("sorc", ""), "andariel"))
+	}
+
+	for i := 0; i < 20; i++ {
+		select {
+		case <-fast:
+		case <-time.After(time.Second):
+			t.Fatalf("fast subscriber only received %d events", i)
+		}
+	}
+
+	if m := slow.Metrics(); m.Dropped == 0 {
+		t.Errorf("expected dropped events on the slow subscriber, got %+v", m)
+	}
+}
+
+func TestDropOldestKeepsLatest(t *testing.T) {
+	l := NewListener(slog.New(slog.NewTextHandler(io.Discard, nil)))
+	s := l.Subscribe(nil, WithQueueSize(2), WithPolicy(PolicyDropOldest))
+
+	for _, run := range []string{"a", "b", "c"} {
+		l.dispatch(context.Background(), RunStarted(Text("sorc", ""), run))
+	}
+
+	for _, expected := range []string{"b", "c"} {
+		e, ok := s.Next(context.Background())
+		if !ok || e.(RunStartedEvent).RunName != expected {
+			t.Fatalf("expected run %s, got %v", expected, e)
+		}
+	}
+	if m := s.Metrics(); m.Dropped != 1 || m.QueueDepth != 0 {
+		t.Errorf("unexpected metrics %+v", m)
+	}
+}
+
+func TestWaitForEvent(t *testing.T) {
+	l, _ := newTestListener(t)
+
+	// Many concurrent waiters subscribing and unsubscribing while events are being sent
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+			defer cancel()
+			if e := l.WaitForEvent(ctx, OfType[GamePausedEvent]()); e == nil {
+				t.Error("expected GamePausedEvent")
+			}
+		}()
+	}
+
+	stop := make(chan struct{})
+	go func() {
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+				Send(GamePaused(Text("sorc", ""), true))
+				time.Sleep(time.Millisecond)
+			}
+		}
+	}()
+
+	wg.Wait()
+	close(stop)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if e := l.WaitForEvent(ctx, ForSupervisor("nobody")); e != nil {
+		t.Errorf("expected nil on context timeout, got %v", e)
+	}
+}
@@ -8,6 +8,8 @@ const (
 	CollisionTypeLowPriority
 	CollisionTypeMonster
 	CollisionTypeObject
+	// Close to a dangerous monster, walkable but better to go around
+	CollisionTypeDanger
 )
 
 type CollisionType uint8
@@ -1,21 +1,59 @@
 package map_client
 
 import (
-	"encoding/json"
+	"bytes"
 	"fmt"
+	"log/slog"
 	"os/exec"
-	"strings"
+	"sync"
 	"syscall"
 
-	"github.com/hectorgimenez/d2go/pkg/data"
-	"github.com/hectorgimenez/d2go/pkg/data/area"
 	"github.com/hectorgimenez/d2go/pkg/data/difficulty"
-	"github.com/hectorgimenez/d2go/pkg/data/npc"
-	"github.com/hectorgimenez/d2go/pkg/data/object"
 	"github.com/hectorgimenez/koolo/internal/config"
+	"github.com/hectorgimenez/koolo/internal/mapdata"
 )
 
+type MapData = mapdata.MapData
+
+// The cache is shared by all the supervisors, companions usually join the same game
+var mapCache = sync.OnceValue(func() *mapdata.Cache {
+	cfg := config.Koolo.MapCache
+	if !cfg.Enabled || cfg.Directory == "" {
+		return nil
+	}
+
+	cache, err := mapdata.NewCache(cfg.Directory, int64(cfg.MaxSizeMB)*1024*1024)
+	if err != nil {
+		slog.Error("Map cache could not be initialized, map data will be fetched for every game", slog.Any("error", err))
+		return nil
+	}
+
+	return cache
+})
+
 func GetMapData(seed string, difficulty difficulty.Difficulty) (MapData, error) {
+	cache := mapCache()
+	if cache == nil {
+		return fetchMapData(seed, difficulty)
+	}
+
+	data, cached, err := cache.Fetch(seed, string(difficulty), func() (MapData, error) {
+		return fetchMapData(seed, difficulty)
+	})
+	if err != nil && data == nil {
+		return nil, err
+	}
+	if err != nil {
+		slog.Warn("Map data could not be cached", slog.Any("error", err))
+	}
+	if cached {
+		slog.Debug("Map data loaded from cache", slog.String("seed", seed), slog.String("difficulty", string(difficulty)))
+	}
+
+	return data, nil
+}
+
+func fetchMapData(seed string, difficulty difficulty.Difficulty) (MapData, error) {
 	cmd := exec.Command("./tools/koolo-map.exe", config.Koolo.D2LoDPath, "-s", seed, "-d", getDifficultyAsNum(difficulty))
 	cmd.SysProcAttr = &syscall.SysProcAttr{HideWindow: true}
 	stdout, err := cmd.Output()
@@ -23,19 +61,7 @@ func GetMapData(seed string, difficulty difficulty.Difficulty) (MapData, error)
 		return nil, fmt.Errorf("error fetching Map data from Diablo II: LoD 1.13c game: %w", err)
 	}
 
-	stdoutLines := strings.Split(string(stdout), "\r\n")
-
-	lvls := make([]serverLevel, 0)
-	for _, line := range stdoutLines {
-		var lvl serverLevel
-		err = json.Unmarshal([]byte(line), &lvl)
-		// Discard empty lines or lines that don't contain level information
-		if err == nil && lvl.Type != "" && len(lvl.Map) > 0 {
-			lvls = append(lvls, lvl)
-		}
-	}
-
-	return lvls, nil
+	return mapdata.Parse(bytes.NewReader(stdout))
 }
 
 func getDifficultyAsNum(df difficulty.Difficulty) string {
@@ -50,120 +76,3 @@ func getDifficultyAsNum(df difficulty.Difficulty) string {
 
 	return "0"
 }
-
-type MapData []serverLevel
-
-func (lvl serverLevel) CollisionGrid() [][]bool {
-	var cg [][]bool
-
-	for y := 0; y < lvl.Size.Height; y++ {
-		var row []bool
-		for x := 0; x < lvl.Size.Width; x++ {
-			row = append(row, false)
-		}
-
-		// Documentation about how this works: https://github.com/blacha/diablo2/tree/master/packages/map
-		if len(lvl.Map) > y {
-			mapRow := lvl.Map[y]
-			isWalkable := false
-			xPos := 0
-			for k, xs := range mapRow {
-				if k != 0 {
-					for xOffset := 0; xOffset < xs; xOffset++ {
-						row[xPos+xOffset] = isWalkable
-					}
-				}
-				isWalkable = !isWalkable
-				xPos += xs
-			}
-			for xPos < len(row) {
-				row[xPos] = isWalkable
-				xPos++
-			}
-		}
-
-		cg = append(cg, row)
-	}
-
-	return cg
-}
-
-func (lvl serverLevel) NPCsExitsAndObjects() (data.NPCs, []data.Level, []data.Object, []data.Room) {
-	var npcs []data.NPC
-	var exits []data.Level
-	var objects []data.Object
-	var rooms []data.Room
-
-	for _, r := range lvl.Rooms {
-		rooms = append(rooms, data.Room{
-			Position: data.Position{X: r.X,
-				Y: r.Y,
-			},
-			Width:  r.Width,
-			Height: r.Height,
-		})
-	}
-
-	for _, obj := range lvl.Objects {
-		switch obj.Type {
-		case "npc":
-			n := data.NPC{
-				ID:   npc.ID(obj.ID),
-				Name: obj.Name,
-				Positions: []data.Position{{
-					X: obj.X + lvl.Offset.X,
-					Y: obj.Y + lvl.Offset.Y,
-				}},
-			}
-			npcs = append(npcs, n)
-		case "exit":
-			exit := data.Level{
-				Area: area.ID(obj.ID),
-				Position: data.Position{
-					X: obj.X + lvl.Offset.X,
-					Y: obj.Y + lvl.Offset.Y,
-				},
-				IsEntrance: true,
-			}
-			exits = append(exits, exit)
-		case "object":
-			o := data.Object{
-				Name: object.Name(obj.ID),
-				Position: data.Position{
-					X: obj.X + lvl.Offset.X,
-					Y: obj.Y + lvl.Offset.Y,
-				},
-			}
-			objects = append(objects, o)
-		}
-	}
-
-	for _, obj := range lvl.Objects {
-		switch obj.Type {
-		case "exit_area":
-			found := false
-			for _, exit := range exits {
-				if exit.Area == area.ID(obj.ID) {
-					exit.IsEntrance = false
-					found = true
-					break
-				}
-			}
-
-			if !found {
-				lvl := data.Level{
-					Area: area.ID(obj.ID),
-					Position: data.Position{
-						X: obj.X + lvl.Offset.X,
-						Y: obj.Y + lvl.Offset.Y,
-					},
-					IsEntrance: false,
-				}
-				exits = append(exits, lvl)
-			}
-		}
-
-	}
-
-	return npcs, exits, objects, rooms
-}
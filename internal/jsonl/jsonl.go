@@ -0,0 +1,74 @@
+// Package jsonl reads and appends JSON lines files, used to persist the stats, the drop ledger and the terror zones
+package jsonl
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"strings"
+)
+
+// Max length of a line, items with all their stats are the longest ones
+const maxLineSize = 1024 * 1024
+
+// OpenAppend opens the file to add lines at the end, it's created if it doesn't exist
+func OpenAppend(path string) (*os.File, error) {
+	return os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+}
+
+// Append writes the value as a single line, with a single write so lines of different values are never mixed
+func Append(w io.Writer, v any) error {
+	line, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(line, '\n'))
+
+	return err
+}
+
+// Read calls fn for every line of the file decoded as T, oldest first, a missing file has no lines. Lines that can't
+// be decoded are skipped instead of invalidating the whole file, Koolo could have crashed while writing the last one
+func Read[T any](path string, fn func(T)) error {
+	return ReadRange(path, 0, -1, fn)
+}
+
+// ReadRange is Read for the lines between the byte offsets from and to, to < 0 reads until the end of the file. Offsets
+// must be at the start of a line, like the size of the file after a write
+func ReadRange[T any](path string, from, to int64, fn func(T)) error {
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if _, err = f.Seek(from, io.SeekStart); err != nil {
+		return err
+	}
+	var r io.Reader = f
+	if to >= 0 {
+		r = io.LimitReader(f, max(to-from, 0))
+	}
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxLineSize)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+
+		var v T
+		if err := json.Unmarshal([]byte(line), &v); err != nil {
+			continue
+		}
+		fn(v)
+	}
+
+	return scanner.Err()
+}
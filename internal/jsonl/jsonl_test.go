@@ -0,0 +1,114 @@
+package jsonl
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+type line struct {
+	Name  string `json:"name"`
+	Value int    `json:"value"`
+}
+
+func TestAppendAndRead(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "lines.jsonl")
+
+	read := func() []line {
+		t.Helper()
+		lines := make([]line, 0)
+		if err := Read(path, func(l line) { lines = append(lines, l) }); err != nil {
+			t.Fatal(err)
+		}
+		return lines
+	}
+
+	if lines := read(); len(lines) != 0 {
+		t.Fatalf("a missing file should have no lines, got %v", lines)
+	}
+
+	f, err := OpenAppend(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, l := range []line{{"a", 1}, {"b", 2}} {
+		if err = Append(f, l); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Partially written line and an empty one, like after a crash
+	if _, err = f.WriteString("\n{\"name\":\"c\",\"va"); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	// Reopening keeps appending after the broken line
+	if f, err = OpenAppend(path); err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if _, err = f.WriteString("\n"); err != nil {
+		t.Fatal(err)
+	}
+	if err = Append(f, line{"d", 4}); err != nil {
+		t.Fatal(err)
+	}
+
+	if lines := read(); !slices.Equal(lines, []line{{"a", 1}, {"b", 2}, {"d", 4}}) {
+		t.Errorf("unexpected lines %v", lines)
+	}
+}
+
+func TestReadRange(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "lines.jsonl")
+	f, err := OpenAppend(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	var sizes []int64
+	for _, l := range []line{{"a", 1}, {"b", 2}, {"c", 3}} {
+		if err = Append(f, l); err != nil {
+			t.Fatal(err)
+		}
+		info, err := f.Stat()
+		if err != nil {
+			t.Fatal(err)
+		}
+		sizes = append(sizes, info.Size())
+	}
+
+	for _, tc := range []struct {
+		from, to int64
+		expected []line
+	}{
+		{0, sizes[0], []line{{"a", 1}}},
+		{sizes[0], sizes[2], []line{{"b", 2}, {"c", 3}}},
+		{sizes[1], -1, []line{{"c", 3}}},
+		{sizes[2], -1, []line{}},
+	} {
+		lines := make([]line, 0)
+		if err = ReadRange(path, tc.from, tc.to, func(l line) { lines = append(lines, l) }); err != nil {
+			t.Fatal(err)
+		}
+		if !slices.Equal(lines, tc.expected) {
+			t.Errorf("%d-%d: expected %v, got %v", tc.from, tc.to, tc.expected, lines)
+		}
+	}
+}
+
+func TestReadErrors(t *testing.T) {
+	if err := Read(t.TempDir(), func(line) {}); err == nil {
+		t.Error("reading a directory should fail")
+	}
+
+	path := filepath.Join(t.TempDir(), "long.jsonl")
+	if err := os.WriteFile(path, make([]byte, maxLineSize+1), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := Read(path, func(line) {}); err == nil {
+		t.Error("lines longer than the limit should fail")
+	}
+}
@@ -0,0 +1,60 @@
+package ledger
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var csvHeader = []string{"id", "time", "supervisor", "game", "run", "area", "name", "quality", "ethereal", "identified", "rule", "rule_file", "stats"}
+
+// WriteCSV writes the entries with a header line, stats are joined in a single "name=value; name=value" column
+func WriteCSV(w io.Writer, entries []Entry) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(csvHeader); err != nil {
+		return err
+	}
+
+	for _, e := range entries {
+		stats := make([]string, 0, len(e.Stats))
+		for _, st := range e.Stats {
+			stats = append(stats, fmt.Sprintf("%s=%d", st.Name, st.Value))
+		}
+
+		if err := cw.Write([]string{
+			strconv.Itoa(e.ID),
+			e.Time.Format(time.RFC3339),
+			e.Supervisor,
+			e.Game,
+			e.Run,
+			e.Area,
+			e.Name,
+			e.Quality,
+			strconv.FormatBool(e.Ethereal),
+			strconv.FormatBool(e.Identified),
+			e.Rule,
+			e.RuleFile,
+			strings.Join(stats, "; "),
+		}); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+
+	return cw.Error()
+}
+
+func WriteJSON(w io.Writer, entries []Entry) error {
+	if entries == nil {
+		entries = make([]Entry, 0)
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+
+	return enc.Encode(entries)
+}
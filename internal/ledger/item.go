@@ -0,0 +1,40 @@
+package ledger
+
+import (
+	"github.com/hectorgimenez/d2go/pkg/data"
+	"github.com/hectorgimenez/d2go/pkg/data/item"
+	"github.com/hectorgimenez/d2go/pkg/data/stat"
+)
+
+var statIDs = func() map[string]stat.ID {
+	ids := make(map[string]stat.ID, len(stat.StringStats))
+	for id, name := range stat.StringStats {
+		ids[name] = stat.ID(id)
+	}
+	return ids
+}()
+
+// Item rebuilds the stashed item from the entry so it can be evaluated again by the pickit rules. Stat layers are not
+// stored in the ledger, every stat is restored in layer 0
+func (e Entry) Item() data.Item {
+	it := data.Item{
+		ID:         item.GetIDByName(e.Name),
+		Name:       item.Name(e.Name),
+		Ethereal:   e.Ethereal,
+		Identified: e.Identified,
+		Stats:      make(stat.Stats, 0, len(e.Stats)),
+	}
+	for q := item.QualityLowQuality; q <= item.QualityCrafted; q++ {
+		if q.ToString() == e.Quality {
+			it.Quality = q
+			break
+		}
+	}
+	for _, st := range e.Stats {
+		if id, found := statIDs[st.Name]; found {
+			it.Stats = append(it.Stats, stat.Data{ID: id, Value: st.Value})
+		}
+	}
+
+	return it
+}
@@ -0,0 +1,222 @@
+// Package ledger keeps a persistent record of every stashed item, with the context where it was found
+package ledger
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hectorgimenez/d2go/pkg/data/stat"
+	"github.com/hectorgimenez/koolo/internal/event"
+	"github.com/hectorgimenez/koolo/internal/jsonl"
+)
+
+const fileName = "drops.jsonl"
+
+type Entry struct {
+	ID         int       `json:"id"`
+	Time       time.Time `json:"time"`
+	Supervisor string    `json:"supervisor"`
+	Game       string    `json:"game"`
+	Run        string    `json:"run"`
+	Area       string    `json:"area"`
+	Name       string    `json:"name"`
+	Quality    string    `json:"quality"`
+	Ethereal   bool      `json:"ethereal"`
+	Identified bool      `json:"identified"`
+	Rule       string    `json:"rule"`
+	RuleFile   string    `json:"ruleFile"`
+	Stats      []Stat    `json:"stats"`
+}
+
+type Stat struct {
+	Name  string `json:"name"`
+	Value int    `json:"value"`
+}
+
+// Filter selects ledger entries, empty fields match everything. Text fields are case insensitive, name and rule file
+// match partially
+type Filter struct {
+	Supervisor string
+	Quality    string
+	Name       string
+	RuleFile   string
+	From       time.Time
+	To         time.Time
+}
+
+func (f Filter) Match(e Entry) bool {
+	if f.Supervisor != "" && e.Supervisor != f.Supervisor {
+		return false
+	}
+	if f.Quality != "" && !strings.EqualFold(e.Quality, f.Quality) {
+		return false
+	}
+	if f.Name != "" && !containsFold(e.Name, f.Name) {
+		return false
+	}
+	if f.RuleFile != "" && !containsFold(e.RuleFile, f.RuleFile) {
+		return false
+	}
+	if !f.From.IsZero() && e.Time.Before(f.From) {
+		return false
+	}
+	if !f.To.IsZero() && e.Time.After(f.To) {
+		return false
+	}
+
+	return true
+}
+
+func containsFold(s, substr string) bool {
+	return strings.Contains(strings.ToLower(s), strings.ToLower(substr))
+}
+
+// Ledger is an event handler appending an Entry per stashed item to {dir}/drops.jsonl. The game is not part of the
+// ItemStashed event, it's tracked per supervisor from the previous GameCreated event, same for the run when the event
+// doesn't know where the item was picked up
+type Ledger struct {
+	path string
+
+	mu      sync.Mutex
+	file    *os.File
+	entries []Entry
+	games   map[string]string
+	runs    map[string]string
+}
+
+// NewInMemory returns a ledger that is not written to disk, used when stats are not persisted
+func NewInMemory() *Ledger {
+	return &Ledger{
+		games: make(map[string]string),
+		runs:  make(map[string]string),
+	}
+}
+
+func New(dir string) (*Ledger, error) {
+	if dir == "" {
+		dir = "stats"
+	}
+
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		return nil, fmt.Errorf("error creating ledger directory: %w", err)
+	}
+
+	l := &Ledger{
+		path:  filepath.Join(dir, fileName),
+		games: make(map[string]string),
+		runs:  make(map[string]string),
+	}
+	if err := l.load(); err != nil {
+		return nil, err
+	}
+
+	f, err := jsonl.OpenAppend(l.path)
+	if err != nil {
+		return nil, fmt.Errorf("error opening ledger file: %w", err)
+	}
+	l.file = f
+
+	return l, nil
+}
+
+func (l *Ledger) Handle(_ context.Context, e event.Event) error {
+	sup := e.Supervisor()
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	switch evt := e.(type) {
+	case event.GameCreatedEvent:
+		l.games[sup] = evt.Name
+		delete(l.runs, sup)
+	case event.RunStartedEvent:
+		l.runs[sup] = evt.RunName
+	case event.RunFinishedEvent:
+		delete(l.runs, sup)
+	case event.ItemStashedEvent:
+		it := evt.Item.Item
+		run := evt.RunName
+		if run == "" {
+			run = l.runs[sup]
+		}
+		entry := Entry{
+			Time:       evt.OccurredAt(),
+			Supervisor: sup,
+			Game:       l.games[sup],
+			Run:        run,
+			Area:       evt.Item.DropLocation,
+			Name:       string(it.Name),
+			Quality:    it.Quality.ToString(),
+			Ethereal:   it.Ethereal,
+			Identified: it.Identified,
+			Rule:       evt.Item.Rule,
+			RuleFile:   evt.Item.RuleFile,
+			Stats:      make([]Stat, 0, len(it.Stats)),
+		}
+		for _, st := range it.Stats {
+			entry.Stats = append(entry.Stats, Stat{Name: stat.StringStats[st.ID], Value: st.Value})
+		}
+
+		return l.append(entry)
+	}
+
+	return nil
+}
+
+// append adds the entry, writing it to disk if the ledger is persisted, mutex must be held
+func (l *Ledger) append(entry Entry) error {
+	entry.ID = len(l.entries) + 1
+	if len(l.entries) > 0 {
+		entry.ID = l.entries[len(l.entries)-1].ID + 1
+	}
+
+	if l.file != nil {
+		if err := jsonl.Append(l.file, entry); err != nil {
+			return fmt.Errorf("error writing ledger entry: %w", err)
+		}
+	}
+
+	l.entries = append(l.entries, entry)
+
+	return nil
+}
+
+// Query returns the entries matching the filter, newest first
+func (l *Ledger) Query(f Filter) []Entry {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	result := make([]Entry, 0)
+	for _, e := range slices.Backward(l.entries) {
+		if f.Match(e) {
+			result = append(result, e)
+		}
+	}
+
+	return result
+}
+
+func (l *Ledger) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.file == nil {
+		return nil
+	}
+
+	return l.file.Close()
+}
+
+func (l *Ledger) load() error {
+	if err := jsonl.Read(l.path, func(e Entry) { l.entries = append(l.entries, e) }); err != nil {
+		return fmt.Errorf("error reading ledger file: %w", err)
+	}
+
+	return nil
+}
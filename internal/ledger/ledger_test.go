@@ -0,0 +1,144 @@
+package ledger
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"testing"
+	"time"
+
+	"github.com/hectorgimenez/d2go/pkg/data"
+	"github.com/hectorgimenez/d2go/pkg/data/item"
+	"github.com/hectorgimenez/d2go/pkg/data/stat"
+	"github.com/hectorgimenez/koolo/internal/event"
+)
+
+func send(t *testing.T, l *Ledger, events ...event.Event) {
+	t.Helper()
+
+	for _, e := range events {
+		if err := l.Handle(context.Background(), e); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func stashed(sup string, at time.Time, name item.Name, quality item.Quality, ruleFile string) event.Event {
+	return event.ItemStashed(event.TextAt(sup, "", at), data.Drop{
+		Item: data.Item{
+			Name:    name,
+			Quality: quality,
+			Stats:   []stat.Data{{ID: stat.MagicFind, Value: 50}},
+		},
+		Rule:         "[name] == " + string(name),
+		RuleFile:     ruleFile,
+		DropLocation: "Durance of Hate Level 3",
+	}, "", time.Time{})
+}
+
+func TestLedgerContextAndFilters(t *testing.T) {
+	dir := t.TempDir()
+	l, err := New(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	day := time.Date(2024, 12, 1, 10, 0, 0, 0, time.UTC)
+	send(t, l,
+		event.GameCreated(event.TextAt("sorc", "", day), "mf-1", ""),
+		event.RunStarted(event.TextAt("sorc", "", day), "mephisto"),
+		stashed("sorc", day.Add(time.Minute), "Shako", item.QualityUnique, "unique.nip"),
+		event.RunFinished(event.TextAt("sorc", "", day), "mephisto", event.FinishedOK),
+		stashed("pala", day.Add(24*time.Hour), "Ring", item.QualityRare, "rares.nip"),
+	)
+
+	all := l.Query(Filter{})
+	if len(all) != 2 || all[0].Name != "Ring" {
+		t.Fatalf("expected 2 entries newest first, got %+v", all)
+	}
+
+	shako := all[1]
+	if shako.Supervisor != "sorc" || shako.Game != "mf-1" || shako.Run != "mephisto" || shako.Area != "Durance of Hate Level 3" {
+		t.Errorf("missing context %+v", shako)
+	}
+	if shako.RuleFile != "unique.nip" || len(shako.Stats) != 1 || shako.Stats[0].Value != 50 {
+		t.Errorf("missing rule or stats %+v", shako)
+	}
+	if all[0].Run != "" || all[0].Game != "" {
+		t.Errorf("pala entry should have no run or game %+v", all[0])
+	}
+
+	filters := map[string]Filter{
+		"quality":    {Quality: "unique"},
+		"name":       {Name: "sha"},
+		"ruleFile":   {RuleFile: "UNIQUE"},
+		"supervisor": {Supervisor: "sorc"},
+		"dateRange":  {From: day, To: day.Add(time.Hour)},
+	}
+	for name, f := range filters {
+		if res := l.Query(f); len(res) != 1 || res[0].Name != "Shako" {
+			t.Errorf("%s filter: expected only Shako, got %+v", name, res)
+		}
+	}
+
+	// Entries must survive a restart and keep increasing IDs
+	if err = l.Close(); err != nil {
+		t.Fatal(err)
+	}
+	l, err = New(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	send(t, l, stashed("sorc", day.Add(48*time.Hour), "Gloves", item.QualityMagic, "magic.nip"))
+	all = l.Query(Filter{})
+	if len(all) != 3 || all[0].ID != 3 || all[2].ID != 1 {
+		t.Errorf("unexpected entries after reload %+v", all)
+	}
+}
+
+func TestInMemoryLedger(t *testing.T) {
+	l := NewInMemory()
+	day := time.Date(2024, 12, 1, 10, 0, 0, 0, time.UTC)
+	send(t, l,
+		event.GameCreated(event.TextAt("sorc", "", day), "mf-1", ""),
+		stashed("sorc", day, "Shako", item.QualityUnique, "unique.nip"),
+		stashed("sorc", day, "Ring", item.QualityRare, "rares.nip"),
+	)
+
+	if all := l.Query(Filter{}); len(all) != 2 || all[0].ID != 2 || all[1].Game != "mf-1" {
+		t.Errorf("unexpected entries %+v", all)
+	}
+	if err := l.Close(); err != nil {
+		t.Errorf("closing an in memory ledger should not fail: %v", err)
+	}
+}
+
+func TestWriteCSV(t *testing.T) {
+	entries := []Entry{{
+		ID:         1,
+		Time:       time.Date(2024, 12, 1, 10, 0, 0, 0, time.UTC),
+		Supervisor: "sorc",
+		Name:       "Shako",
+		Quality:    "Unique",
+		Rule:       `[name] == shako # [itemchargedskill] >= 1, "quoted"`,
+		Stats:      []Stat{{Name: "magicfind", Value: 50}, {Name: "strength", Value: 2}},
+	}}
+
+	buf := new(bytes.Buffer)
+	if err := WriteCSV(buf, entries); err != nil {
+		t.Fatal(err)
+	}
+
+	records, err := csv.NewReader(buf).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 2 || len(records[1]) != len(csvHeader) {
+		t.Fatalf("unexpected csv %v", records)
+	}
+	if records[1][10] != entries[0].Rule || records[1][12] != "magicfind=50; strength=2" || records[1][1] != "2024-12-01T10:00:00Z" {
+		t.Errorf("unexpected csv row %v", records[1])
+	}
+}
@@ -0,0 +1,194 @@
+package mapdata
+
+import (
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Bump it when the stored format changes, old entries will be ignored and evicted eventually
+const cacheVersion = "1"
+
+const cacheExtension = ".json.gz"
+
+// Cache stores parsed map data on disk, compressed. Entries are named after the hash of seed and difficulty, so the
+// same game (companions joining it, replaying a seed) doesn't need koolo-map again. When the cache is bigger than
+// maxBytes, the least recently used entries are removed
+type Cache struct {
+	dir      string
+	maxBytes int64
+
+	mu       sync.Mutex
+	inflight map[string]*cacheCall
+}
+
+type cacheCall struct {
+	done chan struct{}
+	data MapData
+	err  error
+}
+
+func NewCache(dir string, maxBytes int64) (*Cache, error) {
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		return nil, fmt.Errorf("error creating map cache directory: %w", err)
+	}
+
+	return &Cache{dir: dir, maxBytes: maxBytes, inflight: make(map[string]*cacheCall)}, nil
+}
+
+func cacheKey(seed, difficulty string) string {
+	sum := sha256.Sum256([]byte(cacheVersion + "|" + seed + "|" + difficulty))
+
+	return hex.EncodeToString(sum[:])
+}
+
+func (c *Cache) path(key string) string {
+	return filepath.Join(c.dir, key+cacheExtension)
+}
+
+// Get returns the cached map data, broken entries are removed and reported as not found
+func (c *Cache) Get(seed, difficulty string) (MapData, bool) {
+	path := c.path(cacheKey(seed, difficulty))
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, false
+	}
+	defer f.Close()
+
+	zr, err := gzip.NewReader(f)
+	if err != nil {
+		os.Remove(path)
+		return nil, false
+	}
+	defer zr.Close()
+
+	var data MapData
+	if err = json.NewDecoder(zr).Decode(&data); err != nil {
+		os.Remove(path)
+		return nil, false
+	}
+
+	// Modification time is used as last access time for eviction
+	now := time.Now()
+	os.Chtimes(path, now, now)
+
+	return data, true
+}
+
+// Put stores the map data and evicts old entries if the cache is too big
+func (c *Cache) Put(seed, difficulty string, data MapData) error {
+	path := c.path(cacheKey(seed, difficulty))
+
+	// Written to a temporary file first, a half written entry would be read as a broken one
+	tmp, err := os.CreateTemp(c.dir, "tmp-*")
+	if err != nil {
+		return fmt.Errorf("error creating map cache entry: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	zw := gzip.NewWriter(tmp)
+	if err = json.NewEncoder(zw).Encode(data); err == nil {
+		err = zw.Close()
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("error writing map cache entry: %w", err)
+	}
+
+	if err = os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("error writing map cache entry: %w", err)
+	}
+
+	return c.evict()
+}
+
+// Fetch returns the cached map data, or calls fetch and stores the result. Concurrent calls for the same game wait for
+// a single fetch. The returned bool is true if fetch was not called by this call
+func (c *Cache) Fetch(seed, difficulty string, fetch func() (MapData, error)) (MapData, bool, error) {
+	key := cacheKey(seed, difficulty)
+
+	c.mu.Lock()
+	if call, found := c.inflight[key]; found {
+		c.mu.Unlock()
+		<-call.done
+		return call.data, call.err == nil, call.err
+	}
+	call := &cacheCall{done: make(chan struct{})}
+	c.inflight[key] = call
+	c.mu.Unlock()
+
+	defer func() {
+		c.mu.Lock()
+		delete(c.inflight, key)
+		c.mu.Unlock()
+		close(call.done)
+	}()
+
+	if data, found := c.Get(seed, difficulty); found {
+		call.data = data
+		return data, true, nil
+	}
+
+	call.data, call.err = fetch()
+	if call.err != nil {
+		return nil, false, call.err
+	}
+
+	// The data is valid even if we can't store it
+	if err := c.Put(seed, difficulty, call.data); err != nil {
+		return call.data, false, err
+	}
+
+	return call.data, false, nil
+}
+
+// evict removes the least recently used entries until the cache fits in maxBytes, 0 means no limit
+func (c *Cache) evict() error {
+	if c.maxBytes <= 0 {
+		return nil
+	}
+
+	entries := make([]fs.FileInfo, 0)
+	size := int64(0)
+	dirEntries, err := os.ReadDir(c.dir)
+	if err != nil {
+		return fmt.Errorf("error reading map cache directory: %w", err)
+	}
+	for _, e := range dirEntries {
+		if e.IsDir() || !strings.HasSuffix(e.Name(), cacheExtension) {
+			continue
+		}
+		info, err := e.Info()
+		if err != nil {
+			continue
+		}
+		entries = append(entries, info)
+		size += info.Size()
+	}
+
+	slices.SortFunc(entries, func(a, b fs.FileInfo) int {
+		return a.ModTime().Compare(b.ModTime())
+	})
+
+	for _, e := range entries {
+		if size <= c.maxBytes {
+			break
+		}
+		if err = os.Remove(filepath.Join(c.dir, e.Name())); err == nil {
+			size -= e.Size()
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,138 @@
+package mapdata
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func testMapData(id int) MapData {
+	lvl := Level{Type: "map", ID: id, Name: "Test", Map: [][]int{{1, 2}, {0, 3}}}
+	lvl.Size.Width = 3
+	lvl.Size.Height = 2
+
+	return MapData{lvl}
+}
+
+func TestCacheRoundTrip(t *testing.T) {
+	c, err := NewCache(t.TempDir(), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, found := c.Get("1234", "normal"); found {
+		t.Fatal("empty cache returned data")
+	}
+	if err = c.Put("1234", "normal", testMapData(1)); err != nil {
+		t.Fatal(err)
+	}
+
+	data, found := c.Get("1234", "normal")
+	if !found || len(data) != 1 || data[0].ID != 1 || data[0].Map[1][1] != 3 {
+		t.Fatalf("unexpected cached data: %+v", data)
+	}
+	if _, found = c.Get("1234", "hell"); found {
+		t.Error("difficulty is not part of the key")
+	}
+}
+
+func TestCacheBrokenEntry(t *testing.T) {
+	dir := t.TempDir()
+	c, err := NewCache(dir, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(dir, cacheKey("1234", "normal")+cacheExtension)
+	if err = os.WriteFile(path, []byte("not gzip"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, found := c.Get("1234", "normal"); found {
+		t.Fatal("broken entry returned data")
+	}
+	if _, err = os.Stat(path); !os.IsNotExist(err) {
+		t.Error("broken entry was not removed")
+	}
+}
+
+func TestCacheEviction(t *testing.T) {
+	dir := t.TempDir()
+	c, err := NewCache(dir, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Entries older first, seed "1" is the least recently used
+	seeds := []string{"1", "2", "3"}
+	for i, seed := range seeds {
+		if err = c.Put(seed, "normal", testMapData(i)); err != nil {
+			t.Fatal(err)
+		}
+		modTime := time.Now().Add(time.Duration(i-len(seeds)) * time.Hour)
+		os.Chtimes(filepath.Join(dir, cacheKey(seed, "normal")+cacheExtension), modTime, modTime)
+	}
+	info, err := os.Stat(filepath.Join(dir, cacheKey("1", "normal")+cacheExtension))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Reading seed "1" makes seed "2" the least recently used one
+	if _, found := c.Get("1", "normal"); !found {
+		t.Fatal("entry not found")
+	}
+
+	// Room for 2 entries, adding a new one removes the oldest
+	c.maxBytes = info.Size()*2 + info.Size()/2
+	if err = c.Put("4", "normal", testMapData(4)); err != nil {
+		t.Fatal(err)
+	}
+
+	for seed, expected := range map[string]bool{"1": true, "2": false, "3": false, "4": true} {
+		if _, found := c.Get(seed, "normal"); found != expected {
+			t.Errorf("seed %s: expected found %v, got %v", seed, expected, found)
+		}
+	}
+}
+
+func TestCacheFetch(t *testing.T) {
+	c, err := NewCache(t.TempDir(), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	calls := atomic.Int32{}
+	release := make(chan struct{})
+	fetch := func() (MapData, error) {
+		calls.Add(1)
+		<-release
+		return testMapData(7), nil
+	}
+
+	wg := sync.WaitGroup{}
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			data, _, err := c.Fetch("1234", "normal", fetch)
+			if err != nil || len(data) != 1 || data[0].ID != 7 {
+				t.Errorf("unexpected fetch result: %+v, %v", data, err)
+			}
+		}()
+	}
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	if calls.Load() != 1 {
+		t.Errorf("expected a single fetch, got %d", calls.Load())
+	}
+
+	_, cached, err := c.Fetch("1234", "normal", fetch)
+	if err != nil || !cached || calls.Load() != 1 {
+		t.Errorf("expected cached data, got cached %v, err %v, calls %d", cached, err, calls.Load())
+	}
+}
@@ -0,0 +1,160 @@
+package mapdata
+
+import (
+	"github.com/hectorgimenez/d2go/pkg/data"
+	"github.com/hectorgimenez/d2go/pkg/data/area"
+	"github.com/hectorgimenez/d2go/pkg/data/npc"
+	"github.com/hectorgimenez/d2go/pkg/data/object"
+)
+
+// Level is a single area of the map data, positions of objects and rooms are relative to Offset
+type Level struct {
+	Type   string   `json:"type"`
+	ID     int      `json:"id"`
+	Name   string   `json:"name"`
+	Offset Position `json:"offset"`
+	Size   struct {
+		Width  int `json:"width"`
+		Height int `json:"height"`
+	} `json:"size"`
+	Objects []Object `json:"objects"`
+	Rooms   []Room   `json:"rooms"`
+	// Walkable and non-walkable runs of every row, starting with a non-walkable one
+	Map [][]int `json:"map"`
+}
+
+type Position struct {
+	X int `json:"x"`
+	Y int `json:"y"`
+}
+
+type Object struct {
+	ID   int    `json:"id"`
+	Type string `json:"type"`
+	Name string `json:"name"`
+	Position
+}
+
+type Room struct {
+	Position
+	Width  int `json:"width"`
+	Height int `json:"height"`
+}
+
+// MapData contains every level of a game, as generated by koolo-map
+type MapData []Level
+
+func (lvl Level) CollisionGrid() [][]bool {
+	var cg [][]bool
+
+	for y := 0; y < lvl.Size.Height; y++ {
+		var row []bool
+		for x := 0; x < lvl.Size.Width; x++ {
+			row = append(row, false)
+		}
+
+		// Documentation about how this works: https://github.com/blacha/diablo2/tree/master/packages/map
+		if len(lvl.Map) > y {
+			mapRow := lvl.Map[y]
+			isWalkable := false
+			xPos := 0
+			for k, xs := range mapRow {
+				if k != 0 {
+					for xOffset := 0; xOffset < xs; xOffset++ {
+						row[xPos+xOffset] = isWalkable
+					}
+				}
+				isWalkable = !isWalkable
+				xPos += xs
+			}
+			for xPos < len(row) {
+				row[xPos] = isWalkable
+				xPos++
+			}
+		}
+
+		cg = append(cg, row)
+	}
+
+	return cg
+}
+
+func (lvl Level) NPCsExitsAndObjects() (data.NPCs, []data.Level, []data.Object, []data.Room) {
+	var npcs []data.NPC
+	var exits []data.Level
+	var objects []data.Object
+	var rooms []data.Room
+
+	for _, r := range lvl.Rooms {
+		rooms = append(rooms, data.Room{
+			Position: data.Position{X: r.X,
+				Y: r.Y,
+			},
+			Width:  r.Width,
+			Height: r.Height,
+		})
+	}
+
+	for _, obj := range lvl.Objects {
+		switch obj.Type {
+		case "npc":
+			n := data.NPC{
+				ID:   npc.ID(obj.ID),
+				Name: obj.Name,
+				Positions: []data.Position{{
+					X: obj.X + lvl.Offset.X,
+					Y: obj.Y + lvl.Offset.Y,
+				}},
+			}
+			npcs = append(npcs, n)
+		case "exit":
+			exit := data.Level{
+				Area: area.ID(obj.ID),
+				Position: data.Position{
+					X: obj.X + lvl.Offset.X,
+					Y: obj.Y + lvl.Offset.Y,
+				},
+				IsEntrance: true,
+			}
+			exits = append(exits, exit)
+		case "object":
+			o := data.Object{
+				Name: object.Name(obj.ID),
+				Position: data.Position{
+					X: obj.X + lvl.Offset.X,
+					Y: obj.Y + lvl.Offset.Y,
+				},
+			}
+			objects = append(objects, o)
+		}
+	}
+
+	for _, obj := range lvl.Objects {
+		switch obj.Type {
+		case "exit_area":
+			found := false
+			for _, exit := range exits {
+				if exit.Area == area.ID(obj.ID) {
+					exit.IsEntrance = false
+					found = true
+					break
+				}
+			}
+
+			if !found {
+				lvl := data.Level{
+					Area: area.ID(obj.ID),
+					Position: data.Position{
+						X: obj.X + lvl.Offset.X,
+						Y: obj.Y + lvl.Offset.Y,
+					},
+					IsEntrance: false,
+				}
+				exits = append(exits, lvl)
+			}
+		}
+
+	}
+
+	return npcs, exits, objects, rooms
+}
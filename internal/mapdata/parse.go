@@ -0,0 +1,46 @@
+package mapdata
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// Parse reads the koolo-map output, one JSON document per line. Lines without level information (logs, seed info,
+// empty lines) are discarded
+func Parse(r io.Reader) (MapData, error) {
+	// Levels are stored in a single line and some of them are really long, bufio.Scanner would need a huge buffer
+	br := bufio.NewReader(r)
+
+	lvls := make(MapData, 0)
+	for {
+		line, err := br.ReadBytes('\n')
+		if err != nil && !errors.Is(err, io.EOF) {
+			return nil, fmt.Errorf("error reading map data: %w", err)
+		}
+
+		if lvl, ok := parseLevel(bytes.TrimSpace(line)); ok {
+			lvls = append(lvls, lvl)
+		}
+
+		if errors.Is(err, io.EOF) {
+			return lvls, nil
+		}
+	}
+}
+
+func parseLevel(line []byte) (Level, bool) {
+	if len(line) == 0 || line[0] != '{' {
+		return Level{}, false
+	}
+
+	var lvl Level
+	if err := json.Unmarshal(line, &lvl); err != nil || lvl.Type == "" || len(lvl.Map) == 0 {
+		return Level{}, false
+	}
+
+	return lvl, true
+}
@@ -0,0 +1,85 @@
+package mapdata
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/hectorgimenez/d2go/pkg/data/area"
+)
+
+const testOutput = "seed: 1234\r\n" +
+	`{"type":"map","id":1,"name":"Rogue Encampment","offset":{"x":100,"y":200},"size":{"width":4,"height":2},` +
+	`"objects":[{"id":148,"type":"npc","name":"Akara","x":1,"y":1},{"id":2,"type":"exit","x":3,"y":0},{"id":2,"type":"exit_area","x":3,"y":1},{"id":3,"type":"exit_area","x":0,"y":1}],` +
+	`"rooms":[{"x":100,"y":200,"width":4,"height":2}],"map":[[1,2],[0,1,1]]}` + "\r\n" +
+	`{"type":"map","id":2,"name":"Blood Moor","size":{"width":1,"height":1},"map":[]}` + "\r\n" +
+	`{"broken json` + "\r\n" +
+	"\r\n" +
+	`{"type":"map","id":3,"name":"Cold Plains","size":{"width":2,"height":1},"map":[[0]]}`
+
+func TestParse(t *testing.T) {
+	lvls, err := Parse(strings.NewReader(testOutput))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Blood Moor has no map and the broken line is discarded
+	if len(lvls) != 2 || lvls[0].ID != 1 || lvls[1].ID != 3 {
+		t.Fatalf("unexpected levels: %+v", lvls)
+	}
+
+	lvl := lvls[0]
+	if lvl.Offset.X != 100 || lvl.Offset.Y != 200 || lvl.Size.Width != 4 || lvl.Size.Height != 2 {
+		t.Errorf("unexpected level header: %+v", lvl)
+	}
+}
+
+func TestCollisionGrid(t *testing.T) {
+	lvl := Level{Map: [][]int{{1, 2}, {0, 1, 1}}}
+	lvl.Size.Width = 4
+	lvl.Size.Height = 3
+
+	// Runs alternate starting with a non-walkable one, the rest of the row takes the next state. Missing rows are not
+	// walkable
+	expected := [][]bool{
+		{false, true, true, false},
+		{true, false, true, true},
+		{false, false, false, false},
+	}
+	grid := lvl.CollisionGrid()
+	for y, row := range expected {
+		for x, walkable := range row {
+			if grid[y][x] != walkable {
+				t.Errorf("tile %d,%d: expected %v, got %v", x, y, walkable, grid[y][x])
+			}
+		}
+	}
+}
+
+func TestNPCsExitsAndObjects(t *testing.T) {
+	lvls, err := Parse(strings.NewReader(testOutput))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	npcs, exits, objects, rooms := lvls[0].NPCsExitsAndObjects()
+	if len(npcs) != 1 || npcs[0].Name != "Akara" || npcs[0].Positions[0].X != 101 || npcs[0].Positions[0].Y != 201 {
+		t.Errorf("unexpected npcs: %+v", npcs)
+	}
+	if len(objects) != 0 {
+		t.Errorf("unexpected objects: %+v", objects)
+	}
+	if len(rooms) != 1 || rooms[0].Width != 4 {
+		t.Errorf("unexpected rooms: %+v", rooms)
+	}
+
+	// The exit_area of an existing exit is not added again
+	if len(exits) != 2 {
+		t.Fatalf("unexpected exits: %+v", exits)
+	}
+	if exits[0].Area != area.ID(2) || !exits[0].IsEntrance || exits[0].Position.X != 103 {
+		t.Errorf("unexpected exit: %+v", exits[0])
+	}
+	if exits[1].Area != area.ID(3) || exits[1].IsEntrance || exits[1].Position.Y != 201 {
+		t.Errorf("unexpected exit area: %+v", exits[1])
+	}
+}
@@ -0,0 +1,191 @@
+package maprender
+
+import (
+	"bufio"
+	"fmt"
+	"html"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"strings"
+
+	"github.com/hectorgimenez/d2go/pkg/data"
+	"github.com/hectorgimenez/koolo/internal/game"
+)
+
+const (
+	FormatPNG = "png"
+	FormatSVG = "svg"
+
+	// Pixels per tile, big areas are over 1000 tiles wide so the image size grows fast
+	MinScale = 1
+	MaxScale = 8
+)
+
+// Same colors used since the first version of the debug map
+var (
+	colorPath        = color.RGBA{R: 36, G: 255, A: 255}
+	colorLowPriority = color.RGBA{R: 200, G: 200, B: 200, A: 255}
+	colorMonster     = color.RGBA{R: 255, A: 255}
+	colorObject      = color.RGBA{R: 160, G: 32, B: 240, A: 255}
+	colorDanger      = color.RGBA{R: 255, G: 165, A: 255}
+	colorRoom        = color.RGBA{R: 204, G: 204, A: 255}
+	colorPlayer      = color.RGBA{R: 158, A: 255}
+	colorDestination = color.RGBA{B: 255, A: 255}
+)
+
+func tileColor(t game.CollisionType) color.RGBA {
+	switch t {
+	case game.CollisionTypeWalkable:
+		return color.RGBA{R: 255, G: 255, B: 255, A: 255}
+	case game.CollisionTypeLowPriority:
+		return colorLowPriority
+	case game.CollisionTypeMonster:
+		return colorMonster
+	case game.CollisionTypeObject:
+		return colorObject
+	case game.CollisionTypeDanger:
+		return colorDanger
+	}
+
+	return color.RGBA{A: 255}
+}
+
+// Render writes the scene in the given format, every tile is drawn as a square of scale pixels, scale is clamped
+// between MinScale and MaxScale
+func Render(w io.Writer, s Scene, format string, scale int) error {
+	if err := s.validate(); err != nil {
+		return err
+	}
+	scale = min(max(scale, MinScale), MaxScale)
+
+	switch strings.ToLower(format) {
+	case FormatPNG:
+		return png.Encode(w, Image(s, scale))
+	case FormatSVG:
+		return writeSVG(w, s, scale)
+	}
+
+	return fmt.Errorf("unknown map format %q, expected %s or %s", format, FormatPNG, FormatSVG)
+}
+
+// Image draws the scene, the scene is expected to be valid
+func Image(s Scene, scale int) *image.RGBA {
+	g := s.Grid
+	img := image.NewRGBA(image.Rect(0, 0, g.Width*scale, g.Height*scale))
+	for y := 0; y < g.Height; y++ {
+		for x := 0; x < g.Width; x++ {
+			fillTile(img, x, y, 0, scale, tileColor(g.CollisionGrid[y][x]))
+		}
+	}
+
+	for _, p := range s.Path {
+		p = g.RelativePosition(p)
+		fillTile(img, p.X, p.Y, 0, scale, colorPath)
+	}
+
+	// Markers are a bit bigger than a tile, a single pixel is not visible on big areas
+	for _, r := range s.Rooms {
+		p := g.RelativePosition(r.GetCenter())
+		fillTile(img, p.X, p.Y, 1, scale, colorRoom)
+	}
+	for _, o := range s.Objects {
+		p := g.RelativePosition(o.Position)
+		fillTile(img, p.X, p.Y, 1, scale, colorObject)
+	}
+	for _, m := range s.Monsters {
+		p := g.RelativePosition(m.Position)
+		fillTile(img, p.X, p.Y, 1, scale, colorMonster)
+	}
+
+	player := g.RelativePosition(s.Player)
+	fillTile(img, player.X, player.Y, 1, scale, colorPlayer)
+	destination := g.RelativePosition(s.Destination)
+	fillTile(img, destination.X, destination.Y, 1, scale, colorDestination)
+
+	return img
+}
+
+// fillTile paints the tile at x,y and the ones around it up to radius tiles, out of bounds pixels are ignored
+func fillTile(img *image.RGBA, x, y, radius, scale int, c color.RGBA) {
+	for py := (y - radius) * scale; py < (y+radius+1)*scale; py++ {
+		for px := (x - radius) * scale; px < (x+radius+1)*scale; px++ {
+			img.SetRGBA(px, py, c)
+		}
+	}
+}
+
+func writeSVG(w io.Writer, s Scene, scale int) error {
+	g := s.Grid
+	bw := bufio.NewWriter(w)
+
+	fmt.Fprintf(bw, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d" shape-rendering="crispEdges">`+"\n", g.Width*scale, g.Height*scale, g.Width, g.Height)
+	if s.Area != "" {
+		fmt.Fprintf(bw, "<title>%s</title>\n", html.EscapeString(s.Area))
+	}
+	fmt.Fprintf(bw, `<rect width="%d" height="%d" fill="%s"/>`+"\n", g.Width, g.Height, hexColor(tileColor(game.CollisionTypeNonWalkable)))
+
+	// One rect per run of equal tiles, a rect per tile would be huge on big areas
+	for y, row := range g.CollisionGrid {
+		for x := 0; x < len(row); {
+			end := x + 1
+			for end < len(row) && row[end] == row[x] {
+				end++
+			}
+			if row[x] != game.CollisionTypeNonWalkable {
+				fmt.Fprintf(bw, `<rect x="%d" y="%d" width="%d" height="1" fill="%s"/>`+"\n", x, y, end-x, hexColor(tileColor(row[x])))
+			}
+			x = end
+		}
+	}
+
+	if len(s.Path) > 0 {
+		points := make([]string, 0, len(s.Path))
+		for _, p := range s.Path {
+			p = g.RelativePosition(p)
+			points = append(points, fmt.Sprintf("%.1f,%.1f", float64(p.X)+0.5, float64(p.Y)+0.5))
+		}
+		fmt.Fprintf(bw, `<polyline points="%s" fill="none" stroke="%s" stroke-width="1"/>`+"\n", strings.Join(points, " "), hexColor(colorPath))
+	}
+
+	for _, r := range s.Rooms {
+		p := g.RelativePosition(r.Position)
+		fmt.Fprintf(bw, `<rect x="%d" y="%d" width="%d" height="%d" fill="none" stroke="%s" stroke-width="0.5"/>`+"\n", p.X, p.Y, r.Width, r.Height, hexColor(colorRoom))
+	}
+	for _, o := range s.Objects {
+		writeSVGMarker(bw, g, o, colorObject)
+	}
+	for _, m := range s.Monsters {
+		writeSVGMarker(bw, g, m, colorMonster)
+	}
+	writeSVGMarker(bw, g, Marker{Label: "Player", Position: s.Player}, colorPlayer)
+	writeSVGMarker(bw, g, Marker{Label: "Destination", Position: s.Destination}, colorDestination)
+
+	fmt.Fprintln(bw, "</svg>")
+
+	return bw.Flush()
+}
+
+func writeSVGMarker(w io.Writer, g *game.Grid, m Marker, c color.RGBA) {
+	p := g.RelativePosition(m.Position)
+	cx, cy := float64(p.X)+0.5, float64(p.Y)+0.5
+	if m.Radius > 0 {
+		fmt.Fprintf(w, `<circle cx="%.1f" cy="%.1f" r="%d" fill="none" stroke="%s" stroke-width="0.5"/>`+"\n", cx, cy, m.Radius, hexColor(colorDanger))
+	}
+	fmt.Fprintf(w, `<circle cx="%.1f" cy="%.1f" r="1.5" fill="%s"><title>%s</title></circle>`+"\n", cx, cy, hexColor(c), html.EscapeString(fmt.Sprintf("%s (%d, %d)", m.Label, m.Position.X, m.Position.Y)))
+}
+
+func hexColor(c color.RGBA) string {
+	return fmt.Sprintf("#%02x%02x%02x", c.R, c.G, c.B)
+}
+
+// PositionsFrom converts a path relative to the grid, as returned by the path finder, to absolute positions
+func PositionsFrom(g *game.Grid, path []data.Position) []data.Position {
+	positions := make([]data.Position, 0, len(path))
+	for _, p := range path {
+		positions = append(positions, data.Position{X: p.X + g.OffsetX, Y: p.Y + g.OffsetY})
+	}
+
+	return positions
+}
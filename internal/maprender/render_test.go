@@ -0,0 +1,131 @@
+package maprender
+
+import (
+	"bytes"
+	"image/color"
+	"image/png"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/hectorgimenez/d2go/pkg/data"
+	"github.com/hectorgimenez/koolo/internal/game"
+)
+
+func testScene() Scene {
+	cg := make([][]game.CollisionType, 10)
+	for y := range cg {
+		cg[y] = make([]game.CollisionType, 20)
+		for x := 1; x < 19; x++ {
+			cg[y][x] = game.CollisionTypeWalkable
+		}
+	}
+	cg[5][10] = game.CollisionTypeDanger
+
+	grid := &game.Grid{OffsetX: 1000, OffsetY: 2000, Width: 20, Height: 10, CollisionGrid: cg}
+
+	return Scene{
+		Area:        "Blood Moor",
+		Grid:        grid,
+		Path:        PositionsFrom(grid, []data.Position{{X: 2, Y: 2}, {X: 3, Y: 2}, {X: 4, Y: 3}}),
+		Player:      data.Position{X: 1002, Y: 2002},
+		Destination: data.Position{X: 1016, Y: 2007},
+		Monsters:    []Marker{{Label: "Monster <1>", Position: data.Position{X: 1010, Y: 2005}, Radius: 3}},
+	}
+}
+
+func TestRenderPNG(t *testing.T) {
+	s := testScene()
+	buf := bytes.Buffer{}
+	if err := Render(&buf, s, FormatPNG, 2); err != nil {
+		t.Fatal(err)
+	}
+
+	img, err := png.Decode(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b := img.Bounds(); b.Dx() != 40 || b.Dy() != 20 {
+		t.Fatalf("unexpected size %v", b)
+	}
+
+	// Scale 2, tile x,y starts on pixel 2x,2y
+	expected := map[[2]int]color.Color{
+		{0, 0}:   tileColor(game.CollisionTypeNonWalkable),
+		{6, 14}:  tileColor(game.CollisionTypeWalkable),
+		{8, 6}:   colorPath,
+		{4, 4}:   colorPlayer,
+		{20, 10}: colorMonster,
+		{33, 15}: colorDestination,
+	}
+	for p, c := range expected {
+		r, g, b, _ := img.At(p[0], p[1]).RGBA()
+		er, eg, eb, _ := c.RGBA()
+		if r != er || g != eg || b != eb {
+			t.Errorf("pixel %v: expected %v, got %v", p, c, img.At(p[0], p[1]))
+		}
+	}
+}
+
+func TestRenderSVG(t *testing.T) {
+	buf := bytes.Buffer{}
+	if err := Render(&buf, testScene(), "SVG", 3); err != nil {
+		t.Fatal(err)
+	}
+
+	svg := buf.String()
+	for _, expected := range []string{
+		`width="60" height="30" viewBox="0 0 20 10"`,
+		"<title>Blood Moor</title>",
+		// Walkable row 0 is a single run, the danger tile splits row 5
+		`<rect x="1" y="0" width="18" height="1" fill="#ffffff"/>`,
+		`<rect x="10" y="5" width="1" height="1" fill="#ffa500"/>`,
+		`<polyline points="2.5,2.5 3.5,2.5 4.5,3.5"`,
+		`r="3" fill="none"`,
+		"Monster &lt;1&gt; (1010, 2005)",
+	} {
+		if !strings.Contains(svg, expected) {
+			t.Errorf("svg doesn't contain %q", expected)
+		}
+	}
+}
+
+func TestRenderScaleIsClamped(t *testing.T) {
+	for scale, width := range map[int]string{-3: `width="20"`, 0: `width="20"`, 8: `width="160"`, 500: `width="160"`} {
+		buf := bytes.Buffer{}
+		if err := Render(&buf, testScene(), FormatSVG, scale); err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(buf.String(), width) {
+			t.Errorf("scale %d: expected %s", scale, width)
+		}
+	}
+}
+
+func TestRenderErrors(t *testing.T) {
+	if err := Render(&bytes.Buffer{}, testScene(), "gif", 1); err == nil {
+		t.Error("unknown format accepted")
+	}
+
+	s := testScene()
+	s.Grid.Height = 11
+	if err := Render(&bytes.Buffer{}, s, FormatPNG, 1); err == nil {
+		t.Error("grid with wrong size accepted")
+	}
+}
+
+func TestSceneSaveLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "scene.json")
+	s := testScene()
+	if err := s.Save(path); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, err := LoadScene(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if loaded.Grid.OffsetX != 1000 || loaded.Grid.CollisionGrid[5][10] != game.CollisionTypeDanger || len(loaded.Path) != 3 || loaded.Monsters[0].Radius != 3 {
+		t.Errorf("unexpected scene: %+v", loaded)
+	}
+}
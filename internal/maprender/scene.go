@@ -0,0 +1,79 @@
+package maprender
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/hectorgimenez/d2go/pkg/data"
+	"github.com/hectorgimenez/koolo/internal/game"
+)
+
+// Scene is everything the path finder saw for a single search. It can be saved to disk and rendered later without the
+// game running. All the positions are absolute game coordinates, the same ones used by the game data
+type Scene struct {
+	Area        string          `json:"area"`
+	Grid        *game.Grid      `json:"grid"`
+	Path        []data.Position `json:"path"`
+	Player      data.Position   `json:"player"`
+	Destination data.Position   `json:"destination"`
+	Rooms       []data.Room     `json:"rooms"`
+	Objects     []Marker        `json:"objects"`
+	Monsters    []Marker        `json:"monsters"`
+}
+
+// Marker is a single object or monster, Radius is the danger zone around it, 0 if there is none
+type Marker struct {
+	Label    string        `json:"label"`
+	Position data.Position `json:"position"`
+	Radius   int           `json:"radius,omitempty"`
+}
+
+func LoadScene(path string) (Scene, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return Scene{}, fmt.Errorf("error opening scene file: %w", err)
+	}
+	defer f.Close()
+
+	var s Scene
+	if err = json.NewDecoder(f).Decode(&s); err != nil {
+		return Scene{}, fmt.Errorf("error reading scene file %s: %w", path, err)
+	}
+	if err = s.validate(); err != nil {
+		return Scene{}, fmt.Errorf("error reading scene file %s: %w", path, err)
+	}
+
+	return s, nil
+}
+
+func (s Scene) Save(path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("error creating scene file: %w", err)
+	}
+	defer f.Close()
+
+	if err = json.NewEncoder(f).Encode(s); err != nil {
+		return fmt.Errorf("error writing scene file: %w", err)
+	}
+
+	return nil
+}
+
+// validate makes sure the grid size matches the collision data, renderers index it directly
+func (s Scene) validate() error {
+	if s.Grid == nil || s.Grid.Width <= 0 || s.Grid.Height <= 0 {
+		return fmt.Errorf("scene has no grid")
+	}
+	if len(s.Grid.CollisionGrid) != s.Grid.Height {
+		return fmt.Errorf("grid has %d rows, expected %d", len(s.Grid.CollisionGrid), s.Grid.Height)
+	}
+	for y, row := range s.Grid.CollisionGrid {
+		if len(row) != s.Grid.Width {
+			return fmt.Errorf("grid row %d has %d tiles, expected %d", y, len(row), s.Grid.Width)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,172 @@
+// Package metrics builds Prometheus metrics from the bot events, exposed by the web server in /metrics
+package metrics
+
+import (
+	"context"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/hectorgimenez/koolo/internal/event"
+)
+
+var runDurationBuckets = []float64{10, 30, 60, 90, 120, 180, 300, 600, 1200}
+
+// Collector is an event handler counting runs, games, potions and items per supervisor
+type Collector struct {
+	runs            *family
+	runDuration     *family
+	gamesCreated    *family
+	gamesFinished   *family
+	chickens        *family
+	deaths          *family
+	errors          *family
+	potions         *family
+	itemsStashed    *family
+	gamesPerHour    *family
+	status          *family
+	published       *family
+	queueDepth      *family
+	queueDropped    *family
+	handlerLatency  *family
+	handlerFailures *family
+
+	// Scrapes rebuild the gauges, avoid two of them doing it at the same time
+	writeMu sync.Mutex
+	mu      sync.Mutex
+	// Start time of the current run, keyed by supervisor
+	runStarts map[string]time.Time
+	// Games created during the last hour, keyed by supervisor
+	recentGames map[string][]time.Time
+}
+
+func NewCollector() *Collector {
+	return &Collector{
+		runs:            newFamily("koolo_runs_total", "Finished runs by run name and finish reason", typeCounter, "supervisor", "run", "reason"),
+		runDuration:     newHistogram("koolo_run_duration_seconds", "Run duration in seconds", runDurationBuckets, "supervisor", "run"),
+		gamesCreated:    newFamily("koolo_games_created_total", "Games created", typeCounter, "supervisor"),
+		gamesFinished:   newFamily("koolo_games_finished_total", "Games finished by reason", typeCounter, "supervisor", "reason"),
+		chickens:        newFamily("koolo_chickens_total", "Chickens, target is player or merc", typeCounter, "supervisor", "target"),
+		deaths:          newFamily("koolo_deaths_total", "Character deaths", typeCounter, "supervisor"),
+		errors:          newFamily("koolo_errors_total", "Games finished with an error", typeCounter, "supervisor"),
+		potions:         newFamily("koolo_potions_used_total", "Potions used by type, target is player or merc", typeCounter, "supervisor", "type", "target"),
+		itemsStashed:    newFamily("koolo_items_stashed_total", "Items stashed by quality", typeCounter, "supervisor", "quality"),
+		gamesPerHour:    newFamily("koolo_games_per_hour", "Games created during the last hour", typeGauge, "supervisor"),
+		status:          newFamily("koolo_supervisor_status", "Current supervisor status, 1 for the active one", typeGauge, "supervisor", "status"),
+		published:       newFamily("koolo_events_published_total", "Events sent to the event bus", typeCounter),
+		queueDepth:      newFamily("koolo_event_queue_depth", "Events waiting in the subscriber queue", typeGauge, "subscriber"),
+		queueDropped:    newFamily("koolo_event_dropped_total", "Events dropped because the subscriber queue was full", typeCounter, "subscriber"),
+		handlerLatency:  newFamily("koolo_event_handler_latency_seconds", "Average event handler latency", typeGauge, "subscriber"),
+		handlerFailures: newFamily("koolo_event_handler_failures_total", "Event handler errors", typeCounter, "subscriber"),
+		runStarts:       make(map[string]time.Time),
+		recentGames:     make(map[string][]time.Time),
+	}
+}
+
+func (c *Collector) Handle(_ context.Context, e event.Event) error {
+	sup := e.Supervisor()
+
+	switch evt := e.(type) {
+	case event.GameCreatedEvent:
+		c.gamesCreated.add(1, sup)
+		c.mu.Lock()
+		c.recentGames[sup] = append(c.recentGames[sup], evt.OccurredAt())
+		c.mu.Unlock()
+	case event.GameFinishedEvent:
+		c.gamesFinished.add(1, sup, string(evt.Reason))
+		switch evt.Reason {
+		case event.FinishedChicken:
+			c.chickens.add(1, sup, "player")
+		case event.FinishedMercChicken:
+			c.chickens.add(1, sup, "merc")
+		case event.FinishedDied:
+			c.deaths.add(1, sup)
+		case event.FinishedError:
+			c.errors.add(1, sup)
+		}
+	case event.RunStartedEvent:
+		c.mu.Lock()
+		c.runStarts[sup] = evt.OccurredAt()
+		c.mu.Unlock()
+	case event.RunFinishedEvent:
+		c.runs.add(1, sup, evt.RunName, string(evt.Reason))
+		c.mu.Lock()
+		startedAt, found := c.runStarts[sup]
+		delete(c.runStarts, sup)
+		c.mu.Unlock()
+		if found {
+			c.runDuration.observe(evt.OccurredAt().Sub(startedAt).Seconds(), sup, evt.RunName)
+		}
+	case event.UsedPotionEvent:
+		target := "player"
+		if evt.OnMerc {
+			target = "merc"
+		}
+		c.potions.add(1, sup, string(evt.PotionType), target)
+	case event.ItemStashedEvent:
+		c.itemsStashed.add(1, sup, evt.Item.Item.Quality.ToString())
+	}
+
+	return nil
+}
+
+// Write writes all the metrics in the Prometheus text format, status and bus metrics are taken at this point.
+// Statuses are keyed by supervisor, with the values of bot.SupervisorStatus
+func (c *Collector) Write(w io.Writer, statuses map[string]string, bus event.Metrics) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+
+	c.status.reset()
+	for sup, status := range statuses {
+		for _, s := range knownStatuses {
+			v := 0.0
+			if s == status || (s == statusNotStarted && status == "") {
+				v = 1
+			}
+			c.status.set(v, sup, s)
+		}
+	}
+
+	c.gamesPerHour.reset()
+	c.mu.Lock()
+	hourAgo := time.Now().Add(-time.Hour)
+	for sup, games := range c.recentGames {
+		recent := games[:0]
+		for _, t := range games {
+			if t.After(hourAgo) {
+				recent = append(recent, t)
+			}
+		}
+		c.recentGames[sup] = recent
+		c.gamesPerHour.set(float64(len(recent)), sup)
+	}
+	c.mu.Unlock()
+
+	c.published.set(float64(bus.Published))
+	c.queueDepth.reset()
+	c.queueDropped.reset()
+	c.handlerLatency.reset()
+	c.handlerFailures.reset()
+	for _, s := range bus.Subscribers {
+		c.queueDepth.set(float64(s.QueueDepth), s.Name)
+		c.queueDropped.set(float64(s.Dropped), s.Name)
+		c.handlerLatency.set(s.AvgLatency.Seconds(), s.Name)
+		c.handlerFailures.set(float64(s.Failed), s.Name)
+	}
+
+	for _, f := range []*family{
+		c.runs, c.runDuration, c.gamesCreated, c.gamesFinished, c.gamesPerHour, c.chickens, c.deaths, c.errors,
+		c.potions, c.itemsStashed, c.status, c.published, c.queueDepth, c.queueDropped, c.handlerLatency, c.handlerFailures,
+	} {
+		if err := f.write(w); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Supervisor statuses, see bot.SupervisorStatus
+const statusNotStarted = "Not Started"
+
+var knownStatuses = []string{statusNotStarted, "Starting", "In game", "Paused", "Crashed"}
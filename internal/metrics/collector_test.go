@@ -0,0 +1,109 @@
+package metrics
+
+import (
+	"bufio"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hectorgimenez/d2go/pkg/data"
+	"github.com/hectorgimenez/d2go/pkg/data/item"
+	"github.com/hectorgimenez/koolo/internal/event"
+)
+
+// scrape requests /metrics like Prometheus does, returning the value of every sample
+func scrape(t *testing.T, url string) map[string]string {
+	t.Helper()
+
+	resp, err := http.Get(url + "/metrics")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	samples := make(map[string]string)
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.HasPrefix(line, "#") {
+			continue
+		}
+		i := strings.LastIndex(line, " ")
+		samples[line[:i]] = line[i+1:]
+	}
+
+	return samples
+}
+
+func TestScrapeAfterEvents(t *testing.T) {
+	c := NewCollector()
+	statuses := map[string]string{"sorc": "In game", "barb": ""}
+	bus := event.Metrics{Published: 7, Subscribers: []event.SubscriberMetrics{{Name: "metrics", QueueDepth: 2, Dropped: 1, AvgLatency: 500 * time.Millisecond}}}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := c.Write(w, statuses, bus); err != nil {
+			t.Error(err)
+		}
+	}))
+	defer srv.Close()
+
+	now := time.Now()
+	for _, e := range []event.Event{
+		event.GameCreated(event.TextAt("sorc", "", now), "mf-1", ""),
+		event.RunStarted(event.TextAt("sorc", "", now), "mephisto"),
+		event.ItemStashed(event.TextAt("sorc", "", now), data.Drop{Item: data.Item{Name: "Shako", Quality: item.QualityUnique}}, "mephisto", now),
+		event.RunFinished(event.TextAt("sorc", "", now.Add(45*time.Second)), "mephisto", event.FinishedOK),
+		event.UsedPotion(event.TextAt("sorc", "", now), data.HealingPotion, true),
+		event.GameFinished(event.TextAt("sorc", "", now), event.FinishedChicken),
+	} {
+		if err := c.Handle(context.Background(), e); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	samples := scrape(t, srv.URL)
+	for sample, expected := range map[string]string{
+		`koolo_runs_total{supervisor="sorc",run="mephisto",reason="ok"}`:                 "1",
+		`koolo_run_duration_seconds_bucket{supervisor="sorc",run="mephisto",le="30"}`:    "0",
+		`koolo_run_duration_seconds_bucket{supervisor="sorc",run="mephisto",le="60"}`:    "1",
+		`koolo_run_duration_seconds_sum{supervisor="sorc",run="mephisto"}`:               "45",
+		`koolo_games_created_total{supervisor="sorc"}`:                                   "1",
+		`koolo_games_per_hour{supervisor="sorc"}`:                                        "1",
+		`koolo_chickens_total{supervisor="sorc",target="player"}`:                        "1",
+		`koolo_potions_used_total{supervisor="sorc",type="HealingPotion",target="merc"}`: "1",
+		`koolo_items_stashed_total{supervisor="sorc",quality="Unique"}`:                  "1",
+		`koolo_supervisor_status{supervisor="sorc",status="In game"}`:                    "1",
+		`koolo_supervisor_status{supervisor="sorc",status="Paused"}`:                     "0",
+		`koolo_supervisor_status{supervisor="barb",status="Not Started"}`:                "1",
+		`koolo_events_published_total`:                                                   "7",
+		`koolo_event_queue_depth{subscriber="metrics"}`:                                  "2",
+		`koolo_event_handler_latency_seconds{subscriber="metrics"}`:                      "0.5",
+	} {
+		if samples[sample] != expected {
+			t.Errorf("%s: expected %s, got %q", sample, expected, samples[sample])
+		}
+	}
+
+	// Counters keep growing between scrapes, the status is taken from the last one
+	c.Handle(context.Background(), event.GameCreated(event.TextAt("sorc", "", now), "mf-2", ""))
+	statuses["sorc"] = "Paused"
+	samples = scrape(t, srv.URL)
+	if samples[`koolo_games_created_total{supervisor="sorc"}`] != "2" || samples[`koolo_supervisor_status{supervisor="sorc",status="Paused"}`] != "1" {
+		t.Errorf("unexpected samples after the second scrape %v", samples)
+	}
+}
+
+func TestLabelEscaping(t *testing.T) {
+	f := newFamily("koolo_test", "Test", typeCounter, "name")
+	f.add(1, "a \"quoted\"\\name\n")
+
+	w := new(strings.Builder)
+	if err := f.write(w); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(w.String(), `koolo_test{name="a \"quoted\"\\name\n"} 1`) {
+		t.Errorf("unexpected output %q", w.String())
+	}
+}
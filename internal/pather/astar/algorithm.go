@@ -0,0 +1,34 @@
+package astar
+
+import (
+	"github.com/hectorgimenez/d2go/pkg/data"
+	"github.com/hectorgimenez/koolo/internal/game"
+)
+
+const (
+	AlgorithmAStar        = "astar"
+	AlgorithmHierarchical = "hierarchical"
+)
+
+// Algorithm finds a path between two positions relative to the grid, the path includes every position from start to
+// goal and the distance is the amount of positions
+type Algorithm interface {
+	CalculatePath(g *game.Grid, start, goal data.Position) ([]data.Position, int, bool)
+}
+
+// AStar is the plain A* search, it doesn't need any preprocessing
+type AStar struct{}
+
+func (AStar) CalculatePath(g *game.Grid, start, goal data.Position) ([]data.Position, int, bool) {
+	return CalculatePath(g, start, goal)
+}
+
+// NewAlgorithm returns the algorithm by name ready to search on g, preprocessing the grid if needed. Unknown names
+// fall back to A*
+func NewAlgorithm(name string, g *game.Grid) Algorithm {
+	if name == AlgorithmHierarchical {
+		return NewHierarchy(g)
+	}
+
+	return AStar{}
+}
@@ -1,8 +1,8 @@
 package astar
 
 import (
-	"container/heap"
 	"math"
+	"slices"
 
 	"github.com/hectorgimenez/d2go/pkg/data"
 	"github.com/hectorgimenez/koolo/internal/game"
@@ -23,7 +23,8 @@ type Node struct {
 	data.Position
 	Cost     int
 	Priority int
-	Index    int
+	// Entrance index, only used by the hierarchical search
+	Index int
 }
 
 func direction(from, to data.Position) (dx, dy int) {
@@ -33,43 +34,30 @@ func direction(from, to data.Position) (dx, dy int) {
 }
 
 func CalculatePath(g *game.Grid, start, goal data.Position) ([]data.Position, int, bool) {
-	pq := make(PriorityQueue, 0)
-	heap.Init(&pq)
-
-	// Use a 2D slice to store the cost of each node
-	costSoFar := make([][]int, g.Width)
-	cameFrom := make([][]data.Position, g.Width)
-	for i := range costSoFar {
-		costSoFar[i] = make([]int, g.Height)
-		cameFrom[i] = make([]data.Position, g.Height)
-		for j := range costSoFar[i] {
-			costSoFar[i][j] = math.MaxInt32
-		}
-	}
+	b := getBuffers(g.Width * g.Height)
+	defer putBuffers(b)
 
-	startNode := &Node{Position: start, Cost: 0, Priority: heuristic(start, goal)}
-	heap.Push(&pq, startNode)
-	costSoFar[start.X][start.Y] = 0
+	// Costs are stored in a flat slice, indexed by y * width + x
+	startIdx := start.Y*g.Width + start.X
+	b.queue.Push(Node{Position: start, Cost: 0, Priority: heuristic(start, goal)})
+	b.set(startIdx, 0, -1)
 
 	neighbors := make([]data.Position, 0, 8)
 
-	for pq.Len() > 0 {
-		current := heap.Pop(&pq).(*Node)
+	for b.queue.Len() > 0 {
+		current := b.queue.Pop()
 
 		// Let's build the path if we reached the goal
 		if current.Position == goal {
-			var path []data.Position
-			for p := goal; p != start; p = cameFrom[p.X][p.Y] {
-				path = append([]data.Position{p}, path...)
-			}
-			path = append([]data.Position{start}, path...)
+			path := buildPath(g, b, goal)
 			return path, len(path), true
 		}
 
-		updateNeighbors(g, current, &neighbors)
+		updateNeighbors(g, &current, &neighbors)
 
+		currentCost := b.costAt(current.Y*g.Width + current.X)
 		for _, neighbor := range neighbors {
-			newCost := costSoFar[current.X][current.Y] + getCost(g.CollisionGrid[neighbor.Y][neighbor.X])
+			newCost := currentCost + getCost(g.CollisionGrid[neighbor.Y][neighbor.X])
 
 			// Handicap for changing direction, this prevents zig-zagging around obstacles
 			//curDirX, curDirY := direction(cameFrom[current.X][current.Y], current.Position)
@@ -78,11 +66,11 @@ func CalculatePath(g *game.Grid, start, goal data.Position) ([]data.Position, in
 			//	newCost++
 			//}
 
-			if newCost < costSoFar[neighbor.X][neighbor.Y] {
-				costSoFar[neighbor.X][neighbor.Y] = newCost
+			neighborIdx := neighbor.Y*g.Width + neighbor.X
+			if newCost < b.costAt(neighborIdx) {
+				b.set(neighborIdx, newCost, current.Y*g.Width+current.X)
 				priority := newCost + int(0.5*float64(heuristic(neighbor, goal)))
-				heap.Push(&pq, &Node{Position: neighbor, Cost: newCost, Priority: priority})
-				cameFrom[neighbor.X][neighbor.Y] = current.Position
+				b.queue.Push(Node{Position: neighbor, Cost: newCost, Priority: priority})
 			}
 		}
 	}
@@ -90,6 +78,16 @@ func CalculatePath(g *game.Grid, start, goal data.Position) ([]data.Position, in
 	return nil, 0, false
 }
 
+func buildPath(g *game.Grid, b *buffers, goal data.Position) []data.Position {
+	path := make([]data.Position, 0, 64)
+	for idx := goal.Y*g.Width + goal.X; idx >= 0; idx = int(b.cameFrom[idx]) {
+		path = append(path, data.Position{X: idx % g.Width, Y: idx / g.Width})
+	}
+	slices.Reverse(path)
+
+	return path
+}
+
 // Get walkable neighbors of a given node
 func updateNeighbors(grid *game.Grid, node *Node, neighbors *[]data.Position) {
 	*neighbors = (*neighbors)[:0]
@@ -116,6 +114,8 @@ func getCost(tileType game.CollisionType) int {
 		return 4 // Soft blocker
 	case game.CollisionTypeLowPriority:
 		return 20
+	case game.CollisionTypeDanger:
+		return 40
 	default:
 		return math.MaxInt32
 	}
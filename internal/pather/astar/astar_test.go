@@ -2,6 +2,7 @@ package astar
 
 import (
 	"encoding/gob"
+	"math"
 	"os"
 	"testing"
 
@@ -39,6 +40,122 @@ func TestAstar(t *testing.T) {
 	}
 }
 
+func BenchmarkHierarchical(b *testing.B) {
+	grid := loadGrid()
+	h := NewHierarchy(grid)
+
+	start := data.Position{X: 336, Y: 701}
+	goal := data.Position{X: 11, Y: 330}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		h.CalculatePath(grid, start, goal)
+	}
+}
+
+func BenchmarkNewHierarchy(b *testing.B) {
+	grid := loadGrid()
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		NewHierarchy(grid)
+	}
+}
+
+func TestHierarchical(t *testing.T) {
+	grid := loadGrid()
+	h := NewHierarchy(grid)
+
+	start := data.Position{X: 336, Y: 701}
+	goal := data.Position{X: 11, Y: 330}
+
+	expected, _, _ := CalculatePath(grid, start, goal)
+	p, dist, found := h.CalculatePath(grid, start, goal)
+	if !found {
+		t.Fatalf("Expected path to be found")
+	}
+	if dist != len(p) {
+		t.Errorf("Expected distance %d to match path length %d", dist, len(p))
+	}
+	if p[0] != start || p[len(p)-1] != goal {
+		t.Errorf("Expected path from %v to %v, got from %v to %v", start, goal, p[0], p[len(p)-1])
+	}
+	for i := 1; i < len(p); i++ {
+		if max(abs(p[i].X-p[i-1].X), abs(p[i].Y-p[i-1].Y)) != 1 {
+			t.Fatalf("Positions %v and %v are not adjacent", p[i-1], p[i])
+		}
+		if grid.CollisionGrid[p[i].Y][p[i].X] == game.CollisionTypeNonWalkable {
+			t.Fatalf("Position %v is not walkable", p[i])
+		}
+	}
+
+	// Entrances are not always on the optimal path, but it should be close
+	if cost, expectedCost := pathCost(grid, p), pathCost(grid, expected); float64(cost) > float64(expectedCost)*1.1 {
+		t.Errorf("Expected cost to be close to %d, got %d", expectedCost, cost)
+	}
+}
+
+func BenchmarkTeleportPath(b *testing.B) {
+	grid := loadGrid()
+
+	start := data.Position{X: 336, Y: 701}
+	goal := data.Position{X: 11, Y: 330}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		CalculateTeleportPath(grid, start, goal, teleportRange(25))
+	}
+}
+
+func TestTeleportPath(t *testing.T) {
+	grid := loadGrid()
+	canReach := teleportRange(25)
+
+	start := data.Position{X: 336, Y: 701}
+	goal := data.Position{X: 11, Y: 330}
+
+	p, found := CalculateTeleportPath(grid, start, goal, canReach)
+	if !found {
+		t.Fatalf("Expected teleport path to be found")
+	}
+	if p[0] != start || p[len(p)-1] != goal {
+		t.Errorf("Expected path from %v to %v, got from %v to %v", start, goal, p[0], p[len(p)-1])
+	}
+	for i := 1; i < len(p); i++ {
+		if !canReach(p[i].X-p[i-1].X, p[i].Y-p[i-1].Y) {
+			t.Errorf("Teleport from %v to %v is out of range", p[i-1], p[i])
+		}
+		if !canLand(grid, p[i]) {
+			t.Errorf("Landing point %v is not walkable", p[i])
+		}
+	}
+
+	// Walls are ignored, it should be close to the straight line
+	minCasts := int(math.Ceil(math.Hypot(float64(goal.X-start.X), float64(goal.Y-start.Y)) / 25))
+	if casts := len(p) - 1; casts > minCasts+2 {
+		t.Errorf("Expected around %d casts, got %d", minCasts, casts)
+	}
+
+	if _, found = CalculateTeleportPath(grid, start, data.Position{X: 0, Y: 0}, canReach); found {
+		t.Errorf("Expected no path to a non walkable goal")
+	}
+}
+
+func teleportRange(distance float64) func(dx, dy int) bool {
+	return func(dx, dy int) bool {
+		return math.Hypot(float64(dx), float64(dy)) <= distance
+	}
+}
+
+func pathCost(g *game.Grid, path []data.Position) int {
+	cost := 0
+	for _, p := range path[1:] {
+		cost += getCost(g.CollisionGrid[p.Y][p.X])
+	}
+
+	return cost
+}
+
 func loadGrid() *game.Grid {
 	var grid game.Grid
 	file, err := os.Open("durance_of_hate_grid.bin")
@@ -0,0 +1,59 @@
+package astar
+
+import (
+	"math"
+	"sync"
+)
+
+// buffers holds the state of a single search, they are pooled so we don't allocate two grid sized matrices on every
+// call. Instead of clearing them, every search gets a new generation and entries from older ones are ignored
+type buffers struct {
+	cost       []int
+	cameFrom   []int32
+	generation []uint32
+	current    uint32
+	queue      PriorityQueue
+}
+
+var bufferPool = sync.Pool{New: func() any { return &buffers{} }}
+
+func getBuffers(size int) *buffers {
+	b := bufferPool.Get().(*buffers)
+	if cap(b.cost) < size {
+		b.cost = make([]int, size)
+		b.cameFrom = make([]int32, size)
+		b.generation = make([]uint32, size)
+		b.current = 0
+	}
+	b.cost = b.cost[:size]
+	b.cameFrom = b.cameFrom[:size]
+	b.generation = b.generation[:size]
+	b.queue = b.queue[:0]
+
+	b.current++
+	// Overflow, old generations could match again
+	if b.current == 0 {
+		clear(b.generation)
+		b.current = 1
+	}
+
+	return b
+}
+
+func putBuffers(b *buffers) {
+	bufferPool.Put(b)
+}
+
+func (b *buffers) costAt(idx int) int {
+	if b.generation[idx] != b.current {
+		return math.MaxInt32
+	}
+
+	return b.cost[idx]
+}
+
+func (b *buffers) set(idx, cost, from int) {
+	b.generation[idx] = b.current
+	b.cost[idx] = cost
+	b.cameFrom[idx] = int32(from)
+}
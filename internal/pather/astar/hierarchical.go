@@ -0,0 +1,303 @@
+package astar
+
+import (
+	"math"
+	"slices"
+
+	"github.com/hectorgimenez/d2go/pkg/data"
+	"github.com/hectorgimenez/koolo/internal/game"
+)
+
+const (
+	clusterSize = 16
+	// Long walkable borders get one entrance every entranceSpacing tiles, a single one would add detours
+	entranceSpacing = 8
+	// Max distance between the positions used to build the final path, further entrances are skipped
+	refineDistance = clusterSize
+)
+
+// Hierarchy is a precomputed graph of a grid for hierarchical A*. The grid is split in clusters connected by
+// entrances placed on their borders, and the cost between every pair of entrances of the same cluster is calculated
+// when the hierarchy is built. Searches run first over the entrances, then the real path is built with A* between
+// consecutive entrances, so only a small part of the grid is explored.
+// Build it once per grid, it's meant to be built from a grid without monsters, they move all the time. Monsters in the
+// grid used for the search are still avoided when the path between entrances is built.
+// Same for danger zones, entrance costs don't include them so the route between clusters can cross one, the path
+// only goes around it inside the clusters of that route.
+type Hierarchy struct {
+	width     int
+	height    int
+	clustersX int
+	clustersY int
+	// Grid index (y * width + x) of every entrance
+	nodes        []int
+	edges        [][]hierarchyEdge
+	clusterNodes [][]int
+	nodeByIdx    map[int]int
+}
+
+type hierarchyEdge struct {
+	to   int
+	cost int
+}
+
+func NewHierarchy(g *game.Grid) *Hierarchy {
+	h := &Hierarchy{
+		width:     g.Width,
+		height:    g.Height,
+		clustersX: (g.Width + clusterSize - 1) / clusterSize,
+		clustersY: (g.Height + clusterSize - 1) / clusterSize,
+		nodeByIdx: make(map[int]int),
+	}
+	h.clusterNodes = make([][]int, h.clustersX*h.clustersY)
+
+	for cy := 0; cy < h.clustersY; cy++ {
+		for cx := 0; cx < h.clustersX; cx++ {
+			minX, minY, maxX, maxY := h.clusterBounds(cx + cy*h.clustersX)
+			// Border with the cluster on the right
+			if cx < h.clustersX-1 {
+				h.addEntrances(g, data.Position{X: maxX - 1, Y: minY}, data.Position{X: 0, Y: 1}, data.Position{X: 1, Y: 0}, maxY-minY)
+			}
+			// Border with the cluster below
+			if cy < h.clustersY-1 {
+				h.addEntrances(g, data.Position{X: minX, Y: maxY - 1}, data.Position{X: 1, Y: 0}, data.Position{X: 0, Y: 1}, maxX-minX)
+			}
+		}
+	}
+
+	costs := make([]int, clusterSize*clusterSize)
+	queue := make(PriorityQueue, 0, clusterSize*clusterSize)
+	for cluster, nodes := range h.clusterNodes {
+		for _, from := range nodes {
+			h.clusterCosts(g, cluster, h.position(h.nodes[from]), false, costs, &queue)
+			for _, to := range nodes {
+				if c := costs[h.localIdx(cluster, h.position(h.nodes[to]))]; to != from && c < math.MaxInt32 {
+					h.edges[from] = append(h.edges[from], hierarchyEdge{to: to, cost: c})
+				}
+			}
+		}
+	}
+
+	return h
+}
+
+// addEntrances walks along a border starting from start, every step moves along and the opposite tile in the other
+// cluster is at across. Walkable runs are split in entrances, placed on the cheapest tile of each chunk
+func (h *Hierarchy) addEntrances(g *game.Grid, start, along, across data.Position, length int) {
+	best, bestCost, runLength := -1, math.MaxInt32, 0
+	flush := func() {
+		if best >= 0 {
+			a := data.Position{X: start.X + along.X*best, Y: start.Y + along.Y*best}
+			b := data.Position{X: a.X + across.X, Y: a.Y + across.Y}
+			na, nb := h.addNode(a), h.addNode(b)
+			h.edges[na] = append(h.edges[na], hierarchyEdge{to: nb, cost: getCost(g.CollisionGrid[b.Y][b.X])})
+			h.edges[nb] = append(h.edges[nb], hierarchyEdge{to: na, cost: getCost(g.CollisionGrid[a.Y][a.X])})
+		}
+		best, bestCost, runLength = -1, math.MaxInt32, 0
+	}
+
+	for i := 0; i < length; i++ {
+		a := data.Position{X: start.X + along.X*i, Y: start.Y + along.Y*i}
+		b := data.Position{X: a.X + across.X, Y: a.Y + across.Y}
+		costA, costB := getCost(g.CollisionGrid[a.Y][a.X]), getCost(g.CollisionGrid[b.Y][b.X])
+		if costA == math.MaxInt32 || costB == math.MaxInt32 {
+			flush()
+			continue
+		}
+
+		if costA+costB < bestCost {
+			best, bestCost = i, costA+costB
+		}
+		runLength++
+		if runLength == entranceSpacing {
+			flush()
+		}
+	}
+	flush()
+}
+
+func (h *Hierarchy) addNode(p data.Position) int {
+	idx := p.Y*h.width + p.X
+	if n, found := h.nodeByIdx[idx]; found {
+		return n
+	}
+
+	n := len(h.nodes)
+	h.nodes = append(h.nodes, idx)
+	h.edges = append(h.edges, nil)
+	h.nodeByIdx[idx] = n
+	cluster := h.cluster(p)
+	h.clusterNodes[cluster] = append(h.clusterNodes[cluster], n)
+
+	return n
+}
+
+func (h *Hierarchy) CalculatePath(g *game.Grid, start, goal data.Position) ([]data.Position, int, bool) {
+	// Short paths are faster with plain A*, and the hierarchy is useless if it was built for another grid
+	if g.Width != h.width || g.Height != h.height || !inside(g, start) || !inside(g, goal) ||
+		h.cluster(start) == h.cluster(goal) || max(abs(start.X-goal.X), abs(start.Y-goal.Y)) < clusterSize {
+		return CalculatePath(g, start, goal)
+	}
+
+	waypoints, found := h.entrancesPath(g, start, goal)
+	if !found {
+		// Corners between clusters are not connected, let's make sure there is really no path
+		return CalculatePath(g, start, goal)
+	}
+
+	waypoints = append(waypoints, goal)
+	path := []data.Position{start}
+	from := start
+	for i := 0; i < len(waypoints); i++ {
+		// Entrances are not always on the shortest path, skipping the close ones removes most of the detours
+		for i+1 < len(waypoints) && max(abs(waypoints[i+1].X-from.X), abs(waypoints[i+1].Y-from.Y)) < refineDistance {
+			i++
+		}
+		to := waypoints[i]
+		if to == from {
+			continue
+		}
+		segment, _, found := CalculatePath(g, from, to)
+		if !found {
+			return CalculatePath(g, start, goal)
+		}
+		path = append(path, segment[1:]...)
+		from = to
+	}
+
+	return path, len(path), true
+}
+
+// entrancesPath returns the entrances to go through from start to goal
+func (h *Hierarchy) entrancesPath(g *game.Grid, start, goal data.Position) ([]data.Position, bool) {
+	b := getBuffers(len(h.nodes) + 1)
+	defer putBuffers(b)
+
+	// Virtual node, reached from the entrances of the goal cluster
+	goalNode := len(h.nodes)
+
+	startCluster := h.cluster(start)
+	startCosts := make([]int, clusterSize*clusterSize)
+	h.clusterCosts(g, startCluster, start, false, startCosts, &b.queue)
+	for _, n := range h.clusterNodes[startCluster] {
+		p := h.position(h.nodes[n])
+		if c := startCosts[h.localIdx(startCluster, p)]; c < math.MaxInt32 {
+			b.set(n, c, -1)
+			b.queue.Push(Node{Position: p, Cost: c, Priority: c + int(0.5*float64(heuristic(p, goal))), Index: n})
+		}
+	}
+
+	goalCluster := h.cluster(goal)
+	goalCosts := make([]int, clusterSize*clusterSize)
+	queue := make(PriorityQueue, 0, clusterSize*clusterSize)
+	h.clusterCosts(g, goalCluster, goal, true, goalCosts, &queue)
+
+	for b.queue.Len() > 0 {
+		current := b.queue.Pop()
+		if current.Cost > b.costAt(current.Index) {
+			continue
+		}
+
+		if current.Index == goalNode {
+			waypoints := make([]data.Position, 0)
+			for n := int(b.cameFrom[goalNode]); n >= 0; n = int(b.cameFrom[n]) {
+				waypoints = append(waypoints, h.position(h.nodes[n]))
+			}
+			slices.Reverse(waypoints)
+			return waypoints, true
+		}
+
+		if h.cluster(current.Position) == goalCluster {
+			if c := goalCosts[h.localIdx(goalCluster, current.Position)]; c < math.MaxInt32 && current.Cost+c < b.costAt(goalNode) {
+				b.set(goalNode, current.Cost+c, current.Index)
+				b.queue.Push(Node{Position: goal, Cost: current.Cost + c, Priority: current.Cost + c, Index: goalNode})
+			}
+		}
+
+		for _, e := range h.edges[current.Index] {
+			newCost := current.Cost + e.cost
+			if newCost < b.costAt(e.to) {
+				b.set(e.to, newCost, current.Index)
+				p := h.position(h.nodes[e.to])
+				b.queue.Push(Node{Position: p, Cost: newCost, Priority: newCost + int(0.5*float64(heuristic(p, goal))), Index: e.to})
+			}
+		}
+	}
+
+	return nil, false
+}
+
+// clusterCosts runs Dijkstra from p without leaving the cluster, filling costs indexed by localIdx. When reverse is
+// true it calculates the cost from every tile to p instead. Costs and queue are only passed to reuse them
+func (h *Hierarchy) clusterCosts(g *game.Grid, cluster int, p data.Position, reverse bool, costs []int, queue *PriorityQueue) {
+	minX, minY, maxX, maxY := h.clusterBounds(cluster)
+	for i := range costs {
+		costs[i] = math.MaxInt32
+	}
+
+	*queue = (*queue)[:0]
+	costs[h.localIdx(cluster, p)] = 0
+	queue.Push(Node{Position: p})
+	for queue.Len() > 0 {
+		current := queue.Pop()
+		if current.Cost > costs[h.localIdx(cluster, current.Position)] {
+			continue
+		}
+
+		// Moving into a tile costs the cost of that tile, walking backwards we pay for the tile we come from
+		currentTileCost := getCost(g.CollisionGrid[current.Y][current.X])
+		for _, d := range directions {
+			n := data.Position{X: current.X + d.X, Y: current.Y + d.Y}
+			if n.X < minX || n.X >= maxX || n.Y < minY || n.Y >= maxY {
+				continue
+			}
+
+			tileCost := getCost(g.CollisionGrid[n.Y][n.X])
+			if tileCost == math.MaxInt32 {
+				continue
+			}
+			if reverse {
+				tileCost = currentTileCost
+			}
+
+			newCost := current.Cost + tileCost
+			if idx := h.localIdx(cluster, n); newCost < costs[idx] {
+				costs[idx] = newCost
+				queue.Push(Node{Position: n, Cost: newCost, Priority: newCost})
+			}
+		}
+	}
+}
+
+func (h *Hierarchy) cluster(p data.Position) int {
+	return p.X/clusterSize + p.Y/clusterSize*h.clustersX
+}
+
+func (h *Hierarchy) clusterBounds(cluster int) (minX, minY, maxX, maxY int) {
+	minX = cluster % h.clustersX * clusterSize
+	minY = cluster / h.clustersX * clusterSize
+
+	return minX, minY, min(minX+clusterSize, h.width), min(minY+clusterSize, h.height)
+}
+
+func (h *Hierarchy) localIdx(cluster int, p data.Position) int {
+	minX, minY, _, _ := h.clusterBounds(cluster)
+
+	return (p.Y-minY)*clusterSize + p.X - minX
+}
+
+func (h *Hierarchy) position(idx int) data.Position {
+	return data.Position{X: idx % h.width, Y: idx / h.width}
+}
+
+func inside(g *game.Grid, p data.Position) bool {
+	return p.X >= 0 && p.X < g.Width && p.Y >= 0 && p.Y < g.Height
+}
+
+func abs(v int) int {
+	if v < 0 {
+		return -v
+	}
+
+	return v
+}
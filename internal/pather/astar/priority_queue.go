@@ -1,25 +1,52 @@
 package astar
 
-type PriorityQueue []*Node
+// PriorityQueue is a binary min-heap of nodes stored by value, same ordering as container/heap but without boxing
+// every node into an interface
+type PriorityQueue []Node
 
-func (pq PriorityQueue) Len() int           { return len(pq) }
-func (pq PriorityQueue) Less(i, j int) bool { return pq[i].Priority < pq[j].Priority }
-func (pq PriorityQueue) Swap(i, j int) {
-	pq[i], pq[j] = pq[j], pq[i]
-	pq[i].Index = i
-	pq[j].Index = j
-}
-func (pq *PriorityQueue) Push(x interface{}) {
-	n := x.(*Node)
-	n.Index = len(*pq)
+func (pq PriorityQueue) Len() int { return len(pq) }
+
+func (pq *PriorityQueue) Push(n Node) {
 	*pq = append(*pq, n)
+	pq.up(len(*pq) - 1)
 }
-func (pq *PriorityQueue) Pop() interface{} {
+
+func (pq *PriorityQueue) Pop() Node {
 	old := *pq
-	n := len(old)
-	node := old[n-1]
-	old[n-1] = nil
-	node.Index = -1
-	*pq = old[0 : n-1]
+	n := len(old) - 1
+	old[0], old[n] = old[n], old[0]
+	pq.down(0, n)
+	node := old[n]
+	*pq = old[:n]
+
 	return node
 }
+
+func (pq PriorityQueue) up(j int) {
+	for {
+		i := (j - 1) / 2
+		if i == j || pq[j].Priority >= pq[i].Priority {
+			break
+		}
+		pq[i], pq[j] = pq[j], pq[i]
+		j = i
+	}
+}
+
+func (pq PriorityQueue) down(i, n int) {
+	for {
+		j1 := 2*i + 1
+		if j1 >= n || j1 < 0 {
+			break
+		}
+		j := j1
+		if j2 := j1 + 1; j2 < n && pq[j2].Priority < pq[j1].Priority {
+			j = j2
+		}
+		if pq[j].Priority >= pq[i].Priority {
+			break
+		}
+		pq[i], pq[j] = pq[j], pq[i]
+		i = j
+	}
+}
@@ -0,0 +1,112 @@
+package astar
+
+import (
+	"math"
+	"slices"
+
+	"github.com/hectorgimenez/d2go/pkg/data"
+	"github.com/hectorgimenez/koolo/internal/game"
+)
+
+const (
+	// Landing points are searched every teleportStep tiles, checking every tile in range is too slow and a couple of
+	// tiles don't make any difference in the amount of casts
+	teleportStep = 2
+	// Bigger than any teleport range, canReach decides the real one
+	teleportSearchRadius = 64
+	// Cast count has priority over the distance to the goal, which is only used to break ties
+	castPriority = 1 << 16
+)
+
+// CalculateTeleportPath plans the landing points to go from start to goal teleporting, minimizing the amount of casts.
+// Teleport ignores walls, any walkable tile accepted by canReach (offset from the position we teleport from) is a valid
+// landing point. The returned path contains start, every landing point and goal, the goal must be walkable.
+func CalculateTeleportPath(g *game.Grid, start, goal data.Position, canReach func(dx, dy int) bool) ([]data.Position, bool) {
+	// The goal can be close to a dangerous monster, we want to go there anyway
+	if !inside(g, start) || !inside(g, goal) || !canLand(g, goal) && g.CollisionGrid[goal.Y][goal.X] != game.CollisionTypeDanger {
+		return nil, false
+	}
+	if start == goal {
+		return []data.Position{start}, true
+	}
+
+	offsets, maxReach := teleportOffsets(canReach)
+	if len(offsets) == 0 {
+		return nil, false
+	}
+
+	b := getBuffers(g.Width * g.Height)
+	defer putBuffers(b)
+
+	// Casts left can't be less than the straight distance divided by the longest teleport
+	priority := func(casts int, p data.Position) int {
+		distance := math.Hypot(float64(goal.X-p.X), float64(goal.Y-p.Y))
+		return (casts+int(math.Ceil(distance/maxReach)))*castPriority + int(distance)
+	}
+
+	b.set(start.Y*g.Width+start.X, 0, -1)
+	b.queue.Push(Node{Position: start, Cost: 0, Priority: priority(0, start)})
+	for b.queue.Len() > 0 {
+		current := b.queue.Pop()
+		currentIdx := current.Y*g.Width + current.X
+		if current.Cost > b.costAt(currentIdx) {
+			continue
+		}
+
+		if current.Position == goal {
+			return buildPath(g, b, goal), true
+		}
+
+		casts := current.Cost + 1
+		if canReach(goal.X-current.X, goal.Y-current.Y) {
+			if goalIdx := goal.Y*g.Width + goal.X; casts < b.costAt(goalIdx) {
+				b.set(goalIdx, casts, currentIdx)
+				b.queue.Push(Node{Position: goal, Cost: casts, Priority: priority(casts, goal)})
+			}
+			continue
+		}
+
+		for _, o := range offsets {
+			p := data.Position{X: current.X + o.X, Y: current.Y + o.Y}
+			if !inside(g, p) || !canLand(g, p) {
+				continue
+			}
+
+			if idx := p.Y*g.Width + p.X; casts < b.costAt(idx) {
+				b.set(idx, casts, currentIdx)
+				b.queue.Push(Node{Position: p, Cost: casts, Priority: priority(casts, p)})
+			}
+		}
+	}
+
+	return nil, false
+}
+
+// teleportOffsets returns all the offsets accepted by canReach, longest first, and the longest distance
+func teleportOffsets(canReach func(dx, dy int) bool) ([]data.Position, float64) {
+	offsets := make([]data.Position, 0)
+	maxReach := 0.0
+	for dy := -teleportSearchRadius; dy <= teleportSearchRadius; dy += teleportStep {
+		for dx := -teleportSearchRadius; dx <= teleportSearchRadius; dx += teleportStep {
+			if (dx == 0 && dy == 0) || !canReach(dx, dy) {
+				continue
+			}
+			offsets = append(offsets, data.Position{X: dx, Y: dy})
+			maxReach = max(maxReach, math.Hypot(float64(dx), float64(dy)))
+		}
+	}
+
+	slices.SortFunc(offsets, func(a, b data.Position) int {
+		return (b.X*b.X + b.Y*b.Y) - (a.X*a.X + a.Y*a.Y)
+	})
+
+	return offsets, maxReach
+}
+
+// canLand returns if the character can end a teleport on p, objects are walkable for A* but we can't stand on them, and
+// landing next to dangerous monsters is avoided
+func canLand(g *game.Grid, p data.Position) bool {
+	t := g.CollisionGrid[p.Y][p.X]
+
+	return t != game.CollisionTypeNonWalkable && t != game.CollisionTypeObject && t != game.CollisionTypeDanger
+}
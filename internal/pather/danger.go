@@ -0,0 +1,109 @@
+package pather
+
+import (
+	"slices"
+
+	"github.com/hectorgimenez/d2go/pkg/data"
+	"github.com/hectorgimenez/d2go/pkg/data/npc"
+	"github.com/hectorgimenez/d2go/pkg/data/stat"
+	"github.com/hectorgimenez/d2go/pkg/data/state"
+	"github.com/hectorgimenez/koolo/internal/game"
+)
+
+const (
+	hazardDangerRadius = 10
+	auraDangerRadius   = 8
+	eliteDangerRadius  = 6
+)
+
+// Monsters that are dangerous even if they are not elite
+var hazardMonsters = []npc.ID{
+	npc.StygianDoll, npc.StygianDoll2, npc.StygianDoll3, npc.StygianDoll4, npc.UndeadStygianDoll, npc.UndeadStygianDoll2,
+	npc.BurningSoul, npc.BurningSoul2, npc.BurningSoul3, npc.BlackSoul, npc.BlackSoul2,
+	npc.OblivionKnight, npc.OblivionKnight2, npc.OblivionKnight3, npc.OblivionKnight4,
+}
+
+// Auras making a pack a lot more dangerous, monster enchantments are not available so this is the closest we have
+var dangerousAuras = []state.State{state.Conviction, state.Fanaticism, state.Might, state.Holyfire, state.Holyshock, state.Holywindcold}
+
+// DangerRadius returns the distance we want to keep from the monster when we are just passing by, 0 if it's not
+// dangerous
+func DangerRadius(m data.Monster) int {
+	if m.Stats[stat.Life] <= 0 {
+		return 0
+	}
+
+	if slices.Contains(hazardMonsters, m.Name) {
+		return hazardDangerRadius
+	}
+
+	for _, s := range dangerousAuras {
+		if m.States.HasState(s) {
+			return auraDangerRadius
+		}
+	}
+
+	if m.IsElite() {
+		return eliteDangerRadius
+	}
+
+	return 0
+}
+
+// overlay changes some tiles of a cached grid during a single search, restore puts back the original values
+type overlay struct {
+	grid     *game.Grid
+	original map[data.Position]game.CollisionType
+}
+
+func newOverlay(grid *game.Grid) *overlay {
+	return &overlay{grid: grid, original: make(map[data.Position]game.CollisionType)}
+}
+
+// set changes the tile at p, relative to the grid
+func (o *overlay) set(p data.Position, t game.CollisionType) {
+	if _, found := o.original[p]; !found {
+		o.original[p] = o.grid.CollisionGrid[p.Y][p.X]
+	}
+	o.grid.CollisionGrid[p.Y][p.X] = t
+}
+
+func (o *overlay) restore() {
+	for p, t := range o.original {
+		o.grid.CollisionGrid[p.Y][p.X] = t
+	}
+}
+
+// addMonsters marks monsters as obstacles
+func (o *overlay) addMonsters(monsters data.Monsters) {
+	for _, m := range monsters {
+		if !o.grid.IsWalkable(m.Position) {
+			continue
+		}
+		o.set(o.grid.RelativePosition(m.Position), game.CollisionTypeMonster)
+	}
+}
+
+// addDangerZones marks the walkable tiles around dangerous enemies, monsters and objects are kept as they are
+func (o *overlay) addDangerZones(monsters data.Monsters) {
+	for _, m := range monsters.Enemies() {
+		radius := DangerRadius(m)
+		if radius == 0 {
+			continue
+		}
+
+		center := o.grid.RelativePosition(m.Position)
+		for y := center.Y - radius; y <= center.Y+radius; y++ {
+			for x := center.X - radius; x <= center.X+radius; x++ {
+				if x < 0 || y < 0 || x >= o.grid.Width || y >= o.grid.Height || (x-center.X)*(x-center.X)+(y-center.Y)*(y-center.Y) > radius*radius {
+					continue
+				}
+
+				switch o.grid.CollisionGrid[y][x] {
+				case game.CollisionTypeWalkable, game.CollisionTypeLowPriority:
+					o.set(data.Position{X: x, Y: y}, game.CollisionTypeDanger)
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,90 @@
+package pather
+
+import (
+	"testing"
+
+	"github.com/hectorgimenez/d2go/pkg/data"
+	"github.com/hectorgimenez/d2go/pkg/data/npc"
+	"github.com/hectorgimenez/d2go/pkg/data/stat"
+	"github.com/hectorgimenez/d2go/pkg/data/state"
+	"github.com/hectorgimenez/koolo/internal/game"
+	"github.com/hectorgimenez/koolo/internal/pather/astar"
+)
+
+func monster(name npc.ID, t data.MonsterType, life int, states ...state.State) data.Monster {
+	return data.Monster{UnitID: 1, Name: name, Type: t, Stats: map[stat.ID]int{stat.Life: life}, States: states}
+}
+
+func TestDangerRadius(t *testing.T) {
+	for name, tc := range map[string]struct {
+		monster  data.Monster
+		expected int
+	}{
+		"regular monster":       {monster(npc.FallenShaman, data.MonsterTypeNone, 100), 0},
+		"dead unique":           {monster(npc.FallenShaman, data.MonsterTypeUnique, 0), 0},
+		"unique":                {monster(npc.FallenShaman, data.MonsterTypeUnique, 100), eliteDangerRadius},
+		"champion":              {monster(npc.FallenShaman, data.MonsterTypeChampion, 100), eliteDangerRadius},
+		"minion":                {monster(npc.FallenShaman, data.MonsterTypeMinion, 100), eliteDangerRadius},
+		"super unique":          {monster(npc.FallenShaman, data.MonsterTypeSuperUnique, 100), eliteDangerRadius},
+		"conviction aura":       {monster(npc.FallenShaman, data.MonsterTypeNone, 100, state.Conviction), auraDangerRadius},
+		"unique with aura":      {monster(npc.FallenShaman, data.MonsterTypeUnique, 100, state.Fanaticism), auraDangerRadius},
+		"harmless state":        {monster(npc.FallenShaman, data.MonsterTypeNone, 100, state.Poison), 0},
+		"doll":                  {monster(npc.StygianDoll, data.MonsterTypeNone, 100), hazardDangerRadius},
+		"soul":                  {monster(npc.BlackSoul, data.MonsterTypeNone, 100), hazardDangerRadius},
+		"oblivion knight":       {monster(npc.OblivionKnight, data.MonsterTypeNone, 100), hazardDangerRadius},
+		"unique doll with aura": {monster(npc.StygianDoll, data.MonsterTypeUnique, 100, state.Might), hazardDangerRadius},
+		"dead doll":             {monster(npc.StygianDoll, data.MonsterTypeNone, 0), 0},
+	} {
+		if radius := DangerRadius(tc.monster); radius != tc.expected {
+			t.Errorf("%s: expected radius %d, got %d", name, tc.expected, radius)
+		}
+	}
+}
+
+func TestOverlayDetoursAroundUnique(t *testing.T) {
+	cg := make([][]game.CollisionType, 21)
+	for y := range cg {
+		cg[y] = make([]game.CollisionType, 40)
+		for x := range cg[y] {
+			cg[y][x] = game.CollisionTypeWalkable
+		}
+	}
+	grid := game.NewGrid(cg, 1000, 2000)
+	start, goal := data.Position{X: 0, Y: 10}, data.Position{X: 39, Y: 10}
+	unique := monster(npc.FallenShaman, data.MonsterTypeUnique, 100)
+	unique.Position = data.Position{X: 1020, Y: 2010}
+
+	near := func(path []data.Position) bool {
+		for _, p := range path {
+			if dx, dy := p.X-20, p.Y-10; dx*dx+dy*dy <= eliteDangerRadius*eliteDangerRadius {
+				return true
+			}
+		}
+		return false
+	}
+
+	path, _, found := astar.CalculatePath(grid, start, goal)
+	if !found || !near(path) {
+		t.Fatalf("without the overlay the path should go straight, found: %v", found)
+	}
+
+	o := newOverlay(grid)
+	o.addMonsters(data.Monsters{unique})
+	o.addDangerZones(data.Monsters{unique})
+	path, _, found = astar.CalculatePath(grid, start, goal)
+	o.restore()
+	if !found {
+		t.Fatal("expected a path around the unique")
+	}
+	if near(path) {
+		t.Errorf("path should not get close to the unique: %v", path)
+	}
+
+	for y := range grid.CollisionGrid {
+		for x, c := range grid.CollisionGrid[y] {
+			if c != game.CollisionTypeWalkable {
+				t.Fatalf("tile %d,%d was not restored, got %d", x, y, c)
+			}
+		}
+	}
+}
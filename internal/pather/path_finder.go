@@ -1,13 +1,14 @@
 package pather
 
 import (
-	"fmt"
 	"math"
+	"sync"
 
 	"github.com/hectorgimenez/d2go/pkg/data"
 	"github.com/hectorgimenez/d2go/pkg/data/area"
 	"github.com/hectorgimenez/koolo/internal/config"
 	"github.com/hectorgimenez/koolo/internal/game"
+	"github.com/hectorgimenez/koolo/internal/maprender"
 	"github.com/hectorgimenez/koolo/internal/pather/astar"
 )
 
@@ -16,6 +17,22 @@ type PathFinder struct {
 	data *game.Data
 	hid  *game.HID
 	cfg  *config.CharacterCfg
+
+	mu    sync.Mutex
+	seed  uint
+	grids map[gridKey]*cachedGrid
+}
+
+type gridKey struct {
+	area area.ID
+	// Adjacent area merged into the grid, when the destination is outside the current area
+	merged       area.ID
+	walkableVoid bool
+}
+
+type cachedGrid struct {
+	grid      *game.Grid
+	algorithm astar.Algorithm
 }
 
 func NewPathFinder(gr *game.MemoryReader, data *game.Data, hid *game.HID, cfg *config.CharacterCfg) *PathFinder {
@@ -42,13 +59,92 @@ func (pf *PathFinder) GetPath(to data.Position) (Path, int, bool) {
 }
 
 func (pf *PathFinder) GetPathFrom(from, to data.Position) (Path, int, bool) {
-	a := pf.data.AreaData
+	path, distance, found, scene := pf.calculatePath(from, to, config.Koolo.Debug.RenderMap)
+	if scene != nil {
+		pf.renderMap(*scene)
+	}
 
-	// We don't want to modify the original grid
-	grid := a.Grid.Copy()
+	return path, distance, found
+}
+
+// calculatePath searches the path between two absolute positions, the returned path is relative to the grid. If
+// withScene is set, it also returns what the search saw, even if no path was found
+func (pf *PathFinder) calculatePath(from, to data.Position, withScene bool) (Path, int, bool, *maprender.Scene) {
+	pf.mu.Lock()
+	defer pf.mu.Unlock()
+
+	// Lut Gholein map is a bit bugged, we should close this fake path to avoid pathing issues
+	a := pf.data.AreaData
+	if a.Area == area.LutGholein {
+		a.CollisionGrid[13][210] = game.CollisionTypeNonWalkable
+	}
 
 	// Special handling for Arcane Sanctuary (to allow pathing with platforms)
-	if pf.data.PlayerUnit.Area == area.ArcaneSanctuary && pf.data.CanTeleport() {
+	cached, found := pf.cachedGrid(to, pf.data.PlayerUnit.Area == area.ArcaneSanctuary && pf.data.CanTeleport())
+	if !found {
+		return nil, 0, false, nil
+	}
+	grid := cached.grid
+
+	// Monsters move all the time, they are only added to the cached grid during this search
+	o := newOverlay(grid)
+	defer o.restore()
+	o.addMonsters(pf.data.Monsters)
+	if pf.cfg.Character.AvoidDangerousMonsters {
+		o.addDangerZones(pf.data.Monsters)
+	}
+
+	path, distance, found := cached.algorithm.CalculatePath(grid, grid.RelativePosition(from), grid.RelativePosition(to))
+	if !withScene {
+		return path, distance, found, nil
+	}
+
+	scene := pf.scene(grid, from, to, path)
+
+	return path, distance, found, &scene
+}
+
+// cachedGrid returns the grid to go from the current area to the destination, with the objects already added as
+// obstacles and preprocessed by the pathfinding algorithm. Grids are built once per area and map seed. If
+// walkableVoid is set, non-walkable tiles of the current area are handled as low priority ones
+func (pf *PathFinder) cachedGrid(to data.Position, walkableVoid bool) (*cachedGrid, bool) {
+	a := pf.data.AreaData
+	key := gridKey{area: a.Area}
+
+	var destination game.AreaData
+	if !a.IsInside(to) {
+		found := false
+		for _, l := range a.AdjacentLevels {
+			if destination, found = pf.data.Areas[l.Area]; found && destination.IsInside(to) {
+				key.merged = l.Area
+				break
+			}
+			found = false
+		}
+		if !found {
+			return nil, false
+		}
+	} else {
+		key.walkableVoid = walkableVoid
+	}
+
+	if seed := pf.gr.MapSeed(); seed != pf.seed || pf.grids == nil {
+		pf.seed = seed
+		pf.grids = make(map[gridKey]*cachedGrid)
+	}
+	if cached, found := pf.grids[key]; found {
+		return cached, true
+	}
+
+	var grid *game.Grid
+	if key.merged != 0 {
+		grid = mergeGrids(a, destination)
+	} else {
+		// We don't want to modify the original grid
+		grid = a.Grid.Copy()
+	}
+
+	if key.walkableVoid {
 		// Make all non-walkable tiles into low priority tiles for teleport pathing
 		for y := 0; y < len(grid.CollisionGrid); y++ {
 			for x := 0; x < len(grid.CollisionGrid[y]); x++ {
@@ -58,24 +154,9 @@ func (pf *PathFinder) GetPathFrom(from, to data.Position) (Path, int, bool) {
 			}
 		}
 	}
-	// Lut Gholein map is a bit bugged, we should close this fake path to avoid pathing issues
-	if a.Area == area.LutGholein {
-		a.CollisionGrid[13][210] = game.CollisionTypeNonWalkable
-	}
-
-	if !a.IsInside(to) {
-		expandedGrid, err := pf.mergeGrids(to)
-		if err != nil {
-			return nil, 0, false
-		}
-		grid = expandedGrid
-	}
-
-	from = grid.RelativePosition(from)
-	to = grid.RelativePosition(to)
 
 	// Add objects to the collision grid as obstacles
-	for _, o := range pf.data.AreaData.Objects {
+	for _, o := range a.Objects {
 		if !grid.IsWalkable(o.Position) {
 			continue
 		}
@@ -97,59 +178,36 @@ func (pf *PathFinder) GetPathFrom(from, to data.Position) (Path, int, bool) {
 		}
 	}
 
-	// Add monsters to the collision grid as obstacles
-	for _, m := range pf.data.Monsters {
-		if !grid.IsWalkable(m.Position) {
-			continue
-		}
-		relativePos := grid.RelativePosition(m.Position)
-		grid.CollisionGrid[relativePos.Y][relativePos.X] = game.CollisionTypeMonster
-	}
-
-	path, distance, found := astar.CalculatePath(grid, from, to)
-
-	if config.Koolo.Debug.RenderMap {
-		pf.renderMap(grid, from, to, path)
-	}
+	cached := &cachedGrid{grid: grid, algorithm: astar.NewAlgorithm(config.Koolo.Pathfinding, grid)}
+	pf.grids[key] = cached
 
-	return path, distance, found
+	return cached, true
 }
 
-func (pf *PathFinder) mergeGrids(to data.Position) (*game.Grid, error) {
-	for _, a := range pf.data.AreaData.AdjacentLevels {
-		destination := pf.data.Areas[a.Area]
-		if destination.IsInside(to) {
-			origin := pf.data.AreaData
+func mergeGrids(origin, destination game.AreaData) *game.Grid {
+	endX1 := origin.OffsetX + len(origin.Grid.CollisionGrid[0])
+	endY1 := origin.OffsetY + len(origin.Grid.CollisionGrid)
+	endX2 := destination.OffsetX + len(destination.Grid.CollisionGrid[0])
+	endY2 := destination.OffsetY + len(destination.Grid.CollisionGrid)
 
-			endX1 := origin.OffsetX + len(origin.Grid.CollisionGrid[0])
-			endY1 := origin.OffsetY + len(origin.Grid.CollisionGrid)
-			endX2 := destination.OffsetX + len(destination.Grid.CollisionGrid[0])
-			endY2 := destination.OffsetY + len(destination.Grid.CollisionGrid)
+	minX := min(origin.OffsetX, destination.OffsetX)
+	minY := min(origin.OffsetY, destination.OffsetY)
+	maxX := max(endX1, endX2)
+	maxY := max(endY1, endY2)
 
-			minX := min(origin.OffsetX, destination.OffsetX)
-			minY := min(origin.OffsetY, destination.OffsetY)
-			maxX := max(endX1, endX2)
-			maxY := max(endY1, endY2)
+	width := maxX - minX
+	height := maxY - minY
 
-			width := maxX - minX
-			height := maxY - minY
-
-			resultGrid := make([][]game.CollisionType, height)
-			for i := range resultGrid {
-				resultGrid[i] = make([]game.CollisionType, width)
-			}
-
-			// Let's copy both grids into the result grid
-			copyGrid(resultGrid, origin.CollisionGrid, origin.OffsetX-minX, origin.OffsetY-minY)
-			copyGrid(resultGrid, destination.CollisionGrid, destination.OffsetX-minX, destination.OffsetY-minY)
-
-			grid := game.NewGrid(resultGrid, minX, minY)
-
-			return grid, nil
-		}
+	resultGrid := make([][]game.CollisionType, height)
+	for i := range resultGrid {
+		resultGrid[i] = make([]game.CollisionType, width)
 	}
 
-	return nil, fmt.Errorf("destination grid not found")
+	// Let's copy both grids into the result grid
+	copyGrid(resultGrid, origin.CollisionGrid, origin.OffsetX-minX, origin.OffsetY-minY)
+	copyGrid(resultGrid, destination.CollisionGrid, destination.OffsetX-minX, destination.OffsetY-minY)
+
+	return game.NewGrid(resultGrid, minX, minY)
 }
 
 func copyGrid(dest [][]game.CollisionType, src [][]game.CollisionType, offsetX, offsetY int) {
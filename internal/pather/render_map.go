@@ -2,56 +2,57 @@ package pather
 
 import (
 	"fmt"
-	"image"
-	"image/color"
-	"image/draw"
-	"image/png"
 	"os"
 
 	"github.com/hectorgimenez/d2go/pkg/data"
 	"github.com/hectorgimenez/koolo/internal/game"
+	"github.com/hectorgimenez/koolo/internal/maprender"
 )
 
-func (pf *PathFinder) renderMap(grid *game.Grid, from, to data.Position, path Path) {
-	img := image.NewRGBA(image.Rect(0, 0, grid.Width, grid.Height))
-	draw.Draw(img, img.Bounds(), img, image.Point{}, draw.Over)
-
-	pathLocs := map[string]bool{}
-	for _, p := range path {
-		pathLocs[fmt.Sprintf("%d,%d", p.X, p.Y)] = true
+// DebugScene searches the path from the character to the destination and returns everything the path finder saw, the
+// path is empty if it can't be reached. It returns false if the destination is not in the current or an adjacent area
+func (pf *PathFinder) DebugScene(to data.Position) (maprender.Scene, bool) {
+	_, _, _, scene := pf.calculatePath(pf.data.PlayerUnit.Position, to, true)
+	if scene == nil {
+		return maprender.Scene{}, false
 	}
 
-	for x := 0; x < grid.Width; x++ {
-		for y := 0; y < grid.Height; y++ {
-			if pathLocs[fmt.Sprintf("%d,%d", x, y)] {
-				img.Set(x, y, color.RGBA{R: 36, G: 255, B: 0, A: 255})
-			} else {
-				switch grid.CollisionGrid[y][x] {
-				case game.CollisionTypeNonWalkable:
-					img.Set(x, y, color.Black)
-				case game.CollisionTypeWalkable:
-					img.Set(x, y, color.White)
-				case game.CollisionTypeLowPriority:
-					img.Set(x, y, color.RGBA{R: 200, G: 200, B: 200, A: 255}) // Gray
-				case game.CollisionTypeMonster:
-					img.Set(x, y, color.RGBA{R: 255, A: 255}) // Red
-				case game.CollisionTypeObject:
-					img.Set(x, y, color.RGBA{R: 160, G: 32, B: 240, A: 255}) // Purple
-				}
-			}
-		}
-	}
+	return *scene, true
+}
 
-	for _, r := range pf.data.Rooms {
-		pos := grid.RelativePosition(r.GetCenter())
-		img.Set(pos.X, pos.Y, color.RGBA{R: 204, G: 204, A: 255}) // Dark yellow
+// scene copies the grid, it's a cached one and the overlay will be restored after the search
+func (pf *PathFinder) scene(grid *game.Grid, from, to data.Position, path Path) maprender.Scene {
+	s := maprender.Scene{
+		Area:        pf.data.AreaData.Name,
+		Grid:        grid.Copy(),
+		Path:        maprender.PositionsFrom(grid, path),
+		Player:      from,
+		Destination: to,
+		Rooms:       pf.data.Rooms,
 	}
 
-	img.Set(from.X, from.Y, color.RGBA{R: 158, G: 0, B: 0, A: 255}) // Garnet
+	for _, o := range pf.data.AreaData.Objects {
+		s.Objects = append(s.Objects, maprender.Marker{Label: o.Name.Desc().Name, Position: o.Position})
+	}
+	for _, m := range pf.data.Monsters.Enemies() {
+		radius := 0
+		if pf.cfg.Character.AvoidDangerousMonsters {
+			radius = DangerRadius(m)
+		}
+		s.Monsters = append(s.Monsters, maprender.Marker{Label: fmt.Sprintf("Monster %d", m.Name), Position: m.Position, Radius: radius})
+	}
 
-	img.Set(to.X, to.Y, color.RGBA{R: 0, G: 0, B: 255, A: 255}) // Blue
+	return s
+}
 
-	outFile, _ := os.Create("cg.png")
+// renderMap writes the last search to cg.png. The scene is not saved, it would be written on every search, use
+// /api/debug/map?format=json to get one that can be rendered again with "koolo.exe render-map"
+func (pf *PathFinder) renderMap(s maprender.Scene) {
+	outFile, err := os.Create("cg.png")
+	if err != nil {
+		return
+	}
 	defer outFile.Close()
-	png.Encode(outFile, img)
+
+	maprender.Render(outFile, s, maprender.FormatPNG, 1)
 }
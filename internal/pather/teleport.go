@@ -0,0 +1,69 @@
+package pather
+
+import (
+	"github.com/hectorgimenez/d2go/pkg/data"
+	"github.com/hectorgimenez/koolo/internal/pather/astar"
+)
+
+// Max distance of a single teleport, clicking further away doesn't make us land further
+const maxTeleportDistance = 30
+
+// GetTeleportPath returns the landing points to reach the destination teleporting, minimizing the amount of casts.
+// Walls are ignored, but every landing point is walkable and visible on screen from the previous one. The distance is
+// the sum of the length of every teleport
+func (pf *PathFinder) GetTeleportPath(to data.Position) (Path, int, bool) {
+	// Same as walking, we try to land close to the destination if it's not walkable
+	if !pf.data.AreaData.IsWalkable(to) && pf.data.AreaData.IsInside(to) {
+		if walkableTo, found := pf.findNearbyWalkablePosition(to); found {
+			to = walkableTo
+		}
+	}
+
+	pf.mu.Lock()
+	defer pf.mu.Unlock()
+
+	cached, found := pf.cachedGrid(to, false)
+	if !found {
+		return nil, 0, false
+	}
+	grid := cached.grid
+
+	if pf.cfg.Character.AvoidDangerousMonsters {
+		o := newOverlay(grid)
+		defer o.restore()
+		o.addDangerZones(pf.data.Monsters)
+	}
+
+	path, found := astar.CalculateTeleportPath(grid, grid.RelativePosition(pf.data.PlayerUnit.Position), grid.RelativePosition(to), pf.canTeleportTo)
+	if !found {
+		return nil, 0, false
+	}
+
+	distance := 0
+	for i := 1; i < len(path); i++ {
+		distance += DistanceFromPoint(path[i-1], path[i])
+	}
+
+	return path, distance, true
+}
+
+// canTeleportTo returns if we can click on the given offset from the character position, same limits as MoveThroughPath
+func (pf *PathFinder) canTeleportTo(dx, dy int) bool {
+	if DistanceFromPoint(data.Position{}, data.Position{X: dx, Y: dy}) > maxTeleportDistance {
+		return false
+	}
+
+	screenX, screenY := pf.gameCoordsToScreenCords(0, 0, dx, dy)
+
+	return screenX >= 0 && screenY >= 0 && screenX <= pf.gr.GameAreaSizeX && screenY <= int(float32(pf.gr.GameAreaSizeY)/1.21)
+}
+
+// TeleportThroughPath casts teleport on the next landing point of a path returned by GetTeleportPath
+func (pf *PathFinder) TeleportThroughPath(p Path) {
+	if len(p) < 2 {
+		return
+	}
+
+	screenX, screenY := pf.gameCoordsToScreenCords(p.From().X, p.From().Y, p[1].X, p[1].Y)
+	pf.MoveCharacter(screenX, screenY)
+}
@@ -0,0 +1,136 @@
+package pickit
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+const defaultCaptureSizeMB = 10
+
+// Capture is a ground item seen by the bot and the pickup decision taken. Capture files can be used as corpus by the
+// simulator, the item and source fields are the same as in a Sample
+type Capture struct {
+	Sample
+	Time       time.Time `json:"time"`
+	Supervisor string    `json:"supervisor"`
+	Game       string    `json:"game,omitempty"`
+	Area       string    `json:"area"`
+	Run        string    `json:"run,omitempty"`
+	Quality    string    `json:"quality"`
+	Sockets    int       `json:"sockets,omitempty"`
+	Pickup     bool      `json:"pickup"`
+	Reason     string    `json:"reason"`
+	// Location (file:line) of the rule that decided the pickup
+	Rule string `json:"rule,omitempty"`
+}
+
+// Recorder appends captures to {dir}/captures/{supervisor}.jsonl. When the file reaches the max size it's renamed to
+// {supervisor}.1.jsonl, replacing the previous one, and a new file is started
+type Recorder struct {
+	path    string
+	maxSize int64
+
+	mu   sync.Mutex
+	file *os.File
+	size int64
+}
+
+func NewRecorder(dir, supervisor string, maxSizeMB int) (*Recorder, error) {
+	if dir == "" {
+		dir = "stats"
+	}
+	if maxSizeMB <= 0 {
+		maxSizeMB = defaultCaptureSizeMB
+	}
+
+	dir = filepath.Join(dir, "captures")
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		return nil, fmt.Errorf("error creating item capture directory: %w", err)
+	}
+
+	r := &Recorder{
+		path:    filepath.Join(dir, supervisor+".jsonl"),
+		maxSize: int64(maxSizeMB) * 1024 * 1024,
+	}
+	if err := r.open(); err != nil {
+		return nil, err
+	}
+
+	return r, nil
+}
+
+// Path returns the file currently written
+func (r *Recorder) Path() string {
+	return r.path
+}
+
+func (r *Recorder) Record(c Capture) error {
+	line, err := json.Marshal(c)
+	if err != nil {
+		return fmt.Errorf("error encoding item capture: %w", err)
+	}
+	line = append(line, '\n')
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.size > 0 && r.size+int64(len(line)) > r.maxSize {
+		if err = r.rotate(); err != nil {
+			return err
+		}
+	}
+
+	n, err := r.file.Write(line)
+	r.size += int64(n)
+	if err != nil {
+		return fmt.Errorf("error writing item capture: %w", err)
+	}
+
+	return nil
+}
+
+func (r *Recorder) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.file.Close()
+}
+
+func (r *Recorder) open() error {
+	f, err := os.OpenFile(r.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("error opening item capture file: %w", err)
+	}
+
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return fmt.Errorf("error opening item capture file: %w", err)
+	}
+
+	r.file = f
+	r.size = info.Size()
+
+	return nil
+}
+
+// rotate moves the current file to .1.jsonl, mutex must be held
+func (r *Recorder) rotate() error {
+	if err := r.file.Close(); err != nil {
+		return fmt.Errorf("error closing item capture file: %w", err)
+	}
+
+	previous := strings.TrimSuffix(r.path, ".jsonl") + ".1.jsonl"
+	if err := os.Rename(r.path, previous); err != nil {
+		// Keep writing to the same file, it will be rotated on the next capture
+		return errors.Join(fmt.Errorf("error rotating item capture file: %w", err), r.open())
+	}
+
+	return r.open()
+}
@@ -0,0 +1,50 @@
+package pickit
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/hectorgimenez/d2go/pkg/data/item"
+)
+
+func TestRecorderRotatesAndIsACorpus(t *testing.T) {
+	dir := t.TempDir()
+	r, err := NewRecorder(dir, "sorc", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Small enough to rotate after a few captures
+	r.maxSize = 1024
+
+	ring := sample("sorc mf-1 (Durance of Hate Level 2)", "Ring", item.QualityRare)
+	for range 10 {
+		err = r.Record(Capture{Sample: ring, Time: time.Now(), Supervisor: "sorc", Area: "Durance of Hate Level 2", Quality: "Rare", Reason: ReasonNoMatch})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err = r.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	current, err := LoadCorpus(filepath.Join(dir, "captures", "sorc.jsonl"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	previous, err := LoadCorpus(filepath.Join(dir, "captures", "sorc.1.jsonl"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(current) == 0 || len(previous) == 0 || len(current)+len(previous) > 10 {
+		t.Fatalf("unexpected rotation: %d current, %d previous", len(current), len(previous))
+	}
+	if current[0].Source != ring.Source || current[0].Item.ID != ring.Item.ID || current[0].Item.Quality != item.QualityRare {
+		t.Errorf("capture not readable as corpus: %+v", current[0])
+	}
+
+	if info, err := os.Stat(filepath.Join(dir, "captures", "sorc.jsonl")); err != nil || info.Size() > 1024 {
+		t.Errorf("file bigger than the max size: %v %v", info, err)
+	}
+}
@@ -0,0 +1,109 @@
+package pickit
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+
+	"github.com/hectorgimenez/d2go/pkg/data"
+	"github.com/hectorgimenez/koolo/internal/ledger"
+)
+
+// Sample is a recorded item used to test the rules, source tells where it comes from (ledger entry, capture file line...)
+type Sample struct {
+	Source string    `json:"source,omitempty"`
+	Item   data.Item `json:"item"`
+}
+
+// LoadCorpus reads the samples from a file, see ReadCorpus for the supported formats
+func LoadCorpus(path string) ([]Sample, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("error opening pickit corpus: %w", err)
+	}
+	defer f.Close()
+
+	return ReadCorpus(f)
+}
+
+// ReadCorpus reads a JSON array or one JSON object per line. Every element can be a drop ledger entry (drops.jsonl or
+// the json export), a ground item capture line with an "item" field, or a raw data.Item
+func ReadCorpus(r io.Reader) ([]Sample, error) {
+	content, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("error reading pickit corpus: %w", err)
+	}
+
+	var raws []json.RawMessage
+	content = bytes.TrimSpace(content)
+	if bytes.HasPrefix(content, []byte("[")) {
+		if err = json.Unmarshal(content, &raws); err != nil {
+			return nil, fmt.Errorf("error decoding pickit corpus: %w", err)
+		}
+	} else {
+		scanner := bufio.NewScanner(bytes.NewReader(content))
+		scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+		for scanner.Scan() {
+			if line := bytes.TrimSpace(scanner.Bytes()); len(line) > 0 {
+				raws = append(raws, bytes.Clone(line))
+			}
+		}
+		if err = scanner.Err(); err != nil {
+			return nil, fmt.Errorf("error reading pickit corpus: %w", err)
+		}
+	}
+
+	samples := make([]Sample, 0, len(raws))
+	for i, raw := range raws {
+		s, err := decodeSample(raw)
+		if err != nil {
+			return nil, fmt.Errorf("error decoding pickit corpus item %d: %w", i+1, err)
+		}
+		if s.Source == "" {
+			s.Source = "#" + strconv.Itoa(i+1)
+		}
+		samples = append(samples, s)
+	}
+
+	return samples, nil
+}
+
+func decodeSample(raw json.RawMessage) (Sample, error) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &fields); err != nil {
+		return Sample{}, err
+	}
+
+	if _, found := fields["ruleFile"]; found {
+		var e ledger.Entry
+		if err := json.Unmarshal(raw, &e); err != nil {
+			return Sample{}, err
+		}
+		return FromLedger([]ledger.Entry{e})[0], nil
+	}
+
+	if _, found := fields["item"]; found {
+		var s Sample
+		err := json.Unmarshal(raw, &s)
+		return s, err
+	}
+
+	var it data.Item
+	err := json.Unmarshal(raw, &it)
+
+	return Sample{Item: it}, err
+}
+
+// FromLedger converts drop ledger entries into samples
+func FromLedger(entries []ledger.Entry) []Sample {
+	samples := make([]Sample, 0, len(entries))
+	for _, e := range entries {
+		samples = append(samples, Sample{Source: fmt.Sprintf("ledger %d (%s)", e.ID, e.Supervisor), Item: e.Item()})
+	}
+
+	return samples
+}
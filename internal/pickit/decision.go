@@ -0,0 +1,175 @@
+// Package pickit decides which items are picked up and stashed, it's used by the bot and by the pickit simulator so
+// both always take the same decisions
+package pickit
+
+import (
+	"slices"
+	"strconv"
+
+	"github.com/hectorgimenez/d2go/pkg/data"
+	"github.com/hectorgimenez/d2go/pkg/data/item"
+	"github.com/hectorgimenez/d2go/pkg/nip"
+)
+
+const (
+	ReasonRuneword     = "Runeword"
+	ReasonQuestItem    = "Quest item"
+	ReasonBookOfSkill  = "Book of Skill"
+	ReasonGoldFull     = "Gold is full"
+	ReasonLevelingGold = "Leveling with low gold"
+	ReasonLowGold      = "Gold below minGoldPickupThreshold"
+	ReasonPartialMatch = "Partial match, not identified"
+	ReasonRuleMatch    = "Rule match"
+	ReasonMaxQuantity  = "Max quantity reached"
+	ReasonNoMatch      = "No rule match"
+	ReasonRecipe       = "Item is part of a enabled recipe"
+	ReasonNeverStashed = "Never stashed"
+	ReasonFirstRun     = "FirstRun"
+)
+
+// Quest items only picked up during leveling and quest runs
+var questItems = []item.Name{"Scrollofinifuss", "LamEsensTome", "HoradricCube", "AmuletoftheViper", "StaffofKings", "HoradricStaff", "AJadeFigurine", "KhalimsEye", "KhalimsBrain", "KhalimsHeart", "KhalimsFlail"}
+
+// State is everything besides the item and the rules taken into account to decide
+type State struct {
+	// Items in the personal and shared stash, used by maxquantity and recipes
+	Stash     []data.Item
+	Gold      int
+	MaxGold   int
+	TotalGold int
+	// Character settings
+	MinGoldPickupThreshold int
+	Leveling               bool
+	QuestRuns              bool
+	// Items used by the enabled cube recipes
+	RecipeItems []string
+	// First stash of the session, everything is stashed
+	FirstRun bool
+}
+
+// ExceedsQuantity returns true if the stash already has the max quantity of items matching the rule
+func ExceedsQuantity(rule nip.Rule, stash []data.Item) bool {
+	maxQuantity := rule.MaxQuantity()
+	if maxQuantity == 0 {
+		return false
+	}
+
+	matchedItemsInStash := 0
+	for _, stashItem := range stash {
+		res, _ := rule.Evaluate(stashItem)
+		if res == nip.RuleResultFullMatch {
+			matchedItemsInStash += 1
+		}
+	}
+
+	return matchedItemsInStash >= maxQuantity
+}
+
+// ShouldPickup returns if the item on the ground should be picked up, why and the location (file:line) of the rule that
+// decided it, if any
+func ShouldPickup(rules nip.Rules, i data.Item, st State) (bool, string, string) {
+	// Always pickup Runewords and Wirt's Leg
+	if i.IsRuneword || i.Name == "WirtsLeg" {
+		return true, ReasonRuneword, ""
+	}
+
+	// Pick up quest items if we're in leveling or questing run
+	if st.QuestRuns && slices.Contains(questItems, i.Name) {
+		return true, ReasonQuestItem, ""
+	}
+	if i.ID == 552 { // Book of Skill doesnt work by name, so we find it by ID
+		return true, ReasonBookOfSkill, ""
+	}
+	// Skip picking up gold if we can not carry more
+	if st.Gold >= st.MaxGold && i.Name == "Gold" {
+		return false, ReasonGoldFull, ""
+	}
+
+	// Skip picking up gold, usually early game there are small amounts of gold in many places full of enemies, better
+	// stay away of that
+	if st.Leveling && st.TotalGold < 50000 && i.Name != "Gold" {
+		return true, ReasonLevelingGold, ""
+	}
+
+	// Pickup all magic or superior items if total gold is low, filter will not pass and items will be sold to vendor
+	if st.TotalGold < st.MinGoldPickupThreshold && i.Quality >= item.QualityMagic {
+		return true, ReasonLowGold, ""
+	}
+
+	// Evaluate item based on NIP rules
+	matchedRule, result := rules.EvaluateAll(i)
+	if result == nip.RuleResultNoMatch {
+		return false, ReasonNoMatch, ""
+	}
+	if result == nip.RuleResultPartial {
+		return true, ReasonPartialMatch, RuleLocation(matchedRule)
+	}
+	if ExceedsQuantity(matchedRule, st.Stash) {
+		return false, ReasonMaxQuantity, RuleLocation(matchedRule)
+	}
+
+	return true, ReasonRuleMatch, RuleLocation(matchedRule)
+}
+
+// ShouldStash returns if the item in the inventory should be stashed, with the reason or the matched rule and its
+// location (file:line). Locked inventory slots are not checked here, they depend on the item position
+func ShouldStash(rules nip.Rules, i data.Item, st State) (bool, string, string) {
+	// Don't stash items from quests during leveling process, it makes things easier to track
+	if st.Leveling && i.IsFromQuest() {
+		return false, ReasonQuestItem, ""
+	}
+
+	if i.IsRuneword {
+		return true, ReasonRuneword, ""
+	}
+
+	// Stash items that are part of a recipe which are not covered by the NIP rules
+	if KeepRecipeItem(rules, i, st) {
+		return true, ReasonRecipe, ""
+	}
+
+	// Don't stash the Tomes, keys, WirtsLeg and potions
+	if i.Name == item.TomeOfTownPortal || i.Name == item.TomeOfIdentify || i.Name == item.Key || i.Name == "WirtsLeg" || i.IsPotion() {
+		return false, ReasonNeverStashed, ""
+	}
+
+	// Let's stash everything during first run, we don't want to sell items from the user
+	if st.FirstRun {
+		return true, ReasonFirstRun, ""
+	}
+
+	rule, res := rules.EvaluateAll(i)
+	if res != nip.RuleResultFullMatch {
+		return false, ReasonNoMatch, ""
+	}
+	if ExceedsQuantity(rule, st.Stash) {
+		return false, ReasonMaxQuantity, ""
+	}
+
+	return true, rule.RawLine, RuleLocation(rule)
+}
+
+// KeepRecipeItem returns true for normal and magic items used by an enabled recipe, unless the stash already has the
+// same item not matching any rule
+func KeepRecipeItem(rules nip.Rules, i data.Item, st State) bool {
+	// No items with quality higher than magic can be part of a recipe
+	if i.Quality > item.QualityMagic {
+		return false
+	}
+
+	// Check if we already have the item in our stash and if it doesn't match any of our pickit rules
+	for _, it := range st.Stash {
+		if it.Name == i.Name {
+			if _, res := rules.EvaluateAll(it); res != nip.RuleResultFullMatch {
+				return false
+			}
+		}
+	}
+
+	return slices.Contains(st.RecipeItems, string(i.Name))
+}
+
+// RuleLocation returns where the rule is defined, as file:line
+func RuleLocation(rule nip.Rule) string {
+	return rule.Filename + ":" + strconv.Itoa(rule.LineNumber)
+}
@@ -0,0 +1,186 @@
+package pickit
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/hectorgimenez/d2go/pkg/data"
+	"github.com/hectorgimenez/d2go/pkg/data/item"
+	"github.com/hectorgimenez/d2go/pkg/data/stat"
+	"github.com/hectorgimenez/d2go/pkg/nip"
+)
+
+func rules(t *testing.T, file string, lines ...string) nip.Rules {
+	t.Helper()
+
+	result := make(nip.Rules, 0, len(lines))
+	for i, line := range lines {
+		r, err := nip.NewRule(line, file, i+1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		result = append(result, r)
+	}
+
+	return result
+}
+
+func sample(source string, name item.Name, quality item.Quality) Sample {
+	return Sample{Source: source, Item: data.Item{ID: item.GetIDByName(string(name)), Name: name, Quality: quality, Identified: true}}
+}
+
+var richState = State{MaxGold: 1000000, TotalGold: 1000000, MinGoldPickupThreshold: 50000}
+
+func TestShouldPickup(t *testing.T) {
+	r := rules(t, "unique.nip", "[name] == shako && [quality] == unique")
+
+	tests := []struct {
+		name   string
+		item   data.Item
+		state  State
+		pickup bool
+		reason string
+	}{
+		{"rule match", sample("", "Shako", item.QualityUnique).Item, richState, true, ReasonRuleMatch},
+		{"no match", sample("", "Shako", item.QualityRare).Item, richState, false, ReasonNoMatch},
+		{"runeword", data.Item{Name: "Spirit", IsRuneword: true}, richState, true, ReasonRuneword},
+		{"gold full", data.Item{Name: "Gold"}, State{Gold: 10, MaxGold: 10}, false, ReasonGoldFull},
+		{"low gold", sample("", "Ring", item.QualityMagic).Item, State{MaxGold: 10, MinGoldPickupThreshold: 50000}, true, ReasonLowGold},
+		{"quest item", data.Item{Name: "HoradricCube"}, State{QuestRuns: true}, true, ReasonQuestItem},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pickup, reason, _ := ShouldPickup(r, tt.item, tt.state)
+			if pickup != tt.pickup || reason != tt.reason {
+				t.Errorf("got %v (%s), expected %v (%s)", pickup, reason, tt.pickup, tt.reason)
+			}
+		})
+	}
+}
+
+func TestSimulateMaxQuantityAndRecipes(t *testing.T) {
+	r := rules(t, "runes.nip", "[name] == berrune # # [maxquantity] == 1")
+	samples := []Sample{
+		sample("1", "BerRune", item.QualityNormal),
+		sample("2", "BerRune", item.QualityNormal),
+		sample("3", "Ring", item.QualityMagic),
+	}
+	st := richState
+	st.RecipeItems = []string{"Ring"}
+
+	decisions := Simulate(r, samples, st)
+	if !decisions[0].Stash || decisions[0].Rule != "runes.nip:1" {
+		t.Errorf("first ber should be stashed: %+v", decisions[0])
+	}
+	if decisions[1].Pickup || decisions[1].PickupReason != ReasonMaxQuantity || decisions[1].PickupRule != "runes.nip:1" {
+		t.Errorf("second ber should exceed max quantity: %+v", decisions[1])
+	}
+	// Recipe items are only stashed if something picks them up
+	if decisions[2].Pickup || decisions[2].Stash {
+		t.Errorf("ring should not be picked up: %+v", decisions[2])
+	}
+	if len(st.Stash) != 0 {
+		t.Errorf("simulation modified the stash: %v", st.Stash)
+	}
+
+	if s := Summarize(decisions); s != (Summary{Items: 3, Pickup: 1, Stash: 1}) {
+		t.Errorf("unexpected summary: %+v", s)
+	}
+}
+
+func TestEvaluateDiff(t *testing.T) {
+	current := rules(t, "unique.nip", "[name] == shako && [quality] == unique")
+	candidate := rules(t, "unique.nip", "[name] == shako && [quality] == unique", "[name] == ring && [quality] == unique")
+	samples := []Sample{
+		sample("shako", "Shako", item.QualityUnique),
+		sample("soj", "Ring", item.QualityUnique),
+	}
+
+	report := Evaluate(current, candidate, samples, richState)
+	if report.Summary.Stash != 1 || report.CandidateSummary == nil || report.CandidateSummary.Stash != 2 {
+		t.Fatalf("unexpected summaries: %+v %+v", report.Summary, report.CandidateSummary)
+	}
+	if len(report.Changes) != 1 || report.Changes[0].Source != "soj" || report.Changes[0].Old.Stash || report.Changes[0].New.Rule != "unique.nip:2" {
+		t.Errorf("unexpected changes: %+v", report.Changes)
+	}
+
+	if report = Evaluate(current, nil, samples, richState); report.CandidateSummary != nil || report.Changes != nil {
+		t.Errorf("no changes expected without candidate rules: %+v", report)
+	}
+}
+
+func TestRulesDirWithin(t *testing.T) {
+	root := t.TempDir()
+	config, central, outside := filepath.Join(root, "config"), filepath.Join(root, "central"), filepath.Join(root, "outside")
+	for _, dir := range []string{filepath.Join(config, "sorc", "pickit"), central, outside} {
+		if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+			t.Fatal(err)
+		}
+	}
+	link := filepath.Join(config, "link")
+	if err := os.Symlink(outside, link); err != nil {
+		t.Logf("symlinks not supported: %v", err)
+		link = ""
+	}
+
+	for dir, allowed := range map[string]bool{
+		filepath.Join(config, "sorc", "pickit"): true,
+		central:                                 true,
+		outside:                                 false,
+		filepath.Join(config, "..", "outside"):  false,
+		filepath.Join(config, "sorc", "..", "missing"): false,
+		root: false,
+	} {
+		if _, err := RulesDirWithin(dir, config, central); (err == nil) != allowed {
+			t.Errorf("%s: expected allowed %v, got error %v", dir, allowed, err)
+		}
+	}
+	if link != "" {
+		if _, err := RulesDirWithin(link, config); err == nil {
+			t.Error("symlinks pointing outside the roots should not be allowed")
+		}
+	}
+	if _, err := RulesDirWithin(central, config, ""); err == nil {
+		t.Error("an empty root should not allow anything")
+	}
+}
+
+func TestReadCorpus(t *testing.T) {
+	corpus := strings.Join([]string{
+		`{"id":7,"supervisor":"sorc","name":"Shako","quality":"Unique","identified":true,"rule":"[name] == shako","ruleFile":"unique.nip:1","stats":[{"name":"magicfind","value":50}]}`,
+		`{"source":"capture","item":{"ID":` + strconv.Itoa(item.GetIDByName("Ring")) + `,"Name":"Ring","Quality":4}}`,
+		``,
+		`{"ID":` + strconv.Itoa(item.GetIDByName("BerRune")) + `,"Name":"BerRune","Quality":2}`,
+	}, "\n")
+
+	samples, err := ReadCorpus(strings.NewReader(corpus))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(samples) != 3 {
+		t.Fatalf("expected 3 samples, got %d", len(samples))
+	}
+
+	shako := samples[0]
+	if shako.Source != "ledger 7 (sorc)" || shako.Item.ID != item.GetIDByName("Shako") || shako.Item.Quality != item.QualityUnique || !shako.Item.Identified {
+		t.Errorf("unexpected ledger sample: %+v", shako)
+	}
+	if mf, _ := shako.Item.FindStat(stat.MagicFind, 0); mf.Value != 50 {
+		t.Errorf("ledger stats not restored: %v", shako.Item.Stats)
+	}
+	if samples[1].Source != "capture" || samples[1].Item.Quality != item.QualityMagic {
+		t.Errorf("unexpected capture sample: %+v", samples[1])
+	}
+	if samples[2].Source != "#3" || samples[2].Item.Name != "BerRune" {
+		t.Errorf("unexpected item sample: %+v", samples[2])
+	}
+
+	// A json array, like the ledger export
+	samples, err = ReadCorpus(strings.NewReader(`[{"Name":"Ring"},{"Name":"Amulet"}]`))
+	if err != nil || len(samples) != 2 {
+		t.Errorf("error reading json array: %v %v", samples, err)
+	}
+}
@@ -0,0 +1,173 @@
+package pickit
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/hectorgimenez/d2go/pkg/nip"
+)
+
+// Decision is the result of the rules for a sample
+type Decision struct {
+	Source       string `json:"source"`
+	Name         string `json:"name"`
+	Quality      string `json:"quality"`
+	Ethereal     bool   `json:"ethereal"`
+	Pickup       bool   `json:"pickup"`
+	PickupReason string `json:"pickupReason"`
+	// Location (file:line) of the rule that decided the pickup
+	PickupRule string `json:"pickupRule,omitempty"`
+	Stash      bool   `json:"stash"`
+	// Matched rule or why the item is (not) stashed
+	StashReason string `json:"stashReason,omitempty"`
+	// Location (file:line) of the rule that decided the stash
+	Rule string `json:"rule,omitempty"`
+}
+
+// Change is a sample with a different decision between two rule sets
+type Change struct {
+	Source string   `json:"source"`
+	Name   string   `json:"name"`
+	Old    Decision `json:"old"`
+	New    Decision `json:"new"`
+}
+
+type Summary struct {
+	Items  int `json:"items"`
+	Pickup int `json:"pickup"`
+	Stash  int `json:"stash"`
+}
+
+// Report is the result of a simulation, changes are only set when comparing with a candidate rule set
+type Report struct {
+	Summary          Summary    `json:"summary"`
+	Decisions        []Decision `json:"decisions"`
+	CandidateSummary *Summary   `json:"candidateSummary,omitempty"`
+	Changes          []Change   `json:"changes,omitempty"`
+}
+
+// LoadRules reads every .nip file in the directory, same as the character pickit folder
+func LoadRules(dir string) (nip.Rules, error) {
+	if _, err := os.Stat(dir); err != nil {
+		return nil, fmt.Errorf("error reading pickit directory %s: %w", dir, err)
+	}
+
+	// nip.ReadDir concatenates the path and the file names
+	if !strings.HasSuffix(dir, string(filepath.Separator)) {
+		dir += string(filepath.Separator)
+	}
+
+	rules, err := nip.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("error reading pickit directory %s: %w", dir, err)
+	}
+
+	return rules, nil
+}
+
+// RulesDirWithin returns the absolute path of dir if it's one of the roots or inside them, symlinks are resolved so
+// they can't point outside
+func RulesDirWithin(dir string, roots ...string) (string, error) {
+	resolved, err := resolvePath(dir)
+	if err != nil {
+		return "", fmt.Errorf("error reading pickit directory %s: %w", dir, err)
+	}
+
+	for _, root := range roots {
+		if root == "" {
+			continue
+		}
+		resolvedRoot, err := resolvePath(root)
+		if err != nil {
+			continue
+		}
+		if rel, err := filepath.Rel(resolvedRoot, resolved); err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return resolved, nil
+		}
+	}
+
+	return "", fmt.Errorf("pickit directory %s is not inside the pickit directories", dir)
+}
+
+func resolvePath(path string) (string, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.EvalSymlinks(abs)
+}
+
+// Simulate evaluates the samples in order as if the bot found them on the ground. Items are only stashed if they are
+// picked up, and stashed items count for the maxquantity of the next ones. The stash of the given state is not modified
+func Simulate(rules nip.Rules, samples []Sample, st State) []Decision {
+	st.Stash = slices.Clone(st.Stash)
+
+	decisions := make([]Decision, 0, len(samples))
+	for _, s := range samples {
+		d := Decision{
+			Source:   s.Source,
+			Name:     string(s.Item.Name),
+			Quality:  s.Item.Quality.ToString(),
+			Ethereal: s.Item.Ethereal,
+		}
+
+		d.Pickup, d.PickupReason, d.PickupRule = ShouldPickup(rules, s.Item, st)
+		if d.Pickup {
+			d.Stash, d.StashReason, d.Rule = ShouldStash(rules, s.Item, st)
+		}
+		if d.Stash {
+			st.Stash = append(st.Stash, s.Item)
+		}
+
+		decisions = append(decisions, d)
+	}
+
+	return decisions
+}
+
+// Diff compares the decisions of two simulations over the same samples, a different matched rule is also a change
+func Diff(old, new []Decision) []Change {
+	changes := make([]Change, 0)
+	for i := range min(len(old), len(new)) {
+		o, n := old[i], new[i]
+		if o.Pickup != n.Pickup || o.Stash != n.Stash || o.Rule != n.Rule || o.StashReason != n.StashReason {
+			changes = append(changes, Change{Source: n.Source, Name: n.Name, Old: o, New: n})
+		}
+	}
+
+	return changes
+}
+
+func Summarize(decisions []Decision) Summary {
+	s := Summary{Items: len(decisions)}
+	for _, d := range decisions {
+		if d.Pickup {
+			s.Pickup++
+		}
+		if d.Stash {
+			s.Stash++
+		}
+	}
+
+	return s
+}
+
+// Evaluate simulates the samples with the rules and, if candidate is not nil, with the candidate rules too
+func Evaluate(rules, candidate nip.Rules, samples []Sample, st State) Report {
+	decisions := Simulate(rules, samples, st)
+	r := Report{Summary: Summarize(decisions), Decisions: decisions}
+	if candidate == nil {
+		return r
+	}
+
+	candidateDecisions := Simulate(candidate, samples, st)
+	candidateSummary := Summarize(candidateDecisions)
+	r.CandidateSummary = &candidateSummary
+	r.Changes = Diff(decisions, candidateDecisions)
+
+	return r
+}
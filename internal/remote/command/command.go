@@ -0,0 +1,241 @@
+// Package command implements the bot commands shared by the chat integrations. Integrations parse the user input into
+// a Request, run it with the Registry and render the returned Messages in their own format, so they only deal with
+// the transport
+package command
+
+import (
+	"fmt"
+	"image"
+	"slices"
+	"strings"
+
+	"github.com/hectorgimenez/d2go/pkg/data"
+	"github.com/hectorgimenez/koolo/internal/remote/hub"
+)
+
+// Manager is the part of the supervisor manager used by the commands
+type Manager interface {
+	AvailableSupervisors() []string
+	Start(supervisorName string, attachToExisting bool, pidHwnd ...uint32) error
+	Stop(supervisor string)
+	TogglePause(supervisor string)
+	// HubStatus returns the stats summary of every supervisor
+	HubStatus() []hub.SupervisorStatus
+	Drops(supervisor string) []data.Drop
+	Screenshot(supervisor string) (image.Image, error)
+}
+
+type Request struct {
+	// ID of the user in the integration, checked against the admins
+	User    string
+	Command string
+	Args    []string
+	// Reply sends messages once the command already returned, like start errors. Optional
+	Reply func(Message)
+}
+
+// Parse parses a command line like "/stats sorc barb", the slash is optional and a bot name suffix like
+// "/stats@koolo_bot" is removed
+func Parse(user, text string) Request {
+	words := strings.Fields(text)
+	if len(words) == 0 {
+		return Request{User: user}
+	}
+
+	name, _, _ := strings.Cut(strings.TrimPrefix(words[0], "/"), "@")
+
+	return Request{User: user, Command: strings.ToLower(name), Args: words[1:]}
+}
+
+type ButtonStyle int
+
+const (
+	ButtonNormal ButtonStyle = iota
+	ButtonPositive
+	ButtonDanger
+)
+
+type Button struct {
+	Label string
+	Style ButtonStyle
+	// Command line run when the button is pressed, see Parse
+	Action string
+}
+
+type Field struct {
+	Name   string
+	Value  string
+	Inline bool
+}
+
+// Message is a reply to a command, integrations render the parts they support
+type Message struct {
+	Title  string
+	Text   string
+	Fields []Field
+	// RGB color, 0 for the default one
+	Color   int
+	Image   image.Image
+	Buttons []Button
+	// Only visible to the user running the command, when the integration supports it
+	Private bool
+}
+
+// PlainText renders the message for integrations without rich messages
+func (m Message) PlainText() string {
+	lines := make([]string, 0, len(m.Fields)+2)
+	if m.Title != "" {
+		lines = append(lines, m.Title)
+	}
+	for _, f := range m.Fields {
+		lines = append(lines, f.Name+": "+f.Value)
+	}
+	if m.Text != "" {
+		lines = append(lines, m.Text)
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+func text(format string, args ...any) Message {
+	return Message{Text: fmt.Sprintf(format, args...)}
+}
+
+type Command struct {
+	Name        string
+	Description string
+	// The command takes supervisor names as arguments, it runs once per supervisor and without arguments a message
+	// with a button per supervisor is returned to pick one
+	Supervisor bool
+	// Allowed to everyone, otherwise only admins can run it
+	Public bool
+
+	run func(req Request) []Message
+	// Used by commands taking supervisors
+	runFor func(req Request, status hub.SupervisorStatus) Message
+}
+
+// Registry runs the commands, permissions are checked against the admins user IDs
+type Registry struct {
+	manager  Manager
+	admins   []string
+	commands []Command
+}
+
+func NewRegistry(manager Manager, admins []string) *Registry {
+	r := &Registry{
+		manager: manager,
+		admins:  slices.DeleteFunc(slices.Clone(admins), func(a string) bool { return strings.TrimSpace(a) == "" }),
+	}
+	r.commands = r.supervisorCommands()
+
+	return r
+}
+
+// Commands returns the available commands, integrations use it to register them
+func (r *Registry) Commands() []Command {
+	return slices.Clone(r.commands)
+}
+
+func (r *Registry) find(name string) (Command, bool) {
+	idx := slices.IndexFunc(r.commands, func(c Command) bool { return c.Name == name })
+	if idx < 0 {
+		return Command{}, false
+	}
+
+	return r.commands[idx], true
+}
+
+// Allowed checks if the user can run the command, unknown commands are only allowed to admins. Nobody is an admin
+// if there are no admins configured
+func (r *Registry) Allowed(req Request) bool {
+	if c, found := r.find(req.Command); found && c.Public {
+		return true
+	}
+
+	return req.User != "" && slices.Contains(r.admins, req.User)
+}
+
+// Execute runs the command and returns the messages to reply with
+func (r *Registry) Execute(req Request) []Message {
+	if !r.Allowed(req) {
+		return []Message{{Text: "You are not allowed to use this command.", Private: true}}
+	}
+
+	c, found := r.find(req.Command)
+	if !found {
+		return []Message{r.usage()}
+	}
+
+	if !c.Supervisor {
+		return c.run(req)
+	}
+
+	if len(req.Args) == 0 {
+		return []Message{r.supervisorPicker(c.Name)}
+	}
+
+	messages := make([]Message, 0, len(req.Args))
+	for _, supervisor := range req.Args {
+		status, found := r.status(supervisor)
+		if !found {
+			messages = append(messages, text("Supervisor '%s' not found.", supervisor))
+			continue
+		}
+		messages = append(messages, c.runFor(req, status))
+	}
+
+	return messages
+}
+
+// Complete returns the supervisors containing the text typed so far, for integrations with autocompletion
+func (r *Registry) Complete(typed string) []string {
+	supervisors := r.manager.AvailableSupervisors()
+	slices.Sort(supervisors)
+
+	return slices.DeleteFunc(supervisors, func(s string) bool {
+		return !strings.Contains(strings.ToLower(s), strings.ToLower(typed))
+	})
+}
+
+func (r *Registry) usage() Message {
+	lines := make([]string, 0, len(r.commands))
+	for _, c := range r.commands {
+		line := "/" + c.Name
+		if c.Supervisor {
+			line += " [supervisor...]"
+		}
+		lines = append(lines, line+" - "+c.Description)
+	}
+
+	return Message{Title: "Commands", Text: strings.Join(lines, "\n")}
+}
+
+func (r *Registry) supervisorPicker(command string) Message {
+	supervisors := r.manager.AvailableSupervisors()
+	if len(supervisors) == 0 {
+		return text("There are no supervisors configured.")
+	}
+	slices.Sort(supervisors)
+
+	m := text("Pick a supervisor for /%s", command)
+	for _, supervisor := range supervisors {
+		m.Buttons = append(m.Buttons, Button{Label: supervisor, Action: command + " " + supervisor})
+	}
+
+	return m
+}
+
+func (r *Registry) status(supervisor string) (hub.SupervisorStatus, bool) {
+	if !slices.Contains(r.manager.AvailableSupervisors(), supervisor) {
+		return hub.SupervisorStatus{}, false
+	}
+
+	for _, s := range r.manager.HubStatus() {
+		if s.Name == supervisor {
+			return s, true
+		}
+	}
+
+	return hub.SupervisorStatus{Name: supervisor, Status: statusNotStarted}, true
+}
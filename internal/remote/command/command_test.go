@@ -0,0 +1,181 @@
+package command
+
+import (
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/hectorgimenez/d2go/pkg/data"
+	"github.com/hectorgimenez/d2go/pkg/data/item"
+	"github.com/hectorgimenez/koolo/internal/remote/command/commandtest"
+	"github.com/hectorgimenez/koolo/internal/remote/hub"
+)
+
+const admin = "7"
+
+func testRegistry() (*Registry, *commandtest.Manager) {
+	manager := &commandtest.Manager{NoWindow: []string{"paladin"}, Statuses: []hub.SupervisorStatus{
+		{Name: "sorc", Status: "In game", StartedAt: time.Now().Add(-time.Hour), Run: "mephisto", Games: 12, Drops: 3, Deaths: 1},
+		{Name: "barb", Status: "Not Started"},
+		{Name: "paladin", Status: "Paused"},
+	}}
+
+	return NewRegistry(manager, []string{admin, ""}), manager
+}
+
+func run(r *Registry, line string) []Message {
+	return r.Execute(Parse(admin, line))
+}
+
+func plainTexts(messages []Message) []string {
+	texts := make([]string, 0, len(messages))
+	for _, m := range messages {
+		texts = append(texts, m.PlainText())
+	}
+	return texts
+}
+
+func actions(m Message) []string {
+	var a []string
+	for _, b := range m.Buttons {
+		a = append(a, b.Label+" -> "+b.Action)
+	}
+	return a
+}
+
+func TestParse(t *testing.T) {
+	for line, expected := range map[string]Request{
+		"/stats sorc barb":       {User: "1", Command: "stats", Args: []string{"sorc", "barb"}},
+		"/Stats@koolo_bot  sorc": {User: "1", Command: "stats", Args: []string{"sorc"}},
+		"status":                 {User: "1", Command: "status", Args: []string{}},
+		"   ":                    {User: "1"},
+	} {
+		req := Parse("1", line)
+		if req.User != expected.User || req.Command != expected.Command || !slices.Equal(req.Args, expected.Args) {
+			t.Errorf("%q: expected %+v, got %+v", line, expected, req)
+		}
+	}
+}
+
+func TestPermissions(t *testing.T) {
+	r, manager := testRegistry()
+
+	for _, user := range []string{"8", ""} {
+		req := Parse(user, "/stop sorc")
+		if r.Allowed(req) {
+			t.Errorf("user %q should not be allowed", user)
+		}
+		if m := r.Execute(req); len(m) != 1 || !m[0].Private || m[0].Text != "You are not allowed to use this command." {
+			t.Errorf("unexpected reply %+v", m)
+		}
+	}
+	if len(manager.Stopped()) != 0 {
+		t.Errorf("nothing should be stopped: %v", manager.Stopped())
+	}
+
+	if !r.Allowed(Parse("8", "/help")) {
+		t.Errorf("help should be public")
+	}
+	if r.Allowed(Parse("8", "/unknown")) || !r.Allowed(Parse(admin, "/unknown")) {
+		t.Errorf("unknown commands should be allowed to admins only")
+	}
+	if NewRegistry(manager, []string{""}).Allowed(Parse("", "/stop sorc")) {
+		t.Errorf("nobody should be allowed without admins")
+	}
+}
+
+func TestSupervisorCommands(t *testing.T) {
+	r, manager := testRegistry()
+	manager.PickedUp = []data.Drop{{Item: data.Item{Name: "BerRune", Quality: item.QualityNormal}, Rule: "[name] == berrune"}}
+
+	for line, expected := range map[string][]string{
+		"/stats sorc ghost": {
+			"Stats for sorc\nStatus: In game\nUptime: 1h0m0s\nGames: 12\nDrops: 3\nDeaths: 1\nChickens: 0\nErrors: 0",
+			"Supervisor 'ghost' not found.",
+		},
+		"/status barb":     {"Status of barb\nStatus: Offline\nUptime: -"},
+		"/drops sorc":      {"Drops for sorc (1)\nBerRune [Normal] - [name] == berrune"},
+		"/start sorc":      {"Supervisor 'sorc' is already running."},
+		"/stop barb":       {"Supervisor 'barb' is not running."},
+		"/screenshot barb": {"Supervisor 'barb' is not running."},
+		"/screenshot paladin": {
+			"Screenshot for 'paladin' could not be taken: window not found",
+		},
+	} {
+		if texts := plainTexts(run(r, line)); !slices.Equal(texts, expected) {
+			t.Errorf("%s: expected %q, got %q", line, expected, texts)
+		}
+	}
+
+	if m := run(r, "/screenshot sorc"); len(m) != 1 || m[0].Image == nil || m[0].Text != "sorc" {
+		t.Errorf("unexpected screenshot %+v", m)
+	}
+}
+
+func TestStatusButtons(t *testing.T) {
+	r, manager := testRegistry()
+
+	status := run(r, "/status sorc")[0]
+	if status.Fields[2].Value != "mephisto" || !slices.Equal(actions(status), []string{"Pause -> pause sorc", "Stop -> stop sorc"}) {
+		t.Fatalf("unexpected status %+v", status)
+	}
+
+	// Pressing the buttons runs their actions
+	paused := run(r, status.Buttons[0].Action)[0]
+	if !slices.Equal(manager.Paused(), []string{"sorc"}) || paused.Fields[0].Value != "Paused" || paused.Text != "Supervisor 'sorc' has been paused." {
+		t.Errorf("sorc should be paused, paused: %v, status: %+v", manager.Paused(), paused)
+	}
+	if !slices.Equal(actions(paused), []string{"Resume -> pause sorc", "Stop -> stop sorc"}) {
+		t.Errorf("unexpected buttons %v", actions(paused))
+	}
+	if resumed := run(r, "pause paladin")[0]; resumed.Fields[0].Value != "In game" || resumed.Buttons[0].Label != "Pause" {
+		t.Errorf("paladin should be resumed: %+v", resumed)
+	}
+
+	stopped := run(r, status.Buttons[1].Action)[0]
+	if !slices.Equal(manager.Stopped(), []string{"sorc"}) || stopped.Fields[0].Value != "Offline" || len(stopped.Buttons) != 0 {
+		t.Errorf("sorc should be stopped, stopped: %v, status: %+v", manager.Stopped(), stopped)
+	}
+}
+
+func TestStartErrorsAreReplied(t *testing.T) {
+	r, manager := testRegistry()
+	manager.StartErr = errors.New("max concurrent supervisors reached (1)")
+
+	replies := make(chan Message, 1)
+	req := Parse(admin, "/start barb")
+	req.Reply = func(m Message) { replies <- m }
+	if texts := plainTexts(r.Execute(req)); !slices.Equal(texts, []string{"Starting supervisor 'barb'."}) {
+		t.Errorf("unexpected reply %q", texts)
+	}
+
+	select {
+	case m := <-replies:
+		if m.Text != "Supervisor 'barb' could not be started: max concurrent supervisors reached (1)" {
+			t.Errorf("unexpected reply %q", m.Text)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("start error was not replied")
+	}
+}
+
+func TestSupervisorPickerAndCompletion(t *testing.T) {
+	r, _ := testRegistry()
+
+	picker := run(r, "/stats")
+	if len(picker) != 1 || !slices.Equal(actions(picker[0]), []string{"barb -> stats barb", "paladin -> stats paladin", "sorc -> stats sorc"}) {
+		t.Errorf("unexpected picker %+v", picker)
+	}
+
+	if c := r.Complete("A"); !slices.Equal(c, []string{"barb", "paladin"}) {
+		t.Errorf("unexpected completion %v", c)
+	}
+	if c := r.Complete(""); len(c) != 3 {
+		t.Errorf("all the supervisors should be suggested when nothing is typed, got %v", c)
+	}
+
+	if help := run(r, "/what"); len(help) != 1 || help[0].Title != "Commands" {
+		t.Errorf("unknown commands should reply with the usage: %+v", help)
+	}
+}
@@ -0,0 +1,93 @@
+// Package commandtest provides a fake command.Manager for the tests of the chat integrations
+package commandtest
+
+import (
+	"errors"
+	"image"
+	"slices"
+	"sync"
+
+	"github.com/hectorgimenez/d2go/pkg/data"
+	"github.com/hectorgimenez/koolo/internal/remote/hub"
+)
+
+// Manager records the supervisors started, stopped and paused, the available supervisors are the ones in Statuses.
+// Fields can be changed between commands, calls can come from other goroutines (start runs in the background)
+type Manager struct {
+	// Returned by HubStatus
+	Statuses []hub.SupervisorStatus
+	// Returned by Drops for every supervisor
+	PickedUp []data.Drop
+	// Returned by Start
+	StartErr error
+	// Supervisors without game window, their screenshot fails
+	NoWindow []string
+
+	mu      sync.Mutex
+	started []string
+	stopped []string
+	paused  []string
+}
+
+func (m *Manager) AvailableSupervisors() []string {
+	names := make([]string, 0, len(m.Statuses))
+	for _, s := range m.Statuses {
+		names = append(names, s.Name)
+	}
+	return names
+}
+
+func (m *Manager) Start(supervisorName string, _ bool, _ ...uint32) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.started = append(m.started, supervisorName)
+	return m.StartErr
+}
+
+func (m *Manager) Stop(supervisor string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.stopped = append(m.stopped, supervisor)
+}
+
+func (m *Manager) TogglePause(supervisor string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.paused = append(m.paused, supervisor)
+}
+
+func (m *Manager) HubStatus() []hub.SupervisorStatus {
+	return m.Statuses
+}
+
+func (m *Manager) Drops(string) []data.Drop {
+	return m.PickedUp
+}
+
+func (m *Manager) Screenshot(supervisor string) (image.Image, error) {
+	if slices.Contains(m.NoWindow, supervisor) {
+		return nil, errors.New("window not found")
+	}
+	return image.NewRGBA(image.Rect(0, 0, 8, 8)), nil
+}
+
+// Started returns the supervisors started so far, in order
+func (m *Manager) Started() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return slices.Clone(m.started)
+}
+
+// Stopped returns the supervisors stopped so far, in order
+func (m *Manager) Stopped() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return slices.Clone(m.stopped)
+}
+
+// Paused returns the supervisors paused or resumed so far, in order
+func (m *Manager) Paused() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return slices.Clone(m.paused)
+}
@@ -0,0 +1,192 @@
+package command
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/hectorgimenez/koolo/internal/remote/hub"
+)
+
+const (
+	// Supervisor statuses, see bot.SupervisorStatus
+	statusNotStarted = "Not Started"
+	statusInGame     = "In game"
+	statusPaused     = "Paused"
+
+	// Chat messages have a length limit, only the latest drops are listed
+	maxDropsListed = 20
+
+	colorOffline = 0x95a5a6
+	colorPaused  = 0xf1c40f
+	colorRunning = 0x2ecc71
+)
+
+func (r *Registry) supervisorCommands() []Command {
+	return []Command{
+		{Name: "start", Description: "Start a supervisor", Supervisor: true, runFor: r.start},
+		{Name: "stop", Description: "Stop a supervisor", Supervisor: true, runFor: r.stop},
+		{Name: "pause", Description: "Pause or resume a supervisor", Supervisor: true, runFor: r.pause},
+		{Name: "status", Description: "Show the status of a supervisor, with buttons to pause, resume or stop it", Supervisor: true, runFor: r.statusMessage},
+		{Name: "stats", Description: "Show the stats of a supervisor for the current session", Supervisor: true, runFor: r.stats},
+		{Name: "drops", Description: "List the items found by a supervisor in the current session", Supervisor: true, runFor: r.drops},
+		{Name: "screenshot", Description: "Take a screenshot of the game of a supervisor", Supervisor: true, runFor: r.screenshot},
+		{Name: "help", Description: "List the available commands", Public: true, run: func(Request) []Message { return []Message{r.usage()} }},
+	}
+}
+
+func isRunning(status hub.SupervisorStatus) bool {
+	return status.Status != statusNotStarted && status.Status != ""
+}
+
+func (r *Registry) start(req Request, status hub.SupervisorStatus) Message {
+	if isRunning(status) {
+		return text("Supervisor '%s' is already running.", status.Name)
+	}
+
+	// Starting the game takes a while, the integrations have to answer sooner
+	go func() {
+		if err := r.manager.Start(status.Name, false); err != nil && req.Reply != nil {
+			req.Reply(text("Supervisor '%s' could not be started: %s", status.Name, err.Error()))
+		}
+	}()
+
+	return text("Starting supervisor '%s'.", status.Name)
+}
+
+func (r *Registry) stop(_ Request, status hub.SupervisorStatus) Message {
+	if !isRunning(status) {
+		return text("Supervisor '%s' is not running.", status.Name)
+	}
+
+	r.manager.Stop(status.Name)
+	status.Status = statusNotStarted
+	m := r.statusMessage(Request{}, status)
+	m.Text = fmt.Sprintf("Supervisor '%s' has been stopped.", status.Name)
+
+	return m
+}
+
+// pause toggles the pause and returns the new status, the supervisor doesn't report it right away so it's guessed
+func (r *Registry) pause(_ Request, status hub.SupervisorStatus) Message {
+	if !isRunning(status) {
+		return text("Supervisor '%s' is not running.", status.Name)
+	}
+
+	r.manager.TogglePause(status.Name)
+	action := "paused"
+	if status.Status == statusPaused {
+		status.Status, action = statusInGame, "resumed"
+	} else {
+		status.Status = statusPaused
+	}
+	m := r.statusMessage(Request{}, status)
+	m.Text = fmt.Sprintf("Supervisor '%s' has been %s.", status.Name, action)
+
+	return m
+}
+
+func (r *Registry) statusMessage(_ Request, status hub.SupervisorStatus) Message {
+	m := Message{
+		Title: fmt.Sprintf("Status of %s", status.Name),
+		Color: statusColor(status),
+		Fields: []Field{
+			{Name: "Status", Value: statusText(status), Inline: true},
+			{Name: "Uptime", Value: uptime(status), Inline: true},
+		},
+	}
+	if status.Run != "" && isRunning(status) {
+		m.Fields = append(m.Fields, Field{Name: "Run", Value: status.Run, Inline: true})
+	}
+
+	if isRunning(status) {
+		pause := Button{Label: "Pause", Action: "pause " + status.Name}
+		if status.Status == statusPaused {
+			pause.Label, pause.Style = "Resume", ButtonPositive
+		}
+		m.Buttons = []Button{pause, {Label: "Stop", Style: ButtonDanger, Action: "stop " + status.Name}}
+	}
+
+	return m
+}
+
+func (r *Registry) stats(_ Request, status hub.SupervisorStatus) Message {
+	return Message{
+		Title: fmt.Sprintf("Stats for %s", status.Name),
+		Color: statusColor(status),
+		Fields: []Field{
+			{Name: "Status", Value: statusText(status), Inline: true},
+			{Name: "Uptime", Value: uptime(status), Inline: true},
+			{Name: "Games", Value: fmt.Sprintf("%d", status.Games), Inline: true},
+			{Name: "Drops", Value: fmt.Sprintf("%d", status.Drops), Inline: true},
+			{Name: "Deaths", Value: fmt.Sprintf("%d", status.Deaths), Inline: true},
+			{Name: "Chickens", Value: fmt.Sprintf("%d", status.Chickens), Inline: true},
+			{Name: "Errors", Value: fmt.Sprintf("%d", status.Errors), Inline: true},
+		},
+	}
+}
+
+func (r *Registry) drops(_ Request, status hub.SupervisorStatus) Message {
+	drops := r.manager.Drops(status.Name)
+	if len(drops) == 0 {
+		return text("No drops for '%s' yet.", status.Name)
+	}
+
+	m := Message{Title: fmt.Sprintf("Drops for %s (%d)", status.Name, len(drops))}
+	if len(drops) > maxDropsListed {
+		m.Title += fmt.Sprintf(", latest %d", maxDropsListed)
+		drops = drops[len(drops)-maxDropsListed:]
+	}
+
+	lines := make([]string, 0, len(drops))
+	for _, d := range drops {
+		line := fmt.Sprintf("%s [%s]", d.Item.Name, d.Item.Quality.ToString())
+		if d.Rule != "" {
+			line += " - " + d.Rule
+		}
+		lines = append(lines, line)
+	}
+	m.Text = strings.Join(lines, "\n")
+
+	return m
+}
+
+func (r *Registry) screenshot(_ Request, status hub.SupervisorStatus) Message {
+	if !isRunning(status) {
+		return text("Supervisor '%s' is not running.", status.Name)
+	}
+
+	img, err := r.manager.Screenshot(status.Name)
+	if err != nil {
+		return text("Screenshot for '%s' could not be taken: %s", status.Name, err.Error())
+	}
+
+	return Message{Text: status.Name, Image: img}
+}
+
+func statusText(status hub.SupervisorStatus) string {
+	if !isRunning(status) {
+		return "Offline"
+	}
+
+	return status.Status
+}
+
+func uptime(status hub.SupervisorStatus) string {
+	if !isRunning(status) || status.StartedAt.IsZero() {
+		return "-"
+	}
+
+	return time.Since(status.StartedAt).Round(time.Second).String()
+}
+
+func statusColor(status hub.SupervisorStatus) int {
+	switch {
+	case !isRunning(status):
+		return colorOffline
+	case status.Status == statusPaused:
+		return colorPaused
+	default:
+		return colorRunning
+	}
+}
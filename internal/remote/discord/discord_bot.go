@@ -3,21 +3,21 @@ package discord
 import (
 	"context"
 	"fmt"
-	"slices"
-	"strings"
+	"log/slog"
 
 	"github.com/bwmarrin/discordgo"
-	"github.com/hectorgimenez/koolo/internal/bot"
 	"github.com/hectorgimenez/koolo/internal/config"
+	"github.com/hectorgimenez/koolo/internal/remote/command"
 )
 
 type Bot struct {
 	discordSession *discordgo.Session
 	channelID      string
-	manager        *bot.SupervisorManager
+	commands       *command.Registry
+	logger         *slog.Logger
 }
 
-func NewBot(token, channelID string, manager *bot.SupervisorManager) (*Bot, error) {
+func NewBot(token, channelID string, commands *command.Registry, logger *slog.Logger) (*Bot, error) {
 	dg, err := discordgo.New("Bot " + token)
 	if err != nil {
 		return nil, fmt.Errorf("error creating Discord session: %w", err)
@@ -26,45 +26,30 @@ func NewBot(token, channelID string, manager *bot.SupervisorManager) (*Bot, erro
 	return &Bot{
 		discordSession: dg,
 		channelID:      channelID,
-		manager:        manager,
+		commands:       commands,
+		logger:         logger,
 	}, nil
 }
 
 func (b *Bot) Start(ctx context.Context) error {
 	//b.discordSession.Debug = true
-	b.discordSession.AddHandler(b.onMessageCreated)
-	b.discordSession.Identify.Intents = discordgo.IntentsGuildMessages
+	b.discordSession.AddHandler(b.onInteractionCreated)
+	// Slash commands don't need to read the messages
+	b.discordSession.Identify.Intents = discordgo.IntentsGuilds
 	err := b.discordSession.Open()
 	if err != nil {
 		return fmt.Errorf("error opening connection: %w", err)
 	}
 
+	// Registering replaces the commands of previous versions
+	_, err = b.discordSession.ApplicationCommandBulkOverwrite(b.discordSession.State.User.ID, config.Koolo.Discord.GuildID, slashCommands(b.commands.Commands()))
+	if err != nil {
+		b.discordSession.Close()
+		return fmt.Errorf("error registering slash commands: %w", err)
+	}
+
 	// Wait until context is finished
 	<-ctx.Done()
 
 	return b.discordSession.Close()
 }
-
-func (b *Bot) onMessageCreated(s *discordgo.Session, m *discordgo.MessageCreate) {
-	if m.Author.ID == s.State.User.ID {
-		return
-	}
-
-	// Check if the message is from a bot admin
-	if !slices.Contains(config.Koolo.Discord.BotAdmins, m.Author.ID) {
-		return
-	}
-
-	prefix := strings.Split(m.Content, " ")[0]
-	switch prefix {
-	case "!start":
-		b.handleStartRequest(s, m)
-	case "!stop":
-		b.handleStopRequest(s, m)
-	case "!stats":
-		b.handleStatsRequest(s, m)
-	case "!status":
-		b.handleStatusRequest(s, m)
-	}
-
-}
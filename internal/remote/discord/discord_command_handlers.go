@@ -1,181 +1,191 @@
 package discord
 
 import (
-	"fmt"
+	"bytes"
+	"image/jpeg"
+	"log/slog"
 	"slices"
-	"strings"
-	"time"
 
 	"github.com/bwmarrin/discordgo"
-	"github.com/hectorgimenez/koolo/internal/bot"
+	"github.com/hectorgimenez/koolo/internal/remote/command"
 )
 
-func (b *Bot) supervisorExists(supervisor string) bool {
-	supervisors := b.manager.AvailableSupervisors()
-	return slices.Contains(supervisors, supervisor)
-}
+const (
+	supervisorOption = "supervisor"
+	// Discord limits
+	maxChoices       = 25
+	maxButtonsPerRow = 5
+	maxButtonRows    = 5
+)
 
-func (b *Bot) handleStartRequest(s *discordgo.Session, m *discordgo.MessageCreate) {
+func slashCommands(commands []command.Command) []*discordgo.ApplicationCommand {
+	slash := make([]*discordgo.ApplicationCommand, 0, len(commands))
+	for _, c := range commands {
+		ac := &discordgo.ApplicationCommand{Name: c.Name, Description: c.Description}
+		// Optional, without it the command answers with a button per supervisor
+		if c.Supervisor {
+			ac.Options = []*discordgo.ApplicationCommandOption{{
+				Type:         discordgo.ApplicationCommandOptionString,
+				Name:         supervisorOption,
+				Description:  "Supervisor name, empty to pick it from a list",
+				Autocomplete: true,
+			}}
+		}
+		slash = append(slash, ac)
+	}
 
-	// Start supervisor(s) provided as in the stop command
-	words := strings.Fields(m.Content)
+	return slash
+}
 
-	if len(words) > 1 {
-		// Iterate through the supervisors specified
-		for _, supervisor := range words[1:] {
+func (b *Bot) onInteractionCreated(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	user := interactionUser(i)
 
-			if !b.supervisorExists(supervisor) {
-				s.ChannelMessageSend(m.ChannelID, fmt.Sprintf("Supervisor '%s' not found.", supervisor))
-				continue
+	switch i.Type {
+	case discordgo.InteractionApplicationCommandAutocomplete:
+		data := i.ApplicationCommandData()
+		// No suggestions for users not allowed to run the command
+		if !b.commands.Allowed(command.Request{User: user, Command: data.Name}) {
+			return
+		}
+		for _, opt := range data.Options {
+			if opt.Focused {
+				b.respond(s, i, discordgo.InteractionApplicationCommandAutocompleteResult, &discordgo.InteractionResponseData{
+					Choices: choices(b.commands.Complete(opt.StringValue())),
+				})
+				return
+			}
+		}
+	case discordgo.InteractionApplicationCommand:
+		data := i.ApplicationCommandData()
+		req := command.Request{User: user, Command: data.Name}
+		for _, opt := range data.Options {
+			if opt.Name == supervisorOption && opt.StringValue() != "" {
+				req.Args = append(req.Args, opt.StringValue())
 			}
-
-			// Attempt to start the specified supervisor
-			b.manager.Start(supervisor, false)
-
-			// Wait for the supervisor to start
-			time.Sleep(1 * time.Second)
-
-			// Send a confirmation message
-			s.ChannelMessageSend(m.ChannelID, fmt.Sprintf("Supervisor '%s' has been started.", supervisor))
 		}
-	} else {
-		// If no supervisors were specified, send a usage message
-		s.ChannelMessageSend(m.ChannelID, "Usage: !start <supervisor1> [supervisor2] ...")
+		b.reply(s, i, req, discordgo.InteractionResponseChannelMessageWithSource)
+	case discordgo.InteractionMessageComponent:
+		// Buttons replace the message they belong to
+		b.reply(s, i, command.Parse(user, i.MessageComponentData().CustomID), discordgo.InteractionResponseUpdateMessage)
 	}
 }
 
-func (b *Bot) handleStopRequest(s *discordgo.Session, m *discordgo.MessageCreate) {
+// reply runs the command, the first message answers the interaction and the rest of them are sent as follow-ups
+func (b *Bot) reply(s *discordgo.Session, i *discordgo.InteractionCreate, req command.Request, typ discordgo.InteractionResponseType) {
+	req.Reply = func(m command.Message) { b.followUp(s, i, m) }
 
-	// Split the message content into words
-	words := strings.Fields(m.Content)
+	messages := b.commands.Execute(req)
+	if len(messages) == 0 {
+		return
+	}
 
-	// Check if there are any supervisors specified after "!stop"
-	if len(words) > 1 {
-		// Iterate through the supervisors specified
-		for _, supervisor := range words[1:] {
+	// Private messages can't replace public ones
+	if messages[0].Private {
+		typ = discordgo.InteractionResponseChannelMessageWithSource
+	}
+	b.respond(s, i, typ, b.render(messages[0]))
+	for _, m := range messages[1:] {
+		b.followUp(s, i, m)
+	}
+}
 
-			if !b.supervisorExists(supervisor) {
-				s.ChannelMessageSend(m.ChannelID, fmt.Sprintf("Supervisor '%s' not found.", supervisor))
-				continue
-			}
+func (b *Bot) respond(s *discordgo.Session, i *discordgo.InteractionCreate, typ discordgo.InteractionResponseType, data *discordgo.InteractionResponseData) {
+	err := s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{Type: typ, Data: data})
+	if err != nil {
+		b.logger.Error("Error answering Discord interaction", slog.String("error", err.Error()))
+	}
+}
 
-			// Check if the supervisor is running
-			if b.manager.Status(supervisor).SupervisorStatus == bot.NotStarted || b.manager.Status(supervisor).SupervisorStatus == "" {
-				s.ChannelMessageSend(m.ChannelID, fmt.Sprintf("Supervisor '%s' is not running.", supervisor))
-				continue
-			}
+func (b *Bot) followUp(s *discordgo.Session, i *discordgo.InteractionCreate, m command.Message) {
+	data := b.render(m)
+	_, err := s.FollowupMessageCreate(i.Interaction, false, &discordgo.WebhookParams{
+		Content:    data.Content,
+		Embeds:     data.Embeds,
+		Components: data.Components,
+		Files:      data.Files,
+		Flags:      data.Flags,
+	})
+	if err != nil {
+		b.logger.Error("Error sending Discord message", slog.String("error", err.Error()))
+	}
+}
 
-			// Attempt to stop the specified supervisor
-			b.manager.Stop(supervisor)
+// interactionUser returns the ID of the user, interactions have a member in servers and a user in DMs
+func interactionUser(i *discordgo.InteractionCreate) string {
+	switch {
+	case i.Member != nil && i.Member.User != nil:
+		return i.Member.User.ID
+	case i.User != nil:
+		return i.User.ID
+	}
 
-			// Wait for the supervisor to stop
-			time.Sleep(1 * time.Second)
+	return ""
+}
 
-			// Send a confirmation message
-			s.ChannelMessageSend(m.ChannelID, fmt.Sprintf("Supervisor '%s' has been stopped.", supervisor))
-		}
-	} else {
-		// If no supervisors were specified, send a usage message
-		s.ChannelMessageSend(m.ChannelID, "Usage: !stop <supervisor1> [supervisor2] ...")
+func choices(supervisors []string) []*discordgo.ApplicationCommandOptionChoice {
+	if len(supervisors) > maxChoices {
+		supervisors = supervisors[:maxChoices]
 	}
-}
 
-func (b *Bot) handleStatusRequest(s *discordgo.Session, m *discordgo.MessageCreate) {
+	ch := make([]*discordgo.ApplicationCommandOptionChoice, 0, len(supervisors))
+	for _, supervisor := range supervisors {
+		ch = append(ch, &discordgo.ApplicationCommandOptionChoice{Name: supervisor, Value: supervisor})
+	}
 
-	// Split the message content into words
-	words := strings.Fields(m.Content)
+	return ch
+}
 
-	if len(words) > 1 {
-		for _, supervisor := range words[1:] {
-			if !b.supervisorExists(supervisor) {
-				s.ChannelMessageSend(m.ChannelID, fmt.Sprintf("Supervisor '%s' not found.", supervisor))
-				continue
-			}
+// render converts the command message, plain texts are sent as content and the rest as an embed
+func (b *Bot) render(m command.Message) *discordgo.InteractionResponseData {
+	// Embeds and components have to be sent always, otherwise the old ones are kept when the message is updated
+	data := &discordgo.InteractionResponseData{Embeds: []*discordgo.MessageEmbed{}, Components: components(m.Buttons)}
+	if m.Private {
+		data.Flags = discordgo.MessageFlagsEphemeral
+	}
 
-			status := b.manager.Status(supervisor)
-			if status.SupervisorStatus == bot.NotStarted || status.SupervisorStatus == "" {
-				s.ChannelMessageSend(m.ChannelID, fmt.Sprintf("Supervisor '%s' is offline.", supervisor))
-				continue
-			}
+	if m.Title == "" && len(m.Fields) == 0 && m.Image == nil {
+		data.Content = m.Text
+		return data
+	}
 
-			s.ChannelMessageSend(m.ChannelID, fmt.Sprintf("Supervisor '%s' is %s", supervisor, status.SupervisorStatus))
+	embed := &discordgo.MessageEmbed{Title: m.Title, Description: m.Text, Color: m.Color}
+	for _, f := range m.Fields {
+		embed.Fields = append(embed.Fields, &discordgo.MessageEmbedField{Name: f.Name, Value: f.Value, Inline: f.Inline})
+	}
+	if m.Image != nil {
+		buf := new(bytes.Buffer)
+		if err := jpeg.Encode(buf, m.Image, &jpeg.Options{Quality: 80}); err != nil {
+			b.logger.Error("Error encoding Discord image", slog.String("error", err.Error()))
+		} else {
+			data.Files = []*discordgo.File{{Name: "Screenshot.jpeg", ContentType: "image/jpeg", Reader: buf}}
+			embed.Image = &discordgo.MessageEmbedImage{URL: "attachment://Screenshot.jpeg"}
 		}
-	} else {
-		// If no supervisors were specified, send a usage message
-		s.ChannelMessageSend(m.ChannelID, "Usage: !status <supervisor1> [supervisor2] ...")
 	}
-}
-
-func (b *Bot) handleStatsRequest(s *discordgo.Session, m *discordgo.MessageCreate) {
+	data.Embeds = append(data.Embeds, embed)
 
-	// Split the message content into words
-	words := strings.Fields(m.Content)
-
-	// Check if there are any supervisors specified after "!stats"
-	if len(words) > 1 {
-		// Iterate through the supervisors specified
-		for _, supervisor := range words[1:] {
+	return data
+}
 
-			if !b.supervisorExists(supervisor) {
-				s.ChannelMessageSend(m.ChannelID, fmt.Sprintf("Supervisor '%s' not found.", supervisor))
-				continue
-			}
+func components(buttons []command.Button) []discordgo.MessageComponent {
+	rows := make([]discordgo.MessageComponent, 0)
+	for chunk := range slices.Chunk(buttons, maxButtonsPerRow) {
+		if len(rows) == maxButtonRows {
+			break
+		}
 
-			// Fix for the status not being started
-			supStatus := string(b.manager.Status(supervisor).SupervisorStatus)
-			if supStatus == string(bot.NotStarted) || supStatus == "" {
-				supStatus = "Offline"
-			}
-			// Create the embed
-			embed := &discordgo.MessageEmbed{
-				Title: fmt.Sprintf("Stats for %s", supervisor),
-				Fields: []*discordgo.MessageEmbedField{
-					{
-						Name:   "Status",
-						Value:  supStatus,
-						Inline: true,
-					},
-					{
-						Name:   "Uptime",
-						Value:  time.Since(b.manager.Status(supervisor).StartedAt).String(),
-						Inline: true,
-					},
-
-					// Runs data
-
-					{
-						Name:   "Games",
-						Value:  fmt.Sprintf("%d", b.manager.GetSupervisorStats(supervisor).TotalGames()),
-						Inline: true,
-					},
-					{
-						Name:   "Drops",
-						Value:  fmt.Sprintf("%d", len(b.manager.GetSupervisorStats(supervisor).Drops)),
-						Inline: true,
-					},
-					{
-						Name:   "Deaths",
-						Value:  fmt.Sprintf("%d", b.manager.GetSupervisorStats(supervisor).TotalDeaths()),
-						Inline: true,
-					},
-					{
-						Name:   "Chickens",
-						Value:  fmt.Sprintf("%d", b.manager.GetSupervisorStats(supervisor).TotalChickens()),
-						Inline: true,
-					},
-					{
-						Name:   "Errors",
-						Value:  fmt.Sprintf("%d", b.manager.GetSupervisorStats(supervisor).TotalErrors()),
-						Inline: true,
-					},
-				},
+		row := discordgo.ActionsRow{}
+		for _, button := range chunk {
+			style := discordgo.SecondaryButton
+			switch button.Style {
+			case command.ButtonPositive:
+				style = discordgo.SuccessButton
+			case command.ButtonDanger:
+				style = discordgo.DangerButton
 			}
-
-			// Send the embed to the channel
-			s.ChannelMessageSendEmbed(m.ChannelID, embed)
+			row.Components = append(row.Components, discordgo.Button{Label: button.Label, Style: style, CustomID: button.Action})
 		}
-	} else {
-		// If no supervisors were specified, send a usage message
-		s.ChannelMessageSend(m.ChannelID, "Usage: !stats <supervisor1> [supervisor2] ...")
+		rows = append(rows, row)
 	}
+
+	return rows
 }
@@ -0,0 +1,137 @@
+package discord
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image/jpeg"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/hectorgimenez/d2go/pkg/data/item"
+	"github.com/hectorgimenez/d2go/pkg/data/stat"
+	"github.com/hectorgimenez/koolo/internal/config"
+	"github.com/hectorgimenez/koolo/internal/event"
+)
+
+// Discord rejects embed fields longer than this
+const maxFieldLength = 1024
+
+// publishDrop sends the stashed item to its drop channels
+func (b *Bot) publishDrop(evt event.ItemStashedEvent) error {
+	embed := dropEmbed(evt)
+
+	var screenshot []byte
+	if evt.Image() != nil {
+		buf := new(bytes.Buffer)
+		if err := jpeg.Encode(buf, evt.Image(), &jpeg.Options{Quality: 80}); err != nil {
+			return err
+		}
+		screenshot = buf.Bytes()
+		embed.Image = &discordgo.MessageEmbedImage{URL: "attachment://Screenshot.jpeg"}
+	}
+
+	var errs []error
+	for _, channelID := range dropChannels(config.Koolo.Discord.DropChannels, evt.Supervisor(), evt.Item.Item.Quality.ToString(), b.channelID) {
+		msg := &discordgo.MessageSend{Embeds: []*discordgo.MessageEmbed{embed}}
+		if screenshot != nil {
+			msg.Files = []*discordgo.File{{Name: "Screenshot.jpeg", ContentType: "image/jpeg", Reader: bytes.NewReader(screenshot)}}
+		}
+		if _, err := b.discordSession.ChannelMessageSendComplex(channelID, msg); err != nil {
+			errs = append(errs, fmt.Errorf("error sending drop to channel %s: %w", channelID, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// dropChannels returns the channels of the supervisor and the item quality, or the default one if none of them is
+// configured
+func dropChannels(cfg config.DiscordDropChannels, supervisor, quality, mainChannelID string) []string {
+	var channels []string
+	for _, channelID := range []string{cfg.Supervisors[supervisor], cfg.Qualities[quality]} {
+		if channelID != "" && !slices.Contains(channels, channelID) {
+			channels = append(channels, channelID)
+		}
+	}
+
+	if len(channels) == 0 {
+		if cfg.ChannelID != "" {
+			return []string{cfg.ChannelID}
+		}
+		return []string{mainChannelID}
+	}
+
+	return channels
+}
+
+func dropEmbed(evt event.ItemStashedEvent) *discordgo.MessageEmbed {
+	it := evt.Item.Item
+
+	title := string(it.Name)
+	if it.Ethereal {
+		title += " (Ethereal)"
+	}
+
+	embed := &discordgo.MessageEmbed{
+		Title:     title,
+		Color:     qualityColor(it.Quality),
+		Timestamp: evt.OccurredAt().Format(time.RFC3339),
+		Fields: []*discordgo.MessageEmbedField{
+			{Name: "Supervisor", Value: evt.Supervisor(), Inline: true},
+			{Name: "Quality", Value: it.Quality.ToString(), Inline: true},
+		},
+	}
+	if evt.RunName != "" {
+		embed.Fields = append(embed.Fields, &discordgo.MessageEmbedField{Name: "Run", Value: evt.RunName, Inline: true})
+	}
+	if evt.Item.DropLocation != "" {
+		embed.Fields = append(embed.Fields, &discordgo.MessageEmbedField{Name: "Location", Value: evt.Item.DropLocation, Inline: true})
+	}
+
+	if len(it.Stats) > 0 {
+		lines := make([]string, 0, len(it.Stats))
+		for _, st := range it.Stats {
+			lines = append(lines, fmt.Sprintf("%s: %d", stat.StringStats[st.ID], st.Value))
+		}
+		embed.Fields = append(embed.Fields, &discordgo.MessageEmbedField{Name: "Stats", Value: truncate(strings.Join(lines, "\n"))})
+	}
+
+	if evt.Item.Rule != "" {
+		rule := fmt.Sprintf("`%s`", evt.Item.Rule)
+		if evt.Item.RuleFile != "" {
+			rule += "\n" + evt.Item.RuleFile
+		}
+		embed.Fields = append(embed.Fields, &discordgo.MessageEmbedField{Name: "Pickit rule", Value: truncate(rule)})
+	}
+
+	return embed
+}
+
+// qualityColor returns the color of the item name in game
+func qualityColor(q item.Quality) int {
+	switch q {
+	case item.QualityMagic:
+		return 0x6969ff
+	case item.QualitySet:
+		return 0x00c400
+	case item.QualityRare:
+		return 0xffff64
+	case item.QualityUnique:
+		return 0xc7b377
+	case item.QualityCrafted:
+		return 0xffa800
+	default:
+		return 0xc4c4c4
+	}
+}
+
+func truncate(s string) string {
+	if len(s) <= maxFieldLength {
+		return s
+	}
+
+	return s[:maxFieldLength-3] + "..."
+}
@@ -11,6 +11,10 @@ import (
 )
 
 func (b *Bot) Handle(_ context.Context, e event.Event) error {
+	if evt, ok := e.(event.ItemStashedEvent); ok && config.Koolo.Discord.DropChannels.Enabled {
+		return b.publishDrop(evt)
+	}
+
 	if b.shouldPublish(e) {
 
 		switch e.(type) {
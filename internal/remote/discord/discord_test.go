@@ -0,0 +1,132 @@
+package discord
+
+import (
+	"image"
+	"io"
+	"log/slog"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/hectorgimenez/d2go/pkg/data"
+	"github.com/hectorgimenez/d2go/pkg/data/item"
+	"github.com/hectorgimenez/d2go/pkg/data/stat"
+	"github.com/hectorgimenez/koolo/internal/config"
+	"github.com/hectorgimenez/koolo/internal/event"
+	"github.com/hectorgimenez/koolo/internal/remote/command"
+)
+
+func TestSlashCommands(t *testing.T) {
+	commands := slashCommands([]command.Command{
+		{Name: "stats", Description: "Show the stats", Supervisor: true},
+		{Name: "help", Description: "List the commands"},
+	})
+
+	if len(commands) != 2 || commands[0].Name != "stats" || len(commands[1].Options) != 0 {
+		t.Fatalf("unexpected commands %+v", commands)
+	}
+	// Not required, the supervisor picker is shown when it's empty
+	if opt := commands[0].Options[0]; opt.Name != supervisorOption || opt.Required || !opt.Autocomplete {
+		t.Errorf("supervisor option should be optional and autocompleted: %+v", opt)
+	}
+}
+
+func TestRender(t *testing.T) {
+	b := &Bot{logger: slog.New(slog.NewTextHandler(io.Discard, nil))}
+
+	data := b.render(command.Message{Text: "Starting supervisor 'sorc'."})
+	if data.Content != "Starting supervisor 'sorc'." || len(data.Embeds) != 0 || data.Components == nil {
+		t.Errorf("plain texts should be sent as content, clearing embeds and buttons: %+v", data)
+	}
+
+	data = b.render(command.Message{
+		Title:  "Status of sorc",
+		Color:  0x2ecc71,
+		Fields: []command.Field{{Name: "Status", Value: "Paused", Inline: true}},
+		Buttons: []command.Button{
+			{Label: "Resume", Style: command.ButtonPositive, Action: "pause sorc"},
+			{Label: "Stop", Style: command.ButtonDanger, Action: "stop sorc"},
+		},
+		Private: true,
+	})
+	if len(data.Embeds) != 1 || data.Embeds[0].Title != "Status of sorc" || data.Embeds[0].Fields[0].Value != "Paused" || data.Flags != discordgo.MessageFlagsEphemeral {
+		t.Errorf("unexpected embed %+v", data)
+	}
+	buttons := data.Components[0].(discordgo.ActionsRow).Components
+	if resume := buttons[0].(discordgo.Button); resume.CustomID != "pause sorc" || resume.Style != discordgo.SuccessButton {
+		t.Errorf("unexpected resume button %+v", resume)
+	}
+	if stop := buttons[1].(discordgo.Button); stop.CustomID != "stop sorc" || stop.Style != discordgo.DangerButton {
+		t.Errorf("unexpected stop button %+v", stop)
+	}
+
+	data = b.render(command.Message{Text: "sorc", Image: image.NewRGBA(image.Rect(0, 0, 8, 8))})
+	if len(data.Files) != 1 || data.Embeds[0].Image.URL != "attachment://Screenshot.jpeg" {
+		t.Errorf("screenshot should be attached to the embed: %+v", data)
+	}
+}
+
+func TestComponentsLimits(t *testing.T) {
+	buttons := make([]command.Button, 30)
+	rows := components(buttons)
+	if len(rows) != maxButtonRows || len(rows[0].(discordgo.ActionsRow).Components) != maxButtonsPerRow {
+		t.Errorf("expected %d rows of %d buttons, got %d rows", maxButtonRows, maxButtonsPerRow, len(rows))
+	}
+}
+
+func TestDropChannels(t *testing.T) {
+	cfg := config.DiscordDropChannels{
+		Supervisors: map[string]string{"sorc": "sorc-drops"},
+		Qualities:   map[string]string{"Unique": "uniques", "Set": "sorc-drops"},
+	}
+
+	for _, tc := range []struct {
+		supervisor, quality string
+		defaultChannel      string
+		expected            []string
+	}{
+		{"sorc", "Unique", "", []string{"sorc-drops", "uniques"}},
+		{"sorc", "Set", "", []string{"sorc-drops"}},
+		{"barb", "Unique", "", []string{"uniques"}},
+		{"barb", "Rare", "", []string{"main"}},
+		{"barb", "Rare", "drops", []string{"drops"}},
+	} {
+		cfg.ChannelID = tc.defaultChannel
+		if channels := dropChannels(cfg, tc.supervisor, tc.quality, "main"); !slices.Equal(channels, tc.expected) {
+			t.Errorf("%s %s: expected %v, got %v", tc.supervisor, tc.quality, tc.expected, channels)
+		}
+	}
+}
+
+func TestDropEmbed(t *testing.T) {
+	drop := data.Drop{
+		Item: data.Item{
+			Name:     "Shako",
+			Quality:  item.QualityUnique,
+			Ethereal: true,
+			Stats:    stat.Stats{{ID: stat.MagicFind, Value: 50}, {ID: stat.Defense, Value: 141}},
+		},
+		Rule:     "[name] == shako && [quality] == unique",
+		RuleFile: "unique.nip:12",
+	}
+	embed := dropEmbed(event.ItemStashed(event.Text("sorc", ""), drop, "mephisto", time.Time{}))
+
+	if embed.Title != "Shako (Ethereal)" || embed.Color != 0xc7b377 {
+		t.Errorf("unexpected title %q or color %x", embed.Title, embed.Color)
+	}
+	fields := make(map[string]string)
+	for _, f := range embed.Fields {
+		fields[f.Name] = f.Value
+	}
+	if fields["Supervisor"] != "sorc" || fields["Quality"] != "Unique" || fields["Run"] != "mephisto" {
+		t.Errorf("unexpected fields %v", fields)
+	}
+	if fields["Stats"] != "magicfind: 50\ndefense: 141" {
+		t.Errorf("unexpected stats %q", fields["Stats"])
+	}
+	if !strings.HasPrefix(fields["Pickit rule"], "`[name] == shako && [quality] == unique`") || !strings.HasSuffix(fields["Pickit rule"], "unique.nip:12") {
+		t.Errorf("unexpected rule %q", fields["Pickit rule"])
+	}
+}
@@ -0,0 +1,157 @@
+package hub
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/hectorgimenez/koolo/internal/event"
+)
+
+const (
+	statusInterval = 5 * time.Second
+	reconnectDelay = 5 * time.Second
+)
+
+// Client connects the instance to a hub, reconnecting when the connection is lost. Local companion events are sent to
+// the hub (see Handle) and the events of the other instances are delivered to the local event listener
+type Client struct {
+	url      string
+	token    string
+	instance string
+	// Statuses of the local supervisors
+	status func() []SupervisorStatus
+	logger *slog.Logger
+	send   func(event.Event)
+	out    chan Message
+
+	connected      atomic.Bool
+	statusInterval time.Duration
+	reconnectDelay time.Duration
+}
+
+func NewClient(url, token, instance string, status func() []SupervisorStatus, logger *slog.Logger) *Client {
+	return &Client{
+		url:            url,
+		token:          token,
+		instance:       instance,
+		status:         status,
+		logger:         logger,
+		send:           event.Send,
+		out:            make(chan Message, peerQueue),
+		statusInterval: statusInterval,
+		reconnectDelay: reconnectDelay,
+	}
+}
+
+// Handle is the event handler for the local events, they are dropped while the hub is not connected, otherwise old
+// games would be announced after reconnecting
+func (c *Client) Handle(_ context.Context, e event.Event) error {
+	if !c.connected.Load() {
+		return nil
+	}
+
+	m, ok := FromEvent(c.instance, e)
+	if !ok {
+		return nil
+	}
+
+	select {
+	case c.out <- m:
+		return nil
+	default:
+		return errors.New("hub connection is too slow, message dropped")
+	}
+}
+
+// Run keeps the instance connected to the hub until the context is done
+func (c *Client) Run(ctx context.Context) error {
+	for {
+		err := c.connect(ctx)
+		if ctx.Err() != nil {
+			return nil
+		}
+		c.logger.Warn("Hub connection lost, reconnecting", slog.String("hub", c.url), slog.String("error", err.Error()))
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(c.reconnectDelay):
+		}
+	}
+}
+
+func (c *Client) connect(ctx context.Context) error {
+	header := http.Header{}
+	if c.token != "" {
+		header.Set("Authorization", "Bearer "+c.token)
+	}
+
+	conn, _, err := websocket.DefaultDialer.DialContext(ctx, c.url, header)
+	if err != nil {
+		return fmt.Errorf("error connecting to the hub: %w", err)
+	}
+	defer conn.Close()
+
+	if err = c.write(conn, Message{Type: TypeHello, Instance: c.instance, Time: time.Now()}); err != nil {
+		return err
+	}
+	if err = c.writeStatus(conn); err != nil {
+		return err
+	}
+	c.logger.Info("Connected to the hub", slog.String("hub", c.url))
+
+	c.connected.Store(true)
+	defer c.connected.Store(false)
+
+	readErr := make(chan error, 1)
+	go func() {
+		for {
+			var m Message
+			if err := conn.ReadJSON(&m); err != nil {
+				readErr <- fmt.Errorf("error reading from the hub: %w", err)
+				return
+			}
+			if e, ok := m.Event(); ok {
+				c.send(e)
+			}
+		}
+	}()
+
+	ticker := time.NewTicker(c.statusInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""))
+			return ctx.Err()
+		case err = <-readErr:
+			return err
+		case m := <-c.out:
+			err = c.write(conn, m)
+		case <-ticker.C:
+			err = c.writeStatus(conn)
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func (c *Client) writeStatus(conn *websocket.Conn) error {
+	return c.write(conn, Message{Type: TypeStatus, Instance: c.instance, Time: time.Now(), Supervisors: c.status()})
+}
+
+func (c *Client) write(conn *websocket.Conn, m Message) error {
+	conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+	if err := conn.WriteJSON(m); err != nil {
+		return fmt.Errorf("error writing to the hub: %w", err)
+	}
+
+	return nil
+}
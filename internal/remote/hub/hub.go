@@ -0,0 +1,222 @@
+package hub
+
+import (
+	"context"
+	"crypto/subtle"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/hectorgimenez/koolo/internal/event"
+)
+
+const (
+	// Instances send their status every few seconds, if nothing is received for this long the connection is closed
+	peerTimeout  = 30 * time.Second
+	writeTimeout = 10 * time.Second
+	peerQueue    = 64
+)
+
+// Hub accepts connections from other instances, see ServeHTTP. Companion events from an instance are relayed to the
+// other instances and to the local event listener, local companion events are sent to all the instances (see Handle)
+type Hub struct {
+	instance string
+	// Shared secret the instances send as "Authorization: Bearer <token>", connections are refused if it's empty
+	token  string
+	logger *slog.Logger
+	// Delivers the events of other instances to the local listener
+	send     func(event.Event)
+	upgrader websocket.Upgrader
+
+	mu          sync.Mutex
+	peers       map[*peer]struct{}
+	supervisors map[string]RemoteSupervisor
+}
+
+type peer struct {
+	instance string
+	conn     *websocket.Conn
+	out      chan Message
+}
+
+func NewHub(instance, token string, logger *slog.Logger) *Hub {
+	return &Hub{
+		instance: instance,
+		token:    token,
+		logger:   logger,
+		send:     event.Send,
+		// Instances are not browsers, a web page must not be able to connect using the network of the user
+		upgrader:    websocket.Upgrader{CheckOrigin: func(r *http.Request) bool { return r.Header.Get("Origin") == "" }},
+		peers:       make(map[*peer]struct{}),
+		supervisors: make(map[string]RemoteSupervisor),
+	}
+}
+
+// ServeHTTP upgrades the connection to a websocket if the hub token is valid, the first message must be a hello with
+// the instance name
+func (h *Hub) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !h.authorized(r) {
+		h.logger.Warn("Hub connection rejected, invalid token", slog.String("remote", r.RemoteAddr))
+		http.Error(w, "invalid hub token", http.StatusUnauthorized)
+		return
+	}
+
+	conn, err := h.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		h.logger.Warn("Hub connection could not be upgraded", slog.String("error", err.Error()))
+		return
+	}
+	defer conn.Close()
+
+	var hello Message
+	conn.SetReadDeadline(time.Now().Add(peerTimeout))
+	if err = conn.ReadJSON(&hello); err != nil || hello.Type != TypeHello || hello.Instance == "" || hello.Instance == h.instance {
+		h.logger.Warn("Hub connection rejected, invalid hello message", slog.String("remote", r.RemoteAddr), slog.String("instance", hello.Instance))
+		return
+	}
+
+	p := &peer{instance: hello.Instance, conn: conn, out: make(chan Message, peerQueue)}
+	h.register(p)
+	defer h.unregister(p)
+	h.logger.Info("Koolo instance connected to the hub", slog.String("instance", p.instance), slog.String("remote", r.RemoteAddr))
+
+	go h.writePump(p)
+	h.readPump(p)
+}
+
+func (h *Hub) authorized(r *http.Request) bool {
+	token, found := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !found || token == "" || h.token == "" {
+		return false
+	}
+
+	return subtle.ConstantTimeCompare([]byte(token), []byte(h.token)) == 1
+}
+
+func (h *Hub) readPump(p *peer) {
+	for {
+		p.conn.SetReadDeadline(time.Now().Add(peerTimeout))
+
+		var m Message
+		if err := p.conn.ReadJSON(&m); err != nil {
+			if !websocket.IsCloseError(err, websocket.CloseNormalClosure, websocket.CloseGoingAway) {
+				h.logger.Warn("Hub connection lost", slog.String("instance", p.instance), slog.String("error", err.Error()))
+			}
+			return
+		}
+
+		// An instance can only talk for itself
+		m.Instance = p.instance
+		if m.Type == TypeStatus {
+			h.updateStatus(p.instance, m.Supervisors)
+			continue
+		}
+
+		if e, ok := m.Event(); ok {
+			h.send(e)
+			h.broadcast(m, p)
+		}
+	}
+}
+
+func (h *Hub) writePump(p *peer) {
+	for m := range p.out {
+		p.conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+		if err := p.conn.WriteJSON(m); err != nil {
+			// Closing the connection stops the read pump too
+			p.conn.Close()
+			return
+		}
+	}
+}
+
+func (h *Hub) register(p *peer) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.peers[p] = struct{}{}
+}
+
+func (h *Hub) unregister(p *peer) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	delete(h.peers, p)
+	close(p.out)
+
+	for key, s := range h.supervisors {
+		if s.Instance == p.instance {
+			s.Connected = false
+			h.supervisors[key] = s
+		}
+	}
+}
+
+// broadcast sends the message to every instance except the one sending it, slow instances lose messages instead of
+// blocking the others
+func (h *Hub) broadcast(m Message, from *peer) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for p := range h.peers {
+		if p == from {
+			continue
+		}
+
+		select {
+		case p.out <- m:
+		default:
+			h.logger.Warn("Hub instance is too slow, message dropped", slog.String("instance", p.instance), slog.String("type", m.Type))
+		}
+	}
+}
+
+// updateStatus replaces the supervisors of the instance with the reported ones
+func (h *Hub) updateStatus(instance string, supervisors []SupervisorStatus) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for key, s := range h.supervisors {
+		if s.Instance == instance {
+			delete(h.supervisors, key)
+		}
+	}
+
+	now := time.Now()
+	for _, s := range supervisors {
+		h.supervisors[instance+"/"+s.Name] = RemoteSupervisor{Instance: instance, SupervisorStatus: s, Connected: true, UpdatedAt: now}
+	}
+}
+
+// Handle is the event handler for the local events, they are sent to all the connected instances
+func (h *Hub) Handle(_ context.Context, e event.Event) error {
+	if m, ok := FromEvent(h.instance, e); ok {
+		h.broadcast(m, nil)
+	}
+
+	return nil
+}
+
+// Supervisors returns the supervisors reported by the other instances, sorted by instance and name. Supervisors of
+// disconnected instances are kept with their last status
+func (h *Hub) Supervisors() []RemoteSupervisor {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	supervisors := make([]RemoteSupervisor, 0, len(h.supervisors))
+	for _, s := range h.supervisors {
+		supervisors = append(supervisors, s)
+	}
+	slices.SortFunc(supervisors, func(a, b RemoteSupervisor) int {
+		if c := strings.Compare(a.Instance, b.Instance); c != 0 {
+			return c
+		}
+		return strings.Compare(a.Name, b.Name)
+	})
+
+	return supervisors
+}
@@ -0,0 +1,215 @@
+package hub
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/hectorgimenez/koolo/internal/companion"
+	"github.com/hectorgimenez/koolo/internal/companion/companiontest"
+	"github.com/hectorgimenez/koolo/internal/event"
+)
+
+// recorder collects the events delivered to the local listener of an instance
+type recorder struct {
+	mu     sync.Mutex
+	events []event.Event
+}
+
+func (r *recorder) send(e event.Event) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, e)
+}
+
+func (r *recorder) received() []event.Event {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]event.Event(nil), r.events...)
+}
+
+func waitFor(t *testing.T, what string, condition func() bool) {
+	t.Helper()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for !condition() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timeout waiting for %s", what)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+var discard = slog.New(slog.NewTextHandler(io.Discard, nil))
+
+const testToken = "hub-secret"
+
+type instance struct {
+	client *Client
+	events *recorder
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// startInstance connects a new instance to the hub, like another koolo running on localhost
+func startInstance(t *testing.T, srv *httptest.Server, name string, statuses ...SupervisorStatus) *instance {
+	t.Helper()
+
+	url := "ws" + strings.TrimPrefix(srv.URL, "http")
+	i := &instance{events: &recorder{}, done: make(chan struct{})}
+	i.client = NewClient(url, testToken, name, func() []SupervisorStatus { return statuses }, discard)
+	i.client.send = i.events.send
+	i.client.statusInterval = 20 * time.Millisecond
+
+	var ctx context.Context
+	ctx, i.cancel = context.WithCancel(context.Background())
+	go func() {
+		defer close(i.done)
+		i.client.Run(ctx)
+	}()
+	t.Cleanup(i.stop)
+	waitFor(t, name+" connection", i.client.connected.Load)
+
+	return i
+}
+
+func (i *instance) stop() {
+	i.cancel()
+	<-i.done
+}
+
+func newTestHub(t *testing.T) (*Hub, *recorder, *httptest.Server) {
+	t.Helper()
+
+	h := NewHub("hub", testToken, discard)
+	events := &recorder{}
+	h.send = events.send
+	srv := httptest.NewServer(h)
+	t.Cleanup(srv.Close)
+
+	return h, events, srv
+}
+
+func TestHubRelaysCompanionEvents(t *testing.T) {
+	h, hubEvents, srv := newTestHub(t)
+	pc1 := startInstance(t, srv, "pc1")
+	pc2 := startInstance(t, srv, "pc2")
+
+	// Not shared with other instances
+	_ = pc1.client.Handle(context.Background(), event.RunStarted(event.Text("leader", ""), "mephisto"))
+	_ = pc1.client.Handle(context.Background(), event.GameCreated(event.Text("leader", ""), "baal-1", "pass"))
+
+	waitFor(t, "game created in pc2", func() bool { return len(pc2.events.received()) == 1 })
+	waitFor(t, "game created in the hub", func() bool { return len(hubEvents.received()) == 1 })
+	for _, e := range []event.Event{pc2.events.received()[0], hubEvents.received()[0]} {
+		remote, ok := e.(event.RemoteEvent)
+		if !ok || remote.Instance != "pc1" || remote.Supervisor() != "leader" {
+			t.Fatalf("unexpected event %#v", e)
+		}
+		if gc, ok := remote.Event.(event.GameCreatedEvent); !ok || gc.Name != "baal-1" || gc.Password != "pass" {
+			t.Errorf("unexpected game created event %#v", remote.Event)
+		}
+	}
+	if len(pc1.events.received()) != 0 {
+		t.Errorf("events should not be sent back to the instance sending them: %v", pc1.events.received())
+	}
+
+	// Local events of the hub go to every instance
+	_ = h.Handle(context.Background(), event.CompanionLeaderAttack(event.Text("hub-leader", ""), 42))
+	for _, i := range []*instance{pc1, pc2} {
+		waitFor(t, "leader attack", func() bool {
+			for _, e := range i.events.received() {
+				if remote, ok := e.(event.RemoteEvent); ok && remote.Instance == "hub" {
+					return remote.Event.(event.CompanionLeaderAttackEvent).TargetUnitID == 42
+				}
+			}
+			return false
+		})
+	}
+}
+
+func TestHubTracksRemoteSupervisors(t *testing.T) {
+	h, _, srv := newTestHub(t)
+	pc1 := startInstance(t, srv, "pc1", SupervisorStatus{Name: "sorc", Status: "In game", Games: 3}, SupervisorStatus{Name: "barb", Status: "Not Started"})
+	startInstance(t, srv, "pc2", SupervisorStatus{Name: "pala", Status: "In game"})
+
+	waitFor(t, "statuses", func() bool { return len(h.Supervisors()) == 3 })
+	supervisors := h.Supervisors()
+	if supervisors[0].Instance != "pc1" || supervisors[0].Name != "barb" || supervisors[1].Name != "sorc" || supervisors[1].Games != 3 || supervisors[2].Instance != "pc2" {
+		t.Fatalf("unexpected supervisors: %+v", supervisors)
+	}
+
+	pc1.stop()
+	waitFor(t, "pc1 disconnected", func() bool {
+		for _, s := range h.Supervisors() {
+			if s.Instance == "pc1" && s.Connected {
+				return false
+			}
+		}
+		return true
+	})
+	if s := h.Supervisors()[2]; !s.Connected {
+		t.Errorf("pc2 should still be connected: %+v", s)
+	}
+}
+
+func TestHubRejectsInvalidTokens(t *testing.T) {
+	h, _, srv := newTestHub(t)
+	url := "ws" + strings.TrimPrefix(srv.URL, "http")
+
+	for name, header := range map[string]http.Header{
+		"missing token": {},
+		"wrong token":   {"Authorization": {"Bearer nope"}},
+		"empty token":   {"Authorization": {"Bearer "}},
+		"not bearer":    {"Authorization": {testToken}},
+		"browser":       {"Authorization": {"Bearer " + testToken}, "Origin": {"http://evil.example"}},
+	} {
+		conn, resp, err := websocket.DefaultDialer.Dial(url, header)
+		if err == nil {
+			conn.Close()
+			t.Errorf("%s: connection should be rejected", name)
+			continue
+		}
+		if resp == nil || (resp.StatusCode != http.StatusUnauthorized && resp.StatusCode != http.StatusForbidden) {
+			t.Errorf("%s: unexpected response %v", name, err)
+		}
+	}
+
+	// A hub without token refuses everyone, even instances without token
+	open := NewHub("hub", "", discard)
+	openSrv := httptest.NewServer(open)
+	defer openSrv.Close()
+	if conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(openSrv.URL, "http"), nil); err == nil {
+		conn.Close()
+		t.Error("hub without token should refuse the connection")
+	}
+
+	if len(h.Supervisors()) != 0 {
+		t.Errorf("rejected instances should not be registered: %+v", h.Supervisors())
+	}
+}
+
+func TestFollowerJoinsRemoteLeaderGame(t *testing.T) {
+	_, _, srv := newTestHub(t)
+	leaderInstance := startInstance(t, srv, "pc1")
+	followerInstance := startInstance(t, srv, "pc2")
+
+	follower := companion.NewFollower("follower", "leader", "", &companiontest.GameManager{})
+	followerInstance.client.send = func(e event.Event) { _ = follower.Handle(context.Background(), e) }
+
+	_ = leaderInstance.client.Handle(context.Background(), event.GameCreated(event.Text("leader", ""), "cows-7", "moo"))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	g, err := follower.JoinNextGame(ctx)
+	if err != nil || g.Name != "cows-7" || g.Password != "moo" {
+		t.Fatalf("follower didn't join the remote game: %+v %v", g, err)
+	}
+}
@@ -0,0 +1,119 @@
+// Package hub connects koolo instances running on different machines. Every instance connects to a central hub with a
+// websocket and exchanges JSON messages: companion events (games, portals, leader targets) are relayed to all the other
+// instances and supervisor statuses are kept by the hub to show them in its dashboard
+package hub
+
+import (
+	"time"
+
+	"github.com/hectorgimenez/d2go/pkg/data"
+	"github.com/hectorgimenez/koolo/internal/config"
+	"github.com/hectorgimenez/koolo/internal/event"
+)
+
+const (
+	// First message sent by an instance after connecting
+	TypeHello = "hello"
+	// Supervisors of the instance, sent periodically, it also works as heartbeat
+	TypeStatus       = "status"
+	TypeGameCreated  = "game_created"
+	TypeGameFinished = "game_finished"
+	TypeLeaderAttack = "leader_attack"
+	TypeRequestTP    = "request_tp"
+)
+
+// Message is the only message of the protocol, fields are set depending on the type
+type Message struct {
+	Type     string    `json:"type"`
+	Instance string    `json:"instance"`
+	Time     time.Time `json:"time"`
+	// Supervisor sending the event and its in game name
+	Supervisor  string             `json:"supervisor,omitempty"`
+	Character   string             `json:"character,omitempty"`
+	Game        string             `json:"game,omitempty"`
+	Password    string             `json:"password,omitempty"`
+	Reason      string             `json:"reason,omitempty"`
+	Target      data.UnitID        `json:"target,omitempty"`
+	Supervisors []SupervisorStatus `json:"supervisors,omitempty"`
+}
+
+// SupervisorStatus is a summary of the supervisor stats of the current session
+type SupervisorStatus struct {
+	Name      string    `json:"name"`
+	Character string    `json:"character,omitempty"`
+	Status    string    `json:"status"`
+	StartedAt time.Time `json:"startedAt"`
+	Run       string    `json:"run,omitempty"`
+	Games     int       `json:"games"`
+	Drops     int       `json:"drops"`
+	Chickens  int       `json:"chickens"`
+	Deaths    int       `json:"deaths"`
+	Errors    int       `json:"errors"`
+}
+
+// RemoteSupervisor is a supervisor of another instance, as seen by the hub
+type RemoteSupervisor struct {
+	Instance string `json:"instance"`
+	SupervisorStatus
+	Connected bool      `json:"connected"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// FromEvent converts the events shared with other instances, events received from other instances are never sent
+// back, so they are not converted
+func FromEvent(instance string, e event.Event) (Message, bool) {
+	m := Message{
+		Instance:   instance,
+		Time:       e.OccurredAt(),
+		Supervisor: e.Supervisor(),
+		Character:  characterName(e.Supervisor()),
+	}
+
+	switch evt := e.(type) {
+	case event.GameCreatedEvent:
+		m.Type = TypeGameCreated
+		m.Game = evt.Name
+		m.Password = evt.Password
+	case event.GameFinishedEvent:
+		m.Type = TypeGameFinished
+		m.Reason = string(evt.Reason)
+	case event.CompanionLeaderAttackEvent:
+		m.Type = TypeLeaderAttack
+		m.Target = evt.TargetUnitID
+	case event.CompanionRequestedTPEvent:
+		m.Type = TypeRequestTP
+	default:
+		return Message{}, false
+	}
+
+	return m, true
+}
+
+// Event converts a message from another instance into a local event, status and hello messages are not events
+func (m Message) Event() (event.RemoteEvent, bool) {
+	be := event.TextAt(m.Supervisor, "", m.Time)
+
+	var e event.Event
+	switch m.Type {
+	case TypeGameCreated:
+		e = event.GameCreated(be, m.Game, m.Password)
+	case TypeGameFinished:
+		e = event.GameFinished(be, event.FinishReason(m.Reason))
+	case TypeLeaderAttack:
+		e = event.CompanionLeaderAttack(be, m.Target)
+	case TypeRequestTP:
+		e = event.CompanionRequestedTP(be)
+	default:
+		return event.RemoteEvent{}, false
+	}
+
+	return event.Remote(e, m.Instance, m.Character), true
+}
+
+func characterName(supervisor string) string {
+	if cfg, found := config.Characters[supervisor]; found && cfg != nil {
+		return cfg.CharacterName
+	}
+
+	return ""
+}
@@ -2,32 +2,42 @@ package telegram
 
 import (
 	"context"
+	"fmt"
 	"log/slog"
-	"strings"
+	"net/http"
+	"strconv"
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/hectorgimenez/koolo/internal/remote/command"
 )
 
 type Bot struct {
-	bot    *tgbotapi.BotAPI
-	chatID int64
-	logger *slog.Logger
+	bot      *tgbotapi.BotAPI
+	chatID   int64
+	commands *command.Registry
+	logger   *slog.Logger
 }
 
-func NewBot(token string, chatID int64, logger *slog.Logger) (*Bot, error) {
-	bot, err := tgbotapi.NewBotAPI(token)
+func NewBot(token string, chatID int64, commands *command.Registry, logger *slog.Logger) (*Bot, error) {
+	return newBot(token, tgbotapi.APIEndpoint, chatID, commands, logger)
+}
+
+// newBot allows to use a different Bot API server, used by tests
+func newBot(token, apiEndpoint string, chatID int64, commands *command.Registry, logger *slog.Logger) (*Bot, error) {
+	bot, err := tgbotapi.NewBotAPIWithClient(token, apiEndpoint, &http.Client{})
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("error creating Telegram bot: %w", err)
 	}
 
 	return &Bot{
-		bot:    bot,
-		chatID: chatID,
-		logger: logger,
+		bot:      bot,
+		chatID:   chatID,
+		commands: commands,
+		logger:   logger,
 	}, nil
 }
 
-func (b *Bot) Start(_ context.Context) error {
+func (b *Bot) Start(ctx context.Context) error {
 	offset, err := b.getLatestOffset()
 	if err != nil {
 		return err
@@ -36,19 +46,14 @@ func (b *Bot) Start(_ context.Context) error {
 	u := tgbotapi.NewUpdate(offset)
 	u.Timeout = 5
 	updates := b.bot.GetUpdatesChan(u)
+
+	go func() {
+		<-ctx.Done()
+		b.bot.StopReceivingUpdates()
+	}()
+
 	for update := range updates {
-		if update.Message != nil && update.Message.Chat.ID == b.chatID { // If we got a message
-			switch strings.ToLower(update.Message.Text) {
-			case "stats":
-				if err := b.publishStats(); err != nil {
-					b.logger.Error("error sending telegram message", slog.Any("error", err))
-				}
-			case "start":
-				// TODO: Implement
-			case "stop":
-				//b.sueprvisor.Stop()
-			}
-		}
+		b.handleUpdate(update)
 	}
 
 	return nil
@@ -69,27 +74,27 @@ func (b *Bot) getLatestOffset() (int, error) {
 	return offset, nil
 }
 
-func (b *Bot) publishStats() error {
-	return nil
-	//msg := "Run | Items | Deaths | Chickens | Merc Chickens | Errors | Healing Pots | Mana Pots | Reju Pots | Merc Pots \n"
-	//for run, st := range stat.Status.RunStats {
-	//	msg += fmt.Sprintf(
-	//		"%s | %d | %d | %d | %d | %d | %d | %d| %d | %d | %d \n",
-	//		run,
-	//		len(st.ItemsFound),
-	//		st.Kills,
-	//		st.Deaths,
-	//		st.Chickens,
-	//		st.MerChicken,
-	//		st.Errors,
-	//		st.HealingPotionsUsed,
-	//		st.ManaPotionsUsed,
-	//		st.RejuvPotionsUsed,
-	//		st.MercHealingPotionsUsed,
-	//	)
-	//}
-	//
-	//_, err := b.bot.Send(tgbotapi.NewMessage(b.chatID, msg))
-	//
-	//return err
+func (b *Bot) handleUpdate(update tgbotapi.Update) {
+	switch {
+	case update.Message != nil:
+		m := update.Message
+		if m.Chat == nil || m.Chat.ID != b.chatID || m.From == nil {
+			return
+		}
+
+		// Plain text commands like "stats sorc" are accepted too
+		b.execute(command.Parse(strconv.FormatInt(m.From.ID, 10), m.Text))
+	case update.CallbackQuery != nil:
+		q := update.CallbackQuery
+		if q.Message == nil || q.Message.Chat == nil || q.Message.Chat.ID != b.chatID || q.From == nil {
+			return
+		}
+
+		// Stops the loading animation of the button
+		if _, err := b.bot.Request(tgbotapi.NewCallback(q.ID, "")); err != nil {
+			b.logger.Debug("Error answering Telegram callback", slog.String("error", err.Error()))
+		}
+
+		b.execute(command.Parse(strconv.FormatInt(q.From.ID, 10), q.Data))
+	}
 }
@@ -0,0 +1,268 @@
+package telegram
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/hectorgimenez/d2go/pkg/data"
+	"github.com/hectorgimenez/d2go/pkg/data/item"
+	"github.com/hectorgimenez/koolo/internal/remote/command"
+	"github.com/hectorgimenez/koolo/internal/remote/command/commandtest"
+	"github.com/hectorgimenez/koolo/internal/remote/hub"
+)
+
+const (
+	testChatID int64 = 100
+	testAdmin  int64 = 7
+)
+
+// request is a call received by the fake Bot API server
+type request struct {
+	method string
+	params map[string]string
+	files  []string
+}
+
+// fakeAPI is a minimal Telegram Bot API server recording the calls made by the bot
+type fakeAPI struct {
+	mu       sync.Mutex
+	requests []request
+}
+
+func (f *fakeAPI) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	req := request{method: r.URL.Path[strings.LastIndex(r.URL.Path, "/")+1:], params: map[string]string{}}
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/") {
+		_ = r.ParseMultipartForm(1 << 20)
+		for name := range r.MultipartForm.File {
+			req.files = append(req.files, name)
+		}
+	} else {
+		_ = r.ParseForm()
+	}
+	for key := range r.Form {
+		req.params[key] = r.Form.Get(key)
+	}
+
+	var result any = true
+	switch req.method {
+	case "getMe":
+		result = tgbotapi.User{ID: 1, IsBot: true, UserName: "koolo_bot"}
+	case "getUpdates":
+		result = []tgbotapi.Update{}
+	case "sendMessage", "sendPhoto":
+		result = tgbotapi.Message{MessageID: 1, Chat: &tgbotapi.Chat{ID: testChatID}}
+	}
+
+	if req.method != "getMe" {
+		f.mu.Lock()
+		f.requests = append(f.requests, req)
+		f.mu.Unlock()
+	}
+
+	raw, _ := json.Marshal(result)
+	_ = json.NewEncoder(w).Encode(tgbotapi.APIResponse{Ok: true, Result: raw})
+}
+
+func (f *fakeAPI) received() []request {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]request(nil), f.requests...)
+}
+
+// texts returns the text of the messages sent to the chat
+func (f *fakeAPI) texts() []string {
+	var texts []string
+	for _, r := range f.received() {
+		if r.method == "sendMessage" {
+			texts = append(texts, r.params["text"])
+		}
+	}
+	return texts
+}
+
+func newTestBot(t *testing.T, manager *commandtest.Manager) (*Bot, *fakeAPI) {
+	t.Helper()
+
+	api := &fakeAPI{}
+	srv := httptest.NewServer(api)
+	t.Cleanup(srv.Close)
+
+	b, err := newBot("token", srv.URL+"/bot%s/%s", testChatID, command.NewRegistry(manager, []string{"7"}), slog.New(slog.NewTextHandler(io.Discard, nil)))
+	if err != nil {
+		t.Fatalf("error creating the bot: %v", err)
+	}
+
+	return b, api
+}
+
+func message(from int64, text string) tgbotapi.Update {
+	m := &tgbotapi.Message{
+		From: &tgbotapi.User{ID: from},
+		Chat: &tgbotapi.Chat{ID: testChatID},
+		Text: text,
+	}
+	if strings.HasPrefix(text, "/") {
+		command, _, _ := strings.Cut(text, " ")
+		m.Entities = []tgbotapi.MessageEntity{{Type: "bot_command", Offset: 0, Length: len(command)}}
+	}
+
+	return tgbotapi.Update{Message: m}
+}
+
+func callback(from int64, data string) tgbotapi.Update {
+	return tgbotapi.Update{CallbackQuery: &tgbotapi.CallbackQuery{
+		ID:      "cb1",
+		From:    &tgbotapi.User{ID: from},
+		Message: &tgbotapi.Message{Chat: &tgbotapi.Chat{ID: testChatID}},
+		Data:    data,
+	}}
+}
+
+func testManager() *commandtest.Manager {
+	return &commandtest.Manager{NoWindow: []string{"broken"}, Statuses: []hub.SupervisorStatus{
+		{Name: "sorc", Status: "In game", StartedAt: time.Now().Add(-time.Hour), Run: "mephisto", Games: 12, Drops: 3, Deaths: 1},
+		{Name: "barb", Status: "Not Started"},
+		{Name: "broken", Status: "In game"},
+	}}
+}
+
+func TestCommandsAreRestrictedToAdmins(t *testing.T) {
+	manager := testManager()
+	b, api := newTestBot(t, manager)
+
+	b.handleUpdate(message(8, "/stop sorc"))
+	b.handleUpdate(callback(8, "stop sorc"))
+	otherChat := message(testAdmin, "/stop sorc")
+	otherChat.Message.Chat.ID = 200
+	b.handleUpdate(otherChat)
+
+	// Only the button callback is answered
+	if len(manager.Stopped()) != 0 || len(api.texts()) != 0 {
+		t.Fatalf("commands from non admins should be ignored, stopped: %v, requests: %+v", manager.Stopped(), api.received())
+	}
+}
+
+func TestCommandsPerSupervisor(t *testing.T) {
+	manager := testManager()
+	manager.PickedUp = []data.Drop{{Item: data.Item{Name: "BerRune", Quality: item.QualityNormal}, Rule: "[name] == berrune"}}
+	b, api := newTestBot(t, manager)
+
+	b.handleUpdate(message(testAdmin, "/status sorc barb"))
+	b.handleUpdate(message(testAdmin, "/stats@koolo_bot sorc"))
+	b.handleUpdate(message(testAdmin, "drops sorc"))
+	b.handleUpdate(message(testAdmin, "/stop barb ghost"))
+
+	expected := []string{
+		"Status of sorc\nStatus: In game\nUptime: 1h0m0s\nRun: mephisto",
+		"Status of barb\nStatus: Offline\nUptime: -",
+		"Stats for sorc\nStatus: In game\nUptime: 1h0m0s\nGames: 12\nDrops: 3\nDeaths: 1\nChickens: 0\nErrors: 0",
+		"Drops for sorc (1)\nBerRune [Normal] - [name] == berrune",
+		"Supervisor 'barb' is not running.",
+		"Supervisor 'ghost' not found.",
+	}
+	texts := api.texts()
+	if len(texts) != len(expected) {
+		t.Fatalf("expected %d messages, got %q", len(expected), texts)
+	}
+	for i := range expected {
+		if texts[i] != expected[i] {
+			t.Errorf("message %d: expected %q, got %q", i, expected[i], texts[i])
+		}
+	}
+	if keyboard := api.received()[0].params["reply_markup"]; !strings.Contains(keyboard, `"callback_data":"pause sorc"`) || !strings.Contains(keyboard, `"callback_data":"stop sorc"`) {
+		t.Errorf("status should have pause and stop buttons, got %q", keyboard)
+	}
+}
+
+func TestStartAndStop(t *testing.T) {
+	manager := testManager()
+	manager.StartErr = errors.New("max concurrent supervisors reached (1)")
+	b, api := newTestBot(t, manager)
+
+	b.handleUpdate(message(testAdmin, "/start barb"))
+	b.handleUpdate(message(testAdmin, "/start sorc"))
+	b.handleUpdate(message(testAdmin, "/stop sorc"))
+
+	deadline := time.Now().Add(2 * time.Second)
+	for len(api.texts()) < 4 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	texts := api.texts()
+	for _, msg := range []string{
+		"Starting supervisor 'barb'.",
+		"Supervisor 'sorc' is already running.",
+		"Status of sorc\nStatus: Offline\nUptime: -\nSupervisor 'sorc' has been stopped.",
+		"Supervisor 'barb' could not be started: max concurrent supervisors reached (1)",
+	} {
+		if !slices.Contains(texts, msg) {
+			t.Errorf("message %q not sent, got %q", msg, texts)
+		}
+	}
+	if started, stopped := manager.Started(), manager.Stopped(); !slices.Equal(started, []string{"barb"}) || !slices.Equal(stopped, []string{"sorc"}) {
+		t.Errorf("unexpected started %v and stopped %v supervisors", started, stopped)
+	}
+}
+
+func TestSupervisorPicker(t *testing.T) {
+	manager := testManager()
+	b, api := newTestBot(t, manager)
+
+	b.handleUpdate(message(testAdmin, "/stats"))
+
+	requests := api.received()
+	if len(requests) != 1 || requests[0].method != "sendMessage" {
+		t.Fatalf("expected a message with the supervisors keyboard, got %+v", requests)
+	}
+	var keyboard tgbotapi.InlineKeyboardMarkup
+	if err := json.Unmarshal([]byte(requests[0].params["reply_markup"]), &keyboard); err != nil {
+		t.Fatalf("invalid keyboard %q: %v", requests[0].params["reply_markup"], err)
+	}
+	if len(keyboard.InlineKeyboard) != 1 || len(keyboard.InlineKeyboard[0]) != 3 {
+		t.Fatalf("unexpected keyboard %+v", keyboard)
+	}
+	if button := keyboard.InlineKeyboard[0][0]; button.Text != "barb" || *button.CallbackData != "stats barb" {
+		t.Errorf("unexpected button %+v", button)
+	}
+
+	// Pressing a button answers the callback and runs the command
+	b.handleUpdate(callback(testAdmin, "stats barb"))
+	requests = api.received()[1:]
+	if len(requests) != 2 || requests[0].method != "answerCallbackQuery" || requests[0].params["callback_query_id"] != "cb1" {
+		t.Fatalf("callback not answered: %+v", requests)
+	}
+	if !strings.HasPrefix(requests[1].params["text"], "Stats for barb\nStatus: Offline") {
+		t.Errorf("unexpected stats %q", requests[1].params["text"])
+	}
+}
+
+func TestScreenshot(t *testing.T) {
+	manager := testManager()
+	b, api := newTestBot(t, manager)
+
+	b.handleUpdate(message(testAdmin, "/screenshot sorc barb broken"))
+
+	requests := api.received()
+	if len(requests) != 3 {
+		t.Fatalf("expected 3 requests, got %+v", requests)
+	}
+	if requests[0].method != "sendPhoto" || requests[0].params["caption"] != "sorc" || len(requests[0].files) != 1 {
+		t.Errorf("screenshot not sent: %+v", requests[0])
+	}
+	if requests[1].params["text"] != "Supervisor 'barb' is not running." {
+		t.Errorf("unexpected message %q", requests[1].params["text"])
+	}
+	if requests[2].params["text"] != "Screenshot for 'broken' could not be taken: window not found" {
+		t.Errorf("unexpected message %q", requests[2].params["text"])
+	}
+}
@@ -0,0 +1,52 @@
+package telegram
+
+import (
+	"log/slog"
+	"slices"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/hectorgimenez/koolo/internal/remote/command"
+)
+
+// Buttons per keyboard row
+const keyboardColumns = 3
+
+func (b *Bot) execute(req command.Request) {
+	// Messages from other users in the chat are ignored
+	if req.Command == "" || !b.commands.Allowed(req) {
+		return
+	}
+
+	req.Reply = b.sendMessage
+	for _, m := range b.commands.Execute(req) {
+		b.sendMessage(m)
+	}
+}
+
+// sendMessage sends the command reply as plain text, buttons are sent as an inline keyboard running the button action
+// when pressed
+func (b *Bot) sendMessage(m command.Message) {
+	if m.Image != nil {
+		if err := b.sendImage(m.PlainText(), m.Image); err != nil {
+			b.logger.Error("Error sending Telegram photo", slog.String("error", err.Error()))
+		}
+		return
+	}
+
+	msg := tgbotapi.NewMessage(b.chatID, m.PlainText())
+	if len(m.Buttons) > 0 {
+		var rows [][]tgbotapi.InlineKeyboardButton
+		for chunk := range slices.Chunk(m.Buttons, keyboardColumns) {
+			row := make([]tgbotapi.InlineKeyboardButton, 0, len(chunk))
+			for _, button := range chunk {
+				row = append(row, tgbotapi.NewInlineKeyboardButtonData(button.Label, button.Action))
+			}
+			rows = append(rows, row)
+		}
+		msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(rows...)
+	}
+
+	if _, err := b.bot.Send(msg); err != nil {
+		b.logger.Error("Error sending Telegram message", slog.String("error", err.Error()))
+	}
+}
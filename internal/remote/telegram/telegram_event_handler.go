@@ -3,6 +3,7 @@ package telegram
 import (
 	"bytes"
 	"context"
+	"image"
 	"image/jpeg"
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
@@ -10,25 +11,34 @@ import (
 )
 
 func (b *Bot) Handle(_ context.Context, e event.Event) error {
+	// Other instances notify their own events
+	if _, remote := e.(event.RemoteEvent); remote {
+		return nil
+	}
+
 	if e.Image() != nil {
-		buf := new(bytes.Buffer)
-		err := jpeg.Encode(buf, e.Image(), nil)
-		if err != nil {
-			return err
-		}
+		return b.sendImage(e.Message(), e.Image())
+	}
 
-		photo := tgbotapi.NewPhoto(b.chatID, tgbotapi.FileBytes{
-			Name:  e.Message(),
-			Bytes: buf.Bytes(),
-		})
-		photo.Caption = e.Message()
+	_, err := b.bot.Send(tgbotapi.NewMessage(b.chatID, e.Message()))
 
-		_, err = b.bot.Send(photo)
+	return err
+}
 
+func (b *Bot) sendImage(caption string, img image.Image) error {
+	buf := new(bytes.Buffer)
+	err := jpeg.Encode(buf, img, nil)
+	if err != nil {
 		return err
 	}
 
-	_, err := b.bot.Send(tgbotapi.NewMessage(b.chatID, e.Message()))
+	photo := tgbotapi.NewPhoto(b.chatID, tgbotapi.FileBytes{
+		Name:  caption,
+		Bytes: buf.Bytes(),
+	})
+	photo.Caption = caption
+
+	_, err = b.bot.Send(photo)
 
 	return err
 }
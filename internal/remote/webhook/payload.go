@@ -0,0 +1,207 @@
+package webhook
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/hectorgimenez/d2go/pkg/data/stat"
+	"github.com/hectorgimenez/koolo/internal/event"
+)
+
+const (
+	EventGameCreated  = "game_created"
+	EventGameFinished = "game_finished"
+	EventRunStarted   = "run_started"
+	EventRunFinished  = "run_finished"
+	EventItemStashed  = "item_stashed"
+	EventChicken      = "chicken"
+	EventDeath        = "death"
+	EventError        = "error"
+	EventUsedPotion   = "used_potion"
+	EventGamePaused   = "game_paused"
+	EventOther        = "other"
+	EventAll          = "all"
+)
+
+// Sent when the endpoint doesn't configure the event list, potions and pauses are too noisy for notifications
+var defaultEvents = []string{EventGameCreated, EventRunFinished, EventItemStashed, EventChicken, EventDeath, EventError}
+
+// Notification is the generic payload, it's also the data available for custom templates
+type Notification struct {
+	Event      string         `json:"event"`
+	Supervisor string         `json:"supervisor"`
+	Message    string         `json:"message"`
+	Text       string         `json:"text"`
+	OccurredAt time.Time      `json:"occurredAt"`
+	Data       map[string]any `json:"data,omitempty"`
+}
+
+// Kind returns the webhook event name for the event, game finished events are split by reason
+func Kind(e event.Event) string {
+	switch evt := e.(type) {
+	case event.GameCreatedEvent:
+		return EventGameCreated
+	case event.GameFinishedEvent:
+		switch evt.Reason {
+		case event.FinishedChicken, event.FinishedMercChicken:
+			return EventChicken
+		case event.FinishedDied:
+			return EventDeath
+		case event.FinishedError:
+			return EventError
+		}
+		return EventGameFinished
+	case event.RunStartedEvent:
+		return EventRunStarted
+	case event.RunFinishedEvent:
+		return EventRunFinished
+	case event.ItemStashedEvent:
+		return EventItemStashed
+	case event.UsedPotionEvent:
+		return EventUsedPotion
+	case event.GamePausedEvent:
+		return EventGamePaused
+	}
+
+	return EventOther
+}
+
+func NewNotification(e event.Event) Notification {
+	n := Notification{
+		Event:      Kind(e),
+		Supervisor: e.Supervisor(),
+		Message:    e.Message(),
+		OccurredAt: e.OccurredAt(),
+		Data:       make(map[string]any),
+	}
+
+	var summary string
+	switch evt := e.(type) {
+	case event.GameCreatedEvent:
+		n.Data["gameName"] = evt.Name
+		summary = "New game created " + evt.Name
+	case event.GameFinishedEvent:
+		n.Data["reason"] = evt.Reason
+		summary = fmt.Sprintf("Game finished (%s)", evt.Reason)
+	case event.RunStartedEvent:
+		n.Data["runName"] = evt.RunName
+		summary = "Starting run " + evt.RunName
+	case event.RunFinishedEvent:
+		n.Data["runName"] = evt.RunName
+		n.Data["reason"] = evt.Reason
+		summary = fmt.Sprintf("Finished run %s (%s)", evt.RunName, evt.Reason)
+	case event.ItemStashedEvent:
+		stats := make(map[string]int, len(evt.Item.Item.Stats))
+		for _, st := range evt.Item.Item.Stats {
+			stats[stat.StringStats[st.ID]] = st.Value
+		}
+		n.Data["name"] = string(evt.Item.Item.Name)
+		n.Data["quality"] = evt.Item.Item.Quality.ToString()
+		n.Data["ethereal"] = evt.Item.Item.Ethereal
+		n.Data["rule"] = evt.Item.Rule
+		n.Data["ruleFile"] = evt.Item.RuleFile
+		n.Data["stats"] = stats
+		summary = fmt.Sprintf("Item stashed %s (%s)", evt.Item.Item.Name, evt.Item.Item.Quality.ToString())
+		if evt.Item.Rule != "" {
+			summary += " matching rule " + evt.Item.Rule
+		}
+	case event.UsedPotionEvent:
+		n.Data["potionType"] = evt.PotionType
+		n.Data["onMerc"] = evt.OnMerc
+		summary = fmt.Sprintf("Used %s potion", evt.PotionType)
+	case event.GamePausedEvent:
+		n.Data["paused"] = evt.Paused
+		summary = "Game paused"
+		if !evt.Paused {
+			summary = "Game resumed"
+		}
+	}
+
+	if n.Message != "" {
+		summary = n.Message
+	}
+	n.Text = fmt.Sprintf("[%s] %s", n.Supervisor, summary)
+	if len(n.Data) == 0 {
+		n.Data = nil
+	}
+
+	return n
+}
+
+// Built-in templates for the supported services, all of them receive a Notification
+var formatTemplates = map[string]string{
+	"slack":  `{"text": {{ json .Text }}}`,
+	"matrix": `{"msgtype": "m.text", "body": {{ json .Text }}}`,
+	"ntfy":   `{"topic": {{ json .Topic }}, "title": {{ json (printf "Koolo: %s" .Event) }}, "message": {{ json .Text }}, "tags": [{{ json .Event }}]}`,
+	"gotify": `{"title": {{ json (printf "Koolo: %s" .Event) }}, "message": {{ json .Text }}, "priority": {{ if eq .Event "death" "chicken" "error" }}8{{ else }}5{{ end }}}`,
+}
+
+var templateFuncs = template.FuncMap{
+	"json": func(v any) (string, error) {
+		b, err := json.Marshal(v)
+		return string(b), err
+	},
+}
+
+// payload builds the request body for the endpoint format, the generic format is the Notification as JSON
+type payload struct {
+	tmpl *template.Template
+	// Extra data for the ntfy template
+	topic string
+}
+
+func newPayload(format, customTemplate, endpointURL string) (payload, error) {
+	text := customTemplate
+	switch format {
+	case "", "generic":
+		return payload{}, nil
+	case "custom":
+		if customTemplate == "" {
+			return payload{}, fmt.Errorf("custom format requires a template")
+		}
+	default:
+		var found bool
+		if text, found = formatTemplates[format]; !found {
+			return payload{}, fmt.Errorf("unknown webhook format %q", format)
+		}
+	}
+
+	tmpl, err := template.New(format).Funcs(templateFuncs).Parse(text)
+	if err != nil {
+		return payload{}, fmt.Errorf("error parsing webhook template: %w", err)
+	}
+
+	p := payload{tmpl: tmpl}
+	if format == "ntfy" {
+		u, err := url.Parse(endpointURL)
+		if err != nil {
+			return payload{}, fmt.Errorf("invalid ntfy url: %w", err)
+		}
+		p.topic = strings.Trim(u.Path, "/")
+	}
+
+	return p, nil
+}
+
+func (p payload) render(n Notification) ([]byte, error) {
+	if p.tmpl == nil {
+		return json.Marshal(n)
+	}
+
+	data := struct {
+		Notification
+		Topic string
+	}{Notification: n, Topic: p.topic}
+
+	buf := new(bytes.Buffer)
+	if err := p.tmpl.Execute(buf, data); err != nil {
+		return nil, fmt.Errorf("error rendering webhook template: %w", err)
+	}
+
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,245 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hectorgimenez/koolo/internal/config"
+	"github.com/hectorgimenez/koolo/internal/event"
+)
+
+const (
+	defaultMaxRetries = 3
+	defaultBaseDelay  = time.Second
+	maxDelay          = 30 * time.Second
+	requestTimeout    = 10 * time.Second
+
+	SignatureHeader = "X-Koolo-Signature"
+	TimestampHeader = "X-Koolo-Timestamp"
+	EventHeader     = "X-Koolo-Event"
+	DeliveryHeader  = "X-Koolo-Delivery"
+)
+
+// Notifier sends events as JSON to the configured endpoints
+type Notifier struct {
+	endpoints []*endpoint
+	client    *http.Client
+	logger    *slog.Logger
+	// Delay before the first retry, it's doubled on every attempt
+	baseDelay time.Duration
+}
+
+type endpoint struct {
+	cfg     config.WebhookEndpoint
+	url     string
+	method  string
+	events  []string
+	payload payload
+}
+
+func NewNotifier(endpoints []config.WebhookEndpoint, logger *slog.Logger) (*Notifier, error) {
+	n := &Notifier{
+		client:    &http.Client{Timeout: requestTimeout},
+		logger:    logger,
+		baseDelay: defaultBaseDelay,
+	}
+
+	for i, cfg := range endpoints {
+		if cfg.Name == "" {
+			cfg.Name = fmt.Sprintf("webhook-%d", i+1)
+		}
+
+		ep, err := newEndpoint(cfg)
+		if err != nil {
+			return nil, fmt.Errorf("error configuring webhook %s: %w", cfg.Name, err)
+		}
+		n.endpoints = append(n.endpoints, ep)
+	}
+
+	return n, nil
+}
+
+func newEndpoint(cfg config.WebhookEndpoint) (*endpoint, error) {
+	u, err := url.Parse(cfg.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return nil, fmt.Errorf("invalid url %q", cfg.URL)
+	}
+
+	p, err := newPayload(cfg.Format, cfg.Template, cfg.URL)
+	if err != nil {
+		return nil, err
+	}
+
+	ep := &endpoint{cfg: cfg, url: cfg.URL, method: http.MethodPost, events: cfg.Events, payload: p}
+	if len(ep.events) == 0 {
+		ep.events = defaultEvents
+	}
+	// Negative values are fixed when the config is loaded, but they would skip the first attempt too
+	if cfg.MaxRetries <= 0 {
+		ep.cfg.MaxRetries = defaultMaxRetries
+	}
+
+	switch cfg.Format {
+	case "ntfy":
+		// JSON messages are published to the root url, the topic goes in the body
+		u.Path = "/"
+		ep.url = u.String()
+	case "gotify":
+		if !strings.HasSuffix(u.Path, "/message") {
+			u.Path = strings.TrimSuffix(u.Path, "/") + "/message"
+		}
+		ep.url = u.String()
+	case "matrix":
+		// Matrix requires PUT with a transaction ID appended to the url, see send()
+		ep.method = http.MethodPut
+	}
+
+	return ep, nil
+}
+
+func (ep *endpoint) wants(kind string) bool {
+	return slices.Contains(ep.events, EventAll) || slices.Contains(ep.events, kind)
+}
+
+// Handle is the event handler, each endpoint is notified concurrently so a failing one doesn't delay the others
+func (n *Notifier) Handle(ctx context.Context, e event.Event) error {
+	// Other instances notify their own events
+	if _, remote := e.(event.RemoteEvent); remote {
+		return nil
+	}
+
+	notification := NewNotification(e)
+
+	var wg sync.WaitGroup
+	errs := make([]error, len(n.endpoints))
+	for i, ep := range n.endpoints {
+		if !ep.wants(notification.Event) {
+			continue
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := n.send(ctx, ep, notification); err != nil {
+				errs[i] = fmt.Errorf("webhook %s: %w", ep.cfg.Name, err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	return errors.Join(errs...)
+}
+
+func (n *Notifier) send(ctx context.Context, ep *endpoint, notification Notification) error {
+	body, err := ep.payload.render(notification)
+	if err != nil {
+		return err
+	}
+
+	// Same delivery ID for all the attempts, so receivers can deduplicate
+	deliveryID, err := newDeliveryID()
+	if err != nil {
+		return err
+	}
+
+	reqURL := ep.url
+	if ep.cfg.Format == "matrix" {
+		reqURL = strings.TrimSuffix(reqURL, "/") + "/" + deliveryID
+	}
+
+	var lastErr error
+	for attempt := 0; attempt <= ep.cfg.MaxRetries; attempt++ {
+		if attempt > 0 {
+			delay := min(n.baseDelay<<(attempt-1), maxDelay)
+			select {
+			case <-time.After(delay):
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+
+		retry, err := n.do(ctx, ep, reqURL, notification.Event, deliveryID, body)
+		if err == nil {
+			return nil
+		}
+		lastErr = err
+		if !retry {
+			break
+		}
+
+		n.logger.Debug("Webhook delivery failed, retrying", slog.String("webhook", ep.cfg.Name), slog.Int("attempt", attempt+1), slog.Any("error", err))
+	}
+
+	return lastErr
+}
+
+// do sends a single request, it returns if the error is temporary and the request should be retried
+func (n *Notifier) do(ctx context.Context, ep *endpoint, reqURL, kind, deliveryID string, body []byte) (bool, error) {
+	req, err := http.NewRequestWithContext(ctx, ep.method, reqURL, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "koolo/"+config.Version)
+	req.Header.Set(EventHeader, kind)
+	req.Header.Set(DeliveryHeader, deliveryID)
+	for k, v := range ep.cfg.Headers {
+		req.Header.Set(k, v)
+	}
+
+	if ep.cfg.Secret != "" {
+		timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+		req.Header.Set(TimestampHeader, timestamp)
+		req.Header.Set(SignatureHeader, "sha256="+Sign(ep.cfg.Secret, timestamp, body))
+	}
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return ctx.Err() == nil, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64*1024))
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return false, nil
+	}
+
+	retry := resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode == http.StatusRequestTimeout
+	return retry, fmt.Errorf("unexpected status code %d", resp.StatusCode)
+}
+
+// Sign returns the hex encoded HMAC-SHA256 of "timestamp.body", receivers should compute the same value with the
+// X-Koolo-Timestamp header and compare it with X-Koolo-Signature
+func Sign(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func newDeliveryID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,265 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hectorgimenez/d2go/pkg/data"
+	"github.com/hectorgimenez/d2go/pkg/data/item"
+	"github.com/hectorgimenez/koolo/internal/config"
+	"github.com/hectorgimenez/koolo/internal/event"
+)
+
+type receivedRequest struct {
+	method string
+	path   string
+	header http.Header
+	body   []byte
+}
+
+// standIn is a local HTTP server recording the requests, it replies with the given status codes in order and then 200
+type standIn struct {
+	*httptest.Server
+	mu       sync.Mutex
+	requests []receivedRequest
+	statuses []int
+}
+
+func newStandIn(t *testing.T, statuses ...int) *standIn {
+	t.Helper()
+
+	s := &standIn{statuses: statuses}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.requests = append(s.requests, receivedRequest{method: r.Method, path: r.URL.Path, header: r.Header.Clone(), body: body})
+		status := http.StatusOK
+		if len(s.statuses) > 0 {
+			status, s.statuses = s.statuses[0], s.statuses[1:]
+		}
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(s.Close)
+
+	return s
+}
+
+func (s *standIn) received() []receivedRequest {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]receivedRequest(nil), s.requests...)
+}
+
+func newTestNotifier(t *testing.T, endpoints ...config.WebhookEndpoint) *Notifier {
+	t.Helper()
+
+	n, err := NewNotifier(endpoints, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	n.baseDelay = time.Millisecond
+
+	return n
+}
+
+func stashedEvent() event.Event {
+	return event.ItemStashed(event.Text("sorc", ""), data.Drop{
+		Item:     data.Item{Name: "Shako", Quality: item.QualityUnique},
+		Rule:     "[name] == shako",
+		RuleFile: "unique.nip",
+	}, "", time.Time{})
+}
+
+func TestGenericPayloadAndFilters(t *testing.T) {
+	srv := newStandIn(t)
+	n := newTestNotifier(t, config.WebhookEndpoint{URL: srv.URL, Events: []string{EventItemStashed, EventChicken}})
+
+	events := []event.Event{
+		event.RunStarted(event.Text("sorc", ""), "mephisto"),
+		event.GameFinished(event.Text("sorc", ""), event.FinishedDied),
+		event.GameFinished(event.Text("sorc", ""), event.FinishedMercChicken),
+		stashedEvent(),
+	}
+	for _, e := range events {
+		if err := n.Handle(context.Background(), e); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	reqs := srv.received()
+	if len(reqs) != 2 {
+		t.Fatalf("expected 2 requests, got %d", len(reqs))
+	}
+
+	var notification Notification
+	if err := json.Unmarshal(reqs[1].body, &notification); err != nil {
+		t.Fatal(err)
+	}
+	if notification.Event != EventItemStashed || notification.Supervisor != "sorc" {
+		t.Errorf("unexpected notification %+v", notification)
+	}
+	if notification.Data["rule"] != "[name] == shako" || notification.Data["ruleFile"] != "unique.nip" || notification.Data["quality"] != "Unique" {
+		t.Errorf("missing item data %+v", notification.Data)
+	}
+	if reqs[0].header.Get(EventHeader) != EventChicken {
+		t.Errorf("expected chicken event header, got %s", reqs[0].header.Get(EventHeader))
+	}
+}
+
+func TestRemoteEventsAreSkipped(t *testing.T) {
+	srv := newStandIn(t)
+	n := newTestNotifier(t, config.WebhookEndpoint{URL: srv.URL, Events: []string{EventAll}})
+
+	// Other instances notify their own events
+	if err := n.Handle(context.Background(), event.Remote(stashedEvent(), "pc2", "Sorc")); err != nil {
+		t.Fatal(err)
+	}
+	if reqs := srv.received(); len(reqs) != 0 {
+		t.Errorf("remote events should not be sent, got %d requests", len(reqs))
+	}
+}
+
+func TestFormats(t *testing.T) {
+	srv := newStandIn(t)
+	n := newTestNotifier(t,
+		config.WebhookEndpoint{Name: "slack", URL: srv.URL + "/slack", Format: "slack", Events: []string{EventAll}},
+		config.WebhookEndpoint{Name: "matrix", URL: srv.URL + "/_matrix/client/v3/rooms/abc/send/m.room.message", Format: "matrix", Events: []string{EventAll}},
+		config.WebhookEndpoint{Name: "ntfy", URL: srv.URL + "/koolo-drops", Format: "ntfy", Events: []string{EventAll}},
+		config.WebhookEndpoint{Name: "gotify", URL: srv.URL, Format: "gotify", Events: []string{EventAll}, Headers: map[string]string{"X-Gotify-Key": "secret"}},
+		config.WebhookEndpoint{Name: "custom", URL: srv.URL + "/custom", Format: "custom", Template: `{"who": {{ json .Supervisor }}, "what": {{ json .Data.name }}}`, Events: []string{EventAll}},
+	)
+
+	if err := n.Handle(context.Background(), stashedEvent()); err != nil {
+		t.Fatal(err)
+	}
+
+	bodies := make(map[string]map[string]any)
+	for _, r := range srv.received() {
+		var body map[string]any
+		if err := json.Unmarshal(r.body, &body); err != nil {
+			t.Fatalf("invalid json sent to %s: %s", r.path, r.body)
+		}
+
+		switch {
+		case strings.HasPrefix(r.path, "/_matrix"):
+			if r.method != http.MethodPut || !strings.HasPrefix(r.path, "/_matrix/client/v3/rooms/abc/send/m.room.message/") {
+				t.Errorf("unexpected matrix request %s %s", r.method, r.path)
+			}
+			bodies["matrix"] = body
+		case r.path == "/":
+			bodies["ntfy"] = body
+		case r.path == "/message":
+			if r.header.Get("X-Gotify-Key") != "secret" {
+				t.Errorf("missing gotify key header")
+			}
+			bodies["gotify"] = body
+		default:
+			bodies[strings.TrimPrefix(r.path, "/")] = body
+		}
+	}
+
+	text := "[sorc] Item stashed Shako (Unique) matching rule [name] == shako"
+	if bodies["slack"]["text"] != text {
+		t.Errorf("unexpected slack payload %v", bodies["slack"])
+	}
+	if bodies["matrix"]["body"] != text || bodies["matrix"]["msgtype"] != "m.text" {
+		t.Errorf("unexpected matrix payload %v", bodies["matrix"])
+	}
+	if bodies["ntfy"]["topic"] != "koolo-drops" || bodies["ntfy"]["message"] != text {
+		t.Errorf("unexpected ntfy payload %v", bodies["ntfy"])
+	}
+	if bodies["gotify"]["message"] != text {
+		t.Errorf("unexpected gotify payload %v", bodies["gotify"])
+	}
+	if bodies["custom"]["who"] != "sorc" || bodies["custom"]["what"] != "Shako" {
+		t.Errorf("unexpected custom payload %v", bodies["custom"])
+	}
+}
+
+func TestSignature(t *testing.T) {
+	srv := newStandIn(t)
+	n := newTestNotifier(t, config.WebhookEndpoint{URL: srv.URL, Secret: "s3cr3t"})
+
+	if err := n.Handle(context.Background(), stashedEvent()); err != nil {
+		t.Fatal(err)
+	}
+
+	r := srv.received()[0]
+	expected := "sha256=" + Sign("s3cr3t", r.header.Get(TimestampHeader), r.body)
+	if r.header.Get(SignatureHeader) != expected {
+		t.Errorf("invalid signature %s, expected %s", r.header.Get(SignatureHeader), expected)
+	}
+	if Sign("other", r.header.Get(TimestampHeader), r.body) == Sign("s3cr3t", r.header.Get(TimestampHeader), r.body) {
+		t.Errorf("signature must depend on the secret")
+	}
+}
+
+func TestRetries(t *testing.T) {
+	srv := newStandIn(t, http.StatusInternalServerError, http.StatusTooManyRequests)
+	n := newTestNotifier(t, config.WebhookEndpoint{URL: srv.URL})
+
+	if err := n.Handle(context.Background(), stashedEvent()); err != nil {
+		t.Fatalf("expected success after retries, got %v", err)
+	}
+
+	reqs := srv.received()
+	if len(reqs) != 3 {
+		t.Fatalf("expected 3 attempts, got %d", len(reqs))
+	}
+	if reqs[0].header.Get(DeliveryHeader) != reqs[2].header.Get(DeliveryHeader) {
+		t.Errorf("delivery ID must be the same between retries")
+	}
+
+	// Client errors are not retried
+	srv = newStandIn(t, http.StatusBadRequest)
+	n = newTestNotifier(t, config.WebhookEndpoint{URL: srv.URL})
+	if err := n.Handle(context.Background(), stashedEvent()); err == nil {
+		t.Errorf("expected error on bad request")
+	}
+	if len(srv.received()) != 1 {
+		t.Errorf("bad request must not be retried")
+	}
+
+	// Giving up after max retries
+	srv = newStandIn(t, 500, 500, 500)
+	n = newTestNotifier(t, config.WebhookEndpoint{URL: srv.URL, MaxRetries: 2})
+	if err := n.Handle(context.Background(), stashedEvent()); err == nil {
+		t.Errorf("expected error after max retries")
+	}
+	if len(srv.received()) != 3 {
+		t.Errorf("expected 3 attempts, got %d", len(srv.received()))
+	}
+
+	// Negative retries still send the notification
+	srv = newStandIn(t, http.StatusOK)
+	n = newTestNotifier(t, config.WebhookEndpoint{URL: srv.URL, MaxRetries: -1})
+	if err := n.Handle(context.Background(), stashedEvent()); err != nil || len(srv.received()) != 1 {
+		t.Errorf("expected 1 attempt, got %d: %v", len(srv.received()), err)
+	}
+}
+
+func TestInvalidConfig(t *testing.T) {
+	invalid := []config.WebhookEndpoint{
+		{URL: "ftp://example.com"},
+		{URL: "http://example.com", Format: "unknown"},
+		{URL: "http://example.com", Format: "custom"},
+		{URL: "http://example.com", Format: "custom", Template: "{{ .Nope "},
+	}
+
+	for _, cfg := range invalid {
+		if _, err := NewNotifier([]config.WebhookEndpoint{cfg}, slog.Default()); err == nil {
+			t.Errorf("expected error for %+v", cfg)
+		}
+	}
+}
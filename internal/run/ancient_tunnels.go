@@ -1,6 +1,8 @@
 package run
 
 import (
+	"time"
+
 	"github.com/hectorgimenez/d2go/pkg/data"
 	"github.com/hectorgimenez/d2go/pkg/data/area"
 	"github.com/hectorgimenez/koolo/internal/action"
@@ -10,11 +12,14 @@ import (
 
 type AncientTunnels struct {
 	ctx *context.Status
+	// deadline stops the run once reached, zero means no limit
+	deadline time.Time
 }
 
-func NewAncientTunnels() *AncientTunnels {
+func NewAncientTunnels(deadline time.Time) *AncientTunnels {
 	return &AncientTunnels{
-		ctx: context.Get(),
+		ctx:      context.Get(),
+		deadline: deadline,
 	}
 }
 
@@ -44,5 +49,5 @@ func (a AncientTunnels) Run() error {
 
 	// Clear Ancient Tunnels
 
-	return action.ClearCurrentLevel(openChests, filter)
+	return action.ClearCurrentLevelUntil(a.deadline, openChests, filter)
 }
@@ -0,0 +1,112 @@
+package run
+
+import (
+	"log/slog"
+	"time"
+
+	"github.com/hectorgimenez/d2go/pkg/data"
+	"github.com/hectorgimenez/d2go/pkg/data/stat"
+	"github.com/hectorgimenez/koolo/internal/action"
+	"github.com/hectorgimenez/koolo/internal/companion"
+	"github.com/hectorgimenez/koolo/internal/config"
+	"github.com/hectorgimenez/koolo/internal/context"
+	"github.com/hectorgimenez/koolo/internal/game"
+	"github.com/hectorgimenez/koolo/internal/utils"
+)
+
+const (
+	// The follower leaves the game if the leader is not in the party roster for this long
+	companionLeaderGoneTimeout = 15 * time.Second
+	// Don't move closer to the leader than this
+	companionFollowDistance = 8
+)
+
+// Companion is the only run of a companion follower, it assists the leader until the leader leaves the game
+type Companion struct {
+	ctx      *context.Status
+	follower *companion.Follower
+}
+
+func NewCompanion(follower *companion.Follower) *Companion {
+	return &Companion{
+		ctx:      context.Get(),
+		follower: follower,
+	}
+}
+
+func (c Companion) Name() string {
+	return string(config.CompanionRun)
+}
+
+func (c Companion) Run() error {
+	cfg := c.ctx.CharacterCfg.Companion
+	var leaderMissingSince time.Time
+
+	for {
+		c.ctx.PauseIfNotPriority()
+
+		if c.follower.LeaderLeft() {
+			c.ctx.Logger.Info("Leader left the game, leaving too")
+			return nil
+		}
+
+		// Portals are owned by the character name, it can change if the leader runs in another koolo instance
+		leaderCharacter := c.follower.LeaderCharacter()
+		leader, inGame := c.ctx.Data.Roster.FindByName(leaderCharacter)
+		if !inGame {
+			if leaderMissingSince.IsZero() {
+				leaderMissingSince = time.Now()
+			}
+			if time.Since(leaderMissingSince) > companionLeaderGoneTimeout {
+				c.ctx.Logger.Info("Leader is not in the game anymore, leaving")
+				return nil
+			}
+		} else {
+			leaderMissingSince = time.Time{}
+		}
+
+		inTown := c.ctx.Data.PlayerUnit.Area.IsTown()
+		switch {
+		case inTown && (c.follower.TakeTPRequest() || inGame && cfg.FollowLeader && !leader.Area.IsTown()):
+			if err := action.UsePortalFrom(leaderCharacter); err != nil {
+				c.ctx.Logger.Debug("Leader portal not available", slog.String("error", err.Error()))
+			}
+		case !inTown && cfg.Attack && c.attackLeaderTarget():
+			continue
+		case !inTown && inGame && cfg.FollowLeader && leader.Area.IsTown():
+			if err := action.ReturnTown(); err != nil {
+				c.ctx.Logger.Debug("Error following the leader to town", slog.String("error", err.Error()))
+			}
+		case inGame && cfg.FollowLeader && leader.Area == c.ctx.Data.PlayerUnit.Area &&
+			c.ctx.PathFinder.DistanceFromMe(leader.Position) > companionFollowDistance:
+			_ = action.MoveToCoords(leader.Position)
+		}
+
+		utils.Sleep(250)
+	}
+}
+
+// attackLeaderTarget attacks the monster the leader is attacking, returns false if there is nothing to attack
+func (c Companion) attackLeaderTarget() bool {
+	target, found := c.follower.Target()
+	if !found {
+		return false
+	}
+	if m, found := c.ctx.Data.Monsters.FindByID(target); !found || m.Stats[stat.Life] <= 0 {
+		return false
+	}
+
+	err := c.ctx.Char.KillMonsterSequence(func(d game.Data) (data.UnitID, bool) {
+		m, found := d.Monsters.FindByID(target)
+		if !found || m.Stats[stat.Life] <= 0 {
+			return 0, false
+		}
+
+		return target, true
+	}, nil)
+	if err != nil {
+		c.ctx.Logger.Debug("Error attacking the leader target", slog.String("error", err.Error()))
+	}
+
+	return true
+}
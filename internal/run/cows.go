@@ -3,6 +3,7 @@ package run
 import (
 	"errors"
 	"strings"
+	"time"
 
 	"github.com/hectorgimenez/d2go/pkg/data"
 	"github.com/hectorgimenez/d2go/pkg/data/area"
@@ -18,11 +19,14 @@ import (
 
 type Cows struct {
 	ctx *context.Status
+	// deadline stops the run once reached, zero means no limit
+	deadline time.Time
 }
 
-func NewCows() *Cows {
+func NewCows(deadline time.Time) *Cows {
 	return &Cows{
-		ctx: context.Get(),
+		ctx:      context.Get(),
+		deadline: deadline,
 	}
 }
 
@@ -103,7 +107,7 @@ func (a Cows) Run() error {
 		return err
 	}
 
-	return action.ClearCurrentLevel(a.ctx.CharacterCfg.Game.Cows.OpenChests, data.MonsterAnyFilter())
+	return action.ClearCurrentLevelUntil(a.deadline, a.ctx.CharacterCfg.Game.Cows.OpenChests, data.MonsterAnyFilter())
 }
 
 func (a Cows) getWirtsLeg() error {
@@ -16,11 +16,14 @@ var diabloSpawnPosition = data.Position{X: 7792, Y: 5294}
 
 type Diablo struct {
 	ctx *context.Status
+	// deadline stops the run once reached, zero means no limit
+	deadline time.Time
 }
 
-func NewDiablo() *Diablo {
+func NewDiablo(deadline time.Time) *Diablo {
 	return &Diablo{
-		ctx: context.Get(),
+		ctx:      context.Get(),
+		deadline: deadline,
 	}
 }
 
@@ -52,7 +55,7 @@ func (d *Diablo) Run() error {
 		}
 	}
 
-	if d.ctx.CharacterCfg.Companion.Leader {
+	if d.ctx.CharacterCfg.Companion.Enabled && d.ctx.CharacterCfg.Companion.Leader {
 		action.OpenTPIfLeader()
 		action.Buff()
 		action.ClearAreaAroundPlayer(30, data.MonsterAnyFilter())
@@ -66,6 +69,11 @@ func (d *Diablo) Run() error {
 
 	// Thanks Go for the lack of ordered maps
 	for _, bossName := range []string{"Vizier", "Lord De Seis", "Infector"} {
+		if action.DeadlineReached(d.deadline) {
+			d.ctx.Logger.Info(fmt.Sprintf("Time budget exceeded, skipping %s and the remaining seals", bossName))
+			return nil
+		}
+
 		d.ctx.Logger.Debug("Heading to", bossName)
 
 		for _, sealID := range sealGroups[bossName] {
@@ -116,6 +124,11 @@ func (d *Diablo) Run() error {
 	}
 
 	if d.ctx.CharacterCfg.Game.Diablo.KillDiablo {
+		if action.DeadlineReached(d.deadline) {
+			d.ctx.Logger.Info("Time budget exceeded, skipping Diablo")
+			return nil
+		}
+
 		action.Buff()
 
 		action.MoveToCoords(diabloSpawnPosition)
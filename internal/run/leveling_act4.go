@@ -1,6 +1,8 @@
 package run
 
 import (
+	"time"
+
 	"github.com/hectorgimenez/d2go/pkg/data"
 	"github.com/hectorgimenez/d2go/pkg/data/area"
 	"github.com/hectorgimenez/d2go/pkg/data/npc"
@@ -20,7 +22,7 @@ func (a Leveling) act4() error {
 		a.izual()
 	}
 
-	diabloRun := NewDiablo()
+	diabloRun := NewDiablo(time.Time{})
 	err := diabloRun.Run()
 	if err != nil {
 		return err
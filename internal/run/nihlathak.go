@@ -3,6 +3,7 @@ package run
 import (
 	"errors"
 	"log/slog"
+	"time"
 
 	"github.com/hectorgimenez/d2go/pkg/data"
 	"github.com/hectorgimenez/d2go/pkg/data/area"
@@ -16,11 +17,14 @@ import (
 
 type Nihlathak struct {
 	ctx *context.Status
+	// deadline stops the run once reached, zero means no limit
+	deadline time.Time
 }
 
-func NewNihlathak() *Nihlathak {
+func NewNihlathak(deadline time.Time) *Nihlathak {
 	return &Nihlathak{
-		ctx: context.Get(),
+		ctx:      context.Get(),
+		deadline: deadline,
 	}
 }
 
@@ -53,6 +57,11 @@ func (n Nihlathak) Run() error {
 	// Try to position in the safest corner
 	action.MoveToCoords(n.findBestCorner(o.Position))
 
+	if action.DeadlineReached(n.deadline) {
+		n.ctx.Logger.Info("Time budget exceeded, skipping Nihlathak")
+		return nil
+	}
+
 	// Disable item pickup before the fight
 	n.ctx.DisableItemPickup()
 
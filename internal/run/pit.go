@@ -1,6 +1,8 @@
 package run
 
 import (
+	"time"
+
 	"github.com/hectorgimenez/d2go/pkg/data"
 	"github.com/hectorgimenez/d2go/pkg/data/area"
 	"github.com/hectorgimenez/koolo/internal/action"
@@ -10,11 +12,14 @@ import (
 
 type Pit struct {
 	ctx *context.Status
+	// deadline stops the run once reached, zero means no limit
+	deadline time.Time
 }
 
-func NewPit() *Pit {
+func NewPit(deadline time.Time) *Pit {
 	return &Pit{
-		ctx: context.Get(),
+		ctx:      context.Get(),
+		deadline: deadline,
 	}
 }
 
@@ -63,16 +68,21 @@ func (p Pit) Run() error {
 
 	// Clear the area if we don't have only clear lvl2 selected
 	if !p.ctx.CharacterCfg.Game.Pit.OnlyClearLevel2 {
-		if err := action.ClearCurrentLevel(p.ctx.CharacterCfg.Game.Pit.OpenChests, monsterFilter); err != nil {
+		if err := action.ClearCurrentLevelUntil(p.deadline, p.ctx.CharacterCfg.Game.Pit.OpenChests, monsterFilter); err != nil {
 			return err
 		}
 	}
 
+	if action.DeadlineReached(p.deadline) {
+		p.ctx.Logger.Info("Time budget exceeded, skipping Pit level 2")
+		return nil
+	}
+
 	// Move to PitLvl2
 	if err := action.MoveToArea(area.PitLevel2); err != nil {
 		return err
 	}
 
 	// Clear it
-	return action.ClearCurrentLevel(p.ctx.CharacterCfg.Game.Pit.OpenChests, monsterFilter)
+	return action.ClearCurrentLevelUntil(p.deadline, p.ctx.CharacterCfg.Game.Pit.OpenChests, monsterFilter)
 }
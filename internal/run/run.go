@@ -1,6 +1,8 @@
 package run
 
 import (
+	"time"
+
 	"github.com/hectorgimenez/koolo/internal/config"
 )
 
@@ -10,10 +12,6 @@ type Run interface {
 }
 
 func BuildRuns(cfg *config.CharacterCfg) (runs []Run) {
-	//if cfg.Companion.Enabled && !cfg.Companion.Leader {
-	//	return []Run{Companion{baseRun: baseRun}}
-	//}
-
 	for _, run := range cfg.Game.Runs {
 		// Prepend terror zone runs, we want to run it always first
 		if run == config.TerrorZoneRun {
@@ -42,27 +40,27 @@ func BuildRuns(cfg *config.CharacterCfg) (runs []Run) {
 		case config.MephistoRun:
 			runs = append(runs, NewMephisto(nil))
 		case config.TravincalRun:
-			runs = append(runs, NewTravincal())
+			runs = append(runs, NewTravincal(time.Time{}))
 		case config.DiabloRun:
-			runs = append(runs, NewDiablo())
+			runs = append(runs, NewDiablo(time.Time{}))
 		case config.EldritchRun:
 			runs = append(runs, NewEldritch())
 		case config.PindleskinRun:
 			runs = append(runs, NewPindleskin())
 		case config.NihlathakRun:
-			runs = append(runs, NewNihlathak())
+			runs = append(runs, NewNihlathak(time.Time{}))
 		case config.AncientTunnelsRun:
-			runs = append(runs, NewAncientTunnels())
+			runs = append(runs, NewAncientTunnels(time.Time{}))
 		case config.MausoleumRun:
 			runs = append(runs, NewMausoleum())
 		case config.PitRun:
-			runs = append(runs, NewPit())
+			runs = append(runs, NewPit(time.Time{}))
 		case config.StonyTombRun:
-			runs = append(runs, NewStonyTomb())
+			runs = append(runs, NewStonyTomb(time.Time{}))
 		case config.ArachnidLairRun:
 			runs = append(runs, NewArachnidLair())
 		case config.TristramRun:
-			runs = append(runs, NewTristram())
+			runs = append(runs, NewTristram(time.Time{}))
 		case config.LowerKurastRun:
 			runs = append(runs, NewLowerKurast())
 		case config.LowerKurastChestRun:
@@ -70,13 +68,13 @@ func BuildRuns(cfg *config.CharacterCfg) (runs []Run) {
 		case config.BaalRun:
 			runs = append(runs, NewBaal(nil))
 		case config.TalRashaTombsRun:
-			runs = append(runs, NewTalRashaTombs())
+			runs = append(runs, NewTalRashaTombs(time.Time{}))
 		case config.LevelingRun:
 			runs = append(runs, NewLeveling())
 		case config.QuestsRun:
 			runs = append(runs, NewQuests())
 		case config.CowsRun:
-			runs = append(runs, NewCows())
+			runs = append(runs, NewCows(time.Time{}))
 		case config.ThreshsocketRun:
 			runs = append(runs, NewThreshsocket())
 		case config.SpiderCavernRun:
@@ -85,6 +83,11 @@ func BuildRuns(cfg *config.CharacterCfg) (runs []Run) {
 			runs = append(runs, NewDriverCavern())
 		case config.EnduguRun:
 			runs = append(runs, NewEndugu())
+		default:
+			// Custom runs from config/{character}/runs
+			if script, found := cfg.Runtime.RunScripts[run]; found {
+				runs = append(runs, NewScript(script))
+			}
 		}
 	}
 
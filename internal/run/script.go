@@ -0,0 +1,135 @@
+package run
+
+import (
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/hectorgimenez/d2go/pkg/data"
+	"github.com/hectorgimenez/d2go/pkg/data/npc"
+	"github.com/hectorgimenez/d2go/pkg/data/object"
+	"github.com/hectorgimenez/d2go/pkg/data/quest"
+	"github.com/hectorgimenez/d2go/pkg/data/stat"
+	"github.com/hectorgimenez/koolo/internal/action"
+	"github.com/hectorgimenez/koolo/internal/config"
+	"github.com/hectorgimenez/koolo/internal/context"
+	"github.com/hectorgimenez/koolo/internal/game"
+)
+
+// Script is a custom run defined in the character config directory, see config.RunScript for the format
+type Script struct {
+	ctx    *context.Status
+	script *config.RunScript
+}
+
+func NewScript(script *config.RunScript) *Script {
+	return &Script{
+		ctx:    context.Get(),
+		script: script,
+	}
+}
+
+func (s Script) Name() string {
+	return string(s.script.Name)
+}
+
+func (s Script) Run() error {
+	for i, st := range s.script.Steps {
+		if !s.shouldRun(st.If) {
+			s.ctx.Logger.Debug("Skipping run script step, condition not met", slog.String("run", s.Name()), slog.Int("step", i))
+			continue
+		}
+
+		if err := s.runStep(st); err != nil {
+			return fmt.Errorf("%s step %d: %w", s.script.File, i, err)
+		}
+	}
+
+	return nil
+}
+
+// runStep runs the only action of the step, scripts are checked when loaded but they could have been changed later
+func (s Script) runStep(st config.RunStep) error {
+	if actions := st.Actions(); actions != 1 {
+		return fmt.Errorf("step has %d actions, expected one", actions)
+	}
+
+	switch {
+	case st.WayPoint != "":
+		dst, found := config.AreaByName(st.WayPoint)
+		if !found {
+			return fmt.Errorf("unknown area %q", st.WayPoint)
+		}
+		return action.WayPoint(dst)
+	case st.MoveToArea != "":
+		dst, found := config.AreaByName(st.MoveToArea)
+		if !found {
+			return fmt.Errorf("unknown area %q", st.MoveToArea)
+		}
+		return action.MoveToArea(dst)
+	case st.MoveToObject != nil:
+		return s.moveToObject(object.Name(*st.MoveToObject))
+	case st.ClearLevel != nil:
+		filter := data.MonsterAnyFilter()
+		if strings.EqualFold(st.ClearLevel.Filter, config.MonsterFilterElite) {
+			filter = data.MonsterEliteFilter()
+		}
+		return action.ClearCurrentLevel(st.ClearLevel.OpenChests, filter)
+	case st.KillUnique != nil:
+		return s.killUnique(npc.ID(st.KillUnique.NPC), st.KillUnique.SkipOnImmunities)
+	case st.OpenChests != nil:
+		return action.OpenChestsAround(st.OpenChests.ChestsRadius(), st.OpenChests.SuperChestsOnly)
+	case st.ReturnToTown:
+		return action.ReturnTown()
+	}
+
+	return nil
+}
+
+func (s Script) shouldRun(c *config.RunCondition) bool {
+	if c == nil {
+		return true
+	}
+
+	lvl, _ := s.ctx.Data.PlayerUnit.FindStat(stat.Level, 0)
+	if c.MinLevel > 0 && lvl.Value < c.MinLevel {
+		return false
+	}
+	if c.MaxLevel > 0 && lvl.Value > c.MaxLevel {
+		return false
+	}
+	if c.QuestCompleted != nil && !s.ctx.Data.Quests[quest.Quest(*c.QuestCompleted)].Completed() {
+		return false
+	}
+	if c.QuestNotCompleted != nil && s.ctx.Data.Quests[quest.Quest(*c.QuestNotCompleted)].Completed() {
+		return false
+	}
+
+	return true
+}
+
+func (s Script) moveToObject(name object.Name) error {
+	if _, found := s.ctx.Data.Objects.FindOne(name); !found {
+		return fmt.Errorf("object %d not found", name)
+	}
+
+	return action.MoveTo(func() (data.Position, bool) {
+		if o, found := s.ctx.Data.Objects.FindOne(name); found {
+			return o.Position, true
+		}
+		return data.Position{}, false
+	})
+}
+
+// killUnique looks for a super unique first, then any unique with the same id
+func (s Script) killUnique(id npc.ID, skipOnImmunities []stat.Resist) error {
+	return s.ctx.Char.KillMonsterSequence(func(d game.Data) (data.UnitID, bool) {
+		for _, t := range []data.MonsterType{data.MonsterTypeSuperUnique, data.MonsterTypeUnique} {
+			if m, found := d.Monsters.FindOne(id, t); found && m.Stats[stat.Life] > 0 {
+				return m.UnitID, true
+			}
+		}
+
+		return 0, false
+	}, skipOnImmunities)
+}
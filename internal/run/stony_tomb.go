@@ -1,6 +1,8 @@
 package run
 
 import (
+	"time"
+
 	"github.com/hectorgimenez/d2go/pkg/data"
 	"github.com/hectorgimenez/d2go/pkg/data/area"
 	"github.com/hectorgimenez/koolo/internal/action"
@@ -10,11 +12,14 @@ import (
 
 type StonyTomb struct {
 	ctx *context.Status
+	// deadline stops the run once reached, zero means no limit
+	deadline time.Time
 }
 
-func NewStonyTomb() *StonyTomb {
+func NewStonyTomb(deadline time.Time) *StonyTomb {
 	return &StonyTomb{
-		ctx: context.Get(),
+		ctx:      context.Get(),
+		deadline: deadline,
 	}
 }
 
@@ -52,15 +57,20 @@ func (s StonyTomb) Run() error {
 	action.OpenTPIfLeader()
 
 	// Clear the area
-	if err = action.ClearCurrentLevel(s.ctx.CharacterCfg.Game.StonyTomb.OpenChests, monsterFilter); err != nil {
+	if err = action.ClearCurrentLevelUntil(s.deadline, s.ctx.CharacterCfg.Game.StonyTomb.OpenChests, monsterFilter); err != nil {
 		return err
 	}
 
+	if action.DeadlineReached(s.deadline) {
+		s.ctx.Logger.Info("Time budget exceeded, skipping Stony Tomb level 2")
+		return nil
+	}
+
 	// Move to lvl2
 	if err = action.MoveToArea(area.StonyTombLevel2); err != nil {
 		return err
 	}
 
 	// Clear the area
-	return action.ClearCurrentLevel(s.ctx.CharacterCfg.Game.StonyTomb.OpenChests, monsterFilter)
+	return action.ClearCurrentLevelUntil(s.deadline, s.ctx.CharacterCfg.Game.StonyTomb.OpenChests, monsterFilter)
 }
@@ -1,6 +1,8 @@
 package run
 
 import (
+	"time"
+
 	"github.com/hectorgimenez/d2go/pkg/data"
 	"github.com/hectorgimenez/d2go/pkg/data/area"
 	"github.com/hectorgimenez/koolo/internal/action"
@@ -10,11 +12,14 @@ import (
 
 type TalRashaTombs struct {
 	ctx *context.Status
+	// deadline stops the run once reached, zero means no limit
+	deadline time.Time
 }
 
-func NewTalRashaTombs() *TalRashaTombs {
+func NewTalRashaTombs(deadline time.Time) *TalRashaTombs {
 	return &TalRashaTombs{
-		ctx: context.Get(),
+		ctx:      context.Get(),
+		deadline: deadline,
 	}
 }
 
@@ -28,6 +33,10 @@ func (a TalRashaTombs) Run() error {
 
 	// Iterate over all Tal Rasha Tombs
 	for _, tomb := range talRashaTombs {
+		if action.DeadlineReached(a.deadline) {
+			a.ctx.Logger.Info("Time budget exceeded, skipping the remaining tombs")
+			return nil
+		}
 
 		// Use the waypoint to travel to Canyon Of The Magi
 		err := action.WayPoint(area.CanyonOfTheMagi)
@@ -47,7 +56,7 @@ func (a TalRashaTombs) Run() error {
 		action.Buff()
 
 		// Clear the Tomb
-		if err = action.ClearCurrentLevel(true, data.MonsterAnyFilter()); err != nil {
+		if err = action.ClearCurrentLevelUntil(a.deadline, true, data.MonsterAnyFilter()); err != nil {
 			return err
 		}
 
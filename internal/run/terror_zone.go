@@ -3,10 +3,12 @@ package run
 import (
 	"fmt"
 	"slices"
+	"time"
 
 	"github.com/hectorgimenez/d2go/pkg/data"
 	"github.com/hectorgimenez/d2go/pkg/data/area"
 	"github.com/hectorgimenez/koolo/internal/action"
+	"github.com/hectorgimenez/koolo/internal/config"
 	"github.com/hectorgimenez/koolo/internal/context"
 )
 
@@ -21,12 +23,7 @@ func NewTerrorZone() *TerrorZone {
 }
 
 func (tz TerrorZone) Name() string {
-	tzNames := make([]string, 0)
-	for _, tzArea := range tz.AvailableTZs() {
-		tzNames = append(tzNames, tzArea.Area().Name)
-	}
-
-	return fmt.Sprintf("TerrorZone Run: %v", tzNames)
+	return string(config.TerrorZoneRun)
 }
 
 func (tz TerrorZone) Run() error {
@@ -35,29 +32,42 @@ func (tz TerrorZone) Run() error {
 		return nil
 	}
 
+	tzNames := make([]string, 0, len(availableTzs))
+	for _, tzArea := range availableTzs {
+		tzNames = append(tzNames, tzArea.Area().Name)
+	}
+	tz.ctx.Logger.Info(fmt.Sprintf("Running terror zone: %v", tzNames))
+
+	// Time budget and boss runs use the settings of the first enabled area
+	strategy := tz.ctx.CharacterCfg.TerrorZoneStrategy(availableTzs[0])
+	var deadline time.Time
+	if strategy.MaxDuration > 0 {
+		deadline = time.Now().Add(strategy.MaxDuration)
+	}
+
 	switch tz.ctx.Data.TerrorZones[0] {
 	case area.PitLevel1:
-		return NewPit().Run()
+		return NewPit(deadline).Run()
 	case area.Tristram:
-		return NewTristram().Run()
+		return NewTristram(deadline).Run()
 	case area.MooMooFarm:
-		return NewCows().Run()
+		return NewCows(deadline).Run()
 	case area.TalRashasTomb1:
-		return NewTalRashaTombs().Run()
+		return NewTalRashaTombs(deadline).Run()
 	case area.AncientTunnels:
-		return NewAncientTunnels().Run()
+		return NewAncientTunnels(deadline).Run()
 	case area.RockyWaste:
-		return NewStonyTomb().Run()
+		return NewStonyTomb(deadline).Run()
 	case area.Travincal:
-		return NewTravincal().Run()
+		return NewTravincal(deadline).Run()
 	case area.DuranceOfHateLevel1:
-		return NewMephisto(tz.customTZEnemyFilter()).Run()
+		return NewMephisto(tz.customTZEnemyFilter(strategy, deadline)).Run()
 	case area.ChaosSanctuary:
-		return NewDiablo().Run()
+		return NewDiablo(deadline).Run()
 	case area.NihlathaksTemple:
-		return NewNihlathak().Run()
+		return NewNihlathak(deadline).Run()
 	case area.TheWorldStoneKeepLevel1:
-		return NewBaal(tz.customTZEnemyFilter()).Run()
+		return NewBaal(tz.customTZEnemyFilter(strategy, deadline)).Run()
 	}
 
 	tzAreaGroups := tz.tzAreaGroups(tz.ctx.Data.TerrorZones[0])
@@ -79,6 +89,11 @@ func (tz TerrorZone) Run() error {
 		}
 
 		for k, tzArea := range tzAreaGroup {
+			if !deadline.IsZero() && time.Now().After(deadline) {
+				tz.ctx.Logger.Info(fmt.Sprintf("Terror zone time budget of %s exceeded, skipping the remaining areas", strategy.MaxDuration))
+				return nil
+			}
+
 			if k == 0 {
 				err := action.WayPoint(tzArea)
 				if err != nil {
@@ -91,9 +106,10 @@ func (tz TerrorZone) Run() error {
 				}
 			}
 			if slices.Contains(availableTzs, tzArea) {
-				action.ClearCurrentLevel(false, tz.customTZEnemyFilter())
+				areaStrategy := tz.ctx.CharacterCfg.TerrorZoneStrategy(tzArea)
+				action.ClearCurrentLevelUntil(deadline, areaStrategy.OpenChests, tz.customTZEnemyFilter(areaStrategy, deadline))
 			} else {
-				tz.ctx.Logger.Debug("Skipping area %v", tzArea.Area().Name)
+				tz.ctx.Logger.Debug(fmt.Sprintf("Skipping area %v", tzArea.Area().Name))
 			}
 		}
 	}
@@ -101,17 +117,9 @@ func (tz TerrorZone) Run() error {
 	return nil
 }
 
+// AvailableTZs returns the active terror zone areas enabled for the character
 func (tz TerrorZone) AvailableTZs() []area.ID {
-	var availableTZs []area.ID
-	for _, tzone := range tz.ctx.Data.TerrorZones {
-		for _, tzArea := range tz.ctx.CharacterCfg.Game.TerrorZone.Areas {
-			if tzone == tzArea {
-				availableTZs = append(availableTZs, tzone)
-			}
-		}
-	}
-
-	return availableTZs
+	return tz.ctx.CharacterCfg.EnabledTerrorZones(tz.ctx.Data.TerrorZones)
 }
 
 func (tz TerrorZone) tzAreaGroups(firstTZ area.ID) [][]area.ID {
@@ -176,17 +184,22 @@ func (tz TerrorZone) tzAreaGroups(firstTZ area.ID) [][]area.ID {
 	return [][]area.ID{}
 }
 
-func (tz TerrorZone) customTZEnemyFilter() data.MonsterFilter {
+// customTZEnemyFilter returns no monsters once the deadline is reached, so clearing stops when the time budget is over
+func (tz TerrorZone) customTZEnemyFilter(strategy config.TerrorZoneStrategy, deadline time.Time) data.MonsterFilter {
 	return func(m data.Monsters) []data.Monster {
+		if !deadline.IsZero() && time.Now().After(deadline) {
+			return nil
+		}
+
 		var filteredMonsters []data.Monster
 		monsterFilter := data.MonsterAnyFilter()
-		if tz.ctx.CharacterCfg.Game.TerrorZone.FocusOnElitePacks {
+		if strategy.FocusOnElitePacks {
 			monsterFilter = data.MonsterEliteFilter()
 		}
 
 		for _, mo := range m.Enemies(monsterFilter) {
 			isImmune := false
-			for _, resist := range tz.ctx.CharacterCfg.Game.TerrorZone.SkipOnImmunities {
+			for _, resist := range strategy.SkipOnImmunities {
 				if mo.IsImmune(resist) {
 					isImmune = true
 				}
@@ -1,6 +1,8 @@
 package run
 
 import (
+	"time"
+
 	"github.com/hectorgimenez/d2go/pkg/data"
 	"github.com/hectorgimenez/d2go/pkg/data/area"
 	"github.com/hectorgimenez/koolo/internal/action"
@@ -11,11 +13,14 @@ import (
 
 type Travincal struct {
 	ctx *context.Status
+	// deadline stops the run once reached, zero means no limit
+	deadline time.Time
 }
 
-func NewTravincal() *Travincal {
+func NewTravincal(deadline time.Time) *Travincal {
 	return &Travincal{
-		ctx: context.Get(),
+		ctx:      context.Get(),
+		deadline: deadline,
 	}
 }
 
@@ -38,6 +43,11 @@ func (t *Travincal) Run() error {
 	//this is temporary needed for barb because have no cta; isrebuffrequired not working for him
 	action.Buff()
 
+	if action.DeadlineReached(t.deadline) {
+		t.ctx.Logger.Info("Time budget exceeded, skipping the council")
+		return nil
+	}
+
 	councilPosition := t.findCouncilPosition()
 
 	err = action.MoveToCoords(councilPosition)
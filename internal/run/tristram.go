@@ -3,6 +3,7 @@ package run
 import (
 	"errors"
 	"fmt"
+	"time"
 
 	"github.com/hectorgimenez/d2go/pkg/data"
 	"github.com/hectorgimenez/d2go/pkg/data/area"
@@ -16,11 +17,14 @@ import (
 
 type Tristram struct {
 	ctx *context.Status
+	// deadline stops the run once reached, zero means no limit
+	deadline time.Time
 }
 
-func NewTristram() *Tristram {
+func NewTristram(deadline time.Time) *Tristram {
 	return &Tristram{
-		ctx: context.Get(),
+		ctx:      context.Get(),
+		deadline: deadline,
 	}
 }
 
@@ -89,7 +93,7 @@ func (t Tristram) Run() error {
 			filter = data.MonsterEliteFilter()
 		}
 
-		return action.ClearCurrentLevel(false, filter)
+		return action.ClearCurrentLevelUntil(t.deadline, false, filter)
 	}
 
 	return nil
@@ -0,0 +1,370 @@
+// Package schedule decides when a supervisor should be playing. Evaluation is a pure function of the Plan, the play
+// history and the current time, so it can be tested with a fake clock.
+package schedule
+
+import (
+	"hash/fnv"
+	"sort"
+	"time"
+)
+
+// DailyRange is a time window repeated every week on the given day, Start and End are offsets from midnight
+type DailyRange struct {
+	Weekday time.Weekday
+	Start   time.Duration
+	End     time.Duration
+}
+
+// CronWindow is a play window starting at every Cron activation and lasting Duration
+type CronWindow struct {
+	Cron     Cron
+	Duration time.Duration
+}
+
+type Plan struct {
+	// Name is used to seed the jitter, so every supervisor gets different but stable offsets
+	Name  string
+	Daily []DailyRange
+	Cron  []CronWindow
+	// Windows start and stop are randomly moved up to these values, before or after
+	StartJitter time.Duration
+	StopJitter  time.Duration
+	// After PlayBeforeBreak of continuous play the supervisor stops for BreakDuration
+	PlayBeforeBreak time.Duration
+	BreakDuration   time.Duration
+	// Max total play time per calendar day and per week (starting on Monday), zero means no limit
+	DailyLimit  time.Duration
+	WeeklyLimit time.Duration
+}
+
+// Session is a period of time the supervisor was running, End is zero while it's still running
+type Session struct {
+	Start time.Time
+	End   time.Time
+}
+
+type Reason string
+
+const (
+	ReasonInWindow      Reason = "in scheduled window"
+	ReasonOutsideWindow Reason = "outside scheduled windows"
+	ReasonBreak         Reason = "taking a break"
+	ReasonDailyLimit    Reason = "daily play limit reached"
+	ReasonWeeklyLimit   Reason = "weekly play limit reached"
+)
+
+type Decision struct {
+	ShouldRun bool
+	Reason    Reason
+	// NextStart and NextStop are zero when they can not be determined (for example no windows at all)
+	NextStart time.Time
+	NextStop  time.Time
+}
+
+type Clock interface {
+	Now() time.Time
+}
+
+type SystemClock struct{}
+
+func (SystemClock) Now() time.Time {
+	return time.Now()
+}
+
+const (
+	lookBehind = 8 * 24 * time.Hour
+	lookAhead  = 15 * 24 * time.Hour
+	// Max amount of blocked windows skipped while searching the next start, avoids looping forever on weird plans
+	maxSearchSteps = 1000
+)
+
+type window struct {
+	start, end time.Time
+}
+
+// Evaluate returns if the supervisor should be running at the given time, history must be sorted by Start
+func Evaluate(plan Plan, history []Session, now time.Time) Decision {
+	windows := plan.windows(now.Add(-lookBehind), now.Add(lookAhead))
+	running := len(history) > 0 && history[len(history)-1].End.IsZero()
+
+	if running {
+		stop, reason := plan.stopAt(windows, history, now)
+		if stop.After(now) {
+			closed := closeAt(history, stop)
+			start, _ := plan.nextStart(windows, closed, stop)
+			return Decision{ShouldRun: true, Reason: ReasonInWindow, NextStart: start, NextStop: stop}
+		}
+
+		closed := closeAt(history, now)
+		start, _ := plan.nextStart(windows, closed, now)
+		return Decision{ShouldRun: false, Reason: reason, NextStart: start, NextStop: now}
+	}
+
+	start, reason := plan.nextStart(windows, history, now)
+	if start.IsZero() {
+		return Decision{ShouldRun: false, Reason: reason}
+	}
+
+	open := append(append(make([]Session, 0, len(history)+1), history...), Session{Start: start})
+	stop, _ := plan.stopAt(windows, open, start)
+	if !start.After(now) {
+		return Decision{ShouldRun: true, Reason: ReasonInWindow, NextStart: start, NextStop: stop}
+	}
+
+	return Decision{ShouldRun: false, Reason: reason, NextStart: start, NextStop: stop}
+}
+
+// nextStart returns the first time at or after t when the supervisor can play, and the reason why it can't play at t
+func (p Plan) nextStart(windows []window, history []Session, t time.Time) (time.Time, Reason) {
+	breakEnd := p.breakEnd(history)
+	firstReason := Reason("")
+
+	for i := 0; i < maxSearchSteps; i++ {
+		w, found := windowFrom(windows, t)
+		if !found {
+			if firstReason == "" {
+				firstReason = ReasonOutsideWindow
+			}
+			return time.Time{}, firstReason
+		}
+		if w.start.After(t) {
+			if firstReason == "" {
+				firstReason = ReasonOutsideWindow
+			}
+			t = w.start
+		}
+
+		blockedUntil, reason := p.limitsResetAt(history, t)
+		if breakEnd.After(t) && breakEnd.After(blockedUntil) {
+			blockedUntil, reason = breakEnd, ReasonBreak
+		}
+		if !blockedUntil.After(t) {
+			if firstReason == "" {
+				firstReason = ReasonInWindow
+			}
+			return t, firstReason
+		}
+
+		if firstReason == "" {
+			firstReason = reason
+		}
+		t = blockedUntil
+	}
+
+	return time.Time{}, firstReason
+}
+
+// stopAt returns when the current session (last one in history, still open) should stop
+func (p Plan) stopAt(windows []window, history []Session, now time.Time) (time.Time, Reason) {
+	w, found := windowFrom(windows, now)
+	if !found || w.start.After(now) {
+		return now, ReasonOutsideWindow
+	}
+	stop, reason := w.end, ReasonOutsideWindow
+
+	if p.PlayBeforeBreak > 0 {
+		if breakAt := stretchStart(history, p.BreakDuration).Add(p.PlayBeforeBreak); breakAt.Before(stop) {
+			stop, reason = breakAt, ReasonBreak
+		}
+	}
+
+	if p.DailyLimit > 0 {
+		played := playedBetween(history, startOfDay(now), now, now)
+		if limitAt := now.Add(p.DailyLimit - played); limitAt.Before(stop) {
+			stop, reason = limitAt, ReasonDailyLimit
+		}
+	}
+
+	if p.WeeklyLimit > 0 {
+		played := playedBetween(history, startOfWeek(now), now, now)
+		if limitAt := now.Add(p.WeeklyLimit - played); limitAt.Before(stop) {
+			stop, reason = limitAt, ReasonWeeklyLimit
+		}
+	}
+
+	if stop.Before(now) {
+		stop = now
+	}
+
+	return stop, reason
+}
+
+// limitsResetAt returns when the play limits stop blocking at t, zero if they are not blocking
+func (p Plan) limitsResetAt(history []Session, t time.Time) (time.Time, Reason) {
+	var until time.Time
+	var reason Reason
+
+	if p.WeeklyLimit > 0 && playedBetween(history, startOfWeek(t), startOfWeek(t).AddDate(0, 0, 7), t) >= p.WeeklyLimit {
+		until, reason = startOfWeek(t).AddDate(0, 0, 7), ReasonWeeklyLimit
+	}
+
+	if p.DailyLimit > 0 && playedBetween(history, startOfDay(t), startOfDay(t).AddDate(0, 0, 1), t) >= p.DailyLimit {
+		if next := startOfDay(t).AddDate(0, 0, 1); next.After(until) {
+			until, reason = next, ReasonDailyLimit
+		}
+	}
+
+	return until, reason
+}
+
+// breakEnd returns when the current break finishes, history must not have open sessions
+func (p Plan) breakEnd(history []Session) time.Time {
+	if p.PlayBeforeBreak <= 0 || len(history) == 0 {
+		return time.Time{}
+	}
+
+	last := history[len(history)-1]
+	if last.End.Sub(stretchStart(history, p.BreakDuration)) < p.PlayBeforeBreak {
+		return time.Time{}
+	}
+
+	return last.End.Add(p.BreakDuration)
+}
+
+// stretchStart returns the start of the last continuous play stretch, sessions separated by less than a break are
+// considered continuous, otherwise stopping and starting again would skip the break
+func stretchStart(history []Session, breakDuration time.Duration) time.Time {
+	if len(history) == 0 {
+		return time.Time{}
+	}
+
+	start := history[len(history)-1].Start
+	for i := len(history) - 2; i >= 0; i-- {
+		if start.Sub(history[i].End) >= breakDuration {
+			break
+		}
+		start = history[i].Start
+	}
+
+	return start
+}
+
+// playedBetween returns the play time inside [from, to), open sessions are considered running until now
+func playedBetween(history []Session, from, to, now time.Time) time.Duration {
+	var total time.Duration
+	for _, s := range history {
+		end := s.End
+		if end.IsZero() {
+			end = now
+		}
+
+		start := maxTime(s.Start, from)
+		end = minTime(end, to)
+		if end.After(start) {
+			total += end.Sub(start)
+		}
+	}
+
+	return total
+}
+
+func closeAt(history []Session, t time.Time) []Session {
+	closed := append(make([]Session, 0, len(history)), history...)
+	if len(closed) > 0 && closed[len(closed)-1].End.IsZero() {
+		closed[len(closed)-1].End = t
+	}
+
+	return closed
+}
+
+// windowFrom returns the window containing t, or the next one starting after t
+func windowFrom(windows []window, t time.Time) (window, bool) {
+	for _, w := range windows {
+		if w.end.After(t) {
+			return w, true
+		}
+	}
+
+	return window{}, false
+}
+
+// windows returns all the play windows overlapping [from, to], sorted and merged
+func (p Plan) windows(from, to time.Time) []window {
+	windows := make([]window, 0)
+
+	for day := startOfDay(from).AddDate(0, 0, -1); !day.After(to); day = day.AddDate(0, 0, 1) {
+		for _, r := range p.Daily {
+			if r.Weekday != day.Weekday() {
+				continue
+			}
+
+			end := r.End
+			if end <= r.Start {
+				end += 24 * time.Hour
+			}
+			windows = p.appendWindow(windows, day.Add(r.Start), day.Add(end))
+		}
+	}
+
+	for _, c := range p.Cron {
+		if c.Duration <= 0 {
+			continue
+		}
+
+		for t := c.Cron.Next(from.Add(-c.Duration - p.StartJitter)); !t.IsZero() && !t.After(to); t = c.Cron.Next(t) {
+			windows = p.appendWindow(windows, t, t.Add(c.Duration))
+		}
+	}
+
+	sort.Slice(windows, func(i, j int) bool {
+		return windows[i].start.Before(windows[j].start)
+	})
+
+	merged := make([]window, 0, len(windows))
+	for _, w := range windows {
+		if len(merged) > 0 && !w.start.After(merged[len(merged)-1].end) {
+			merged[len(merged)-1].end = maxTime(merged[len(merged)-1].end, w.end)
+			continue
+		}
+		merged = append(merged, w)
+	}
+
+	return merged
+}
+
+func (p Plan) appendWindow(windows []window, start, end time.Time) []window {
+	// Offsets are derived from the planned time, so they are random looking but stable between evaluations
+	start = start.Add(p.jitter("start", start, p.StartJitter))
+	end = end.Add(p.jitter("stop", end, p.StopJitter))
+	if !end.After(start) {
+		return windows
+	}
+
+	return append(windows, window{start: start, end: end})
+}
+
+// jitter returns a deterministic offset between -max and +max
+func (p Plan) jitter(kind string, t time.Time, max time.Duration) time.Duration {
+	if max <= 0 {
+		return 0
+	}
+
+	h := fnv.New64a()
+	h.Write([]byte(p.Name + "|" + kind + "|" + t.UTC().Format(time.RFC3339)))
+	seconds := int64(max / time.Second)
+
+	return time.Duration(int64(h.Sum64()%uint64(2*seconds+1))-seconds) * time.Second
+}
+
+func startOfDay(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+}
+
+func startOfWeek(t time.Time) time.Time {
+	daysSinceMonday := (int(t.Weekday()) + 6) % 7
+	return startOfDay(t).AddDate(0, 0, -daysSinceMonday)
+}
+
+func minTime(a, b time.Time) time.Time {
+	if a.Before(b) {
+		return a
+	}
+	return b
+}
+
+func maxTime(a, b time.Time) time.Time {
+	if a.After(b) {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,241 @@
+package schedule
+
+import (
+	"testing"
+	"time"
+)
+
+type fakeClock struct {
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	return c.now
+}
+
+func (c *fakeClock) Advance(d time.Duration) {
+	c.now = c.now.Add(d)
+}
+
+// Monday
+var monday = time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+func at(day, hour, minute int) time.Time {
+	return monday.AddDate(0, 0, day).Add(time.Duration(hour)*time.Hour + time.Duration(minute)*time.Minute)
+}
+
+// simulate runs the plan minute by minute like the scheduler does, returning the resulting play sessions
+func simulate(plan Plan, clock *fakeClock, until time.Time) []Session {
+	history := make([]Session, 0)
+	for ; clock.Now().Before(until); clock.Advance(time.Minute) {
+		running := len(history) > 0 && history[len(history)-1].End.IsZero()
+		d := Evaluate(plan, history, clock.Now())
+		if d.ShouldRun && !running {
+			history = append(history, Session{Start: clock.Now()})
+		} else if !d.ShouldRun && running {
+			history[len(history)-1].End = clock.Now()
+		}
+	}
+
+	return closeAt(history, clock.Now())
+}
+
+func TestParseCron(t *testing.T) {
+	tests := []struct {
+		expr  string
+		from  time.Time
+		next  time.Time
+		error bool
+	}{
+		{expr: "30 18 * * 1-5", from: at(0, 19, 0), next: at(1, 18, 30)},
+		{expr: "30 18 * * 1-5", from: at(4, 19, 0), next: at(7, 18, 30)},
+		{expr: "*/15 * * * *", from: at(0, 10, 1), next: at(0, 10, 15)},
+		{expr: "0 9 * * 0", from: at(0, 0, 0), next: at(6, 9, 0)},
+		{expr: "0 9 * * 7", from: at(0, 0, 0), next: at(6, 9, 0)},
+		{expr: "0 0 15 * *", from: at(0, 0, 0), next: at(14, 0, 0)},
+		{expr: "@daily", from: at(0, 10, 0), next: at(1, 0, 0)},
+		{expr: "0 8,20 * * *", from: at(0, 10, 0), next: at(0, 20, 0)},
+		// Any of the day fields matches when both are restricted
+		{expr: "0 9 14 * 1", from: at(0, 10, 0), next: at(7, 9, 0)},
+		{expr: "0 9 8 * 5", from: at(0, 10, 0), next: at(4, 9, 0)},
+		// Steps starting with * don't restrict the day, both fields have to match
+		{expr: "0 9 */1 * 1", from: at(0, 10, 0), next: at(7, 9, 0)},
+		{expr: "0 9 */2 * 1", from: at(0, 10, 0), next: at(14, 9, 0)},
+		{expr: "0 9 2 * */1", from: at(1, 10, 0), next: at(32, 9, 0)},
+		{expr: "* * *", error: true},
+		{expr: "61 * * * *", error: true},
+		{expr: "*/0 * * * *", error: true},
+		{expr: "5-1 * * * *", error: true},
+	}
+
+	for _, tt := range tests {
+		c, err := ParseCron(tt.expr)
+		if tt.error {
+			if err == nil {
+				t.Errorf("ParseCron(%q) expected error", tt.expr)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("ParseCron(%q) unexpected error: %v", tt.expr, err)
+		}
+
+		if next := c.Next(tt.from); !next.Equal(tt.next) {
+			t.Errorf("ParseCron(%q).Next(%s) = %s, expected %s", tt.expr, tt.from, next, tt.next)
+		}
+	}
+}
+
+func TestEvaluateDailyRange(t *testing.T) {
+	plan := Plan{Daily: []DailyRange{{Weekday: time.Monday, Start: 10 * time.Hour, End: 12 * time.Hour}}}
+
+	d := Evaluate(plan, nil, at(0, 9, 0))
+	if d.ShouldRun || d.Reason != ReasonOutsideWindow {
+		t.Errorf("expected not running outside the window, got %+v", d)
+	}
+	if !d.NextStart.Equal(at(0, 10, 0)) || !d.NextStop.Equal(at(0, 12, 0)) {
+		t.Errorf("unexpected next start/stop: %s - %s", d.NextStart, d.NextStop)
+	}
+
+	d = Evaluate(plan, nil, at(0, 10, 30))
+	if !d.ShouldRun {
+		t.Errorf("expected running inside the window, got %+v", d)
+	}
+
+	d = Evaluate(plan, []Session{{Start: at(0, 10, 0)}}, at(0, 12, 0))
+	if d.ShouldRun {
+		t.Errorf("expected stop at the end of the window, got %+v", d)
+	}
+	if !d.NextStart.Equal(at(7, 10, 0)) {
+		t.Errorf("expected next start next week, got %s", d.NextStart)
+	}
+}
+
+func TestEvaluateCronWindow(t *testing.T) {
+	c, err := ParseCron("0 20 * * *")
+	if err != nil {
+		t.Fatal(err)
+	}
+	plan := Plan{Cron: []CronWindow{{Cron: c, Duration: 5 * time.Hour}}}
+
+	// Window started the day before and is still open after midnight
+	d := Evaluate(plan, nil, at(1, 0, 30))
+	if !d.ShouldRun || !d.NextStop.Equal(at(1, 1, 0)) {
+		t.Errorf("expected running until 01:00, got %+v", d)
+	}
+}
+
+func TestEvaluateBreaks(t *testing.T) {
+	plan := Plan{
+		Daily:           []DailyRange{{Weekday: time.Monday, Start: 10 * time.Hour, End: 14 * time.Hour}},
+		PlayBeforeBreak: time.Hour,
+		BreakDuration:   15 * time.Minute,
+	}
+
+	clock := &fakeClock{now: at(0, 9, 0)}
+	sessions := simulate(plan, clock, at(0, 15, 0))
+
+	expected := []Session{
+		{Start: at(0, 10, 0), End: at(0, 11, 0)},
+		{Start: at(0, 11, 15), End: at(0, 12, 15)},
+		{Start: at(0, 12, 30), End: at(0, 13, 30)},
+		{Start: at(0, 13, 45), End: at(0, 14, 0)},
+	}
+	assertSessions(t, sessions, expected)
+
+	d := Evaluate(plan, sessions[:1], at(0, 11, 5))
+	if d.ShouldRun || d.Reason != ReasonBreak || !d.NextStart.Equal(at(0, 11, 15)) {
+		t.Errorf("expected break until 11:15, got %+v", d)
+	}
+}
+
+func TestEvaluateDailyLimit(t *testing.T) {
+	plan := Plan{
+		Daily: []DailyRange{
+			{Weekday: time.Monday, Start: 10 * time.Hour, End: 12 * time.Hour},
+			{Weekday: time.Monday, Start: 14 * time.Hour, End: 16 * time.Hour},
+			{Weekday: time.Tuesday, Start: 10 * time.Hour, End: 12 * time.Hour},
+		},
+		DailyLimit: 150 * time.Minute,
+	}
+
+	clock := &fakeClock{now: at(0, 9, 0)}
+	sessions := simulate(plan, clock, at(1, 13, 0))
+
+	expected := []Session{
+		{Start: at(0, 10, 0), End: at(0, 12, 0)},
+		{Start: at(0, 14, 0), End: at(0, 14, 30)},
+		{Start: at(1, 10, 0), End: at(1, 12, 0)},
+	}
+	assertSessions(t, sessions, expected)
+
+	d := Evaluate(plan, sessions[:2], at(0, 15, 0))
+	if d.ShouldRun || d.Reason != ReasonDailyLimit || !d.NextStart.Equal(at(1, 10, 0)) {
+		t.Errorf("expected daily limit until tomorrow, got %+v", d)
+	}
+}
+
+func TestEvaluateWeeklyLimit(t *testing.T) {
+	plan := Plan{WeeklyLimit: 3 * time.Hour}
+	for day := time.Sunday; day <= time.Saturday; day++ {
+		plan.Daily = append(plan.Daily, DailyRange{Weekday: day, Start: 20 * time.Hour, End: 22 * time.Hour})
+	}
+
+	clock := &fakeClock{now: at(0, 0, 0)}
+	sessions := simulate(plan, clock, at(7, 23, 0))
+
+	expected := []Session{
+		{Start: at(0, 20, 0), End: at(0, 22, 0)},
+		{Start: at(1, 20, 0), End: at(1, 21, 0)},
+		{Start: at(7, 20, 0), End: at(7, 22, 0)},
+	}
+	assertSessions(t, sessions, expected)
+}
+
+func TestEvaluateJitter(t *testing.T) {
+	plan := Plan{
+		Name:        "sorc",
+		Daily:       []DailyRange{{Weekday: time.Monday, Start: 10 * time.Hour, End: 12 * time.Hour}},
+		StartJitter: 10 * time.Minute,
+		StopJitter:  10 * time.Minute,
+	}
+
+	first := Evaluate(plan, nil, at(0, 8, 0))
+	second := Evaluate(plan, nil, at(0, 9, 0))
+	if !first.NextStart.Equal(second.NextStart) || !first.NextStop.Equal(second.NextStop) {
+		t.Errorf("jitter must be stable between evaluations: %+v vs %+v", first, second)
+	}
+
+	if diff := first.NextStart.Sub(at(0, 10, 0)).Abs(); diff > 10*time.Minute {
+		t.Errorf("start jitter out of range: %s", diff)
+	}
+	if diff := first.NextStop.Sub(at(0, 12, 0)).Abs(); diff > 10*time.Minute {
+		t.Errorf("stop jitter out of range: %s", diff)
+	}
+
+	other := plan
+	other.Name = "hammerdin"
+	if Evaluate(other, nil, at(0, 8, 0)).NextStart.Equal(first.NextStart) && Evaluate(other, nil, at(0, 8, 0)).NextStop.Equal(first.NextStop) {
+		t.Errorf("different supervisors should get different jitter")
+	}
+}
+
+func TestEvaluateNoWindows(t *testing.T) {
+	d := Evaluate(Plan{}, nil, at(0, 10, 0))
+	if d.ShouldRun || !d.NextStart.IsZero() {
+		t.Errorf("expected no start without windows, got %+v", d)
+	}
+}
+
+func assertSessions(t *testing.T, got, expected []Session) {
+	t.Helper()
+
+	if len(got) != len(expected) {
+		t.Fatalf("expected %d sessions, got %d: %+v", len(expected), len(got), got)
+	}
+	for i := range expected {
+		if !got[i].Start.Equal(expected[i].Start) || !got[i].End.Equal(expected[i].End) {
+			t.Errorf("session %d: expected %s - %s, got %s - %s", i, expected[i].Start.Format("Mon 15:04"), expected[i].End.Format("Mon 15:04"), got[i].Start.Format("Mon 15:04"), got[i].End.Format("Mon 15:04"))
+		}
+	}
+}
@@ -0,0 +1,888 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hectorgimenez/d2go/pkg/data"
+	"github.com/hectorgimenez/d2go/pkg/nip"
+	"github.com/hectorgimenez/koolo/internal/action"
+	"github.com/hectorgimenez/koolo/internal/bot"
+	"github.com/hectorgimenez/koolo/internal/config"
+	ctx "github.com/hectorgimenez/koolo/internal/context"
+	"github.com/hectorgimenez/koolo/internal/ledger"
+	"github.com/hectorgimenez/koolo/internal/pickit"
+	"github.com/hectorgimenez/koolo/internal/remote/hub"
+	"github.com/hectorgimenez/koolo/internal/terrorzone"
+)
+
+const (
+	apiV1Prefix       = "/api/v1"
+	defaultDropsLimit = 50
+	maxDropsLimit     = 500
+)
+
+// apiRoute describes an /api/v1 endpoint, the same definition is used to register the handler and to build the
+// OpenAPI document, so the documentation can not get out of sync with the handlers
+type apiRoute struct {
+	Method      string
+	Path        string
+	Summary     string
+	Params      []apiParam
+	RequestBody any
+	Response    any
+	Status      int
+	Handler     http.HandlerFunc
+}
+
+type apiParam struct {
+	Name        string
+	In          string
+	Type        string
+	Description string
+	Required    bool
+}
+
+type apiErrorResponse struct {
+	Error apiError `json:"error"`
+}
+
+type apiError struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+type apiSupervisor struct {
+	Name      string               `json:"name"`
+	Status    bot.SupervisorStatus `json:"status"`
+	StartedAt time.Time            `json:"startedAt"`
+	Games     int                  `json:"games"`
+	Drops     int                  `json:"drops"`
+	Chickens  int                  `json:"chickens"`
+	Deaths    int                  `json:"deaths"`
+	Errors    int                  `json:"errors"`
+}
+
+type apiStats struct {
+	apiSupervisor
+	History bool            `json:"history"`
+	From    *time.Time      `json:"from,omitempty"`
+	To      *time.Time      `json:"to,omitempty"`
+	Details []bot.GameStats `json:"details"`
+}
+
+type apiDrop struct {
+	Name       string        `json:"name"`
+	Quality    string        `json:"quality"`
+	Ethereal   bool          `json:"ethereal"`
+	Identified bool          `json:"identified"`
+	Rule       string        `json:"rule"`
+	RuleFile   string        `json:"ruleFile"`
+	Stats      []apiItemStat `json:"stats"`
+}
+
+type apiItemStat struct {
+	Name  string `json:"name"`
+	Value int    `json:"value"`
+}
+
+type apiDropsPage struct {
+	Total  int       `json:"total"`
+	Offset int       `json:"offset"`
+	Limit  int       `json:"limit"`
+	Drops  []apiDrop `json:"drops"`
+}
+
+type apiLedgerPage struct {
+	Total  int            `json:"total"`
+	Offset int            `json:"offset"`
+	Limit  int            `json:"limit"`
+	Drops  []ledger.Entry `json:"drops"`
+}
+
+type apiTerrorZones struct {
+	Zones  []terrorzone.ZoneSummary `json:"zones"`
+	Visits []terrorzone.Visit       `json:"visits"`
+}
+
+type apiDebug struct {
+	Supervisor string                      `json:"supervisor"`
+	Debug      map[ctx.Priority]*ctx.Debug `json:"debug"`
+}
+
+type apiToken struct {
+	Name string `json:"name"`
+}
+
+type apiNewToken struct {
+	Name  string `json:"name"`
+	Token string `json:"token"`
+}
+
+type apiPickitSimulateRequest struct {
+	Character string `json:"character"`
+	// Recorded items, the drop ledger is used when empty
+	Items []pickit.Sample `json:"items,omitempty"`
+	// Directory with the candidate .nip files to compare with the character rules, it must be inside the config
+	// directory or the centralized pickit path
+	CompareRulesDir string `json:"compareRulesDir,omitempty"`
+	// Total gold of the character, below minGoldPickupThreshold every magic item is picked up. Ignored when not set
+	TotalGold *int `json:"totalGold,omitempty"`
+}
+
+type apiAttachRequest struct {
+	PID uint32 `json:"pid"`
+}
+
+var supervisorParam = apiParam{Name: "name", In: "path", Type: "string", Description: "Supervisor name", Required: true}
+
+func (s *HttpServer) apiRoutes() []apiRoute {
+	timeWindow := []apiParam{
+		supervisorParam,
+		{Name: "from", In: "query", Type: "string", Description: "Start date (YYYY-MM-DD), requires stats persistence"},
+		{Name: "to", In: "query", Type: "string", Description: "End date (YYYY-MM-DD), requires stats persistence"},
+		{Name: "history", In: "query", Type: "boolean", Description: "Use every persisted session instead of the current one when there is no date, requires stats persistence"},
+	}
+
+	ledgerParams := []apiParam{
+		{Name: "supervisor", In: "query", Type: "string", Description: "Supervisor name"},
+		{Name: "quality", In: "query", Type: "string", Description: "Item quality, for example Unique or Set"},
+		{Name: "name", In: "query", Type: "string", Description: "Case insensitive item name filter"},
+		{Name: "ruleFile", In: "query", Type: "string", Description: "Case insensitive NIP rule file filter"},
+		{Name: "from", In: "query", Type: "string", Description: "Start date (YYYY-MM-DD)"},
+		{Name: "to", In: "query", Type: "string", Description: "End date (YYYY-MM-DD)"},
+	}
+
+	return []apiRoute{
+		{Method: http.MethodGet, Path: "/supervisors", Summary: "List all the supervisors", Response: []apiSupervisor{}, Handler: s.apiListSupervisors},
+		{Method: http.MethodGet, Path: "/supervisors/{name}", Summary: "Get a supervisor", Params: []apiParam{supervisorParam}, Response: apiSupervisor{}, Handler: s.apiGetSupervisor},
+		{Method: http.MethodPost, Path: "/supervisors/{name}/start", Summary: "Start a supervisor", Params: []apiParam{supervisorParam}, Response: apiSupervisor{}, Status: http.StatusAccepted, Handler: s.apiStartSupervisor},
+		{Method: http.MethodPost, Path: "/supervisors/{name}/stop", Summary: "Stop a supervisor", Params: []apiParam{supervisorParam}, Response: apiSupervisor{}, Handler: s.apiStopSupervisor},
+		{Method: http.MethodPost, Path: "/supervisors/{name}/pause", Summary: "Pause a running supervisor", Params: []apiParam{supervisorParam}, Response: apiSupervisor{}, Handler: s.apiPauseSupervisor(true)},
+		{Method: http.MethodPost, Path: "/supervisors/{name}/resume", Summary: "Resume a paused supervisor", Params: []apiParam{supervisorParam}, Response: apiSupervisor{}, Handler: s.apiPauseSupervisor(false)},
+		{Method: http.MethodPost, Path: "/supervisors/{name}/attach", Summary: "Attach a supervisor to an already running game client", Params: []apiParam{supervisorParam}, RequestBody: apiAttachRequest{}, Response: apiSupervisor{}, Status: http.StatusAccepted, Handler: s.apiAttachSupervisor},
+		{Method: http.MethodGet, Path: "/supervisors/{name}/stats", Summary: "Get supervisor stats, for the current session or for a time window if stats are persisted", Params: timeWindow, Response: apiStats{}, Handler: s.apiSupervisorStats},
+		{Method: http.MethodGet, Path: "/supervisors/{name}/drops", Summary: "List supervisor drops", Params: append(timeWindow,
+			apiParam{Name: "quality", In: "query", Type: "string", Description: "Item quality, for example Unique or Set"},
+			apiParam{Name: "name", In: "query", Type: "string", Description: "Case insensitive item name filter"},
+			apiParam{Name: "ruleFile", In: "query", Type: "string", Description: "Case insensitive NIP rule file filter"},
+			apiParam{Name: "offset", In: "query", Type: "integer", Description: "Number of drops to skip"},
+			apiParam{Name: "limit", In: "query", Type: "integer", Description: fmt.Sprintf("Max number of drops to return (default %d, max %d)", defaultDropsLimit, maxDropsLimit)},
+		), Response: apiDropsPage{}, Handler: s.apiSupervisorDrops},
+		{Method: http.MethodGet, Path: "/drops", Summary: "Search the drop ledger, newest drops first", Params: append(ledgerParams,
+			apiParam{Name: "offset", In: "query", Type: "integer", Description: "Number of drops to skip"},
+			apiParam{Name: "limit", In: "query", Type: "integer", Description: fmt.Sprintf("Max number of drops to return (default %d, max %d)", defaultDropsLimit, maxDropsLimit)},
+		), Response: apiLedgerPage{}, Handler: s.apiLedgerDrops},
+		{Method: http.MethodGet, Path: "/drops/export", Summary: "Export the drop ledger as a CSV or JSON file", Params: append(ledgerParams,
+			apiParam{Name: "format", In: "query", Type: "string", Description: "csv or json (default)"},
+		), Response: []ledger.Entry{}, Handler: s.apiExportDrops},
+		{Method: http.MethodGet, Path: "/terrorzones", Summary: "Terror zones seen by the supervisors and the results of each one, newest visits first", Params: []apiParam{
+			{Name: "supervisor", In: "query", Type: "string", Description: "Supervisor name"},
+			{Name: "zone", In: "query", Type: "string", Description: "Area name or id"},
+			{Name: "from", In: "query", Type: "string", Description: "Start date (YYYY-MM-DD)"},
+			{Name: "to", In: "query", Type: "string", Description: "End date (YYYY-MM-DD)"},
+			{Name: "limit", In: "query", Type: "integer", Description: fmt.Sprintf("Max number of visits to return (default %d, max %d)", defaultDropsLimit, maxDropsLimit)},
+		}, Response: apiTerrorZones{}, Handler: s.apiTerrorZones},
+		{Method: http.MethodPost, Path: "/pickit/simulate", Summary: "Evaluate the character pickit rules against recorded items, optionally comparing them with another rules directory", RequestBody: apiPickitSimulateRequest{}, Response: pickit.Report{}, Handler: s.apiPickitSimulate},
+		{Method: http.MethodGet, Path: "/supervisors/{name}/runs", Summary: "Get the efficiency of every run (duration, failures and amount of stashed items by NIP rule) and the adaptive runs decision log", Params: []apiParam{supervisorParam}, Response: bot.RunAnalytics{}, Handler: s.apiSupervisorRuns},
+		{Method: http.MethodGet, Path: "/supervisors/{name}/debug", Summary: "Get the last action and step executed per priority", Params: []apiParam{supervisorParam}, Response: apiDebug{}, Handler: s.apiSupervisorDebug},
+		{Method: http.MethodPost, Path: "/config/reload", Summary: "Reload character configs from disk, running supervisors apply them when the current game finishes", Params: []apiParam{
+			{Name: "keepRules", In: "query", Type: "boolean", Description: "Keep the NIP rules currently in use"},
+		}, Response: bot.ReloadReport{}, Handler: s.apiReloadConfig},
+		{Method: http.MethodGet, Path: "/hub/supervisors", Summary: "List the supervisors of the instances connected to this hub", Response: []hub.RemoteSupervisor{}, Handler: s.apiHubSupervisors},
+		{Method: http.MethodGet, Path: "/tokens", Summary: "List API token names", Response: []apiToken{}, Handler: s.apiListTokens},
+		{Method: http.MethodPost, Path: "/tokens", Summary: "Create an API token, the token is only returned once", RequestBody: apiToken{}, Response: apiNewToken{}, Status: http.StatusCreated, Handler: s.apiCreateToken},
+		{Method: http.MethodDelete, Path: "/tokens/{name}", Summary: "Revoke an API token", Params: []apiParam{{Name: "name", In: "path", Type: "string", Description: "Token name", Required: true}}, Status: http.StatusNoContent, Handler: s.apiDeleteToken},
+		{Method: http.MethodGet, Path: "/openapi.json", Summary: "OpenAPI document for this API", Response: map[string]any{}, Handler: s.apiOpenAPI},
+	}
+}
+
+func (s *HttpServer) registerAPIv1(mux *http.ServeMux) {
+	for _, route := range s.apiRoutes() {
+		mux.HandleFunc(route.Method+" "+apiV1Prefix+route.Path, route.Handler)
+	}
+
+	// Anything else under the API prefix should return a JSON error instead of the dashboard
+	mux.HandleFunc(apiV1Prefix+"/", func(w http.ResponseWriter, r *http.Request) {
+		writeAPIError(w, http.StatusNotFound, "not_found", fmt.Sprintf("%s %s not found", r.Method, r.URL.Path))
+	})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeAPIError(w http.ResponseWriter, status int, code, message string) {
+	writeJSON(w, status, apiErrorResponse{Error: apiError{Code: code, Message: message}})
+}
+
+// apiSupervisorName returns the supervisor from the path, writing an error if it doesn't exist
+func (s *HttpServer) apiSupervisorName(w http.ResponseWriter, r *http.Request) (string, bool) {
+	name := r.PathValue("name")
+	if !slices.Contains(s.manager.AvailableSupervisors(), name) {
+		writeAPIError(w, http.StatusNotFound, "supervisor_not_found", fmt.Sprintf("supervisor %s not found", name))
+		return "", false
+	}
+
+	return name, true
+}
+
+func (s *HttpServer) isSupervisorRunning(name string) bool {
+	status := s.manager.GetSupervisorStats(name).SupervisorStatus
+	return status != "" && status != bot.NotStarted && status != bot.Crashed
+}
+
+func newAPISupervisor(name string, st bot.Stats) apiSupervisor {
+	status := st.SupervisorStatus
+	if status == "" {
+		status = bot.NotStarted
+	}
+
+	return apiSupervisor{
+		Name:      name,
+		Status:    status,
+		StartedAt: st.StartedAt,
+		Games:     st.TotalGames(),
+		Drops:     len(st.Drops),
+		Chickens:  st.TotalChickens(),
+		Deaths:    st.TotalDeaths(),
+		Errors:    st.TotalErrors(),
+	}
+}
+
+func (s *HttpServer) apiListSupervisors(w http.ResponseWriter, r *http.Request) {
+	supervisors := s.manager.AvailableSupervisors()
+	slices.Sort(supervisors)
+
+	result := make([]apiSupervisor, 0, len(supervisors))
+	for _, name := range supervisors {
+		result = append(result, newAPISupervisor(name, s.manager.GetSupervisorStats(name)))
+	}
+
+	writeJSON(w, http.StatusOK, result)
+}
+
+func (s *HttpServer) apiGetSupervisor(w http.ResponseWriter, r *http.Request) {
+	name, ok := s.apiSupervisorName(w, r)
+	if !ok {
+		return
+	}
+
+	writeJSON(w, http.StatusOK, newAPISupervisor(name, s.manager.GetSupervisorStats(name)))
+}
+
+func (s *HttpServer) apiStartSupervisor(w http.ResponseWriter, r *http.Request) {
+	name, ok := s.apiSupervisorName(w, r)
+	if !ok {
+		return
+	}
+
+	if s.isSupervisorRunning(name) {
+		writeAPIError(w, http.StatusConflict, "already_running", fmt.Sprintf("supervisor %s is already running", name))
+		return
+	}
+
+	if err := s.canStartSupervisor(name); err != nil {
+		writeAPIError(w, http.StatusConflict, "cannot_start", err.Error())
+		return
+	}
+
+	// Starting the supervisor blocks until it's stopped, so we just trigger it
+	go s.manager.Start(name, false)
+
+	writeJSON(w, http.StatusAccepted, newAPISupervisor(name, bot.Stats{SupervisorStatus: bot.Starting}))
+}
+
+func (s *HttpServer) apiStopSupervisor(w http.ResponseWriter, r *http.Request) {
+	name, ok := s.apiSupervisorName(w, r)
+	if !ok {
+		return
+	}
+
+	if !s.isSupervisorRunning(name) {
+		writeAPIError(w, http.StatusConflict, "not_running", fmt.Sprintf("supervisor %s is not running", name))
+		return
+	}
+
+	s.manager.Stop(name)
+	writeJSON(w, http.StatusOK, newAPISupervisor(name, s.manager.GetSupervisorStats(name)))
+}
+
+func (s *HttpServer) apiPauseSupervisor(pause bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		name, ok := s.apiSupervisorName(w, r)
+		if !ok {
+			return
+		}
+
+		if !s.isSupervisorRunning(name) {
+			writeAPIError(w, http.StatusConflict, "not_running", fmt.Sprintf("supervisor %s is not running", name))
+			return
+		}
+
+		// TogglePause is not idempotent, only toggle when the current state is not the requested one
+		isPaused := s.manager.GetSupervisorStats(name).SupervisorStatus == bot.Paused
+		if isPaused != pause {
+			s.manager.TogglePause(name)
+		}
+
+		writeJSON(w, http.StatusOK, newAPISupervisor(name, s.manager.GetSupervisorStats(name)))
+	}
+}
+
+func (s *HttpServer) apiAttachSupervisor(w http.ResponseWriter, r *http.Request) {
+	name, ok := s.apiSupervisorName(w, r)
+	if !ok {
+		return
+	}
+
+	if s.isSupervisorRunning(name) {
+		writeAPIError(w, http.StatusConflict, "already_running", fmt.Sprintf("supervisor %s is already running", name))
+		return
+	}
+
+	var req apiAttachRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.PID == 0 {
+		writeAPIError(w, http.StatusBadRequest, "invalid_body", "body must be a JSON object with a valid pid")
+		return
+	}
+
+	hwnd := findWindowForPID(req.PID)
+	if hwnd == 0 {
+		writeAPIError(w, http.StatusNotFound, "window_not_found", fmt.Sprintf("no window found for process %d", req.PID))
+		return
+	}
+
+	go s.manager.Start(name, true, req.PID, uint32(hwnd))
+
+	writeJSON(w, http.StatusAccepted, newAPISupervisor(name, bot.Stats{SupervisorStatus: bot.Starting}))
+}
+
+// apiTimeWindow parses from/to query parameters, to is inclusive for the whole day
+func apiTimeWindow(r *http.Request) (time.Time, time.Time, error) {
+	var from, to time.Time
+	var err error
+
+	if v := r.URL.Query().Get("from"); v != "" {
+		if from, err = time.ParseInLocation(time.DateOnly, v, time.Local); err != nil {
+			return from, to, fmt.Errorf("invalid from date %q, expected YYYY-MM-DD", v)
+		}
+	}
+	if v := r.URL.Query().Get("to"); v != "" {
+		if to, err = time.ParseInLocation(time.DateOnly, v, time.Local); err != nil {
+			return from, to, fmt.Errorf("invalid to date %q, expected YYYY-MM-DD", v)
+		}
+		to = to.Add(24*time.Hour - time.Nanosecond)
+	}
+
+	return from, to, nil
+}
+
+// apiStatsFor returns the stats for the requested time window, or the current session stats if there is no window
+func (s *HttpServer) apiStatsFor(w http.ResponseWriter, r *http.Request, name string) (bot.Stats, time.Time, time.Time, bool) {
+	from, to, err := apiTimeWindow(r)
+	if err != nil {
+		writeAPIError(w, http.StatusBadRequest, "invalid_time_window", err.Error())
+		return bot.Stats{}, from, to, false
+	}
+
+	if from.IsZero() && to.IsZero() && r.URL.Query().Get("history") != "true" {
+		return s.manager.GetSupervisorStats(name), from, to, true
+	}
+
+	if !s.manager.HasStatsHistory() {
+		writeAPIError(w, http.StatusBadRequest, "history_disabled", "stats persistence is disabled, only current session stats are available")
+		return bot.Stats{}, from, to, false
+	}
+
+	st, err := s.manager.StatsBetween(name, from, to)
+	if err != nil {
+		writeAPIError(w, http.StatusInternalServerError, "stats_unavailable", err.Error())
+		return bot.Stats{}, from, to, false
+	}
+
+	return st, from, to, true
+}
+
+func (s *HttpServer) apiSupervisorStats(w http.ResponseWriter, r *http.Request) {
+	name, ok := s.apiSupervisorName(w, r)
+	if !ok {
+		return
+	}
+
+	st, from, to, ok := s.apiStatsFor(w, r, name)
+	if !ok {
+		return
+	}
+
+	result := apiStats{
+		apiSupervisor: newAPISupervisor(name, st),
+		History:       !from.IsZero() || !to.IsZero() || r.URL.Query().Get("history") == "true",
+		Details:       st.Games,
+	}
+	if !from.IsZero() {
+		result.From = &from
+	}
+	if !to.IsZero() {
+		result.To = &to
+	}
+	if result.Details == nil {
+		result.Details = make([]bot.GameStats, 0)
+	}
+
+	writeJSON(w, http.StatusOK, result)
+}
+
+func (s *HttpServer) apiSupervisorDrops(w http.ResponseWriter, r *http.Request) {
+	name, ok := s.apiSupervisorName(w, r)
+	if !ok {
+		return
+	}
+
+	q := r.URL.Query()
+	offset, limit, ok := apiPagination(w, r)
+	if !ok {
+		return
+	}
+
+	st, _, _, ok := s.apiStatsFor(w, r, name)
+	if !ok {
+		return
+	}
+
+	filtered := make([]apiDrop, 0)
+	for _, d := range st.Drops {
+		if q.Get("quality") != "" && !strings.EqualFold(d.Item.Quality.ToString(), q.Get("quality")) {
+			continue
+		}
+		if q.Get("name") != "" && !strings.Contains(strings.ToLower(string(d.Item.Name)), strings.ToLower(q.Get("name"))) {
+			continue
+		}
+		if q.Get("ruleFile") != "" && !strings.Contains(strings.ToLower(d.RuleFile), strings.ToLower(q.Get("ruleFile"))) {
+			continue
+		}
+		filtered = append(filtered, newAPIDrop(d))
+	}
+
+	page := apiDropsPage{Total: len(filtered), Offset: offset, Limit: limit, Drops: make([]apiDrop, 0)}
+	if offset < len(filtered) {
+		page.Drops = filtered[offset:min(offset+limit, len(filtered))]
+	}
+
+	writeJSON(w, http.StatusOK, page)
+}
+
+// apiPagination returns offset and limit from the query string, writing an error if they are not valid
+func apiPagination(w http.ResponseWriter, r *http.Request) (int, int, bool) {
+	offset, err := apiIntParam(r.URL.Query().Get("offset"), 0)
+	if err != nil || offset < 0 {
+		writeAPIError(w, http.StatusBadRequest, "invalid_offset", "offset must be a positive number")
+		return 0, 0, false
+	}
+	limit, err := apiIntParam(r.URL.Query().Get("limit"), defaultDropsLimit)
+	if err != nil || limit <= 0 || limit > maxDropsLimit {
+		writeAPIError(w, http.StatusBadRequest, "invalid_limit", fmt.Sprintf("limit must be between 1 and %d", maxDropsLimit))
+		return 0, 0, false
+	}
+
+	return offset, limit, true
+}
+
+func (s *HttpServer) apiLedgerDrops(w http.ResponseWriter, r *http.Request) {
+	offset, limit, ok := apiPagination(w, r)
+	if !ok {
+		return
+	}
+
+	filter, err := s.ledgerFilter(r)
+	if err != nil {
+		writeAPIError(w, http.StatusBadRequest, "invalid_time_window", err.Error())
+		return
+	}
+
+	entries := s.ledger.Query(filter)
+	page := apiLedgerPage{Total: len(entries), Offset: offset, Limit: limit, Drops: make([]ledger.Entry, 0)}
+	if offset < len(entries) {
+		page.Drops = entries[offset:min(offset+limit, len(entries))]
+	}
+
+	writeJSON(w, http.StatusOK, page)
+}
+
+func (s *HttpServer) apiExportDrops(w http.ResponseWriter, r *http.Request) {
+	format := r.URL.Query().Get("format")
+	if !validExportFormat(format) {
+		writeAPIError(w, http.StatusBadRequest, "invalid_format", "format must be csv or json")
+		return
+	}
+
+	filter, err := s.ledgerFilter(r)
+	if err != nil {
+		writeAPIError(w, http.StatusBadRequest, "invalid_time_window", err.Error())
+		return
+	}
+
+	s.writeDropsExport(w, format, s.ledger.Query(filter))
+}
+
+func newAPIDrop(d data.Drop) apiDrop {
+	stats := make([]apiItemStat, 0, len(d.Item.Stats))
+	for _, st := range d.Item.Stats {
+		stats = append(stats, apiItemStat{Name: statIDToText(st.ID), Value: st.Value})
+	}
+
+	return apiDrop{
+		Name:       string(d.Item.Name),
+		Quality:    d.Item.Quality.ToString(),
+		Ethereal:   d.Item.Ethereal,
+		Identified: d.Item.Identified,
+		Rule:       d.Rule,
+		RuleFile:   d.RuleFile,
+		Stats:      stats,
+	}
+}
+
+func apiIntParam(value string, def int) (int, error) {
+	if value == "" {
+		return def, nil
+	}
+
+	return strconv.Atoi(value)
+}
+
+func (s *HttpServer) apiReloadConfig(w http.ResponseWriter, r *http.Request) {
+	report, err := s.manager.ReloadConfig(bot.ReloadOptions{KeepRules: r.URL.Query().Get("keepRules") == "true"})
+	if err != nil {
+		writeAPIError(w, http.StatusInternalServerError, "reload_failed", err.Error())
+		return
+	}
+
+	writeJSON(w, http.StatusOK, report)
+}
+
+func (s *HttpServer) apiTerrorZones(w http.ResponseWriter, r *http.Request) {
+	_, limit, ok := apiPagination(w, r)
+	if !ok {
+		return
+	}
+
+	from, to, err := apiTimeWindow(r)
+	if err != nil {
+		writeAPIError(w, http.StatusBadRequest, "invalid_time_window", err.Error())
+		return
+	}
+
+	filter := terrorzone.Filter{Supervisor: r.URL.Query().Get("supervisor"), From: from, To: to}
+	if zone := r.URL.Query().Get("zone"); zone != "" {
+		id, found := config.AreaByName(zone)
+		if !found {
+			writeAPIError(w, http.StatusBadRequest, "invalid_zone", fmt.Sprintf("unknown area %q", zone))
+			return
+		}
+		filter.Zone = id
+	}
+
+	visits := s.tzTracker.Visits(filter)
+	writeJSON(w, http.StatusOK, apiTerrorZones{Zones: terrorzone.Summarize(visits), Visits: visits[:min(limit, len(visits))]})
+}
+
+func (s *HttpServer) apiHubSupervisors(w http.ResponseWriter, r *http.Request) {
+	if s.hub == nil {
+		writeAPIError(w, http.StatusNotFound, "hub_disabled", "this instance is not a hub")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, s.hub.Supervisors())
+}
+
+func (s *HttpServer) apiPickitSimulate(w http.ResponseWriter, r *http.Request) {
+	var req apiPickitSimulateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Character == "" {
+		writeAPIError(w, http.StatusBadRequest, "invalid_body", "body must be a JSON object with a character")
+		return
+	}
+
+	cfg, found := config.Characters[req.Character]
+	if !found {
+		writeAPIError(w, http.StatusNotFound, "not_found", fmt.Sprintf("character %s not found", req.Character))
+		return
+	}
+
+	var candidate nip.Rules
+	if req.CompareRulesDir != "" {
+		dir, err := pickit.RulesDirWithin(req.CompareRulesDir, "config", config.Koolo.CentralizedPickitPath)
+		if err != nil {
+			writeAPIError(w, http.StatusBadRequest, "invalid_rules_dir", err.Error())
+			return
+		}
+		rules, err := pickit.LoadRules(dir)
+		if err != nil {
+			writeAPIError(w, http.StatusBadRequest, "invalid_rules", err.Error())
+			return
+		}
+		candidate = rules
+	}
+
+	samples := req.Items
+	if len(samples) == 0 {
+		// Oldest first, the order matters for maxquantity
+		entries := s.ledger.Query(ledger.Filter{})
+		slices.Reverse(entries)
+		samples = pickit.FromLedger(entries)
+	}
+
+	totalGold := math.MaxInt32
+	if req.TotalGold != nil {
+		totalGold = *req.TotalGold
+	}
+
+	writeJSON(w, http.StatusOK, pickit.Evaluate(cfg.Runtime.Rules, candidate, samples, action.PickitSimulationState(cfg, totalGold)))
+}
+
+func (s *HttpServer) apiSupervisorRuns(w http.ResponseWriter, r *http.Request) {
+	name, ok := s.apiSupervisorName(w, r)
+	if !ok {
+		return
+	}
+
+	ra, err := s.manager.GetRunAnalytics(name)
+	if err != nil {
+		writeAPIError(w, http.StatusInternalServerError, "stats_unavailable", err.Error())
+		return
+	}
+
+	writeJSON(w, http.StatusOK, ra)
+}
+
+func (s *HttpServer) apiSupervisorDebug(w http.ResponseWriter, r *http.Request) {
+	name, ok := s.apiSupervisorName(w, r)
+	if !ok {
+		return
+	}
+
+	c := s.manager.GetContext(name)
+	if c == nil {
+		writeAPIError(w, http.StatusConflict, "not_running", fmt.Sprintf("supervisor %s is not running", name))
+		return
+	}
+
+	writeJSON(w, http.StatusOK, apiDebug{Supervisor: name, Debug: c.ContextDebug})
+}
+
+func (s *HttpServer) apiListTokens(w http.ResponseWriter, r *http.Request) {
+	tokens := make([]apiToken, 0, len(config.Koolo.Server.Auth.Tokens))
+	for _, t := range config.Koolo.Server.Auth.Tokens {
+		tokens = append(tokens, apiToken{Name: t.Name})
+	}
+
+	writeJSON(w, http.StatusOK, tokens)
+}
+
+func (s *HttpServer) apiCreateToken(w http.ResponseWriter, r *http.Request) {
+	var req apiToken
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || strings.TrimSpace(req.Name) == "" {
+		writeAPIError(w, http.StatusBadRequest, "invalid_body", "body must be a JSON object with a token name")
+		return
+	}
+	req.Name = strings.TrimSpace(req.Name)
+
+	newConfig := *config.Koolo
+	if slices.ContainsFunc(newConfig.Server.Auth.Tokens, func(t config.APIToken) bool { return t.Name == req.Name }) {
+		writeAPIError(w, http.StatusConflict, "token_exists", fmt.Sprintf("token %s already exists", req.Name))
+		return
+	}
+
+	token, err := randomToken()
+	if err != nil {
+		writeAPIError(w, http.StatusInternalServerError, "token_error", err.Error())
+		return
+	}
+
+	newConfig.Server.Auth.Tokens = append(slices.Clone(newConfig.Server.Auth.Tokens), config.APIToken{Name: req.Name, Hash: hashAPIToken(token)})
+	if err = config.ValidateAndSaveConfig(newConfig); err != nil {
+		writeAPIError(w, http.StatusInternalServerError, "config_error", err.Error())
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, apiNewToken{Name: req.Name, Token: token})
+}
+
+func (s *HttpServer) apiDeleteToken(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+
+	newConfig := *config.Koolo
+	tokens := slices.DeleteFunc(slices.Clone(newConfig.Server.Auth.Tokens), func(t config.APIToken) bool { return t.Name == name })
+	if len(tokens) == len(newConfig.Server.Auth.Tokens) {
+		writeAPIError(w, http.StatusNotFound, "token_not_found", fmt.Sprintf("token %s not found", name))
+		return
+	}
+
+	newConfig.Server.Auth.Tokens = tokens
+	if err := config.ValidateAndSaveConfig(newConfig); err != nil {
+		writeAPIError(w, http.StatusInternalServerError, "config_error", err.Error())
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *HttpServer) apiOpenAPI(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, buildOpenAPI(s.apiRoutes()))
+}
+
+// buildOpenAPI generates an OpenAPI 3 document from the route definitions, schemas are built using reflection
+// from the request and response types
+func buildOpenAPI(routes []apiRoute) map[string]any {
+	errorSchema := jsonSchema(reflect.TypeOf(apiErrorResponse{}), nil)
+
+	paths := make(map[string]map[string]any)
+	for _, route := range routes {
+		status := route.Status
+		if status == 0 {
+			status = http.StatusOK
+		}
+
+		params := make([]map[string]any, 0, len(route.Params))
+		for _, p := range route.Params {
+			params = append(params, map[string]any{
+				"name":        p.Name,
+				"in":          p.In,
+				"required":    p.Required,
+				"description": p.Description,
+				"schema":      map[string]any{"type": p.Type},
+			})
+		}
+
+		success := map[string]any{"description": http.StatusText(status)}
+		if route.Response != nil {
+			success["content"] = map[string]any{"application/json": map[string]any{"schema": jsonSchema(reflect.TypeOf(route.Response), nil)}}
+		}
+
+		op := map[string]any{
+			"summary":     route.Summary,
+			"operationId": strings.ToLower(route.Method) + strings.ReplaceAll(strings.NewReplacer("{", "", "}", "").Replace(route.Path), "/", "_"),
+			"parameters":  params,
+			"security":    []map[string][]string{{"bearerAuth": {}}, {"sessionCookie": {}}},
+			"responses": map[string]any{
+				strconv.Itoa(status): success,
+				"default": map[string]any{
+					"description": "Error",
+					"content":     map[string]any{"application/json": map[string]any{"schema": errorSchema}},
+				},
+			},
+		}
+		if route.RequestBody != nil {
+			op["requestBody"] = map[string]any{
+				"required": true,
+				"content":  map[string]any{"application/json": map[string]any{"schema": jsonSchema(reflect.TypeOf(route.RequestBody), nil)}},
+			}
+		}
+
+		path := apiV1Prefix + route.Path
+		if paths[path] == nil {
+			paths[path] = make(map[string]any)
+		}
+		paths[path][strings.ToLower(route.Method)] = op
+	}
+
+	return map[string]any{
+		"openapi": "3.0.3",
+		"info": map[string]any{
+			"title":   "Koolo API",
+			"version": config.Version,
+		},
+		"paths": paths,
+		"components": map[string]any{
+			"securitySchemes": map[string]any{
+				"bearerAuth":    map[string]any{"type": "http", "scheme": "bearer"},
+				"sessionCookie": map[string]any{"type": "apiKey", "in": "cookie", "name": sessionCookieName},
+			},
+		},
+	}
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+func jsonSchema(t reflect.Type, visiting map[reflect.Type]bool) map[string]any {
+	if t == nil {
+		return map[string]any{}
+	}
+	if visiting == nil {
+		visiting = make(map[reflect.Type]bool)
+	}
+
+	if t == timeType {
+		return map[string]any{"type": "string", "format": "date-time"}
+	}
+
+	switch t.Kind() {
+	case reflect.Pointer:
+		return jsonSchema(t.Elem(), visiting)
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]any{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]any{"type": "number"}
+	case reflect.String:
+		return map[string]any{"type": "string"}
+	case reflect.Slice, reflect.Array:
+		return map[string]any{"type": "array", "items": jsonSchema(t.Elem(), visiting)}
+	case reflect.Map:
+		return map[string]any{"type": "object", "additionalProperties": jsonSchema(t.Elem(), visiting)}
+	case reflect.Struct:
+		// Avoid infinite recursion with self referencing types
+		if visiting[t] {
+			return map[string]any{"type": "object"}
+		}
+		visiting[t] = true
+		defer delete(visiting, t)
+
+		properties := make(map[string]any)
+		addStructFields(t, properties, visiting)
+
+		return map[string]any{"type": "object", "properties": properties}
+	}
+
+	return map[string]any{}
+}
+
+func addStructFields(t reflect.Type, properties map[string]any, visiting map[reflect.Type]bool) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := f.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+
+		name, _, _ := strings.Cut(tag, ",")
+		if f.Anonymous && name == "" {
+			ft := f.Type
+			if ft.Kind() == reflect.Pointer {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				addStructFields(ft, properties, visiting)
+				continue
+			}
+		}
+		if !f.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+
+		properties[name] = jsonSchema(f.Type, visiting)
+	}
+}
@@ -0,0 +1,106 @@
+package server
+
+import (
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/hectorgimenez/koolo/internal/bot"
+	"github.com/hectorgimenez/koolo/internal/config"
+	"github.com/hectorgimenez/koolo/internal/event"
+)
+
+// newTestServer returns a server with a "sorc" supervisor, store is nil when stats are not persisted
+func newTestServer(t *testing.T, store bot.StatsStore) http.Handler {
+	t.Helper()
+
+	characters := config.Characters
+	config.Characters = map[string]*config.CharacterCfg{"sorc": {}}
+	t.Cleanup(func() { config.Characters = characters })
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	s := &HttpServer{logger: logger, manager: bot.NewSupervisorManager(logger, nil, store)}
+	mux := http.NewServeMux()
+	s.registerAPIv1(mux)
+
+	return mux
+}
+
+func getJSON(t *testing.T, h http.Handler, url string, status int, v any) {
+	t.Helper()
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, url, nil))
+	if rec.Code != status {
+		t.Fatalf("%s: expected status %d, got %d: %s", url, status, rec.Code, rec.Body.String())
+	}
+	if err := json.NewDecoder(rec.Body).Decode(v); err != nil {
+		t.Fatalf("%s: invalid response: %v", url, err)
+	}
+}
+
+func TestStatsHistory(t *testing.T) {
+	store, err := bot.NewJSONLStatsStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+
+	// A game from a previous session
+	be := event.TextAt("sorc", "", time.Now().Add(-time.Hour))
+	for _, e := range []event.Event{event.GameCreated(be, "mf-1", ""), event.RunStarted(be, "mephisto"), event.RunFinished(be, "mephisto", event.FinishedDied)} {
+		if err = store.Record("sorc", e); err != nil {
+			t.Fatal(err)
+		}
+	}
+	h := newTestServer(t, store)
+
+	var stats apiStats
+	getJSON(t, h, "/api/v1/supervisors/sorc/stats", http.StatusOK, &stats)
+	if stats.History || stats.Games != 0 || stats.Details == nil {
+		t.Errorf("only the current session stats should be returned by default: %+v", stats)
+	}
+
+	stats = apiStats{}
+	getJSON(t, h, "/api/v1/supervisors/sorc/stats?history=true", http.StatusOK, &stats)
+	if !stats.History || stats.Games != 1 || stats.Deaths != 1 || len(stats.Details) != 1 || stats.From != nil {
+		t.Errorf("the persisted history should be returned: %+v", stats)
+	}
+}
+
+func TestStatsHistoryDisabled(t *testing.T) {
+	h := newTestServer(t, nil)
+
+	var stats apiStats
+	getJSON(t, h, "/api/v1/supervisors/sorc/stats?history=false", http.StatusOK, &stats)
+	if stats.History || stats.Name != "sorc" {
+		t.Errorf("unexpected stats %+v", stats)
+	}
+
+	var apiErr apiErrorResponse
+	getJSON(t, h, "/api/v1/supervisors/sorc/stats?history=true", http.StatusBadRequest, &apiErr)
+	if apiErr.Error.Code != "history_disabled" {
+		t.Errorf("unexpected error %+v", apiErr)
+	}
+}
+
+func TestOpenAPIDocumentsHistory(t *testing.T) {
+	s := &HttpServer{}
+	doc := buildOpenAPI(s.apiRoutes())
+
+	for _, path := range []string{"/api/v1/supervisors/{name}/stats", "/api/v1/supervisors/{name}/drops"} {
+		op := doc["paths"].(map[string]map[string]any)[path]["get"].(map[string]any)
+		names := make([]string, 0)
+		for _, p := range op["parameters"].([]map[string]any) {
+			names = append(names, p["name"].(string))
+		}
+		if !slices.Contains(names, "history") {
+			t.Errorf("%s: history parameter not documented, got %v", path, names)
+		}
+	}
+}
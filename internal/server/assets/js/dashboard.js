@@ -4,7 +4,7 @@ let socket;
     const reconnectDelay = 3000;
 
     function connectWebSocket() {
-        socket = new WebSocket('ws://' + window.location.host + '/ws');
+        socket = new WebSocket((window.location.protocol === 'https:' ? 'wss://' : 'ws://') + window.location.host + '/ws');
 
         socket.onopen = function() {
             console.log('WebSocket connected');
@@ -48,6 +48,8 @@ let socket;
             }
         }
 
+        updateRemoteSupervisors(data.Remote);
+
         const container = document.getElementById('characters-container');
         if (!container) return;
 
@@ -62,7 +64,7 @@ let socket;
                 card = createCharacterCard(key);
                 container.appendChild(card);
             }
-            updateCharacterCard(card, key, value, data.DropCount[key]);
+            updateCharacterCard(card, key, value, data.DropCount[key], data.Lifetime ? data.Lifetime[key] : undefined, data.Schedule ? data.Schedule[key] : undefined, data.Rotation ? data.Rotation[key] : undefined);
         }
 
         // Remove cards for characters that no longer exist
@@ -201,7 +203,7 @@ let socket;
         }
     }
 
-    function updateCharacterCard(card, key, value, dropCount) {
+    function updateCharacterCard(card, key, value, dropCount, lifetime, schedule, rotation) {
         if (!card) return;
 
         const startPauseBtn = card.querySelector('.start-pause');
@@ -226,6 +228,9 @@ let socket;
 
 
         updateStats(card, key, value.Games, dropCount);
+        updateLifetimeStats(card, key, lifetime);
+        updateScheduleInfo(card, schedule);
+        updateRotationInfo(card, rotation);
         updateRunStats(card, value.Games);
         
         if (statusDetails) {
@@ -304,13 +309,124 @@ function updateButtons(startPauseBtn, stopBtn, attachBtn, status) {
         
         card.querySelector('.runs').textContent = stats.totalGames;
         card.querySelector('.drops').innerHTML = dropCount === undefined ? 'None' : 
-            (dropCount === 0 ? 'None' : `<a href="/drops?supervisor=${key}">${dropCount}</a>`);
+            (dropCount === 0 ? 'None' : `<a href="/drops?supervisor=${key}&session=true">${dropCount}</a>`);
         card.querySelector('.chickens').textContent = stats.totalChickens;
         card.querySelector('.deaths').textContent = stats.totalDeaths;
         card.querySelector('.errors').textContent = stats.totalErrors;
     }
 
 
+    function updateLifetimeStats(card, key, lifetime) {
+        let lifetimeElement = card.querySelector('.lifetime-stats');
+        if (!lifetime) {
+            if (lifetimeElement) lifetimeElement.remove();
+            return;
+        }
+
+        if (!lifetimeElement) {
+            lifetimeElement = document.createElement('div');
+            lifetimeElement.className = 'lifetime-stats';
+            card.querySelector('.stats-grid').after(lifetimeElement);
+        }
+
+        const drops = lifetime.Drops === 0 ? '0' : `<a href="/drops?supervisor=${key}">${lifetime.Drops}</a>`;
+        lifetimeElement.innerHTML = `<span class="stat-label">All time:</span> ${lifetime.Games} games, ${drops} drops, ${lifetime.Chickens} chickens, ${lifetime.Deaths} deaths, ${lifetime.Errors} errors`;
+    }
+
+    function updateScheduleInfo(card, schedule) {
+        let scheduleElement = card.querySelector('.schedule-info');
+        if (!schedule) {
+            if (scheduleElement) scheduleElement.remove();
+            return;
+        }
+
+        if (!scheduleElement) {
+            scheduleElement = document.createElement('div');
+            scheduleElement.className = 'schedule-info';
+            card.querySelector('.stats-grid').after(scheduleElement);
+        }
+
+        const formatTime = (t) => t ? new Date(t).toLocaleString([], { weekday: 'short', hour: '2-digit', minute: '2-digit' }) : '-';
+        const next = schedule.ShouldRun
+            ? `next stop ${formatTime(schedule.NextStop)}`
+            : `${schedule.Reason}, next start ${formatTime(schedule.NextStart)}`;
+        scheduleElement.innerHTML = `<span class="stat-label">Schedule:</span> ${next}`;
+    }
+
+    function updateRotationInfo(card, rotation) {
+        let rotationElement = card.querySelector('.rotation-info');
+        if (!rotation) {
+            if (rotationElement) rotationElement.remove();
+            return;
+        }
+
+        if (!rotationElement) {
+            rotationElement = document.createElement('div');
+            rotationElement.className = 'rotation-info';
+            (card.querySelector('.schedule-info') || card.querySelector('.stats-grid')).after(rotationElement);
+        }
+
+        const formatTime = (t) => new Date(t).toLocaleTimeString([], { hour: '2-digit', minute: '2-digit' });
+        const dropped = Object.entries(rotation.Dropped || {})
+            .map(([run, until]) => `${run} (until ${formatTime(until)})`)
+            .join(', ');
+        const decisions = (rotation.Decisions || []).slice().reverse()
+            .map(d => `<li>${formatTime(d.time)} <strong>${d.run || 'all runs'}</strong> ${d.action}: ${d.reason}</li>`)
+            .join('');
+
+        rotationElement.innerHTML = `<span class="stat-label">Adaptive runs:</span> ${dropped ? `skipping ${dropped}` : 'running all'}`
+            + (decisions ? `<ul class="rotation-decisions">${decisions}</ul>` : '');
+    }
+
+    // Supervisors of other Koolo instances connected to this one, only sent when this instance is the hub
+    function updateRemoteSupervisors(remote) {
+        const container = document.getElementById('remote-container');
+        if (!container) return;
+        if (!remote || remote.length === 0) {
+            container.innerHTML = '';
+            return;
+        }
+
+        container.innerHTML = `
+            <h3>Remote supervisors</h3>
+            <table class="remote-supervisors">
+                <thead><tr><th>Instance</th><th>Supervisor</th><th>Status</th><th>Games</th><th>Drops</th><th>Chickens</th><th>Deaths</th><th>Errors</th><th>Updated</th></tr></thead>
+                <tbody></tbody>
+            </table>`;
+
+        // Values come from other instances, they are only set as text so they can not inject HTML
+        const tbody = container.querySelector('tbody');
+        remote.forEach(s => {
+            const row = document.createElement('tr');
+            if (!s.connected) {
+                row.className = 'remote-disconnected';
+            }
+
+            const cell = (text) => {
+                const td = document.createElement('td');
+                td.textContent = text;
+                row.appendChild(td);
+                return td;
+            };
+
+            cell(`${s.instance}${s.connected ? '' : ' (disconnected)'}`);
+            const name = cell(s.name);
+            if (s.character) {
+                const character = document.createElement('small');
+                character.textContent = s.character;
+                name.append(' ', character);
+            }
+            cell(`${s.status}${s.run ? `: ${s.run}` : ''}`);
+            cell(s.games);
+            cell(s.drops);
+            cell(s.chickens);
+            cell(s.deaths);
+            cell(s.errors);
+            cell(new Date(s.updatedAt).toLocaleTimeString());
+            tbody.appendChild(row);
+        });
+    }
+
     function updateRunStats(card, games) {
     const runStats = calculateRunStats(games);
     const runStatsElement = card.querySelector('.run-stats');
@@ -354,6 +470,9 @@ function updateButtons(startPauseBtn, stopBtn, attachBtn, status) {
                 <div class="run-stat-item" title="Deaths">
                     <span class="stat-label">Deaths:</span> ${stats.runDeaths}
                 </div>
+                <div class="run-stat-item" title="${['Stashed items by rule file', ...Object.entries(stats.stashedByRule).map(([rule, n]) => `${rule}: ${n}`)].join('\n')}">
+                    <span class="stat-label">Stashed:</span> ${stats.stashed}
+                </div>
             </div>
         `;
         runStatsGrid.appendChild(runElement);
@@ -383,6 +502,8 @@ function updateButtons(startPauseBtn, stopBtn, attachBtn, status) {
                             runChickens: 0,
                             runDeaths: 0,
                             successfulRunCount: 0,
+                            stashed: 0,
+                            stashedByRule: {},
                             isCurrentRun: false
                         };
                     }
@@ -415,6 +536,12 @@ function updateButtons(startPauseBtn, stopBtn, attachBtn, status) {
                     if (run.Reason == 'death') {
                         runStats[run.Name].runDeaths++;
                     }
+
+                    (run.Drops || []).forEach(drop => {
+                        const rule = drop.RuleFile || 'unknown';
+                        runStats[run.Name].stashed++;
+                        runStats[run.Name].stashedByRule[rule] = (runStats[run.Name].stashedByRule[rule] || 0) + 1;
+                    });
                 });
             }
         });
@@ -632,6 +759,20 @@ function updateButtons(startPauseBtn, stopBtn, attachBtn, status) {
             if (!response.ok) {
                 throw new Error('Failed to reload config');
             }
+
+            const report = await response.json();
+            const messages = [];
+            report.supervisors.forEach(sup => {
+                if (sup.errors && sup.errors.length > 0) {
+                    messages.push(`${sup.supervisor}: config not applied, ${sup.errors.map(e => e.field ? `${e.field}: ${e.message}` : e.message).join(', ')}`);
+                } else if (sup.restartRequired) {
+                    const fields = sup.changes.filter(c => c.restartRequired).map(c => c.field);
+                    messages.push(`${sup.supervisor}: restart required to apply ${fields.join(', ')}`);
+                }
+            });
+            if (messages.length > 0) {
+                alert(messages.join('\n'));
+            }
         } catch (error) {
             console.error('Error reloading config:', error);
         } finally {
@@ -316,6 +316,13 @@ function createCopyDataButton() {
     });
 }
 
+function createMapButton() {
+    const characterName = new URLSearchParams(window.location.search).get('characterName') || 'nullref';
+    document.getElementById('map-btn').addEventListener('click', () => {
+        window.open(`/api/debug/map?characterName=${encodeURIComponent(characterName)}&format=svg`, '_blank');
+    });
+}
+
 // Event Listeners
 setIntervalBtn.addEventListener('click', setRefreshInterval);
 expandAllBtn.addEventListener('click', toggleExpandAll);
@@ -325,5 +332,6 @@ searchPrevBtn.addEventListener('click', goToPreviousSearchResult);
 
 // Initialize
 createCopyDataButton();
+createMapButton();
 fetchDebugData();
 refreshIntervalId = setInterval(fetchDebugData, refreshInterval);
\ No newline at end of file
@@ -0,0 +1,273 @@
+package server
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hectorgimenez/koolo/internal/config"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	sessionCookieName   = "koolo_session"
+	defaultSessionHours = 24
+)
+
+// authenticator protects all the routes when auth is enabled in the Koolo config, requests are allowed with a valid
+// session cookie (web UI) or with an API token sent as "Authorization: Bearer <token>" (scripts)
+type authenticator struct {
+	mu       sync.Mutex
+	sessions map[string]time.Time
+}
+
+func newAuthenticator() *authenticator {
+	return &authenticator{
+		sessions: make(map[string]time.Time),
+	}
+}
+
+// Paths that need to be reachable without being logged in, the hub checks its own token
+func isPublicPath(path string) bool {
+	return path == "/login" || path == "/hub/ws" || strings.HasPrefix(path, "/assets/")
+}
+
+func (a *authenticator) middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		cfg := config.Koolo.Server.Auth
+		if !cfg.Enabled || isPublicPath(r.URL.Path) || (cfg.TrustLocalhost && isLoopback(r)) || a.isAuthenticated(r) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		// Browsers navigating the UI are sent to the login page, anything else (API, fetch, websocket) gets a 401
+		if r.Method == http.MethodGet && strings.Contains(r.Header.Get("Accept"), "text/html") {
+			http.Redirect(w, r, "/login?next="+url.QueryEscape(r.URL.RequestURI()), http.StatusSeeOther)
+			return
+		}
+
+		w.Header().Set("WWW-Authenticate", `Bearer realm="koolo"`)
+		writeAPIError(w, http.StatusUnauthorized, "unauthorized", "authentication required")
+	})
+}
+
+func (a *authenticator) isAuthenticated(r *http.Request) bool {
+	if token, found := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); found {
+		return isValidAPIToken(strings.TrimSpace(token))
+	}
+
+	cookie, err := r.Cookie(sessionCookieName)
+	if err != nil {
+		return false
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	expiresAt, found := a.sessions[cookie.Value]
+	if !found {
+		return false
+	}
+	if time.Now().After(expiresAt) {
+		delete(a.sessions, cookie.Value)
+		return false
+	}
+
+	return true
+}
+
+func isValidAPIToken(token string) bool {
+	if token == "" {
+		return false
+	}
+
+	hash := hashAPIToken(token)
+	valid := false
+	// Check all of them to avoid leaking which token matched through timing
+	for _, t := range config.Koolo.Server.Auth.Tokens {
+		if subtle.ConstantTimeCompare([]byte(hash), []byte(t.Hash)) == 1 {
+			valid = true
+		}
+	}
+
+	return valid
+}
+
+func hashAPIToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func randomToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(b), nil
+}
+
+func checkCredentials(username, password string) bool {
+	cfg := config.Koolo.Server.Auth
+	if cfg.Username == "" || cfg.PasswordHash == "" {
+		return false
+	}
+
+	userMatches := subtle.ConstantTimeCompare([]byte(username), []byte(cfg.Username)) == 1
+	// Always run bcrypt, so a wrong username takes the same time as a wrong password
+	passwordMatches := bcrypt.CompareHashAndPassword([]byte(cfg.PasswordHash), []byte(password)) == nil
+
+	return userMatches && passwordMatches
+}
+
+func hashPassword(password string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", err
+	}
+
+	return string(hash), nil
+}
+
+func (a *authenticator) newSession() (string, time.Time, error) {
+	id, err := randomToken()
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	hours := config.Koolo.Server.Auth.SessionHours
+	if hours <= 0 {
+		hours = defaultSessionHours
+	}
+	expiresAt := time.Now().Add(time.Duration(hours) * time.Hour)
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	// Cleanup expired sessions while we are here
+	for sid, exp := range a.sessions {
+		if time.Now().After(exp) {
+			delete(a.sessions, sid)
+		}
+	}
+	a.sessions[id] = expiresAt
+
+	return id, expiresAt, nil
+}
+
+func (a *authenticator) deleteSession(id string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	delete(a.sessions, id)
+}
+
+// clearSessions logs out everyone, used when the credentials change
+func (a *authenticator) clearSessions() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	clear(a.sessions)
+}
+
+// isLoopback checks the address of the TCP connection, forwarding headers are ignored so they can not be spoofed.
+// Behind a reverse proxy running on the same computer every request comes from localhost, TrustLocalhost must be
+// disabled there
+func isLoopback(r *http.Request) bool {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// checkWebSocketOrigin allows any origin while auth is disabled (same behavior as before), otherwise the websocket
+// can only be opened from the dashboard itself, so other websites can not use the session cookie
+func checkWebSocketOrigin(r *http.Request) bool {
+	if !config.Koolo.Server.Auth.Enabled {
+		return true
+	}
+
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+
+	u, err := url.Parse(origin)
+	if err != nil {
+		return false
+	}
+
+	return strings.EqualFold(u.Host, r.Host)
+}
+
+func (s *HttpServer) login(w http.ResponseWriter, r *http.Request) {
+	next := r.URL.Query().Get("next")
+	// Only allow local redirects
+	if !strings.HasPrefix(next, "/") || strings.HasPrefix(next, "//") {
+		next = "/"
+	}
+
+	if r.Method != http.MethodPost {
+		s.templates.ExecuteTemplate(w, "login.gohtml", LoginData{Next: next})
+		return
+	}
+
+	if err := r.ParseForm(); err != nil {
+		s.templates.ExecuteTemplate(w, "login.gohtml", LoginData{Next: next, ErrorMessage: "Error parsing form"})
+		return
+	}
+
+	username := r.Form.Get("username")
+	if !checkCredentials(username, r.Form.Get("password")) {
+		s.logger.Warn("Failed login attempt on the web UI", "username", username, "remoteAddr", r.RemoteAddr)
+		w.WriteHeader(http.StatusUnauthorized)
+		s.templates.ExecuteTemplate(w, "login.gohtml", LoginData{Next: next, ErrorMessage: "Invalid username or password"})
+		return
+	}
+
+	sessionID, expiresAt, err := s.auth.newSession()
+	if err != nil {
+		s.templates.ExecuteTemplate(w, "login.gohtml", LoginData{Next: next, ErrorMessage: "Error creating session"})
+		return
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     sessionCookieName,
+		Value:    sessionID,
+		Path:     "/",
+		Expires:  expiresAt,
+		HttpOnly: true,
+		Secure:   r.TLS != nil,
+		SameSite: http.SameSiteStrictMode,
+	})
+
+	http.Redirect(w, r, next, http.StatusSeeOther)
+}
+
+func (s *HttpServer) logout(w http.ResponseWriter, r *http.Request) {
+	if cookie, err := r.Cookie(sessionCookieName); err == nil {
+		s.auth.deleteSession(cookie.Value)
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     sessionCookieName,
+		Value:    "",
+		Path:     "/",
+		MaxAge:   -1,
+		HttpOnly: true,
+		Secure:   r.TLS != nil,
+		SameSite: http.SameSiteStrictMode,
+	})
+
+	http.Redirect(w, r, "/login", http.StatusSeeOther)
+}
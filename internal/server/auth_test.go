@@ -0,0 +1,109 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/hectorgimenez/koolo/internal/config"
+)
+
+func setAuthConfig(t *testing.T, trustLocalhost bool, tokens ...string) {
+	t.Helper()
+
+	koolo := config.Koolo
+	config.Koolo = &config.KooloCfg{}
+	t.Cleanup(func() { config.Koolo = koolo })
+
+	config.Koolo.Server.Auth.Enabled = true
+	config.Koolo.Server.Auth.TrustLocalhost = trustLocalhost
+	for _, token := range tokens {
+		config.Koolo.Server.Auth.Tokens = append(config.Koolo.Server.Auth.Tokens, config.APIToken{Name: "script", Hash: hashAPIToken(token)})
+	}
+}
+
+func TestAuthMiddleware(t *testing.T) {
+	handler := newAuthenticator().middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	for name, tc := range map[string]struct {
+		trustLocalhost bool
+		remoteAddr     string
+		authorization  string
+		expected       int
+	}{
+		"valid token":                 {remoteAddr: "192.168.1.20:51000", authorization: "Bearer secret", expected: http.StatusNoContent},
+		"bad token":                   {remoteAddr: "192.168.1.20:51000", authorization: "Bearer wrong", expected: http.StatusUnauthorized},
+		"empty token":                 {remoteAddr: "192.168.1.20:51000", authorization: "Bearer ", expected: http.StatusUnauthorized},
+		"missing token":               {remoteAddr: "192.168.1.20:51000", expected: http.StatusUnauthorized},
+		"localhost trusted":           {trustLocalhost: true, remoteAddr: "127.0.0.1:51000", expected: http.StatusNoContent},
+		"ipv6 localhost trusted":      {trustLocalhost: true, remoteAddr: "[::1]:51000", expected: http.StatusNoContent},
+		"localhost not trusted":       {remoteAddr: "127.0.0.1:51000", expected: http.StatusUnauthorized},
+		"remote with localhost trust": {trustLocalhost: true, remoteAddr: "192.168.1.20:51000", expected: http.StatusUnauthorized},
+	} {
+		setAuthConfig(t, tc.trustLocalhost, "secret")
+
+		r := httptest.NewRequest(http.MethodGet, "/api/v1/supervisors", nil)
+		r.RemoteAddr = tc.remoteAddr
+		if tc.authorization != "" {
+			r.Header.Set("Authorization", tc.authorization)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, r)
+
+		if rec.Code != tc.expected {
+			t.Errorf("%s: expected status %d, got %d", name, tc.expected, rec.Code)
+		}
+	}
+}
+
+func TestAuthForwardedHeadersAreNotTrusted(t *testing.T) {
+	setAuthConfig(t, true)
+	handler := newAuthenticator().middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	r := httptest.NewRequest(http.MethodGet, "/api/v1/supervisors", nil)
+	r.RemoteAddr = "192.168.1.20:51000"
+	r.Header.Set("X-Forwarded-For", "127.0.0.1")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, r)
+
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("only the connection address should be checked, got status %d", rec.Code)
+	}
+}
+
+func TestAuthSessionsAndRedirects(t *testing.T) {
+	setAuthConfig(t, false)
+	a := newAuthenticator()
+	handler := a.middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	// Browsers are sent to the login page
+	r := httptest.NewRequest(http.MethodGet, "/drops?supervisor=sorc", nil)
+	r.Header.Set("Accept", "text/html")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, r)
+	if rec.Code != http.StatusSeeOther || rec.Header().Get("Location") != "/login?next=%2Fdrops%3Fsupervisor%3Dsorc" {
+		t.Errorf("expected a redirect to the login page, got %d %q", rec.Code, rec.Header().Get("Location"))
+	}
+
+	session, _, err := a.newSession()
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.AddCookie(&http.Cookie{Name: sessionCookieName, Value: session})
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, r)
+	if rec.Code != http.StatusNoContent {
+		t.Errorf("a valid session should be allowed, got status %d", rec.Code)
+	}
+
+	a.deleteSession(session)
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, r)
+	if rec.Code != http.StatusSeeOther {
+		t.Errorf("a deleted session should not be allowed, got status %d", rec.Code)
+	}
+}
@@ -0,0 +1,123 @@
+package server
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/hectorgimenez/koolo/internal/config"
+	"github.com/hectorgimenez/koolo/internal/ledger"
+)
+
+var itemQualities = []string{"LowQuality", "Normal", "Superior", "Magic", "Set", "Rare", "Unique", "Crafted"}
+
+// ledgerFilter builds the ledger filter from the query string, it's shared by the drops page and the API
+func (s *HttpServer) ledgerFilter(r *http.Request) (ledger.Filter, error) {
+	from, to, err := apiTimeWindow(r)
+	if err != nil {
+		return ledger.Filter{}, err
+	}
+
+	q := r.URL.Query()
+	f := ledger.Filter{
+		Supervisor: q.Get("supervisor"),
+		Quality:    q.Get("quality"),
+		Name:       q.Get("name"),
+		RuleFile:   q.Get("ruleFile"),
+		From:       from,
+		To:         to,
+	}
+
+	// Current session only, used by the drop counter in the dashboard
+	if f.Supervisor != "" && q.Get("session") == "true" {
+		f.From = s.manager.GetSupervisorStats(f.Supervisor).StartedAt
+	}
+
+	return f, nil
+}
+
+func validExportFormat(format string) bool {
+	return format == "csv" || format == "json" || format == ""
+}
+
+// writeDropsExport sends the entries as a file download, format must be validated before calling it
+func (s *HttpServer) writeDropsExport(w http.ResponseWriter, format string, entries []ledger.Entry) {
+	filename := "koolo-drops-" + time.Now().Format("20060102-150405")
+
+	var err error
+	if format == "csv" {
+		w.Header().Set("Content-Type", "text/csv")
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename+".csv"))
+		err = ledger.WriteCSV(w, entries)
+	} else {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename+".json"))
+		err = ledger.WriteJSON(w, entries)
+	}
+
+	if err != nil {
+		s.logger.Warn("Error exporting drops", slog.Any("error", err))
+	}
+}
+
+func (s *HttpServer) drops(w http.ResponseWriter, r *http.Request) {
+	sup := r.URL.Query().Get("supervisor")
+	title := "all characters"
+	if sup != "" {
+		cfg, found := config.Characters[sup]
+		if !found {
+			http.Error(w, "Can't fetch drop data because the configuration "+sup+" wasn't found", http.StatusNotFound)
+			return
+		}
+		title = cfg.CharacterName
+	}
+
+	q := r.URL.Query()
+	data := DropData{
+		Character:   title,
+		Supervisors: s.manager.AvailableSupervisors(),
+		Qualities:   itemQualities,
+		Supervisor:  sup,
+		Quality:     q.Get("quality"),
+		Name:        q.Get("name"),
+		RuleFile:    q.Get("ruleFile"),
+		From:        q.Get("from"),
+		To:          q.Get("to"),
+		Session:     q.Get("session") == "true",
+		Drops:       make([]ledger.Entry, 0),
+	}
+
+	// Export links keep the current filters
+	q.Set("format", "csv")
+	data.ExportCSV = "/drops/export?" + q.Encode()
+	q.Set("format", "json")
+	data.ExportJSON = "/drops/export?" + q.Encode()
+
+	filter, err := s.ledgerFilter(r)
+	if err != nil {
+		data.ErrorMessage = err.Error()
+		s.templates.ExecuteTemplate(w, "drops.gohtml", data)
+		return
+	}
+
+	data.Drops = s.ledger.Query(filter)
+	data.NumberOfDrops = len(data.Drops)
+	s.templates.ExecuteTemplate(w, "drops.gohtml", data)
+}
+
+func (s *HttpServer) exportDrops(w http.ResponseWriter, r *http.Request) {
+	format := r.URL.Query().Get("format")
+	if !validExportFormat(format) {
+		http.Error(w, "unknown export format, expected csv or json", http.StatusBadRequest)
+		return
+	}
+
+	filter, err := s.ledgerFilter(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	s.writeDropsExport(w, format, s.ledger.Query(filter))
+}
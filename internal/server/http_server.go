@@ -28,6 +28,11 @@ import (
 	"github.com/hectorgimenez/koolo/internal/config"
 	ctx "github.com/hectorgimenez/koolo/internal/context"
 	"github.com/hectorgimenez/koolo/internal/game"
+	"github.com/hectorgimenez/koolo/internal/ledger"
+	"github.com/hectorgimenez/koolo/internal/maprender"
+	"github.com/hectorgimenez/koolo/internal/metrics"
+	"github.com/hectorgimenez/koolo/internal/remote/hub"
+	"github.com/hectorgimenez/koolo/internal/terrorzone"
 	"github.com/hectorgimenez/koolo/internal/utils"
 	"github.com/hectorgimenez/koolo/internal/utils/winproc"
 	"github.com/lxn/win"
@@ -38,8 +43,15 @@ type HttpServer struct {
 	logger    *slog.Logger
 	server    *http.Server
 	manager   *bot.SupervisorManager
+	scheduler *bot.Scheduler
+	metrics   *metrics.Collector
+	ledger    *ledger.Ledger
+	tzTracker *terrorzone.Tracker
+	// Nil if this instance is not a hub
+	hub       *hub.Hub
 	templates *template.Template
 	wsServer  *WebSocketServer
+	auth      *authenticator
 }
 
 var (
@@ -49,9 +61,7 @@ var (
 	templatesFS embed.FS
 
 	upgrader = websocket.Upgrader{
-		CheckOrigin: func(r *http.Request) bool {
-			return true
-		},
+		CheckOrigin: checkWebSocketOrigin,
 	}
 )
 
@@ -176,7 +186,7 @@ func (s *HttpServer) BroadcastStatus() {
 	}
 }
 
-func New(logger *slog.Logger, manager *bot.SupervisorManager) (*HttpServer, error) {
+func New(logger *slog.Logger, manager *bot.SupervisorManager, scheduler *bot.Scheduler, metrics *metrics.Collector, ledger *ledger.Ledger, tzTracker *terrorzone.Tracker, h *hub.Hub) (*HttpServer, error) {
 	var templates *template.Template
 	helperFuncs := template.FuncMap{
 		"isInSlice": func(slice []stat.Resist, value string) bool {
@@ -214,7 +224,13 @@ func New(logger *slog.Logger, manager *bot.SupervisorManager) (*HttpServer, erro
 	return &HttpServer{
 		logger:    logger,
 		manager:   manager,
+		scheduler: scheduler,
+		metrics:   metrics,
+		ledger:    ledger,
+		tzTracker: tzTracker,
+		hub:       h,
 		templates: templates,
+		auth:      newAuthenticator(),
 	}, nil
 }
 
@@ -240,11 +256,26 @@ func (s *HttpServer) attachProcess(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Find the main window handle (HWND) for the process
+	hwnd := findWindowForPID(uint32(pid))
+	if hwnd == 0 {
+		s.logger.Error("Failed to find window handle for process", "pid", pid)
+		return
+	}
+
+	// Call manager.Start with the correct arguments, including the HWND
+	go s.manager.Start(characterName, true, uint32(pid), uint32(hwnd))
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]bool{"success": true})
+}
+
+// findWindowForPID returns the main window handle (HWND) for the given process, 0 if not found
+func findWindowForPID(pid uint32) win.HWND {
 	var hwnd win.HWND
 	enumWindowsCallback := func(h win.HWND, param uintptr) uintptr {
 		var processID uint32
 		win.GetWindowThreadProcessId(h, &processID)
-		if processID == uint32(pid) {
+		if processID == pid {
 			hwnd = h
 			return 0 // Stop enumeration
 		}
@@ -253,16 +284,7 @@ func (s *HttpServer) attachProcess(w http.ResponseWriter, r *http.Request) {
 
 	windows.EnumWindows(syscall.NewCallback(enumWindowsCallback), nil)
 
-	if hwnd == 0 {
-		s.logger.Error("Failed to find window handle for process", "pid", pid)
-		return
-	}
-
-	// Call manager.Start with the correct arguments, including the HWND
-	go s.manager.Start(characterName, true, uint32(pid), uint32(hwnd))
-
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]bool{"success": true})
+	return hwnd
 }
 
 // Add this helper function
@@ -394,11 +416,82 @@ func (s *HttpServer) getStatusData() IndexData {
 		}
 	}
 
-	return IndexData{
+	data := IndexData{
 		Version:   config.Version,
 		Status:    status,
 		DropCount: drops,
+		Lifetime:  s.getLifetimeSummary(),
+		Schedule:  s.getScheduleInfo(),
+		Rotation:  s.getRotationInfo(),
+	}
+	if s.hub != nil {
+		data.Remote = s.hub.Supervisors()
+	}
+
+	return data
+}
+
+// Adaptive runs decisions shown in each dashboard card, the full log is available in the API
+const maxDashboardDecisions = 5
+
+func (s *HttpServer) getRotationInfo() map[string]RotationInfo {
+	info := make(map[string]RotationInfo)
+	now := time.Now()
+	for _, name := range s.manager.AvailableSupervisors() {
+		rotation := s.manager.GetRunRotation(name)
+		if rotation == nil {
+			continue
+		}
+
+		decisions := rotation.Decisions()
+		if len(decisions) > maxDashboardDecisions {
+			decisions = decisions[len(decisions)-maxDashboardDecisions:]
+		}
+		info[name] = RotationInfo{Dropped: rotation.Dropped(now), Decisions: decisions}
+	}
+
+	return info
+}
+
+func (s *HttpServer) getScheduleInfo() map[string]ScheduleInfo {
+	info := make(map[string]ScheduleInfo)
+	for name, d := range s.scheduler.Decisions() {
+		si := ScheduleInfo{ShouldRun: d.ShouldRun, Reason: string(d.Reason)}
+		if !d.NextStart.IsZero() {
+			si.NextStart = &d.NextStart
+		}
+		if !d.NextStop.IsZero() {
+			si.NextStop = &d.NextStop
+		}
+		info[name] = si
+	}
+
+	return info
+}
+
+func (s *HttpServer) getLifetimeSummary() map[string]StatsSummary {
+	if !s.manager.HasStatsHistory() {
+		return nil
+	}
+
+	summary := make(map[string]StatsSummary)
+	for _, supervisorName := range s.manager.AvailableSupervisors() {
+		st, err := s.manager.StatsBetween(supervisorName, time.Time{}, time.Time{})
+		if err != nil {
+			s.logger.Warn("Failed loading stats history", slog.String("supervisor", supervisorName), slog.Any("error", err))
+			continue
+		}
+
+		summary[supervisorName] = StatsSummary{
+			Games:    st.TotalGames(),
+			Drops:    len(st.Drops),
+			Chickens: st.TotalChickens(),
+			Deaths:   st.TotalDeaths(),
+			Errors:   st.TotalErrors(),
+		}
 	}
+
+	return summary
 }
 
 func (s *HttpServer) Listen(port int) error {
@@ -407,6 +500,8 @@ func (s *HttpServer) Listen(port int) error {
 	go s.BroadcastStatus()
 
 	http.HandleFunc("/", s.getRoot)
+	http.HandleFunc("/login", s.login)
+	http.HandleFunc("/logout", s.logout)
 	http.HandleFunc("/config", s.config)
 	http.HandleFunc("/supervisorSettings", s.characterSettings)
 	http.HandleFunc("/start", s.startSupervisor)
@@ -414,36 +509,68 @@ func (s *HttpServer) Listen(port int) error {
 	http.HandleFunc("/togglePause", s.togglePause)
 	http.HandleFunc("/debug", s.debugHandler)
 	http.HandleFunc("/debug-data", s.debugData)
+	http.HandleFunc("GET /api/debug/map", s.debugMap)
 	http.HandleFunc("/drops", s.drops)
+	http.HandleFunc("GET /drops/export", s.exportDrops)
 	http.HandleFunc("/process-list", s.getProcessList)
 	http.HandleFunc("/attach-process", s.attachProcess)
 	http.HandleFunc("/ws", s.wsServer.HandleWebSocket)    // Web socket
 	http.HandleFunc("/initial-data", s.initialData)       // Web socket data
 	http.HandleFunc("/api/reload-config", s.reloadConfig) // New handler
+	http.HandleFunc("GET /metrics", s.prometheusMetrics)
+	if s.hub != nil {
+		http.Handle("/hub/ws", s.hub)
+	}
+	s.registerAPIv1(http.DefaultServeMux)
 
 	assets, _ := fs.Sub(assetsFS, "assets")
 	http.Handle("/assets/", http.StripPrefix("/assets/", http.FileServer(http.FS(assets))))
 
 	s.server = &http.Server{
-		Addr: fmt.Sprintf(":%d", port),
+		Addr:    fmt.Sprintf(":%d", port),
+		Handler: s.auth.middleware(http.DefaultServeMux),
+	}
+
+	if config.Koolo.Server.Auth.Enabled && config.Koolo.Server.Auth.PasswordHash == "" {
+		s.logger.Warn("Web UI authentication is enabled but there is no password set, only API tokens will be accepted")
 	}
 
-	if err := s.server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+	var err error
+	if config.Koolo.TLSEnabled() {
+		s.logger.Info("Serving web UI over HTTPS", slog.Int("port", port))
+		err = s.server.ListenAndServeTLS(config.Koolo.Server.TLS.CertFile, config.Koolo.Server.TLS.KeyFile)
+	} else {
+		err = s.server.ListenAndServe()
+	}
+	if err != nil && !errors.Is(err, http.ErrServerClosed) {
 		return err
 	}
 
 	return nil
 }
 
+func (s *HttpServer) prometheusMetrics(w http.ResponseWriter, r *http.Request) {
+	statuses := make(map[string]string)
+	for _, name := range s.manager.AvailableSupervisors() {
+		statuses[name] = string(s.manager.GetSupervisorStats(name).SupervisorStatus)
+	}
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	if err := s.metrics.Write(w, statuses, s.manager.EventMetrics()); err != nil {
+		s.logger.Warn("Failed writing metrics", slog.Any("error", err))
+	}
+}
+
 func (s *HttpServer) reloadConfig(w http.ResponseWriter, r *http.Request) {
-	result := s.manager.ReloadConfig()
-	if result != nil {
-		http.Error(w, result.Error(), http.StatusInternalServerError)
+	report, err := s.manager.ReloadConfig(bot.ReloadOptions{KeepRules: r.URL.Query().Get("keepRules") == "true"})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
 	s.logger.Info("Config reloaded")
-	w.WriteHeader(http.StatusOK)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(report)
 }
 
 func (s *HttpServer) Stop() error {
@@ -495,23 +622,96 @@ func (s *HttpServer) debugData(w http.ResponseWriter, r *http.Request) {
 	w.Write(jsonData)
 }
 
+// debugMap renders what the path finder sees, usage: /api/debug/map?characterName=x&format=png|svg|json&scale=2&x=&y=
+// The destination is optional, without it only the current area is rendered. The json format is the scene file
+// accepted by "koolo.exe render-map"
+func (s *HttpServer) debugMap(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	context := s.manager.GetContext(q.Get("characterName"))
+	if context == nil || context.PathFinder == nil {
+		http.Error(w, "Supervisor is not running", http.StatusNotFound)
+		return
+	}
+
+	to := context.Data.PlayerUnit.Position
+	if q.Has("x") || q.Has("y") {
+		x, errX := strconv.Atoi(q.Get("x"))
+		y, errY := strconv.Atoi(q.Get("y"))
+		if errX != nil || errY != nil {
+			http.Error(w, "x and y must be numbers", http.StatusBadRequest)
+			return
+		}
+		to = data.Position{X: x, Y: y}
+	}
+
+	scale := 1
+	if q.Has("scale") {
+		var err error
+		if scale, err = strconv.Atoi(q.Get("scale")); err != nil || scale < maprender.MinScale || scale > maprender.MaxScale {
+			http.Error(w, fmt.Sprintf("scale must be between %d and %d", maprender.MinScale, maprender.MaxScale), http.StatusBadRequest)
+			return
+		}
+	}
+
+	scene, found := context.PathFinder.DebugScene(to)
+	if !found {
+		http.Error(w, "Destination is not in the current or an adjacent area", http.StatusNotFound)
+		return
+	}
+
+	format := strings.ToLower(q.Get("format"))
+	switch format {
+	case "json":
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Content-Disposition", `attachment; filename="scene.json"`)
+		json.NewEncoder(w).Encode(scene)
+		return
+	case maprender.FormatSVG:
+		w.Header().Set("Content-Type", "image/svg+xml")
+	case "", maprender.FormatPNG:
+		format = maprender.FormatPNG
+		w.Header().Set("Content-Type", "image/png")
+	default:
+		http.Error(w, "format must be png, svg or json", http.StatusBadRequest)
+		return
+	}
+
+	// Rendered in memory first, so errors can still be reported with the right status
+	var buf bytes.Buffer
+	if err := maprender.Render(&buf, scene, format, scale); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Write(buf.Bytes())
+}
+
 func (s *HttpServer) debugHandler(w http.ResponseWriter, r *http.Request) {
 	s.templates.ExecuteTemplate(w, "debug.gohtml", nil)
 }
 
 func (s *HttpServer) startSupervisor(w http.ResponseWriter, r *http.Request) {
-	supervisorList := s.manager.AvailableSupervisors()
 	Supervisor := r.URL.Query().Get("characterName")
+	if err := s.canStartSupervisor(Supervisor); err != nil {
+		return
+	}
 
+	s.manager.Start(Supervisor, false)
+	s.initialData(w, r)
+}
+
+var errTokenAuthStarting = errors.New("another client using token auth is starting, try again later")
+
+// canStartSupervisor checks if the supervisor can be started right now
+func (s *HttpServer) canStartSupervisor(Supervisor string) error {
 	// Get the current auth method for the supervisor we wanna start
 	supCfg, currFound := config.Characters[Supervisor]
 	if !currFound {
 		// There's no config for the current supervisor. THIS SHOULDN'T HAPPEN
-		return
+		return fmt.Errorf("supervisor %s not found", Supervisor)
 	}
 
 	// Prevent launching of other clients while there's a client with TokenAuth still starting
-	for _, sup := range supervisorList {
+	for _, sup := range s.manager.AvailableSupervisors() {
 
 		// If the current don't check against the one we're trying to launch
 		if sup == Supervisor {
@@ -522,21 +722,20 @@ func (s *HttpServer) startSupervisor(w http.ResponseWriter, r *http.Request) {
 
 			// Prevent launching if we're using token auth & another client is starting (no matter what auth method)
 			if supCfg.AuthMethod == "TokenAuth" {
-				return
+				return errTokenAuthStarting
 			}
 
 			// Prevent launching if another client that is using token auth is starting
 			sCfg, found := config.Characters[sup]
 			if found {
 				if sCfg.AuthMethod == "TokenAuth" {
-					return
+					return errTokenAuthStarting
 				}
 			}
 		}
 	}
 
-	s.manager.Start(Supervisor, false)
-	s.initialData(w, r)
+	return nil
 }
 
 func (s *HttpServer) stopSupervisor(w http.ResponseWriter, r *http.Request) {
@@ -569,32 +768,11 @@ func (s *HttpServer) index(w http.ResponseWriter) {
 	}
 
 	s.templates.ExecuteTemplate(w, "index.gohtml", IndexData{
-		Version:   config.Version,
-		Status:    status,
-		DropCount: drops,
-	})
-}
-
-func (s *HttpServer) drops(w http.ResponseWriter, r *http.Request) {
-	sup := r.URL.Query().Get("supervisor")
-	cfg, found := config.Characters[sup]
-	if !found {
-		http.Error(w, "Can't fetch drop data because the configuration "+sup+" wasn't found", http.StatusNotFound)
-		return
-	}
-
-	var Drops []data.Drop
-
-	if s.manager.GetSupervisorStats(sup).Drops == nil {
-		Drops = make([]data.Drop, 0)
-	} else {
-		Drops = s.manager.GetSupervisorStats(sup).Drops
-	}
-
-	s.templates.ExecuteTemplate(w, "drops.gohtml", DropData{
-		NumberOfDrops: len(Drops),
-		Character:     cfg.CharacterName,
-		Drops:         Drops,
+		Version:     config.Version,
+		Status:      status,
+		DropCount:   drops,
+		Lifetime:    s.getLifetimeSummary(),
+		AuthEnabled: config.Koolo.Server.Auth.Enabled,
 	})
 }
 
@@ -624,9 +802,77 @@ func validateSchedulerData(cfg *config.CharacterCfg) error {
 		}
 	}
 
+	if _, err := cfg.Scheduler.Plan(""); err != nil {
+		return fmt.Errorf("invalid scheduler: %w", err)
+	}
+
 	return nil
 }
 
+// parseCronWindows parses one cron window per line, the cron expression followed by the duration in minutes,
+// for example "30 18 * * 1-5 180"
+func parseCronWindows(text string) ([]config.CronWindow, error) {
+	windows := make([]config.CronWindow, 0)
+	for _, line := range strings.Split(text, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		if len(fields) < 2 {
+			return nil, fmt.Errorf("invalid cron line %q, expected cron expression followed by duration in minutes", line)
+		}
+
+		duration, err := strconv.Atoi(fields[len(fields)-1])
+		if err != nil {
+			return nil, fmt.Errorf("invalid duration in cron line %q", line)
+		}
+
+		windows = append(windows, config.CronWindow{
+			Expression:      strings.Join(fields[:len(fields)-1], " "),
+			DurationMinutes: duration,
+		})
+	}
+
+	return windows, nil
+}
+
+func formatCronWindows(windows []config.CronWindow) string {
+	lines := make([]string, 0, len(windows))
+	for _, w := range windows {
+		lines = append(lines, fmt.Sprintf("%s %d", w.Expression, w.DurationMinutes))
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// parseChannelsPerKey parses one "key=channel ID" pair per line, when allowedKeys is set keys are matched case
+// insensitively against it
+func parseChannelsPerKey(text string, allowedKeys []string) (map[string]string, error) {
+	channels := make(map[string]string)
+	for _, line := range strings.Split(text, "\n") {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+
+		key, channel, found := strings.Cut(line, "=")
+		key, channel = strings.TrimSpace(key), strings.TrimSpace(channel)
+		if !found || key == "" || channel == "" {
+			return nil, fmt.Errorf("invalid line %q, expected key=channel ID", line)
+		}
+
+		if allowedKeys != nil {
+			idx := slices.IndexFunc(allowedKeys, func(k string) bool { return strings.EqualFold(k, key) })
+			if idx < 0 {
+				return nil, fmt.Errorf("unknown %q, allowed values: %s", key, strings.Join(allowedKeys, ", "))
+			}
+			key = allowedKeys[idx]
+		}
+		channels[key] = channel
+	}
+
+	return channels, nil
+}
+
 func (s *HttpServer) config(w http.ResponseWriter, r *http.Request) {
 	if r.Method == http.MethodPost {
 		err := r.ParseForm()
@@ -663,6 +909,19 @@ func (s *HttpServer) config(w http.ResponseWriter, r *http.Request) {
 		newConfig.Discord.BotAdmins = strings.Split(cleanedAdmins, ",")
 		newConfig.Discord.Token = r.Form.Get("discord_token")
 		newConfig.Discord.ChannelID = r.Form.Get("discord_channel_id")
+		newConfig.Discord.GuildID = strings.TrimSpace(r.Form.Get("discord_guild_id"))
+		newConfig.Discord.DropChannels.Enabled = r.Form.Get("discord_drop_channels_enabled") == "true"
+		newConfig.Discord.DropChannels.ChannelID = strings.TrimSpace(r.Form.Get("discord_drop_channel_id"))
+		newConfig.Discord.DropChannels.Supervisors, err = parseChannelsPerKey(r.Form.Get("discord_drop_supervisor_channels"), nil)
+		if err != nil {
+			s.templates.ExecuteTemplate(w, "config.gohtml", ConfigData{KooloCfg: &newConfig, ErrorMessage: "Invalid supervisor drop channels: " + err.Error()})
+			return
+		}
+		newConfig.Discord.DropChannels.Qualities, err = parseChannelsPerKey(r.Form.Get("discord_drop_quality_channels"), itemQualities)
+		if err != nil {
+			s.templates.ExecuteTemplate(w, "config.gohtml", ConfigData{KooloCfg: &newConfig, ErrorMessage: "Invalid quality drop channels: " + err.Error()})
+			return
+		}
 		// Telegram
 		newConfig.Telegram.Enabled = r.Form.Get("telegram_enabled") == "true"
 		newConfig.Telegram.Token = r.Form.Get("telegram_token")
@@ -672,6 +931,43 @@ func (s *HttpServer) config(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 		newConfig.Telegram.ChatID = telegramChatId
+		// Telegram admins who can use bot commands
+		newConfig.Telegram.BotAdmins = nil
+		for _, admin := range strings.Split(r.Form.Get("telegram_admins"), ",") {
+			if admin = strings.TrimSpace(admin); admin == "" {
+				continue
+			}
+			adminID, err := strconv.ParseInt(admin, 10, 64)
+			if err != nil {
+				s.templates.ExecuteTemplate(w, "config.gohtml", ConfigData{KooloCfg: &newConfig, ErrorMessage: "Invalid Telegram admin ID: " + admin})
+				return
+			}
+			newConfig.Telegram.BotAdmins = append(newConfig.Telegram.BotAdmins, adminID)
+		}
+		// Multiple instances
+		newConfig.Hub.Enabled = r.Form.Get("hub_enabled") == "true"
+		newConfig.Hub.URL = strings.TrimSpace(r.Form.Get("hub_url"))
+		newConfig.Hub.Token = r.Form.Get("hub_token")
+		newConfig.Hub.Instance = strings.TrimSpace(r.Form.Get("hub_instance"))
+		// Web UI security
+		newConfig.Server.Auth.Enabled = r.Form.Get("auth_enabled") == "true"
+		newConfig.Server.Auth.TrustLocalhost = r.Form.Get("auth_trust_localhost") == "true"
+		newConfig.Server.Auth.Username = strings.TrimSpace(r.Form.Get("auth_username"))
+		// Empty password means keep the current one
+		if password := r.Form.Get("auth_password"); password != "" {
+			newConfig.Server.Auth.PasswordHash, err = hashPassword(password)
+			if err != nil {
+				s.templates.ExecuteTemplate(w, "config.gohtml", ConfigData{KooloCfg: &newConfig, ErrorMessage: "Error hashing password"})
+				return
+			}
+		}
+		if newConfig.Server.Auth.Enabled && (newConfig.Server.Auth.Username == "" || newConfig.Server.Auth.PasswordHash == "") {
+			s.templates.ExecuteTemplate(w, "config.gohtml", ConfigData{KooloCfg: &newConfig, ErrorMessage: "Username and password are required to enable authentication"})
+			return
+		}
+		newConfig.Server.TLS.CertFile = r.Form.Get("tls_cert_file")
+		newConfig.Server.TLS.KeyFile = r.Form.Get("tls_key_file")
+		credentialsChanged := newConfig.Server.Auth.Username != config.Koolo.Server.Auth.Username || newConfig.Server.Auth.PasswordHash != config.Koolo.Server.Auth.PasswordHash
 
 		err = config.ValidateAndSaveConfig(newConfig)
 		if err != nil {
@@ -679,6 +975,10 @@ func (s *HttpServer) config(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
+		if credentialsChanged {
+			s.auth.clearSessions()
+		}
+
 		http.Redirect(w, r, "/", http.StatusSeeOther)
 		return
 	}
@@ -713,6 +1013,9 @@ func (s *HttpServer) characterSettings(w http.ResponseWriter, r *http.Request) {
 			cfg = config.Characters["template"]
 		}
 
+		// Form values are applied to a copy, so an invalid submission doesn't leave a half updated config
+		cfg = cfg.Clone()
+
 		cfg.MaxGameLength, _ = strconv.Atoi(r.Form.Get("maxGameLength"))
 		cfg.CharacterName = r.Form.Get("characterName")
 		cfg.CommandLineArgs = r.Form.Get("commandLineArgs")
@@ -766,8 +1069,22 @@ func (s *HttpServer) characterSettings(w http.ResponseWriter, r *http.Request) {
 			}
 		}
 
+		cfg.Scheduler.Cron, err = parseCronWindows(r.Form.Get("schedulerCron"))
+		if err != nil {
+			s.templates.ExecuteTemplate(w, "character_settings.gohtml", CharacterSettings{
+				ErrorMessage: err.Error(),
+			})
+			return
+		}
+		cfg.Scheduler.StartJitterMinutes, _ = strconv.Atoi(r.Form.Get("schedulerStartJitterMinutes"))
+		cfg.Scheduler.StopJitterMinutes, _ = strconv.Atoi(r.Form.Get("schedulerStopJitterMinutes"))
+		cfg.Scheduler.PlayMinutesBeforeBreak, _ = strconv.Atoi(r.Form.Get("schedulerPlayMinutesBeforeBreak"))
+		cfg.Scheduler.BreakMinutes, _ = strconv.Atoi(r.Form.Get("schedulerBreakMinutes"))
+		cfg.Scheduler.DailyLimitMinutes, _ = strconv.Atoi(r.Form.Get("schedulerDailyLimitMinutes"))
+		cfg.Scheduler.WeeklyLimitMinutes, _ = strconv.Atoi(r.Form.Get("schedulerWeeklyLimitMinutes"))
+
 		// Validate scheduler data
-		err := validateSchedulerData(cfg)
+		err = validateSchedulerData(cfg)
 		if err != nil {
 			s.templates.ExecuteTemplate(w, "character_settings.gohtml", CharacterSettings{
 				ErrorMessage: err.Error(),
@@ -791,6 +1108,7 @@ func (s *HttpServer) characterSettings(w http.ResponseWriter, r *http.Request) {
 		cfg.Character.Class = r.Form.Get("characterClass")
 		cfg.Character.StashToShared = r.Form.Has("characterStashToShared")
 		cfg.Character.UseTeleport = r.Form.Has("characterUseTeleport")
+		cfg.Character.AvoidDangerousMonsters = r.Form.Has("characterAvoidDangerousMonsters")
 
 		// Berserker Barb specific options
 		if cfg.Character.Class == "berserker" {
@@ -855,6 +1173,11 @@ func (s *HttpServer) characterSettings(w http.ResponseWriter, r *http.Request) {
 		cfg.Game.UseCainIdentify = r.Form.Has("useCainIdentify")
 		cfg.Game.Difficulty = difficulty.Difficulty(r.Form.Get("gameDifficulty"))
 		cfg.Game.RandomizeRuns = r.Form.Has("gameRandomizeRuns")
+		cfg.Game.AdaptiveRuns.Enabled = r.Form.Has("gameAdaptiveRunsEnabled")
+		cfg.Game.AdaptiveRuns.Window, _ = strconv.Atoi(r.Form.Get("gameAdaptiveRunsWindow"))
+		cfg.Game.AdaptiveRuns.MinRuns, _ = strconv.Atoi(r.Form.Get("gameAdaptiveRunsMinRuns"))
+		cfg.Game.AdaptiveRuns.MaxFailureRate, _ = strconv.Atoi(r.Form.Get("gameAdaptiveRunsMaxFailureRate"))
+		cfg.Game.AdaptiveRuns.CooldownMinutes, _ = strconv.Atoi(r.Form.Get("gameAdaptiveRunsCooldownMinutes"))
 
 		// Runs specific config
 		enabledRuns := make([]config.Run, 0)
@@ -963,7 +1286,10 @@ func (s *HttpServer) characterSettings(w http.ResponseWriter, r *http.Request) {
 		// Companion
 
 		// Companion config
+		cfg.Companion.Enabled = r.Form.Has("companionEnabled")
 		cfg.Companion.Leader = r.Form.Has("companionLeader")
+		cfg.Companion.Attack = r.Form.Has("companionAttack")
+		cfg.Companion.FollowLeader = r.Form.Has("companionFollowLeader")
 		cfg.Companion.LeaderName = r.Form.Get("companionLeaderName")
 		cfg.Companion.GameNameTemplate = r.Form.Get("companionGameNameTemplate")
 		cfg.Companion.GamePassword = r.Form.Get("companionGamePassword")
@@ -974,7 +1300,23 @@ func (s *HttpServer) characterSettings(w http.ResponseWriter, r *http.Request) {
 		cfg.BackToTown.MercDied = r.Form.Has("mercDied")
 		cfg.BackToTown.EquipmentBroken = r.Form.Has("equipmentBroken")
 
-		config.SaveSupervisorConfig(supervisorName, cfg)
+		cfg.ItemCapture.Enabled = r.Form.Has("itemCaptureEnabled")
+		cfg.ItemCapture.MaxSizeMB, _ = strconv.Atoi(r.Form.Get("itemCaptureMaxSizeMB"))
+
+		if errs := cfg.Check(); len(errs) > 0 {
+			s.renderCharacterSettings(w, supervisorName, cfg, errs)
+			return
+		}
+
+		if err = config.SaveSupervisorConfig(supervisorName, cfg); err != nil {
+			s.renderCharacterSettings(w, supervisorName, cfg, config.ValidationErrors{{Message: err.Error()}})
+			return
+		}
+
+		// Changes were made on a copy, running supervisors need the new config
+		if _, err = s.manager.ReloadConfig(bot.ReloadOptions{}); err != nil {
+			s.logger.Error("Error applying the new config to running supervisors", slog.Any("error", err))
+		}
 		http.Redirect(w, r, "/", http.StatusSeeOther)
 		return
 	}
@@ -985,6 +1327,10 @@ func (s *HttpServer) characterSettings(w http.ResponseWriter, r *http.Request) {
 		cfg = config.Characters[supervisor]
 	}
 
+	s.renderCharacterSettings(w, supervisor, cfg, nil)
+}
+
+func (s *HttpServer) renderCharacterSettings(w http.ResponseWriter, supervisor string, cfg *config.CharacterCfg, errs config.ValidationErrors) {
 	enabledRuns := make([]string, 0)
 	// Let's iterate cfg.Game.Runs to preserve current order
 	for _, run := range cfg.Game.Runs {
@@ -996,6 +1342,11 @@ func (s *HttpServer) characterSettings(w http.ResponseWriter, r *http.Request) {
 			disabledRuns = append(disabledRuns, string(run))
 		}
 	}
+	for run := range cfg.Runtime.RunScripts {
+		if !slices.Contains(cfg.Game.Runs, run) {
+			disabledRuns = append(disabledRuns, string(run))
+		}
+	}
 	sort.Strings(disabledRuns)
 
 	availableTZs := make(map[int]string)
@@ -1018,9 +1369,11 @@ func (s *HttpServer) characterSettings(w http.ResponseWriter, r *http.Request) {
 		Supervisor:   supervisor,
 		Config:       cfg,
 		DayNames:     dayNames,
+		CronWindows:  formatCronWindows(cfg.Scheduler.Cron),
 		EnabledRuns:  enabledRuns,
 		DisabledRuns: disabledRuns,
 		AvailableTZs: availableTZs,
 		RecipeList:   config.AvailableRecipes,
+		Errors:       errs,
 	})
 }
@@ -1,9 +1,13 @@
 package server
 
 import (
-	"github.com/hectorgimenez/d2go/pkg/data"
+	"time"
+
+	"github.com/hectorgimenez/koolo/internal/analytics"
 	"github.com/hectorgimenez/koolo/internal/bot"
 	"github.com/hectorgimenez/koolo/internal/config"
+	"github.com/hectorgimenez/koolo/internal/ledger"
+	"github.com/hectorgimenez/koolo/internal/remote/hub"
 )
 
 type IndexData struct {
@@ -11,12 +15,54 @@ type IndexData struct {
 	Version      string
 	Status       map[string]bot.Stats
 	DropCount    map[string]int
+	Lifetime     map[string]StatsSummary
+	AuthEnabled  bool
+	Schedule     map[string]ScheduleInfo
+	Rotation     map[string]RotationInfo
+	// Supervisors of the instances connected to this one, only when the hub is enabled
+	Remote []hub.RemoteSupervisor
+}
+
+// ScheduleInfo is the scheduler plan for a supervisor, next start and stop are nil when unknown
+type ScheduleInfo struct {
+	ShouldRun bool
+	Reason    string
+	NextStart *time.Time
+	NextStop  *time.Time
+}
+
+// RotationInfo is the adaptive runs state of a supervisor, only the last decisions are included
+type RotationInfo struct {
+	Dropped   map[string]time.Time
+	Decisions []analytics.Decision
+}
+
+// StatsSummary contains the totals shown in the dashboard for the whole stats history
+type StatsSummary struct {
+	Games    int
+	Drops    int
+	Chickens int
+	Deaths   int
+	Errors   int
 }
 
 type DropData struct {
+	ErrorMessage  string
 	NumberOfDrops int
 	Character     string
-	Drops         []data.Drop
+	Supervisors   []string
+	Qualities     []string
+	// Current filters
+	Supervisor string
+	Quality    string
+	Name       string
+	RuleFile   string
+	From       string
+	To         string
+	Session    bool
+	ExportCSV  string
+	ExportJSON string
+	Drops      []ledger.Entry
 }
 
 type CharacterSettings struct {
@@ -24,10 +70,17 @@ type CharacterSettings struct {
 	Supervisor   string
 	Config       *config.CharacterCfg
 	DayNames     []string
+	CronWindows  string
 	EnabledRuns  []string
 	DisabledRuns []string
 	AvailableTZs map[int]string
 	RecipeList   []string
+	Errors       config.ValidationErrors
+}
+
+type LoginData struct {
+	ErrorMessage string
+	Next         string
 }
 
 type ConfigData struct {
@@ -0,0 +1,75 @@
+package terrorzone
+
+import (
+	"slices"
+	"time"
+
+	"github.com/hectorgimenez/d2go/pkg/data/area"
+	"github.com/hectorgimenez/koolo/internal/event"
+)
+
+// ZoneSummary contains the results of every visit to a terror zone, durations only take successful runs into account
+type ZoneSummary struct {
+	Zone        area.ID       `json:"zone"`
+	Name        string        `json:"name"`
+	Seen        int           `json:"seen"`
+	Hopped      int           `json:"hopped"`
+	Runs        int           `json:"runs"`
+	Failures    int           `json:"failures"`
+	AvgDuration time.Duration `json:"avgDuration"`
+	Stashed     int           `json:"stashed"`
+	LastSeen    time.Time     `json:"lastSeen"`
+}
+
+// Summarize groups the visits by zone, most seen zones first
+func Summarize(visits []Visit) []ZoneSummary {
+	byZone := make(map[area.ID]*ZoneSummary)
+	okDuration := make(map[area.ID]time.Duration)
+
+	for _, v := range visits {
+		if len(v.Zones) == 0 {
+			continue
+		}
+
+		zone := v.Zones[0]
+		s, found := byZone[zone]
+		if !found {
+			s = &ZoneSummary{Zone: zone, Name: v.ZoneName()}
+			byZone[zone] = s
+		}
+
+		s.Seen++
+		if v.Time.After(s.LastSeen) {
+			s.LastSeen = v.Time
+		}
+		if v.Hopped {
+			s.Hopped++
+		}
+		if v.Ran {
+			s.Runs++
+			if v.Result == event.FinishedOK {
+				okDuration[zone] += v.Duration
+			} else {
+				s.Failures++
+			}
+		}
+		s.Stashed += v.Stashed
+	}
+
+	summaries := make([]ZoneSummary, 0, len(byZone))
+	for zone, s := range byZone {
+		if ok := s.Runs - s.Failures; ok > 0 {
+			s.AvgDuration = okDuration[zone] / time.Duration(ok)
+		}
+		summaries = append(summaries, *s)
+	}
+
+	slices.SortFunc(summaries, func(a, b ZoneSummary) int {
+		if a.Seen != b.Seen {
+			return b.Seen - a.Seen
+		}
+		return int(a.Zone) - int(b.Zone)
+	})
+
+	return summaries
+}
@@ -0,0 +1,225 @@
+// Package terrorzone keeps a history of the terror zones seen by each supervisor and how the terror zone run went
+package terrorzone
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/hectorgimenez/d2go/pkg/data/area"
+	"github.com/hectorgimenez/koolo/internal/config"
+	"github.com/hectorgimenez/koolo/internal/event"
+	"github.com/hectorgimenez/koolo/internal/jsonl"
+)
+
+const fileName = "terror_zones.jsonl"
+
+// Visit is a game where terror zones were detected, the run fields are empty if the terror zone run didn't start
+type Visit struct {
+	Time       time.Time          `json:"time"`
+	Supervisor string             `json:"supervisor"`
+	Game       string             `json:"game"`
+	Zones      []area.ID          `json:"zones"`
+	Hopped     bool               `json:"hopped"`
+	Ran        bool               `json:"ran"`
+	Result     event.FinishReason `json:"result,omitempty"`
+	Duration   time.Duration      `json:"duration,omitempty"`
+	Stashed    int                `json:"stashed,omitempty"`
+	// Stashed items by NIP rule file
+	StashedByRule map[string]int `json:"stashedByRule,omitempty"`
+}
+
+// ZoneName returns the name of the first zone, the one used to identify the terror zone
+func (v Visit) ZoneName() string {
+	if len(v.Zones) == 0 {
+		return ""
+	}
+
+	return v.Zones[0].Area().Name
+}
+
+// Filter selects visits, empty fields match everything
+type Filter struct {
+	Supervisor string
+	Zone       area.ID
+	From       time.Time
+	To         time.Time
+}
+
+func (f Filter) Match(v Visit) bool {
+	if f.Supervisor != "" && v.Supervisor != f.Supervisor {
+		return false
+	}
+	if f.Zone != 0 && !slices.Contains(v.Zones, f.Zone) {
+		return false
+	}
+	if !f.From.IsZero() && v.Time.Before(f.From) {
+		return false
+	}
+	if !f.To.IsZero() && v.Time.After(f.To) {
+		return false
+	}
+
+	return true
+}
+
+// Tracker is an event handler appending a Visit per game with terror zones to {dir}/terror_zones.jsonl. A visit is
+// written when the game finishes, with the result of the terror zone run and the items stashed from it
+type Tracker struct {
+	path string
+
+	mu      sync.Mutex
+	file    *os.File
+	visits  []Visit
+	games   map[string]string
+	pending map[string]*pendingVisit
+}
+
+type pendingVisit struct {
+	Visit
+	runStartedAt time.Time
+}
+
+// NewInMemory returns a tracker that is not written to disk, used when stats are not persisted
+func NewInMemory() *Tracker {
+	return &Tracker{
+		games:   make(map[string]string),
+		pending: make(map[string]*pendingVisit),
+	}
+}
+
+func New(dir string) (*Tracker, error) {
+	if dir == "" {
+		dir = "stats"
+	}
+
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		return nil, fmt.Errorf("error creating terror zone tracker directory: %w", err)
+	}
+
+	t := &Tracker{
+		path:    filepath.Join(dir, fileName),
+		games:   make(map[string]string),
+		pending: make(map[string]*pendingVisit),
+	}
+	if err := t.load(); err != nil {
+		return nil, err
+	}
+
+	f, err := jsonl.OpenAppend(t.path)
+	if err != nil {
+		return nil, fmt.Errorf("error opening terror zone tracker file: %w", err)
+	}
+	t.file = f
+
+	return t, nil
+}
+
+func (t *Tracker) Handle(_ context.Context, e event.Event) error {
+	sup := e.Supervisor()
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	switch evt := e.(type) {
+	case event.GameCreatedEvent:
+		t.games[sup] = evt.Name
+		delete(t.pending, sup)
+	case event.TerrorZoneDetectedEvent:
+		if len(evt.Zones) == 0 {
+			return nil
+		}
+		t.pending[sup] = &pendingVisit{Visit: Visit{
+			Time:       evt.OccurredAt(),
+			Supervisor: sup,
+			Game:       t.games[sup],
+			Zones:      slices.Clone(evt.Zones),
+			Hopped:     evt.Hopped,
+		}}
+	case event.RunStartedEvent:
+		if p := t.pending[sup]; p != nil && evt.RunName == string(config.TerrorZoneRun) {
+			p.Ran = true
+			p.runStartedAt = evt.OccurredAt()
+		}
+	case event.RunFinishedEvent:
+		if p := t.pending[sup]; p != nil && p.Ran && evt.RunName == string(config.TerrorZoneRun) {
+			p.Result = evt.Reason
+			p.Duration = evt.OccurredAt().Sub(p.runStartedAt)
+		}
+	case event.ItemStashedEvent:
+		// Items are stashed in the town visits of the next runs, they are counted until the game finishes
+		if p := t.pending[sup]; p != nil && evt.RunName == string(config.TerrorZoneRun) {
+			if p.StashedByRule == nil {
+				p.StashedByRule = make(map[string]int)
+			}
+			p.Stashed++
+			p.StashedByRule[evt.Item.RuleFile]++
+		}
+	case event.GameFinishedEvent:
+		p := t.pending[sup]
+		if p == nil {
+			return nil
+		}
+		delete(t.pending, sup)
+		// Run interrupted by the game finishing, for example a chicken
+		if p.Ran && p.Result == "" {
+			p.Result = evt.Reason
+			p.Duration = evt.OccurredAt().Sub(p.runStartedAt)
+		}
+
+		return t.append(p.Visit)
+	}
+
+	return nil
+}
+
+// append adds the visit, writing it to disk if the tracker is persisted, mutex must be held
+func (t *Tracker) append(v Visit) error {
+	if t.file != nil {
+		if err := jsonl.Append(t.file, v); err != nil {
+			return fmt.Errorf("error writing terror zone visit: %w", err)
+		}
+	}
+
+	t.visits = append(t.visits, v)
+
+	return nil
+}
+
+// Visits returns the visits matching the filter, newest first
+func (t *Tracker) Visits(f Filter) []Visit {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	result := make([]Visit, 0)
+	for _, v := range slices.Backward(t.visits) {
+		if f.Match(v) {
+			result = append(result, v)
+		}
+	}
+
+	return result
+}
+
+func (t *Tracker) Close() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.file == nil {
+		return nil
+	}
+
+	return t.file.Close()
+}
+
+func (t *Tracker) load() error {
+	if err := jsonl.Read(t.path, func(v Visit) { t.visits = append(t.visits, v) }); err != nil {
+		return fmt.Errorf("error reading terror zone tracker file: %w", err)
+	}
+
+	return nil
+}
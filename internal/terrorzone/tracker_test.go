@@ -0,0 +1,125 @@
+package terrorzone
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/hectorgimenez/d2go/pkg/data"
+	"github.com/hectorgimenez/d2go/pkg/data/area"
+	"github.com/hectorgimenez/koolo/internal/config"
+	"github.com/hectorgimenez/koolo/internal/event"
+)
+
+func send(t *testing.T, tr *Tracker, events ...event.Event) {
+	t.Helper()
+
+	for _, e := range events {
+		if err := tr.Handle(context.Background(), e); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestTrackerVisits(t *testing.T) {
+	dir := t.TempDir()
+	tr, err := New(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tzRun := string(config.TerrorZoneRun)
+	day := time.Date(2024, 12, 1, 10, 0, 0, 0, time.UTC)
+	at := func(minute int) event.BaseEvent {
+		return event.TextAt("sorc", "", day.Add(time.Duration(minute)*time.Minute))
+	}
+	burial := []area.ID{area.BurialGrounds, area.Crypt, area.Mausoleum}
+
+	send(t, tr,
+		// Zone cleared, one item stashed during the next run
+		event.GameCreated(at(0), "tz-1", ""),
+		event.TerrorZoneDetected(at(0), burial, false),
+		event.RunStarted(at(0), tzRun),
+		event.RunFinished(at(4), tzRun, event.FinishedOK),
+		event.RunStarted(at(4), "pindleskin"),
+		event.ItemStashed(at(5), data.Drop{RuleFile: "unique.nip"}, tzRun, time.Time{}),
+		event.ItemStashed(at(5), data.Drop{RuleFile: "runes.nip"}, "pindleskin", time.Time{}),
+		event.GameFinished(at(6), event.FinishedOK),
+		// Chicken in the zone, the run never finished
+		event.GameCreated(at(10), "tz-2", ""),
+		event.TerrorZoneDetected(at(10), burial, false),
+		event.RunStarted(at(10), tzRun),
+		event.GameFinished(at(12), event.FinishedChicken),
+		// Zone not enabled
+		event.GameCreated(at(70), "tz-3", ""),
+		event.TerrorZoneDetected(at(70), []area.ID{area.ChaosSanctuary}, true),
+		event.GameFinished(at(70), event.FinishedOK),
+		// Game without terror zones is not tracked
+		event.GameCreated(at(80), "tz-4", ""),
+		event.GameFinished(at(81), event.FinishedOK),
+	)
+
+	visits := tr.Visits(Filter{})
+	if len(visits) != 3 {
+		t.Fatalf("expected 3 visits, got %+v", visits)
+	}
+
+	chaos, chicken, cleared := visits[0], visits[1], visits[2]
+	if !chaos.Hopped || chaos.Ran || chaos.Game != "tz-3" || chaos.ZoneName() != "Chaos Sanctum" {
+		t.Errorf("unexpected hopped visit %+v", chaos)
+	}
+	if !chicken.Ran || chicken.Result != event.FinishedChicken || chicken.Duration != 2*time.Minute {
+		t.Errorf("unexpected chicken visit %+v", chicken)
+	}
+	if cleared.Result != event.FinishedOK || cleared.Duration != 4*time.Minute || cleared.Stashed != 1 || cleared.StashedByRule["unique.nip"] != 1 {
+		t.Errorf("unexpected cleared visit %+v", cleared)
+	}
+
+	if res := tr.Visits(Filter{Zone: area.Crypt}); len(res) != 2 {
+		t.Errorf("zone filter: expected 2 visits, got %d", len(res))
+	}
+	if res := tr.Visits(Filter{Supervisor: "pala"}); len(res) != 0 {
+		t.Errorf("supervisor filter: expected no visits, got %d", len(res))
+	}
+
+	summaries := Summarize(visits)
+	if len(summaries) != 2 || summaries[0].Zone != area.BurialGrounds {
+		t.Fatalf("unexpected summaries %+v", summaries)
+	}
+	if s := summaries[0]; s.Seen != 2 || s.Runs != 2 || s.Failures != 1 || s.AvgDuration != 4*time.Minute || s.Stashed != 1 {
+		t.Errorf("unexpected burial grounds summary %+v", s)
+	}
+	if s := summaries[1]; s.Hopped != 1 || s.Runs != 0 {
+		t.Errorf("unexpected chaos summary %+v", s)
+	}
+
+	// History is loaded back from disk
+	if err = tr.Close(); err != nil {
+		t.Fatal(err)
+	}
+	reopened, err := New(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reopened.Close()
+	if res := reopened.Visits(Filter{}); len(res) != 3 || res[2].StashedByRule["unique.nip"] != 1 {
+		t.Errorf("expected 3 visits after reopening, got %+v", res)
+	}
+}
+
+func TestInMemoryTracker(t *testing.T) {
+	tr := NewInMemory()
+	day := time.Date(2024, 12, 1, 10, 0, 0, 0, time.UTC)
+	send(t, tr,
+		event.GameCreated(event.TextAt("sorc", "", day), "tz-1", ""),
+		event.TerrorZoneDetected(event.TextAt("sorc", "", day), []area.ID{area.BloodMoor}, false),
+		event.GameFinished(event.TextAt("sorc", "", day.Add(time.Minute)), event.FinishedOK),
+	)
+
+	if visits := tr.Visits(Filter{}); len(visits) != 1 || visits[0].Game != "tz-1" {
+		t.Errorf("unexpected visits %+v", visits)
+	}
+	if err := tr.Close(); err != nil {
+		t.Errorf("closing an in memory tracker should not fail: %v", err)
+	}
+}